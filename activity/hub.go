@@ -0,0 +1,117 @@
+package activity
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	commonutils "github.com/primarybank/common/utils"
+	db "github.com/primarybank/db/sqlc"
+)
+
+// delays between attempts to listen again after the listening connection failed
+const (
+	listenRetryBaseDelay = time.Second
+	listenRetryMaxDelay  = time.Minute
+)
+
+// subscriptions buffer this many notifications, further ones are dropped until the subscriber catches up
+const subscriptionBuffer = 16
+
+// Hub shares one listening connection between every stream of account activity.
+// A notification only wakes the subscribers of its account up, they read what changed from the store,
+// so a dropped notification is never more than a delay. Notifications sent while the connection was down
+// are lost, so every subscriber is woken up once the hub listens again.
+type Hub struct {
+	store db.Store
+
+	mu          sync.Mutex
+	subscribers map[int64]map[chan db.AccountActivity]struct{}
+}
+
+func NewHub(store db.Store) *Hub {
+	return &Hub{
+		store:       store,
+		subscribers: make(map[int64]map[chan db.AccountActivity]struct{}),
+	}
+}
+
+// Subscribe returns the notifications about an account until the returned cancel func is called
+func (h *Hub) Subscribe(accountID int64) (<-chan db.AccountActivity, func()) {
+	ch := make(chan db.AccountActivity, subscriptionBuffer)
+
+	h.mu.Lock()
+	if h.subscribers[accountID] == nil {
+		h.subscribers[accountID] = make(map[chan db.AccountActivity]struct{})
+	}
+	h.subscribers[accountID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	cancel := func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		delete(h.subscribers[accountID], ch)
+		if len(h.subscribers[accountID]) == 0 {
+			delete(h.subscribers, accountID)
+		}
+	}
+	return ch, cancel
+}
+
+// Publish hands a notification to the subscribers of its account without waiting on any of them
+func (h *Hub) Publish(activity db.AccountActivity) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.subscribers[activity.AccountID] {
+		select {
+		case ch <- activity:
+		default:
+		}
+	}
+}
+
+// Resync wakes every subscriber up, without an entry id, to read what changed since the last entry it saw
+func (h *Hub) Resync() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for accountID, subscribers := range h.subscribers {
+		for ch := range subscribers {
+			select {
+			case ch <- db.AccountActivity{AccountID: accountID}:
+			default:
+			}
+		}
+	}
+}
+
+// Run listens for account activity until ctx is done, listening again whenever the connection fails
+func (h *Hub) Run(ctx context.Context) {
+	var failures int32
+
+	for {
+		started := time.Now()
+		err := h.store.ListenAccountActivity(ctx, h.Resync, h.Publish)
+		if ctx.Err() != nil {
+			return
+		}
+
+		// a connection that held up for a while starts over with the shortest delay
+		if time.Since(started) > listenRetryMaxDelay {
+			failures = 0
+		}
+		failures++
+
+		delay := commonutils.Backoff(listenRetryBaseDelay, listenRetryMaxDelay, failures)
+		log.Printf("cannot listen for account activity, retrying in %s: %v", delay, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package activity
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/primarybank/db/mocks"
+	db "github.com/primarybank/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHubPublish(t *testing.T) {
+	hub := NewHub(nil)
+
+	first, cancelFirst := hub.Subscribe(1)
+	second, cancelSecond := hub.Subscribe(1)
+	other, cancelOther := hub.Subscribe(2)
+	defer cancelFirst()
+	defer cancelOther()
+
+	hub.Publish(db.AccountActivity{AccountID: 1, EntryID: 10})
+	require.Equal(t, db.AccountActivity{AccountID: 1, EntryID: 10}, <-first)
+	require.Equal(t, db.AccountActivity{AccountID: 1, EntryID: 10}, <-second)
+	require.Empty(t, other)
+
+	// cancelled subscriptions are not woken up anymore
+	cancelSecond()
+	hub.Publish(db.AccountActivity{AccountID: 1, EntryID: 11})
+	require.Equal(t, int64(11), (<-first).EntryID)
+	require.Empty(t, second)
+}
+
+func TestHubPublishSlowSubscriber(t *testing.T) {
+	hub := NewHub(nil)
+
+	ch, cancel := hub.Subscribe(1)
+	defer cancel()
+
+	// a subscriber that doesn't keep up doesn't hold the others back
+	for i := 0; i < subscriptionBuffer*2; i++ {
+		hub.Publish(db.AccountActivity{AccountID: 1, EntryID: int64(i)})
+	}
+	require.Len(t, ch, subscriptionBuffer)
+}
+
+func TestHubRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockStore(ctrl)
+	hub := NewHub(store)
+
+	ch, cancel := hub.Subscribe(1)
+	defer cancel()
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+
+	gomock.InOrder(
+		store.EXPECT().
+			ListenAccountActivity(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(errors.New("connection reset")).
+			Times(1),
+		store.EXPECT().
+			ListenAccountActivity(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, _ func(), handle func(db.AccountActivity)) error {
+				handle(db.AccountActivity{AccountID: 1, EntryID: 5})
+				<-ctx.Done()
+				return ctx.Err()
+			}).
+			Times(1),
+	)
+
+	done := make(chan struct{})
+	go func() {
+		hub.Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case activity := <-ch:
+		require.Equal(t, int64(5), activity.EntryID)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no activity after the listener failed once")
+	}
+
+	stop()
+	<-done
+}
+
+func TestHubRunResync(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockStore(ctrl)
+	hub := NewHub(store)
+
+	first, cancelFirst := hub.Subscribe(1)
+	defer cancelFirst()
+	second, cancelSecond := hub.Subscribe(2)
+	defer cancelSecond()
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+
+	gomock.InOrder(
+		store.EXPECT().
+			ListenAccountActivity(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, listening func(), handle func(db.AccountActivity)) error {
+				listening()
+				handle(db.AccountActivity{AccountID: 1, EntryID: 5})
+				return errors.New("connection reset")
+			}).
+			Times(1),
+		store.EXPECT().
+			ListenAccountActivity(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, listening func(), handle func(db.AccountActivity)) error {
+				listening()
+				<-ctx.Done()
+				return ctx.Err()
+			}).
+			Times(1),
+	)
+
+	done := make(chan struct{})
+	go func() {
+		hub.Run(ctx)
+		close(done)
+	}()
+
+	receive := func(ch <-chan db.AccountActivity) db.AccountActivity {
+		select {
+		case activity := <-ch:
+			return activity
+		case <-time.After(5 * time.Second):
+			t.Fatal("no activity")
+			return db.AccountActivity{}
+		}
+	}
+
+	// the first listen wakes everyone up, then the notification comes through
+	require.Equal(t, db.AccountActivity{AccountID: 1}, receive(first))
+	require.Equal(t, db.AccountActivity{AccountID: 2}, receive(second))
+	require.Equal(t, db.AccountActivity{AccountID: 1, EntryID: 5}, receive(first))
+
+	// what was booked while the connection was down was never announced, every subscriber reads it again
+	require.Equal(t, db.AccountActivity{AccountID: 1}, receive(first))
+	require.Equal(t, db.AccountActivity{AccountID: 2}, receive(second))
+
+	stop()
+	<-done
+}
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	commonerrors "github.com/primarybank/common/errors"
+	db "github.com/primarybank/db/sqlc"
+	"github.com/primarybank/token"
+)
+
+// LastEventIDHeader is sent by clients reconnecting to an event stream, with the id of the last event they saw
+const LastEventIDHeader = "Last-Event-ID"
+
+// names of the server-sent events of an account
+const (
+	accountEventEntry   = "entry"
+	accountEventBalance = "balance"
+)
+
+const (
+	// proxies drop connections that stay silent for too long
+	accountEventsHeartbeat = 15 * time.Second
+	accountEventsBatch     = 100
+)
+
+// StreamAccountEvents streams the new entries and the balance of an account of the authenticated user
+// as server-sent events. Entry events carry the entry id, a client reconnecting with a Last-Event-ID header
+// first gets the entries it missed.
+func (s *Server) StreamAccountEvents(ctx *gin.Context) {
+	var req AccountURIRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	lastEntryID, err := parseLastEventID(ctx.GetHeader(LastEventIDHeader))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	payload := ctx.MustGet(AuthzPayloadKey).(*token.Payload)
+
+	account, err := s.store.GetAccount(ctx.Request.Context(), req.ID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	// the accounts of other users are hidden as if they didn't exist
+	if account.Owner != payload.Username {
+		ctx.Error(db.ErrNotFound)
+		return
+	}
+
+	// subscribe before catching up so that nothing booked in between is missed
+	activity, unsubscribe := s.Activity.Subscribe(account.ID)
+	defer unsubscribe()
+
+	// a fresh stream starts after the latest entry, only a resumed one replays entries
+	if lastEntryID == 0 {
+		last, err := s.store.GetLastAccountEntry(ctx.Request.Context(), account.ID)
+		switch {
+		case err == nil:
+			lastEntryID = last.ID
+		case !errors.Is(err, db.ErrNotFound):
+			ctx.Error(err)
+			return
+		}
+	}
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no")
+
+	lastEntryID, err = s.sendAccountEvents(ctx, account.ID, lastEntryID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	heartbeat := time.NewTicker(accountEventsHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := ctx.Writer.WriteString(": heartbeat\n\n"); err != nil {
+				return
+			}
+			ctx.Writer.Flush()
+		case <-activity:
+			// errors past this point can't be reported, the client resumes from the last entry it got
+			lastEntryID, err = s.sendAccountEvents(ctx, account.ID, lastEntryID)
+			if err != nil {
+				ctx.Error(err)
+				return
+			}
+		}
+	}
+}
+
+// sendAccountEvents sends the entries of an account booked after afterID followed by its balance,
+// and returns the id of the last entry sent
+func (s *Server) sendAccountEvents(ctx *gin.Context, accountID int64, afterID int64) (int64, error) {
+	for {
+		entries, err := s.store.ListAccountEntriesAfter(ctx.Request.Context(), db.ListAccountEntriesAfterParams{
+			AccountID: accountID,
+			ID:        afterID,
+			Limit:     accountEventsBatch,
+		})
+		if err != nil {
+			return afterID, err
+		}
+
+		for _, entry := range entries {
+			ctx.Render(-1, sse.Event{
+				Id:    strconv.FormatInt(entry.ID, 10),
+				Event: accountEventEntry,
+				Data:  entry,
+			})
+			afterID = entry.ID
+		}
+
+		if len(entries) < accountEventsBatch {
+			break
+		}
+	}
+
+	account, err := s.store.GetAccount(ctx.Request.Context(), accountID)
+	if err != nil {
+		return afterID, err
+	}
+
+	ctx.Render(-1, sse.Event{
+		Event: accountEventBalance,
+		Data: AccountBalanceEvent{
+			AccountID: account.ID,
+			Balance:   account.Balance,
+			Currency:  account.Currency,
+		},
+	})
+	ctx.Writer.Flush()
+
+	return afterID, nil
+}
+
+func parseLastEventID(header string) (int64, error) {
+	if header == "" {
+		return 0, nil
+	}
+
+	id, err := strconv.ParseInt(header, 10, 64)
+	if err != nil || id < 0 {
+		return 0, errInvalidFields.WithFields(commonerrors.FieldError{Field: LastEventIDHeader, Reason: "must be the id of an entry"})
+	}
+	return id, nil
+}
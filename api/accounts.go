@@ -1,8 +1,6 @@
 package api
 
 import (
-	"database/sql"
-	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -12,7 +10,7 @@ import (
 func (s *Server) CreateAccount(ctx *gin.Context) {
 	var req CreateAccountRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errResp(err))
+		ctx.Error(invalidRequest(err))
 		return
 	}
 
@@ -22,28 +20,26 @@ func (s *Server) CreateAccount(ctx *gin.Context) {
 		Balance:  0,
 	}
 
-	account, err := s.store.CreateAccount(ctx.Request.Context(), args)
+	account, err := s.store.CreateAccountTx(ctx.Request.Context(), args)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errResp(err))
+		ctx.Error(err)
 		return
 	}
 
+	audit(ctx, "account", account.ID, nil, account)
 	ctx.JSON(http.StatusOK, account)
 }
 
 func (s *Server) GetAccount(ctx *gin.Context) {
 	var req GetAccountRequest
 	if err := ctx.ShouldBindUri(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errResp(err))
+		ctx.Error(invalidRequest(err))
 		return
 	}
 
 	account, err := s.store.GetAccount(ctx.Request.Context(), req.ID)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			ctx.JSON(http.StatusNotFound, errResp(err))
-		}
-		ctx.JSON(http.StatusInternalServerError, errResp(err))
+		ctx.Error(err)
 		return
 	}
 
@@ -53,7 +49,7 @@ func (s *Server) GetAccount(ctx *gin.Context) {
 func (s *Server) ListAccounts(ctx *gin.Context) {
 	var req ListAccountsRequest
 	if err := ctx.ShouldBindQuery(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errResp(err))
+		ctx.Error(invalidRequest(err))
 		return
 	}
 
@@ -64,10 +60,7 @@ func (s *Server) ListAccounts(ctx *gin.Context) {
 
 	account, err := s.store.ListAccounts(ctx.Request.Context(), args)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			ctx.JSON(http.StatusNotFound, errResp(err))
-		}
-		ctx.JSON(http.StatusInternalServerError, errResp(err))
+		ctx.Error(err)
 		return
 	}
 
@@ -77,7 +70,13 @@ func (s *Server) ListAccounts(ctx *gin.Context) {
 func (s *Server) UpdateAccount(ctx *gin.Context) {
 	var req UpdateAccountRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errResp(err))
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	before, err := s.store.GetAccount(ctx.Request.Context(), req.ID)
+	if err != nil {
+		ctx.Error(err)
 		return
 	}
 
@@ -86,16 +85,16 @@ func (s *Server) UpdateAccount(ctx *gin.Context) {
 		Balance: req.Balance,
 	}
 
-	err := s.store.UpdateAccount(ctx.Request.Context(), args)
+	err = s.store.UpdateAccount(ctx.Request.Context(), args)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			ctx.JSON(http.StatusNotFound, errResp(err))
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, errResp(err))
+		ctx.Error(err)
 		return
 	}
 
+	after := before
+	after.Balance = req.Balance
+	audit(ctx, "account", req.ID, before, after)
+
 	ctx.JSON(http.StatusNoContent, nil)
 }
 
@@ -103,19 +102,53 @@ func (s *Server) UpdateAccount(ctx *gin.Context) {
 func (s *Server) DeleteAccount(ctx *gin.Context) {
 	var req DeleteAccountRequest
 	if err := ctx.ShouldBindUri(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errResp(err))
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	before, err := s.store.GetAccount(ctx.Request.Context(), req.ID)
+	if err != nil {
+		ctx.Error(err)
 		return
 	}
 
-	err := s.store.DeleteAccount(ctx.Request.Context(), req.ID)
+	err = s.store.DeleteAccount(ctx.Request.Context(), req.ID)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			ctx.JSON(http.StatusNotFound, errResp(err))
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, errResp(err))
+		ctx.Error(err)
 		return
 	}
 
+	audit(ctx, "account", req.ID, before, nil)
+
 	ctx.JSON(http.StatusNoContent, nil)
 }
+
+// Withdraw takes money out of an account within its overdraft limit
+func (s *Server) Withdraw(ctx *gin.Context) {
+	var uri AccountURIRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	var req WithdrawRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	result, err := s.store.WithdrawTx(ctx.Request.Context(), db.WithdrawTxParams{
+		AccountID: uri.ID,
+		Amount:    req.Amount,
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	// the account was locked by the withdrawal, so only the amount changed
+	before := result.Account
+	before.Balance += req.Amount
+	audit(ctx, "account", uri.ID, before, result.Account)
+	ctx.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,189 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	commonerrors "github.com/primarybank/common/errors"
+	commonutils "github.com/primarybank/common/utils"
+	db "github.com/primarybank/db/sqlc"
+	"github.com/primarybank/token"
+)
+
+// API keys look like pbk_<prefix>.<secret>, the prefix finds the key and the secret proves it
+const (
+	apiKeyMarker      = "pbk_"
+	apiKeyPrefixBytes = 9
+	apiKeySecretBytes = 32
+)
+
+var errInvalidAPIKey = unauthorized("invalid api key")
+
+// CreateAPIKey issues an API key for the authenticated user. The key is only ever shown in this response.
+func (s *Server) CreateAPIKey(ctx *gin.Context) {
+	payload := ctx.MustGet(AuthzPayloadKey).(*token.Payload)
+
+	// a leaked key must not be able to mint more keys
+	if _, ok := ctx.Get(AuthzAPIKeyKey); ok {
+		ctx.Error(forbidden("api keys cannot create api keys"))
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	if err := validateAPIKeyRequest(req); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	// keys cannot do more than the token they are created with
+	for _, scope := range req.Scopes {
+		if !payload.HasScope(scope) {
+			ctx.Error(ErrInsufficientScope)
+			return
+		}
+	}
+
+	prefix, err := commonutils.RandomSecret(apiKeyPrefixBytes)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	secret, err := commonutils.RandomSecret(apiKeySecretBytes)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	var expiresAt pgtype.Timestamptz
+	if req.ExpiresAt != nil {
+		expiresAt = pgtype.Timestamptz{Time: *req.ExpiresAt, Valid: true}
+	}
+
+	apiKey, err := s.store.CreateAPIKey(ctx.Request.Context(), db.CreateAPIKeyParams{
+		Username:   payload.Username,
+		Name:       req.Name,
+		Prefix:     prefix,
+		SecretHash: commonutils.HashSecret(secret),
+		Scopes:     req.Scopes,
+		ExpiresAt:  expiresAt,
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	audit(ctx, "api_key", apiKey.ID, nil, newAPIKeyResponse(apiKey))
+	ctx.JSON(http.StatusCreated, CreateAPIKeyResponse{
+		APIKeyResponse: newAPIKeyResponse(apiKey),
+		Key:            apiKeyMarker + prefix + "." + secret,
+	})
+}
+
+// ListAPIKeys returns the API keys of the authenticated user, revoked and expired ones included
+func (s *Server) ListAPIKeys(ctx *gin.Context) {
+	payload := ctx.MustGet(AuthzPayloadKey).(*token.Payload)
+
+	apiKeys, err := s.store.ListAPIKeys(ctx.Request.Context(), payload.Username)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	resp := make([]APIKeyResponse, 0, len(apiKeys))
+	for _, apiKey := range apiKeys {
+		resp = append(resp, newAPIKeyResponse(apiKey))
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// RevokeAPIKey permanently disables an API key of the authenticated user
+func (s *Server) RevokeAPIKey(ctx *gin.Context) {
+	var uri APIKeyURIRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	payload := ctx.MustGet(AuthzPayloadKey).(*token.Payload)
+
+	apiKey, err := s.store.RevokeAPIKey(ctx.Request.Context(), db.RevokeAPIKeyParams{
+		ID:       uri.ID,
+		Username: payload.Username,
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	// only keys that were not revoked yet are revoked
+	before := apiKey
+	before.RevokedAt = pgtype.Timestamptz{}
+	audit(ctx, "api_key", uri.ID, newAPIKeyResponse(before), newAPIKeyResponse(apiKey))
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+func validateAPIKeyRequest(req CreateAPIKeyRequest) error {
+	fields := scopeFieldErrors(req.Scopes)
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		fields = append(fields, commonerrors.FieldError{Field: "expires_at", Reason: "must be in the future"})
+	}
+
+	if len(fields) > 0 {
+		return errInvalidFields.WithFields(fields...)
+	}
+	return nil
+}
+
+// authenticateAPIKey resolves an API key to the payload of its owner and records its use
+func authenticateAPIKey(ctx context.Context, store db.Store, key string) (*token.Payload, *db.ApiKey, error) {
+	prefix, secret, ok := strings.Cut(strings.TrimPrefix(key, apiKeyMarker), ".")
+	if !ok || !strings.HasPrefix(key, apiKeyMarker) {
+		return nil, nil, errInvalidAPIKey
+	}
+
+	apiKey, err := store.GetAPIKeyByPrefix(ctx, prefix)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return nil, nil, errInvalidAPIKey
+		}
+		return nil, nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(commonutils.HashSecret(secret)), []byte(apiKey.SecretHash)) != 1 {
+		return nil, nil, errInvalidAPIKey
+	}
+
+	if apiKey.RevokedAt.Valid {
+		return nil, nil, unauthorized("api key has been revoked")
+	}
+
+	if apiKey.ExpiresAt.Valid && !apiKey.ExpiresAt.Time.After(time.Now()) {
+		return nil, nil, unauthorized("api key has expired")
+	}
+
+	// a payload without scopes would grant them all
+	if len(apiKey.Scopes) == 0 {
+		return nil, nil, errInvalidAPIKey
+	}
+
+	if err := store.TouchAPIKey(ctx, apiKey.ID); err != nil {
+		return nil, nil, err
+	}
+
+	return &token.Payload{Username: apiKey.Username, Scope: strings.Join(apiKey.Scopes, " ")}, &apiKey, nil
+}
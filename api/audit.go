@@ -0,0 +1,280 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/primarybank/db/sqlc"
+	"github.com/primarybank/token"
+)
+
+// auditChangeKey holds the auditChange a handler describes its change with
+const auditChangeKey = "audit_change"
+
+// auditChange is the resource a request changed with its snapshots before and after the change
+type auditChange struct {
+	resourceType string
+	resourceID   string
+	before       []byte
+	after        []byte
+}
+
+// audit describes the change made by the current request for the audit log.
+// Snapshots must not carry secrets, use the response types rather than the db models where they differ.
+// A nil snapshot records that the resource did not exist on that side of the change.
+func audit(ctx *gin.Context, resourceType string, resourceID any, before any, after any) {
+	ctx.Set(auditChangeKey, auditChange{
+		resourceType: resourceType,
+		resourceID:   fmt.Sprint(resourceID),
+		before:       auditSnapshot(before),
+		after:        auditSnapshot(after),
+	})
+}
+
+func auditSnapshot(resource any) []byte {
+	if resource == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(resource)
+	if err != nil {
+		log.Printf("cannot snapshot %T for the audit log: %v", resource, err)
+		return nil
+	}
+	return data
+}
+
+// afterCommitKey holds the funcs a request in an audited transaction runs once it is committed
+const afterCommitKey = "after_commit"
+
+// afterCommit runs fn once the changes of the request are committed, right away for the requests that aren't
+// run in an audited transaction. Slow side effects like mail go there, so they don't hold the transaction and
+// the audit log with it. They are dropped with the changes when the transaction rolls back.
+func afterCommit(ctx *gin.Context, fn func()) {
+	if value, ok := ctx.Get(afterCommitKey); ok {
+		committed := value.(*[]func())
+		*committed = append(*committed, fn)
+		return
+	}
+	fn()
+}
+
+// safeChangeRoutes are the routes making changes with a safe method, like email verification links
+var safeChangeRoutes = map[string]bool{
+	"GET /user/verify_email": true,
+}
+
+// AuditMiddleware runs every state changing request as one db transaction and appends the successful ones to the
+// audit log in that same transaction, so a change is never committed without its audit row.
+// Requests are changes when their method is not safe or their route is one of safeChangeRoutes.
+// The response is held back until the transaction commits, a request whose commit fails answers with the error.
+// What the request left to afterCommit runs once the response is sent.
+// Failed requests commit what they did, like recording a failed login, without an audit row.
+// Actions name the route without its version, the unversioned routes and their v1 paths are the same action.
+func AuditMiddleware(store db.Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if ctx.FullPath() == "" || (!isUnsafeMethod(ctx.Request.Method) && !safeChangeRoutes[ctx.Request.Method+" "+unversionedPath(ctx.FullPath())]) {
+			ctx.Next()
+			return
+		}
+
+		request := ctx.Request
+		writer := newBufferedWriter(ctx.Writer)
+		ctx.Writer = writer
+
+		var committed []func()
+		ctx.Set(afterCommitKey, &committed)
+
+		err := store.ExecAuditedTx(request.Context(), func(txCtx context.Context) (*db.AppendAuditLogTxParams, error) {
+			ctx.Request = request.WithContext(txCtx)
+			ctx.Next()
+			return auditLogArgs(ctx), nil
+		})
+
+		ctx.Request = request
+		ctx.Writer = writer.ResponseWriter
+
+		if err != nil && len(ctx.Errors) == 0 && writer.Status() < http.StatusBadRequest {
+			// nothing the request did was committed, its response must not say otherwise
+			writer.reset()
+			ctx.Error(err)
+			return
+		}
+		if err != nil {
+			log.Printf("cannot commit failed request %s: %v", ctx.GetString(RequestIDKey), err)
+		}
+		writer.flush()
+
+		if err == nil {
+			for _, fn := range committed {
+				fn()
+			}
+		}
+	}
+}
+
+// auditLogArgs returns the audit log row of a request, nil when it failed or isn't audited
+func auditLogArgs(ctx *gin.Context) *db.AppendAuditLogTxParams {
+	if len(ctx.Errors) > 0 || ctx.Writer.Status() >= http.StatusBadRequest {
+		return nil
+	}
+
+	// the gRPC server audits the calls of the gateway itself
+	if ctx.GetBool(gatewayServedKey) {
+		return nil
+	}
+
+	args := &db.AppendAuditLogTxParams{
+		Action:    ctx.Request.Method + " " + routePath(ctx),
+		RequestID: ctx.GetString(RequestIDKey),
+		ClientIP:  ctx.ClientIP(),
+	}
+
+	if payload, ok := ctx.Get(AuthzPayloadKey); ok {
+		args.Actor = payload.(*token.Payload).Username
+	}
+
+	if value, ok := ctx.Get(auditChangeKey); ok {
+		change := value.(auditChange)
+		args.ResourceType = change.resourceType
+		args.ResourceID = change.resourceID
+		args.Before = change.before
+		args.After = change.after
+	}
+	return args
+}
+
+// bufferedWriter holds the response of an audited request back until its transaction commits
+type bufferedWriter struct {
+	gin.ResponseWriter
+	header  http.Header
+	status  int
+	written bool
+	body    bytes.Buffer
+}
+
+func newBufferedWriter(w gin.ResponseWriter) *bufferedWriter {
+	return &bufferedWriter{
+		ResponseWriter: w,
+		header:         w.Header().Clone(),
+		status:         http.StatusOK,
+	}
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	if code > 0 && !w.written {
+		w.status = code
+	}
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {
+	w.written = true
+}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	w.written = true
+	return w.body.Write(data)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	w.written = true
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedWriter) Status() int {
+	return w.status
+}
+
+func (w *bufferedWriter) Size() int {
+	if !w.written {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *bufferedWriter) Written() bool {
+	return w.written
+}
+
+// Flush is left for flush, nothing is sent before the commit
+func (w *bufferedWriter) Flush() {}
+
+// reset drops the response and the headers set since the writer was made
+func (w *bufferedWriter) reset() {
+	header := w.ResponseWriter.Header()
+	for key := range header {
+		delete(header, key)
+	}
+	for key, values := range w.header {
+		header[key] = values
+	}
+	w.status = http.StatusOK
+	w.written = false
+	w.body.Reset()
+}
+
+// flush sends the response held back
+func (w *bufferedWriter) flush() {
+	w.ResponseWriter.WriteHeader(w.status)
+	if w.written {
+		w.ResponseWriter.Write(w.body.Bytes())
+	}
+}
+
+func isUnsafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	default:
+		return true
+	}
+}
+
+// ListAuditLogs returns audit log rows matching the filters, newest first
+func (s *Server) ListAuditLogs(ctx *gin.Context) {
+	var req ListAuditLogsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	logs, err := s.store.ListAuditLogs(ctx.Request.Context(), db.ListAuditLogsParams{
+		Actor:        pgtype.Text{String: req.Actor, Valid: req.Actor != ""},
+		Action:       pgtype.Text{String: req.Action, Valid: req.Action != ""},
+		ResourceType: pgtype.Text{String: req.ResourceType, Valid: req.ResourceType != ""},
+		ResourceID:   pgtype.Text{String: req.ResourceID, Valid: req.ResourceID != ""},
+		RequestID:    pgtype.Text{String: req.RequestID, Valid: req.RequestID != ""},
+		Since:        pgtype.Timestamptz{Time: req.Since, Valid: !req.Since.IsZero()},
+		Until:        pgtype.Timestamptz{Time: req.Until, Valid: !req.Until.IsZero()},
+		LimitCount:   req.PageSize,
+		OffsetCount:  (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	resp := make([]AuditLogResponse, 0, len(logs))
+	for _, row := range logs {
+		resp = append(resp, newAuditLogResponse(row))
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// VerifyAuditLog checks the hash chain of the whole audit log and reports the first row breaking it
+func (s *Server) VerifyAuditLog(ctx *gin.Context) {
+	verification, err := s.store.VerifyAuditLog(ctx.Request.Context())
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, verification)
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/primarybank/config"
+	db "github.com/primarybank/db/sqlc"
+	"github.com/primarybank/mail"
+	"github.com/primarybank/token"
+)
+
+// VerifyEmail marks the email of a user verified from the signed code mailed to them
+func (s *Server) VerifyEmail(ctx *gin.Context) {
+	var req VerifyEmailRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	verification, err := s.EmailVerifier.VerifyCode(req.Code)
+	if err != nil {
+		ctx.Error(errInvalidVerificationCode)
+		return
+	}
+
+	// the user may have changed their email since the code was sent
+	user, err := s.store.VerifyUserEmail(ctx.Request.Context(), db.VerifyUserEmailParams{
+		Username: verification.Username,
+		Email:    verification.Email,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			ctx.Error(errInvalidVerificationCode)
+			return
+		}
+		ctx.Error(err)
+		return
+	}
+
+	audit(ctx, "user", user.Username, nil, newUserResponse(user))
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+// ResendVerificationEmail mails a new verification code to the authenticated user
+func (s *Server) ResendVerificationEmail(ctx *gin.Context) {
+	payload := ctx.MustGet(AuthzPayloadKey).(*token.Payload)
+
+	user, err := s.store.GetUser(ctx.Request.Context(), payload.Username)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	audit(ctx, "user", user.Username, nil, nil)
+
+	if user.EmailVerifiedAt.Valid {
+		ctx.Status(http.StatusNoContent)
+		return
+	}
+
+	s.sendVerificationEmailAfterCommit(ctx, user)
+	ctx.Status(http.StatusAccepted)
+}
+
+// sendVerificationEmailAfterCommit mails the verification code once the request is committed, a lost email can be
+// sent again so failures are only logged
+func (s *Server) sendVerificationEmailAfterCommit(ctx *gin.Context, user db.User) {
+	afterCommit(ctx, func() {
+		if err := s.sendVerificationEmail(ctx.Request.Context(), user); err != nil {
+			log.Printf("cannot send verification email to %s: %v", user.Username, err)
+		}
+	})
+}
+
+func (s *Server) sendVerificationEmail(ctx context.Context, user db.User) error {
+	code, err := s.EmailVerifier.CreateCode(user.Username, user.Email, s.Config.EmailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s%s/user/verify_email?code=%s", s.Config.PublicBaseURL, V1Prefix, url.QueryEscape(code))
+
+	err = s.Mailer.Send(ctx, mail.Message{
+		To:      []string{user.Email},
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nOpen this link to verify your email address: %s\n\nIt expires in %s.\n",
+			user.FullName, link, s.Config.EmailVerificationTTL),
+	})
+	if err != nil {
+		return fmt.Errorf("cannot send verification email: %w", err)
+	}
+
+	return nil
+}
+
+// emailVerificationRequired reports whether requiredFor keeps unverified users from action.
+// Blocking login blocks everything behind it too, so tokens issued before still cannot move money.
+func emailVerificationRequired(requiredFor string, action string) bool {
+	switch requiredFor {
+	case config.EmailVerificationRequiredForLogin:
+		return true
+	case config.EmailVerificationRequiredForMoneyMovement:
+		return action == config.EmailVerificationRequiredForMoneyMovement
+	default:
+		return false
+	}
+}
@@ -1,8 +1,6 @@
 package api
 
 import (
-	"database/sql"
-	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -12,38 +10,36 @@ import (
 func (s *Server) CreateEntry(ctx *gin.Context) {
 	var req CreateEntryRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errResp(err))
+		ctx.Error(invalidRequest(err))
 		return
 	}
 
-	args := db.CreateEntryParams{
+	args := db.CreateEntryTxParams{
 		AccountID: req.AccountID,
 		Amount:    req.Amount,
 	}
 
-	entry, err := s.store.CreateEntry(ctx.Request.Context(), args)
+	entry, err := s.store.CreateEntryTx(ctx.Request.Context(), args)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errResp(err))
+		ctx.Error(err)
 		return
 	}
 
+	audit(ctx, "entry", entry.ID, nil, entry)
+
 	ctx.JSON(http.StatusOK, entry)
 }
 
 func (s *Server) GetEntry(ctx *gin.Context) {
 	var req GetEntryRequest
 	if err := ctx.ShouldBindUri(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errResp(err))
+		ctx.Error(invalidRequest(err))
 		return
 	}
 
 	entry, err := s.store.GetEntry(ctx.Request.Context(), req.ID)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			ctx.JSON(http.StatusNotFound, errResp(err))
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, errResp(err))
+		ctx.Error(err)
 		return
 	}
 
@@ -53,27 +49,31 @@ func (s *Server) GetEntry(ctx *gin.Context) {
 func (s *Server) DeleteEntry(ctx *gin.Context) {
 	var req DeleteEntryRequest
 	if err := ctx.ShouldBindUri(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errResp(err))
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	before, err := s.store.GetEntry(ctx.Request.Context(), req.ID)
+	if err != nil {
+		ctx.Error(err)
 		return
 	}
 
-	err := s.store.DeleteEntry(ctx.Request.Context(), req.ID)
+	err = s.store.DeleteEntryTx(ctx.Request.Context(), req.ID)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			ctx.JSON(http.StatusNotFound, errResp(err))
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, errResp(err))
+		ctx.Error(err)
 		return
 	}
 
+	audit(ctx, "entry", req.ID, before, nil)
+
 	ctx.JSON(http.StatusOK, gin.H{"message": "entry deleted"})
 }
 
 func (s *Server) ListEntries(ctx *gin.Context) {
 	var req ListEntriesRequest
 	if err := ctx.ShouldBindQuery(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errResp(err))
+		ctx.Error(invalidRequest(err))
 		return
 	}
 
@@ -84,7 +84,7 @@ func (s *Server) ListEntries(ctx *gin.Context) {
 
 	transfers, err := s.store.ListEntries(ctx.Request.Context(), args)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errResp(err))
+		ctx.Error(err)
 		return
 	}
 
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	commonerrors "github.com/primarybank/common/errors"
+)
+
+// ProblemContentType is the content type of the problems rendered for failed requests
+const ProblemContentType = "application/problem+json"
+
+// Problem is the RFC 7807 body rendered for every failed request
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// Code identifies the error for machines, Type is derived from it
+	Code          string         `json:"code"`
+	InvalidParams []InvalidParam `json:"invalid_params,omitempty"`
+}
+
+// InvalidParam names a request field that caused the problem
+type InvalidParam struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+var kindStatus = map[commonerrors.Kind]int{
+	commonerrors.KindNotFound:          http.StatusNotFound,
+	commonerrors.KindConflict:          http.StatusConflict,
+	commonerrors.KindInsufficientFunds: http.StatusUnprocessableEntity,
+	commonerrors.KindUnprocessable:     http.StatusUnprocessableEntity,
+	commonerrors.KindForbidden:         http.StatusForbidden,
+	commonerrors.KindUnauthorized:      http.StatusUnauthorized,
+	commonerrors.KindTooManyRequests:   http.StatusTooManyRequests,
+	commonerrors.KindValidation:        http.StatusBadRequest,
+	commonerrors.KindInternal:          http.StatusInternalServerError,
+}
+
+// retryAfterError is implemented by errors telling clients when to try again
+type retryAfterError interface {
+	error
+	RetryAfter() time.Duration
+}
+
+// ErrorHandler renders the last error attached to the context by a handler or middleware.
+// Errors that were never classified are reported as internal without their message,
+// so driver details don't leak to clients.
+func ErrorHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Next()
+
+		if len(ctx.Errors) == 0 || ctx.Writer.Written() {
+			return
+		}
+
+		err := ctx.Errors.Last().Err
+		problem := NewProblem(err)
+		problem.Instance = ctx.Request.URL.Path
+
+		var retryable retryAfterError
+		if errors.As(err, &retryable) {
+			ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryable.RetryAfter().Seconds()))))
+		}
+
+		ctx.Header("Content-Type", ProblemContentType)
+		ctx.JSON(problem.Status, problem)
+	}
+}
+
+// NewProblem describes err the way ErrorHandler renders it, leaving Instance to the caller
+func NewProblem(err error) Problem {
+	typed, ok := commonerrors.As(err)
+	if !ok || typed.Kind == commonerrors.KindInternal {
+		return Problem{
+			Type:   "/problems/internal",
+			Title:  http.StatusText(http.StatusInternalServerError),
+			Status: http.StatusInternalServerError,
+			Detail: "internal server error",
+			Code:   string(commonerrors.KindInternal),
+		}
+	}
+
+	status, ok := kindStatus[typed.Kind]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+
+	problem := Problem{
+		Type:   "/problems/" + typed.Code,
+		Title:  http.StatusText(status),
+		Status: status,
+		// domain errors wrapping a typed error describe themselves more precisely than it
+		Detail: err.Error(),
+		Code:   typed.Code,
+	}
+
+	for _, field := range typed.Fields {
+		problem.InvalidParams = append(problem.InvalidParams, InvalidParam{
+			Name:   field.Field,
+			Reason: field.Reason,
+		})
+	}
+
+	return problem
+}
+
+// ErrApprovalRequired is returned for reservations of transfers above the approval threshold
+var ErrApprovalRequired = commonerrors.New(commonerrors.KindUnprocessable, "approval_required", "transfers above the approval threshold cannot be reserved")
+
+// errTransferPending is returned when deleting a transfer that still holds a reservation of the sender's funds
+var errTransferPending = commonerrors.New(commonerrors.KindConflict, "transfer_pending", "a pending transfer must be posted or voided before it is deleted")
+
+// ErrEmailNotVerified is returned when EMAIL_VERIFICATION_REQUIRED_FOR keeps an unverified user from an action
+var ErrEmailNotVerified = commonerrors.New(commonerrors.KindForbidden, "email_not_verified", "email address is not verified")
+
+var errInvalidVerificationCode = commonerrors.New(commonerrors.KindValidation, "invalid_verification_code", "email verification code is invalid or has expired")
+
+// ErrMFARequired is returned for transfers above the step-up threshold without a recent second factor
+var ErrMFARequired = commonerrors.New(commonerrors.KindForbidden, "mfa_required", "a second factor is required for this action")
+
+var (
+	errInvalidMFACode = commonerrors.New(commonerrors.KindUnauthorized, "invalid_mfa_code", "invalid two-factor code")
+	errMFANotEnabled  = commonerrors.New(commonerrors.KindUnprocessable, "mfa_not_enabled", "two-factor authentication is not enabled")
+)
+
+// ErrInsufficientScope is returned when a token or API key lacks a scope the route requires
+var ErrInsufficientScope = commonerrors.New(commonerrors.KindForbidden, "insufficient_scope", "the credentials do not grant the scope this action requires")
+
+// errInvalidFields is returned with the fields that failed validation beyond what binding checks
+var errInvalidFields = commonerrors.New(commonerrors.KindValidation, "invalid_request", "request has invalid fields")
+
+var errInvalidCredentials = commonerrors.New(commonerrors.KindUnauthorized, "invalid_credentials", "invalid credentials")
+
+func unauthorized(message string) error {
+	return commonerrors.New(commonerrors.KindUnauthorized, "unauthorized", message)
+}
+
+func forbidden(message string) error {
+	return commonerrors.New(commonerrors.KindForbidden, "forbidden", message)
+}
+
+// invalidRequest classifies a binding error
+func invalidRequest(err error) error {
+	return commonerrors.Wrap(err, commonerrors.KindValidation, "invalid_request", err.Error())
+}
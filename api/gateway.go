@@ -0,0 +1,64 @@
+package api
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GatewayRoutes are the routes the gateway can take over, the http annotations of the PrimaryBank service
+// map its methods to their v1 paths. POST /transfer stays with its gin handler: the gRPC method refuses amounts
+// that need approval and takes no second factor along with the request.
+var GatewayRoutes = []string{
+	"GET /user/:username",
+	"POST /account",
+	"GET /account/:id",
+	"GET /accounts",
+	"GET /transfer/:id",
+	"GET /transfers",
+	"GET /entry/:id",
+	"GET /entries",
+}
+
+// gatewayServedKey marks requests handed to the gateway, their calls are audited by the gRPC server
+const gatewayServedKey = "gateway_served"
+
+// parseGatewayRoutes reads the comma separated GATEWAY_ROUTES
+func parseGatewayRoutes(value string) (map[string]bool, error) {
+	routes := make(map[string]bool)
+	for _, route := range strings.Split(value, ",") {
+		route = strings.Join(strings.Fields(route), " ")
+		if route == "" {
+			continue
+		}
+
+		if !slices.Contains(GatewayRoutes, route) {
+			return nil, fmt.Errorf("route %q is not served by the gateway", route)
+		}
+		routes[route] = true
+	}
+	return routes, nil
+}
+
+// viaGateway serves a route with the gateway once it is listed in GATEWAY_ROUTES, and with handler until then.
+// The middlewares of the route still run, the gateway authenticates the call again.
+// The gateway serves the v1 paths, the unversioned routes and the routes v2 kept are forwarded to them.
+func (s *Server) viaGateway(handler gin.HandlerFunc) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if s.Gateway == nil || !s.gatewayRoutes[ctx.Request.Method+" "+routePath(ctx)] {
+			handler(ctx)
+			return
+		}
+
+		// the call is audited under the id of this request
+		ctx.Request.Header.Set(RequestIDHeader, ctx.GetString(RequestIDKey))
+		ctx.Set(gatewayServedKey, true)
+
+		req := ctx.Request.Clone(ctx.Request.Context())
+		req.URL.Path = V1Prefix + strings.TrimPrefix(ctx.Request.URL.Path, ctx.GetString(apiVersionKey))
+		req.URL.RawPath = ""
+		s.Gateway.ServeHTTP(ctx.Writer, req)
+	}
+}
@@ -0,0 +1,21 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/primarybank/token"
+)
+
+// JWKS publishes the public keys access tokens can be verified with.
+// Nothing is published while tokens are signed with the symmetric key.
+func (s *Server) JWKS(ctx *gin.Context) {
+	set := token.JWKSet{Keys: []token.JWK{}}
+	if s.keyring != nil {
+		set = s.keyring.JWKS()
+	}
+
+	// verifiers may cache the keys, a new key is published well before it signs anything
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, set)
+}
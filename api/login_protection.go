@@ -0,0 +1,84 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/primarybank/db/sqlc"
+)
+
+func (s *Server) loginPolicy() db.LoginPolicy {
+	return db.LoginPolicy{
+		MaxFailures:     s.Config.LoginMaxFailures,
+		MaxIPFailures:   s.Config.LoginMaxIPFailures,
+		Window:          s.Config.LoginFailureWindow,
+		LockoutDuration: s.Config.LoginLockoutDuration,
+		BaseDelay:       s.Config.LoginBaseDelay,
+		MaxDelay:        s.Config.LoginMaxDelay,
+	}
+}
+
+// checkLoginThrottle refuses logins of throttled usernames and client IPs and records the refusal
+func (s *Server) checkLoginThrottle(ctx *gin.Context, username string) error {
+	err := s.store.CheckLoginThrottle(ctx.Request.Context(), username, ctx.ClientIP(), s.loginPolicy())
+
+	var throttled *db.LoginThrottledError
+	if errors.As(err, &throttled) {
+		if err := s.recordLoginAttempt(ctx, username, db.LoginOutcomeThrottled); err != nil {
+			return err
+		}
+	}
+
+	return err
+}
+
+func (s *Server) recordLoginAttempt(ctx *gin.Context, username string, outcome string) error {
+	_, err := s.store.RecordLoginAttemptTx(ctx.Request.Context(), db.RecordLoginAttemptTxParams{
+		Username:  username,
+		ClientIP:  ctx.ClientIP(),
+		UserAgent: ctx.Request.UserAgent(),
+		Outcome:   outcome,
+		Policy:    s.loginPolicy(),
+	})
+	return err
+}
+
+// UnlockUser clears the failed logins of a username, lifting its lockout
+func (s *Server) UnlockUser(ctx *gin.Context) {
+	_, err := s.store.ResetLoginThrottle(ctx.Request.Context(), db.ResetLoginThrottleParams{
+		Scope:   db.LoginScopeUsername,
+		Subject: ctx.Param("username"),
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	audit(ctx, "user", ctx.Param("username"), nil, nil)
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+// ListLoginEvents returns recorded login attempts, newest first
+func (s *Server) ListLoginEvents(ctx *gin.Context) {
+	var req ListLoginEventsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	events, err := s.store.ListLoginEvents(ctx.Request.Context(), db.ListLoginEventsParams{
+		Username:    pgtype.Text{String: req.Username, Valid: req.Username != ""},
+		ClientIp:    pgtype.Text{String: req.ClientIP, Valid: req.ClientIP != ""},
+		Outcome:     pgtype.Text{String: req.Outcome, Valid: req.Outcome != ""},
+		LimitCount:  req.PageSize,
+		OffsetCount: (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, events)
+}
@@ -0,0 +1,288 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	commonutils "github.com/primarybank/common/utils"
+	db "github.com/primarybank/db/sqlc"
+	"github.com/primarybank/mfa"
+	"github.com/primarybank/token"
+)
+
+// MFACodeHeader carries a second factor for step-up on requests authenticated by a password only token
+const MFACodeHeader = "X-MFA-Code"
+
+// EnrollMFA starts TOTP enrollment, the factor is only enabled once ConfirmMFA sees a valid code
+func (s *Server) EnrollMFA(ctx *gin.Context) {
+	payload := ctx.MustGet(AuthzPayloadKey).(*token.Payload)
+
+	enabled, err := s.mfaEnabled(ctx.Request.Context(), payload.Username)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	if enabled {
+		ctx.Error(db.ErrMFAAlreadyEnabled)
+		return
+	}
+
+	enrollment, err := mfa.Enroll(s.Config.MFAIssuer, payload.Username)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ciphertext, err := s.mfaCipher.Encrypt([]byte(enrollment.Secret))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	_, err = s.store.UpsertMFAFactor(ctx.Request.Context(), db.UpsertMFAFactorParams{
+		Username:         payload.Username,
+		SecretCiphertext: ciphertext,
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	// secrets and recovery codes stay out of the audit log
+	audit(ctx, "mfa_factor", payload.Username, nil, nil)
+	ctx.JSON(http.StatusOK, EnrollMFAResponse{
+		Secret:     enrollment.Secret,
+		OTPAuthURI: enrollment.URI,
+	})
+}
+
+// ConfirmMFA enables the enrolled factor and hands out recovery codes, which are never shown again
+func (s *Server) ConfirmMFA(ctx *gin.Context) {
+	payload := ctx.MustGet(AuthzPayloadKey).(*token.Payload)
+
+	var req MFACodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	factor, err := s.store.GetMFAFactor(ctx.Request.Context(), payload.Username)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			ctx.Error(errMFANotEnabled)
+			return
+		}
+		ctx.Error(err)
+		return
+	}
+	if factor.ConfirmedAt.Valid {
+		ctx.Error(db.ErrMFAAlreadyEnabled)
+		return
+	}
+
+	step, err := s.validateTOTP(factor, req.Code)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	recoveryCodes, err := mfa.GenerateRecoveryCodes(mfa.RecoveryCodeCount)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	codeHashes := make([]string, 0, len(recoveryCodes))
+	for _, code := range recoveryCodes {
+		codeHashes = append(codeHashes, commonutils.HashSecret(code))
+	}
+
+	_, err = s.store.ConfirmMFATx(ctx.Request.Context(), db.ConfirmMFATxParams{
+		Username:           payload.Username,
+		Step:               step,
+		RecoveryCodeHashes: codeHashes,
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	audit(ctx, "mfa_factor", payload.Username, nil, nil)
+	ctx.JSON(http.StatusOK, ConfirmMFAResponse{RecoveryCodes: recoveryCodes})
+}
+
+// DisableMFA turns two-factor authentication off, which takes a valid second factor
+func (s *Server) DisableMFA(ctx *gin.Context) {
+	payload := ctx.MustGet(AuthzPayloadKey).(*token.Payload)
+
+	var req MFACodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	if err := s.verifyThrottledSecondFactor(ctx, payload.Username, req.Code); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	if err := s.store.DisableMFATx(ctx.Request.Context(), payload.Username); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	audit(ctx, "mfa_factor", payload.Username, nil, nil)
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+// VerifyMFALogin trades the challenge token of a login and a second factor for an access token
+func (s *Server) VerifyMFALogin(ctx *gin.Context) {
+	var req VerifyMFALoginRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	challenge, err := s.TokenMaker.VerifyToken(req.ChallengeToken)
+	if err != nil || challenge.Purpose != token.PurposeMFAChallenge {
+		ctx.Error(unauthorized("invalid challenge token"))
+		return
+	}
+
+	// the password was already right, guessing codes counts as failed logins all the same
+	if err := s.verifyThrottledSecondFactor(ctx, challenge.Username, req.Code); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	if err := s.recordLoginAttempt(ctx, challenge.Username, db.LoginOutcomeSuccess); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	audit(ctx, "user", challenge.Username, nil, nil)
+
+	expirationTime := s.Config.AccessTokenDuration
+	// the scopes asked for at login carry over through the challenge
+	scopes := challenge.Scopes()
+	accessToken, err := s.TokenMaker.CreateToken(challenge.Username, expirationTime, token.WithMFA(time.Now()), token.WithScopes(scopes...))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, LoginUserResponse{
+		AccessToken: accessToken,
+		Scope:       strings.Join(scopes, " "),
+		ExpiresIn:   time.Now().Add(expirationTime).Unix(),
+	})
+}
+
+// requireStepUp asks for a recent second factor before amounts above the step-up threshold are moved.
+// The factor is either in the access token or sent along in the X-MFA-Code header.
+func (s *Server) requireStepUp(ctx *gin.Context, amount int64) error {
+	if s.Config.MFAStepUpThreshold == 0 || amount <= s.Config.MFAStepUpThreshold {
+		return nil
+	}
+
+	payload := ctx.MustGet(AuthzPayloadKey).(*token.Payload)
+	if payload.MFASince(time.Now().Add(-s.Config.MFAStepUpMaxAge)) {
+		return nil
+	}
+
+	code := ctx.GetHeader(MFACodeHeader)
+	if code == "" {
+		return ErrMFARequired
+	}
+
+	err := s.verifyThrottledSecondFactor(ctx, payload.Username, code)
+	if errors.Is(err, errMFANotEnabled) {
+		return ErrMFARequired
+	}
+	return err
+}
+
+// verifyThrottledSecondFactor verifies a second factor behind the login throttle: throttled usernames and client
+// IPs are refused and invalid codes count as failed logins, so codes can't be guessed through any route taking them
+func (s *Server) verifyThrottledSecondFactor(ctx *gin.Context, username string, code string) error {
+	if err := s.checkLoginThrottle(ctx, username); err != nil {
+		return err
+	}
+
+	err := s.verifySecondFactor(ctx.Request.Context(), username, code)
+	if errors.Is(err, errInvalidMFACode) {
+		if err := s.recordLoginAttempt(ctx, username, db.LoginOutcomeFailure); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+func (s *Server) mfaEnabled(ctx context.Context, username string) (bool, error) {
+	factor, err := s.store.GetMFAFactor(ctx, username)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return factor.ConfirmedAt.Valid, nil
+}
+
+// verifySecondFactor accepts a TOTP code or an unused recovery code of the user, each only once
+func (s *Server) verifySecondFactor(ctx context.Context, username string, code string) error {
+	if mfa.IsRecoveryCode(code) {
+		_, err := s.store.UseMFARecoveryCode(ctx, db.UseMFARecoveryCodeParams{
+			Username: username,
+			CodeHash: commonutils.HashSecret(mfa.NormalizeRecoveryCode(code)),
+		})
+		if errors.Is(err, db.ErrNotFound) {
+			return errInvalidMFACode
+		}
+		return err
+	}
+
+	factor, err := s.store.GetMFAFactor(ctx, username)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return errMFANotEnabled
+		}
+		return err
+	}
+	if !factor.ConfirmedAt.Valid {
+		return errMFANotEnabled
+	}
+
+	step, err := s.validateTOTP(factor, code)
+	if err != nil {
+		return err
+	}
+
+	// a code of a step at or before the last accepted one is a replay
+	_, err = s.store.UseMFAStep(ctx, db.UseMFAStepParams{
+		Step:     step,
+		Username: username,
+	})
+	if errors.Is(err, db.ErrNotFound) {
+		return errInvalidMFACode
+	}
+	return err
+}
+
+func (s *Server) validateTOTP(factor db.MfaFactor, code string) (int64, error) {
+	secret, err := s.mfaCipher.Decrypt(factor.SecretCiphertext)
+	if err != nil {
+		return 0, err
+	}
+
+	step, ok := mfa.Validate(string(secret), code, time.Now())
+	if !ok {
+		return 0, errInvalidMFACode
+	}
+	return step, nil
+}
@@ -1,51 +1,173 @@
 package api
 
 import (
-	"errors"
-	"net/http"
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	commonutils "github.com/primarybank/common/utils"
+	"github.com/primarybank/config"
+	db "github.com/primarybank/db/sqlc"
 	"github.com/primarybank/token"
 )
 
 const (
 	AuthHeaderKey   = "authorization"
 	AuthType        = "bearer"
+	APIKeyAuthType  = "apikey"
 	AuthzPayloadKey = "authz_payload"
+	// AuthzAPIKeyKey holds the db.ApiKey of requests authenticated with an API key
+	AuthzAPIKeyKey = "authz_api_key"
+	// RequestIDKey holds the id of the request, taken from RequestIDHeader or generated
+	RequestIDKey    = "request_id"
+	RequestIDHeader = "X-Request-ID"
 )
 
-func AuthMiddleWare(tokenMaker token.Maker) gin.HandlerFunc {
+// request ids sent by clients are kept when they look like ids, anything else is replaced
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// RequestIDMiddleware tags every request with an id, echoed in the response so clients can quote it
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		requestID := ctx.GetHeader(RequestIDHeader)
+		if !requestIDPattern.MatchString(requestID) {
+			var err error
+			requestID, err = commonutils.RandomSecret(16)
+			if err != nil {
+				ctx.Error(err)
+				ctx.Abort()
+				return
+			}
+		}
+
+		ctx.Set(RequestIDKey, requestID)
+		ctx.Header(RequestIDHeader, requestID)
+		ctx.Next()
+	}
+}
+
+// AuthMiddleWare authenticates requests carrying either a bearer access token or an API key
+func AuthMiddleWare(tokenMaker token.Maker, store db.Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		payload, apiKey, err := Authenticate(ctx.Request.Context(), tokenMaker, store, ctx.GetHeader(AuthHeaderKey))
+		if err != nil {
+			ctx.Error(err)
+			ctx.Abort()
+			return
+		}
+
+		if apiKey != nil {
+			ctx.Set(AuthzAPIKeyKey, *apiKey)
+		}
+		ctx.Set(AuthzPayloadKey, payload)
+		ctx.Next()
+	}
+}
+
+// Authenticate resolves the value of an authorization header, a bearer access token or an API key,
+// to the payload of the user behind it. The API key is returned too when one was used.
+// The gRPC server authenticates the authorization metadata of its calls with it as well.
+func Authenticate(ctx context.Context, tokenMaker token.Maker, store db.Store, authzHeader string) (*token.Payload, *db.ApiKey, error) {
+	if len(authzHeader) == 0 {
+		return nil, nil, unauthorized("authorization header is not provided")
+	}
+
+	parts := strings.Fields(authzHeader)
+	if len(parts) < 2 {
+		return nil, nil, unauthorized("invalid authorization header format")
+	}
+
+	switch strings.ToLower(parts[0]) {
+	case AuthType:
+		payload, err := authenticateToken(tokenMaker, parts[1])
+		return payload, nil, err
+	case APIKeyAuthType:
+		return authenticateAPIKey(ctx, store, parts[1])
+	default:
+		return nil, nil, unauthorized("authorization type is not supported")
+	}
+}
+
+func authenticateToken(tokenMaker token.Maker, accessToken string) (*token.Payload, error) {
+	payload, err := tokenMaker.VerifyToken(accessToken)
+	if err != nil {
+		return nil, unauthorized("invalid token")
+	}
+
+	if !payload.IsAccess() {
+		return nil, unauthorized("token cannot be used for access")
+	}
+
+	return payload, nil
+}
+
+// ScopeMiddleware only lets through requests whose token or API key was granted all the given scopes
+func ScopeMiddleware(scopes ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		payload := ctx.MustGet(AuthzPayloadKey).(*token.Payload)
+
+		for _, scope := range scopes {
+			if !payload.HasScope(scope) {
+				ctx.Header("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope="%s"`, strings.Join(scopes, " ")))
+				ctx.Error(ErrInsufficientScope)
+				ctx.Abort()
+				return
+			}
+		}
+
+		ctx.Next()
+	}
+}
+
+// RoleMiddleware only lets through authenticated users having one of the given roles
+func RoleMiddleware(store db.Store, roles ...string) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		authzHeader := ctx.GetHeader(AuthHeaderKey)
-		if len(authzHeader) == 0 {
-			err := errors.New("authorization header is not provided")
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errResp(err))
+		payload := ctx.MustGet(AuthzPayloadKey).(*token.Payload)
+
+		user, err := store.GetUser(ctx.Request.Context(), payload.Username)
+		if err != nil {
+			ctx.Error(forbidden("cannot resolve user role"))
+			ctx.Abort()
 			return
 		}
 
-		parts := strings.Fields(authzHeader)
-		if len(parts) < 2 {
-			err := errors.New("invalid authorization header format")
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errResp(err))
+		if !slices.Contains(roles, user.Role) {
+			ctx.Error(forbidden("user is not allowed to perform this action"))
+			ctx.Abort()
 			return
 		}
 
-		if strings.ToLower(parts[0]) != AuthType {
-			err := errors.New("authorization type is not supported")
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errResp(err))
+		ctx.Next()
+	}
+}
+
+// VerifiedEmailMiddleware keeps authenticated users who have not verified their email out,
+// unless requiredFor says verification is not needed to move money
+func VerifiedEmailMiddleware(store db.Store, requiredFor string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !emailVerificationRequired(requiredFor, config.EmailVerificationRequiredForMoneyMovement) {
+			ctx.Next()
 			return
 		}
 
-		accessToken := parts[1]
-		payload, err := tokenMaker.VerifyToken(accessToken)
+		payload := ctx.MustGet(AuthzPayloadKey).(*token.Payload)
+
+		user, err := store.GetUser(ctx.Request.Context(), payload.Username)
 		if err != nil {
-			err := errors.New("invalid token")
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errResp(err))
+			ctx.Error(err)
+			ctx.Abort()
+			return
+		}
+
+		if !user.EmailVerifiedAt.Valid {
+			ctx.Error(ErrEmailNotVerified)
+			ctx.Abort()
 			return
 		}
 
-		ctx.Set(AuthzPayloadKey, payload)
 		ctx.Next()
 	}
 }
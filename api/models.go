@@ -1,5 +1,13 @@
 package api
 
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/primarybank/db/sqlc"
+)
+
 // Account
 type CreateAccountRequest struct {
 	Owner    string `json:"owner" binding:"required"`
@@ -24,6 +32,61 @@ type DeleteAccountRequest struct {
 	ID int64 `uri:"id" binding:"required,min=1"`
 }
 
+type WithdrawRequest struct {
+	Amount int64 `json:"amount" binding:"required,gt=0"`
+}
+
+// AccountBalanceEvent is streamed whenever the balance of an account changed
+type AccountBalanceEvent struct {
+	AccountID int64  `json:"account_id"`
+	Balance   int64  `json:"balance"`
+	Currency  string `json:"currency"`
+}
+
+// Overdraft
+type AccountURIRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type SetOverdraftLimitRequest struct {
+	OverdraftLimit int64  `json:"overdraft_limit" binding:"min=0"`
+	Reason         string `json:"reason" binding:"required"`
+}
+
+type RevokeOverdraftLimitRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+type ListOverdraftLimitChangesRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+// Transaction limits
+type CreateTransactionLimitRequest struct {
+	Scope     string `json:"scope" binding:"required,oneof=account user"`
+	Period    string `json:"period" binding:"required,oneof=daily monthly"`
+	Role      string `json:"role"`
+	Username  string `json:"username"`
+	AccountID int64  `json:"account_id" binding:"min=0"`
+	MaxAmount int64  `json:"max_amount" binding:"min=0"`
+	MaxCount  int64  `json:"max_count" binding:"min=0"`
+}
+
+type TransactionLimitURIRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type UpdateTransactionLimitRequest struct {
+	MaxAmount int64 `json:"max_amount" binding:"min=0"`
+	MaxCount  int64 `json:"max_count" binding:"min=0"`
+}
+
+type ListTransactionLimitsRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+}
+
 // Transfer
 type CreateTransferRequest struct {
 	FromAccountID int64 `json:"from_account_id" binding:"required,min=1"`
@@ -31,6 +94,12 @@ type CreateTransferRequest struct {
 	Amount        int64 `json:"amount" binding:"required,gt=0"`
 }
 
+type QuoteTransferFeeRequest struct {
+	FromAccountID int64 `form:"from_account_id" binding:"required,min=1"`
+	ToAccountID   int64 `form:"to_account_id" binding:"required,min=1"`
+	Amount        int64 `form:"amount" binding:"required,gt=0"`
+}
+
 type GetTransferRequest struct {
 	ID int64 `uri:"id" binding:"required,min=1"`
 }
@@ -44,6 +113,25 @@ type DeleteTransferRequest struct {
 	ID int64 `uri:"id" binding:"required,min=1"`
 }
 
+type TransferURIRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// Transfer requests
+type TransferRequestURIRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type RejectTransferRequestRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+type ListTransferRequestsRequest struct {
+	Status   string `form:"status,default=pending" binding:"oneof=pending approved rejected expired"`
+	PageID   int32  `form:"page_id" binding:"required,min=1"`
+	PageSize int32  `form:"page_size" binding:"required,min=5,max=10"`
+}
+
 // Entries
 type CreateEntryRequest struct {
 	AccountID int64 `json:"account_id" binding:"required,min=1"`
@@ -66,7 +154,7 @@ type DeleteEntryRequest struct {
 // Users
 type CreateUserRequest struct {
 	Username string `json:"username" binding:"required,alphanum"`
-	Password string `json:"password" binding:"required,min=6"`
+	Password string `json:"password" binding:"required"`
 	FullName string `json:"full_name" binding:"required"`
 	Email    string `json:"email" binding:"required,email"`
 }
@@ -77,12 +165,222 @@ type UpdateUserRequest struct {
 	Password string `json:"password,omitempty"`
 }
 
+// UserResponse is the public view of a user, it never carries the password hash
+type UserResponse struct {
+	Username      string    `json:"username"`
+	FullName      string    `json:"full_name"`
+	Email         string    `json:"email"`
+	Role          string    `json:"role"`
+	EmailVerified bool      `json:"email_verified"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+func newUserResponse(user db.User) UserResponse {
+	return UserResponse{
+		Username:      user.Username,
+		FullName:      user.FullName,
+		Email:         user.Email,
+		Role:          user.Role,
+		EmailVerified: user.EmailVerifiedAt.Valid,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+	}
+}
+
+type UserProfileResponse struct {
+	UserResponse
+	Accounts []db.Account `json:"accounts"`
+}
+
+type VerifyEmailRequest struct {
+	Code string `form:"code" binding:"required"`
+}
+
 type LoginRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	// Scopes narrows down the access token, all scopes are granted when left empty
+	Scopes []string `json:"scopes" binding:"omitempty,unique"`
 }
 
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// LoginUserResponse carries either an access token or, for users with MFA, a challenge token
+// to trade for one at /user/login/mfa
 type LoginUserResponse struct {
-	AccessToken string `json:"access_token"`
-	ExpiresIn   int64  `json:"expires_in"`
+	AccessToken    string `json:"access_token,omitempty"`
+	Scope          string `json:"scope,omitempty"`
+	MFARequired    bool   `json:"mfa_required,omitempty"`
+	ChallengeToken string `json:"challenge_token,omitempty"`
+	ExpiresIn      int64  `json:"expires_in"`
+}
+
+// MFA
+type VerifyMFALoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+type EnrollMFAResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type ConfirmMFAResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// Login protection
+type ListLoginEventsRequest struct {
+	Username string `form:"username"`
+	ClientIP string `form:"client_ip"`
+	Outcome  string `form:"outcome" binding:"omitempty,oneof=success failure throttled"`
+	PageID   int32  `form:"page_id" binding:"required,min=1"`
+	PageSize int32  `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+// API keys
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1,unique"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type APIKeyURIRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// APIKeyResponse describes an API key without its secret
+type APIKeyResponse struct {
+	ID         int64              `json:"id"`
+	Name       string             `json:"name"`
+	Prefix     string             `json:"prefix"`
+	Scopes     []string           `json:"scopes"`
+	ExpiresAt  pgtype.Timestamptz `json:"expires_at"`
+	LastUsedAt pgtype.Timestamptz `json:"last_used_at"`
+	RevokedAt  pgtype.Timestamptz `json:"revoked_at"`
+	CreatedAt  time.Time          `json:"created_at"`
+}
+
+func newAPIKeyResponse(apiKey db.ApiKey) APIKeyResponse {
+	return APIKeyResponse{
+		ID:         apiKey.ID,
+		Name:       apiKey.Name,
+		Prefix:     apiKey.Prefix,
+		Scopes:     apiKey.Scopes,
+		ExpiresAt:  apiKey.ExpiresAt,
+		LastUsedAt: apiKey.LastUsedAt,
+		RevokedAt:  apiKey.RevokedAt,
+		CreatedAt:  apiKey.CreatedAt,
+	}
+}
+
+// CreateAPIKeyResponse is the only response carrying the full key
+type CreateAPIKeyResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
+
+// Audit log
+type ListAuditLogsRequest struct {
+	Actor        string    `form:"actor"`
+	Action       string    `form:"action"`
+	ResourceType string    `form:"resource_type"`
+	ResourceID   string    `form:"resource_id"`
+	RequestID    string    `form:"request_id"`
+	Since        time.Time `form:"since" time_format:"2006-01-02T15:04:05Z07:00"`
+	Until        time.Time `form:"until" time_format:"2006-01-02T15:04:05Z07:00"`
+	PageID       int32     `form:"page_id" binding:"required,min=1"`
+	PageSize     int32     `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+// AuditLogResponse renders the snapshots of an audit log row as JSON rather than base64
+type AuditLogResponse struct {
+	ID           int64           `json:"id"`
+	Actor        *string         `json:"actor"`
+	Action       string          `json:"action"`
+	ResourceType string          `json:"resource_type"`
+	ResourceID   string          `json:"resource_id"`
+	Before       json.RawMessage `json:"before"`
+	After        json.RawMessage `json:"after"`
+	RequestID    string          `json:"request_id"`
+	ClientIP     string          `json:"client_ip"`
+	PrevHash     string          `json:"prev_hash"`
+	Hash         string          `json:"hash"`
+	CreatedAt    time.Time       `json:"created_at"`
+}
+
+func newAuditLogResponse(row db.AuditLog) AuditLogResponse {
+	resp := AuditLogResponse{
+		ID:           row.ID,
+		Action:       row.Action,
+		ResourceType: row.ResourceType,
+		ResourceID:   row.ResourceID,
+		Before:       row.Before,
+		After:        row.After,
+		RequestID:    row.RequestID,
+		ClientIP:     row.ClientIp,
+		PrevHash:     row.PrevHash,
+		Hash:         row.Hash,
+		CreatedAt:    row.CreatedAt,
+	}
+	if row.Actor.Valid {
+		resp.Actor = &row.Actor.String
+	}
+	return resp
+}
+
+// Webhooks
+type CreateWebhookRequest struct {
+	URL        string   `json:"url" binding:"required"`
+	EventTypes []string `json:"event_types" binding:"required,min=1,unique"`
+}
+
+type WebhookURIRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type ListWebhookDeliveriesRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=50"`
+}
+
+// WebhookResponse describes a webhook subscription without its secret
+type WebhookResponse struct {
+	ID         int64     `json:"id"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func newWebhookResponse(subscription db.WebhookSubscription) WebhookResponse {
+	return WebhookResponse{
+		ID:         subscription.ID,
+		URL:        subscription.Url,
+		EventTypes: subscription.EventTypes,
+		CreatedAt:  subscription.CreatedAt,
+	}
+}
+
+// CreateWebhookResponse is the only response carrying the signing secret
+type CreateWebhookResponse struct {
+	WebhookResponse
+	Secret string `json:"secret"`
+}
+
+type WebhookDeliveryResponse struct {
+	db.WebhookDelivery
+	Attempts []db.WebhookDeliveryAttempt `json:"attempts"`
 }
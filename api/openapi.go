@@ -0,0 +1,460 @@
+package api
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// OpenAPIVersion is the version of the OpenAPI specification the document at /openapi.json follows
+const OpenAPIVersion = "3.1.0"
+
+//go:embed swagger_ui.html
+var swaggerUIPage []byte
+
+// newOpenAPIDocumentOnce builds the document of the versions mounted once, from the operations and the types
+// they bind and render
+func newOpenAPIDocumentOnce(versions []apiVersion) func() ([]byte, error) {
+	return sync.OnceValues(func() ([]byte, error) {
+		return json.Marshal(newOpenAPIDocument(apiOperations, versions))
+	})
+}
+
+// OpenAPI serves the OpenAPI document describing every route of the router
+func (s *Server) OpenAPI(ctx *gin.Context) {
+	document, err := s.openAPIDocument()
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.Data(http.StatusOK, "application/json", document)
+}
+
+// SwaggerUI serves a page browsing the OpenAPI document
+func (s *Server) SwaggerUI(ctx *gin.Context) {
+	ctx.Data(http.StatusOK, "text/html; charset=utf-8", swaggerUIPage)
+}
+
+// apiOperation describes a route of the router for the OpenAPI document.
+// The uri, query, header and body values are the structs the handler binds, their binding tags become constraints.
+type apiOperation struct {
+	method  string
+	path    string
+	tag     string
+	summary string
+	// scope is required from the credentials of the caller, public operations have none and aren't authenticated
+	scope  string
+	public bool
+	// unversioned operations are served at their path only, the others under the prefix of every version and
+	// at their deprecated unversioned path
+	unversioned bool
+	// role is required from the user on top of the scope
+	role      string
+	uri       any
+	query     any
+	header    any
+	body      any
+	responses []apiResponse
+}
+
+// apiResponse is a successful response of an operation, body is the value rendered or nil for an empty body
+type apiResponse struct {
+	status      int
+	description string
+	body        any
+	// contentType defaults to application/json
+	contentType string
+}
+
+// names of the security schemes of the document
+const (
+	bearerSecurityScheme = "bearer"
+	apiKeySecurityScheme = "api_key"
+)
+
+// ginParamPattern matches the parameters of gin paths such as :id
+var ginParamPattern = regexp.MustCompile(`:([A-Za-z_]+)`)
+
+// OpenAPIPath turns a gin path into an OpenAPI path, /account/:id becomes /account/{id}
+func OpenAPIPath(path string) string {
+	return ginParamPattern.ReplaceAllString(path, "{$1}")
+}
+
+func newOpenAPIDocument(operations []apiOperation, versions []apiVersion) map[string]any {
+	schemas := newSchemaRegistry()
+	paths := make(map[string]map[string]any)
+
+	add := func(op apiOperation, path string, deprecated bool) {
+		path = OpenAPIPath(path)
+		if paths[path] == nil {
+			paths[path] = make(map[string]any)
+		}
+		paths[path][strings.ToLower(op.method)] = schemas.operation(op, path, deprecated)
+	}
+
+	for _, op := range operations {
+		if op.unversioned {
+			add(op, op.path, false)
+			continue
+		}
+
+		for _, version := range versions {
+			add(op, version.prefix+op.path, false)
+		}
+		add(op, op.path, true)
+	}
+
+	return map[string]any{
+		"openapi": OpenAPIVersion,
+		"info": map[string]any{
+			"title":       "PrimaryBank API",
+			"version":     "1.0",
+			"description": "Accounts, transfers and entries of PrimaryBank. Failed requests are answered with RFC 7807 problems.",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas.schemas,
+			"securitySchemes": map[string]any{
+				bearerSecurityScheme: map[string]any{
+					"type":         "http",
+					"scheme":       "bearer",
+					"bearerFormat": "JWT",
+					"description":  "access token from " + V1Prefix + "/user/login",
+				},
+				apiKeySecurityScheme: map[string]any{
+					"type":        "apiKey",
+					"in":          "header",
+					"name":        "Authorization",
+					"description": "API key from " + V1Prefix + "/user/api_key, sent as \"" + APIKeyAuthType + " <key>\"",
+				},
+			},
+		},
+	}
+}
+
+// schemaRegistry collects the schemas of the named structs referenced by the operations
+type schemaRegistry struct {
+	schemas map[string]any
+	names   map[reflect.Type]string
+}
+
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{
+		schemas: make(map[string]any),
+		names:   make(map[reflect.Type]string),
+	}
+}
+
+// operation describes op served at path, deprecated on the unversioned paths of versioned operations
+func (r *schemaRegistry) operation(op apiOperation, path string, deprecated bool) map[string]any {
+	operation := map[string]any{
+		"operationId": op.method + " " + path,
+		"summary":     op.summary,
+		"tags":        []string{op.tag},
+	}
+
+	var description []string
+	if op.scope != "" {
+		description = append(description, "Requires the `"+op.scope+"` scope.")
+	}
+	if op.role != "" {
+		description = append(description, "Requires the `"+op.role+"` role.")
+	}
+	if deprecated {
+		operation["deprecated"] = true
+		description = append(description, "Deprecated, served like `"+V1Prefix+path+"` until its sunset.")
+	}
+	if len(description) > 0 {
+		operation["description"] = strings.Join(description, " ")
+	}
+
+	if op.public {
+		operation["security"] = []any{}
+	} else {
+		scopes := []string{}
+		if op.scope != "" {
+			scopes = append(scopes, op.scope)
+		}
+		operation["security"] = []any{
+			map[string]any{bearerSecurityScheme: scopes},
+			map[string]any{apiKeySecurityScheme: scopes},
+		}
+	}
+
+	var parameters []any
+	parameters = append(parameters, r.parameters(op.uri, "uri", "path")...)
+	parameters = append(parameters, r.parameters(op.query, "form", "query")...)
+	parameters = append(parameters, r.parameters(op.header, "header", "header")...)
+	if len(parameters) > 0 {
+		operation["parameters"] = parameters
+	}
+
+	if op.body != nil {
+		operation["requestBody"] = map[string]any{
+			"required": true,
+			"content": map[string]any{
+				"application/json": map[string]any{"schema": r.schema(reflect.TypeOf(op.body))},
+			},
+		}
+	}
+
+	responses := make(map[string]any)
+	for _, resp := range op.responses {
+		response := map[string]any{"description": resp.description}
+		if resp.body != nil {
+			contentType := resp.contentType
+			if contentType == "" {
+				contentType = "application/json"
+			}
+			response["content"] = map[string]any{
+				contentType: map[string]any{"schema": r.schema(reflect.TypeOf(resp.body))},
+			}
+		}
+		responses[strconv.Itoa(resp.status)] = response
+	}
+	responses["default"] = map[string]any{
+		"description": "the request failed",
+		"content": map[string]any{
+			ProblemContentType: map[string]any{"schema": r.schema(reflect.TypeOf(Problem{}))},
+		},
+	}
+	operation["responses"] = responses
+
+	return operation
+}
+
+// parameters describes the fields of a struct bound from the uri, the query string or the headers
+func (r *schemaRegistry) parameters(value any, tagName string, in string) []any {
+	if value == nil {
+		return nil
+	}
+
+	var parameters []any
+	t := reflect.TypeOf(value)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, options := splitTag(field.Tag.Get(tagName))
+		if name == "" || name == "-" {
+			continue
+		}
+
+		schema, required := r.fieldSchema(field)
+		for _, option := range options {
+			if value, ok := strings.CutPrefix(option, "default="); ok {
+				schema["default"] = value
+			}
+		}
+
+		parameters = append(parameters, map[string]any{
+			"name":     name,
+			"in":       in,
+			"required": required || in == "path",
+			"schema":   schema,
+		})
+	}
+	return parameters
+}
+
+// schema returns the schema of a type, named structs are registered as components and referenced
+func (r *schemaRegistry) schema(t reflect.Type) map[string]any {
+	switch t {
+	case reflect.TypeOf(time.Time{}):
+		return map[string]any{"type": "string", "format": "date-time"}
+	case reflect.TypeOf(pgtype.Timestamptz{}):
+		return map[string]any{"type": []string{"string", "null"}, "format": "date-time"}
+	case reflect.TypeOf(pgtype.Text{}):
+		return map[string]any{"type": []string{"string", "null"}}
+	case reflect.TypeOf(pgtype.Int4{}):
+		return map[string]any{"type": []string{"integer", "null"}, "format": "int32"}
+	case reflect.TypeOf(pgtype.Int8{}):
+		return map[string]any{"type": []string{"integer", "null"}, "format": "int64"}
+	case reflect.TypeOf(json.RawMessage{}):
+		return map[string]any{"description": "any JSON value"}
+	case reflect.TypeOf([]byte{}):
+		return map[string]any{"type": "string", "contentEncoding": "base64"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return map[string]any{"anyOf": []any{r.schema(t.Elem()), map[string]any{"type": "null"}}}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint32, reflect.Uint, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": r.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": r.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return r.structSchema(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + r.register(t)}
+	default:
+		return map[string]any{}
+	}
+}
+
+// register adds the schema of a named struct to the components, prefixing its name with its package
+// when another type already took it
+func (r *schemaRegistry) register(t reflect.Type) string {
+	if name, ok := r.names[t]; ok {
+		return name
+	}
+
+	// types only declared for the document are unexported
+	name := strings.ToUpper(t.Name()[:1]) + t.Name()[1:]
+	if _, taken := r.schemas[name]; taken {
+		pkg := t.PkgPath()
+		name = pkg[strings.LastIndex(pkg, "/")+1:] + "." + name
+	}
+
+	r.names[t] = name
+	// reserved before the fields are walked so recursive types end up referencing it
+	r.schemas[name] = map[string]any{}
+	r.schemas[name] = r.structSchema(t)
+	return name
+}
+
+func (r *schemaRegistry) structSchema(t reflect.Type) map[string]any {
+	properties := make(map[string]any)
+	required := []string{}
+	r.addProperties(t, properties, &required)
+
+	schema := map[string]any{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// addProperties adds the fields of a struct to properties, the fields of embedded structs are promoted like
+// encoding/json does. Fields with binding tags are required when bound as required, the fields of rendered
+// structs are required unless they are omitted when empty.
+func (r *schemaRegistry) addProperties(t reflect.Type, properties map[string]any, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, options := splitTag(field.Tag.Get("json"))
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			r.addProperties(field.Type, properties, required)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema, bound := r.fieldSchema(field)
+		properties[name] = schema
+
+		if bound || (!isRequestType(t) && !slices.Contains(options, "omitempty")) {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// fieldSchema returns the schema of a field with the constraints of its binding tag,
+// and whether the binding requires it
+func (r *schemaRegistry) fieldSchema(field reflect.StructField) (map[string]any, bool) {
+	schema := r.schema(field.Type)
+
+	var required bool
+	for _, rule := range strings.Split(field.Tag.Get("binding"), ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			required = true
+		case "min":
+			schema[boundKeyword(field.Type, "minimum", "minLength", "minItems")] = parseBound(param)
+		case "max":
+			schema[boundKeyword(field.Type, "maximum", "maxLength", "maxItems")] = parseBound(param)
+		case "gt":
+			schema["exclusiveMinimum"] = parseBound(param)
+		case "oneof":
+			var values []any
+			for _, value := range strings.Fields(param) {
+				if isNumber(field.Type) {
+					values = append(values, parseBound(value))
+				} else {
+					values = append(values, value)
+				}
+			}
+			schema["enum"] = values
+		case "email":
+			schema["format"] = "email"
+		case "alphanum":
+			schema["pattern"] = "^[a-zA-Z0-9]+$"
+		case "unique":
+			schema["uniqueItems"] = true
+		}
+	}
+	return schema, required
+}
+
+// isRequestType reports structs bound from requests, their fields are only required when bound as such
+func isRequestType(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if _, ok := t.Field(i).Tag.Lookup("binding"); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func boundKeyword(t reflect.Type, number string, str string, array string) string {
+	switch {
+	case t.Kind() == reflect.String:
+		return str
+	case t.Kind() == reflect.Slice:
+		return array
+	default:
+		return number
+	}
+}
+
+func isNumber(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+func parseBound(value string) any {
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return n
+	}
+	return value
+}
+
+func splitTag(tag string) (string, []string) {
+	parts := strings.Split(tag, ",")
+	return parts[0], parts[1:]
+}
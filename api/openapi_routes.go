@@ -0,0 +1,430 @@
+package api
+
+import (
+	"net/http"
+
+	db "github.com/primarybank/db/sqlc"
+	"github.com/primarybank/token"
+)
+
+// usernameURIRequest documents the username the user routes read with ctx.Param
+type usernameURIRequest struct {
+	Username string `uri:"username" binding:"required"`
+}
+
+// accountEventsHeaders documents the header resuming an account event stream
+type accountEventsHeaders struct {
+	LastEventID int64 `header:"Last-Event-ID" binding:"omitempty,min=0"`
+}
+
+// messageResponse documents the gin.H confirming deletions
+type messageResponse struct {
+	Message string `json:"message"`
+}
+
+func respond(status int, description string, body any) apiResponse {
+	return apiResponse{status: status, description: description, body: body}
+}
+
+func noContent(description string) apiResponse {
+	return apiResponse{status: http.StatusNoContent, description: description}
+}
+
+// apiOperations documents every route of setUpRouter, in the same order
+var apiOperations = []apiOperation{
+	// keys and documents aren't versioned
+	{
+		method: http.MethodGet, path: "/.well-known/jwks.json", tag: "users", summary: "Public keys of the access tokens",
+		unversioned: true,
+		public:      true,
+		responses:   []apiResponse{respond(http.StatusOK, "the keys access tokens can be verified with", token.JWKSet{})},
+	},
+	{
+		method: http.MethodGet, path: "/openapi.json", tag: "docs", summary: "This document",
+		unversioned: true,
+		public:      true,
+		responses:   []apiResponse{respond(http.StatusOK, "the OpenAPI document", map[string]any{})},
+	},
+	{
+		method: http.MethodGet, path: "/docs", tag: "docs", summary: "Browse this document",
+		unversioned: true,
+		public:      true,
+		responses: []apiResponse{
+			{status: http.StatusOK, description: "a Swagger UI page", body: "", contentType: "text/html"},
+		},
+	},
+
+	// User routes
+	{
+		method: http.MethodPost, path: "/user", tag: "users", summary: "Sign up",
+		public:    true,
+		body:      CreateUserRequest{},
+		responses: []apiResponse{respond(http.StatusCreated, "the new user, a verification email is sent", UserResponse{})},
+	},
+	{
+		method: http.MethodPost, path: "/user/login", tag: "users", summary: "Log in",
+		public:    true,
+		body:      LoginRequest{},
+		responses: []apiResponse{respond(http.StatusOK, "an access token, or a challenge token for users with MFA", LoginUserResponse{})},
+	},
+	{
+		method: http.MethodPost, path: "/user/login/mfa", tag: "users", summary: "Complete a login with a second factor",
+		public:    true,
+		body:      VerifyMFALoginRequest{},
+		responses: []apiResponse{respond(http.StatusOK, "an access token", LoginUserResponse{})},
+	},
+	{
+		method: http.MethodPost, path: "/user/password/forgot", tag: "users", summary: "Mail a password reset token",
+		public: true,
+		body:   ForgotPasswordRequest{},
+		responses: []apiResponse{
+			{status: http.StatusAccepted, description: "a token is mailed if the email belongs to a user"},
+		},
+	},
+	{
+		method: http.MethodPost, path: "/user/password/reset", tag: "users", summary: "Reset a password",
+		public:    true,
+		body:      ResetPasswordRequest{},
+		responses: []apiResponse{noContent("the password was changed and the sessions of the user revoked")},
+	},
+	{
+		method: http.MethodGet, path: "/user/verify_email", tag: "users", summary: "Verify an email address",
+		public:    true,
+		query:     VerifyEmailRequest{},
+		responses: []apiResponse{respond(http.StatusOK, "the verified user", UserResponse{})},
+	},
+
+	// routes require auth
+	{
+		method: http.MethodGet, path: "/user/profile", tag: "users", summary: "Get the authenticated user with their accounts",
+		scope:     token.ScopeProfileRead,
+		responses: []apiResponse{respond(http.StatusOK, "the user", UserProfileResponse{})},
+	},
+	{
+		method: http.MethodGet, path: "/user/:username", tag: "users", summary: "Get a user",
+		scope:     token.ScopeProfileRead,
+		uri:       usernameURIRequest{},
+		responses: []apiResponse{respond(http.StatusOK, "the user", UserResponse{})},
+	},
+	{
+		method: http.MethodPut, path: "/user/:username", tag: "users", summary: "Update the authenticated user",
+		scope:     token.ScopeProfileWrite,
+		uri:       usernameURIRequest{},
+		body:      UpdateUserRequest{},
+		responses: []apiResponse{respond(http.StatusOK, "the updated user", UserResponse{})},
+	},
+	{
+		method: http.MethodPost, path: "/user/verify_email/resend", tag: "users", summary: "Mail a new verification code",
+		scope: token.ScopeProfileWrite,
+		responses: []apiResponse{
+			{status: http.StatusAccepted, description: "a new code was mailed"},
+			noContent("the email address is already verified"),
+		},
+	},
+	{
+		method: http.MethodPost, path: "/user/mfa/enroll", tag: "mfa", summary: "Start enrolling an authenticator",
+		scope:     token.ScopeProfileWrite,
+		responses: []apiResponse{respond(http.StatusOK, "the secret to add to the authenticator", EnrollMFAResponse{})},
+	},
+	{
+		method: http.MethodPost, path: "/user/mfa/confirm", tag: "mfa", summary: "Enable MFA with a code of the authenticator",
+		scope:     token.ScopeProfileWrite,
+		body:      MFACodeRequest{},
+		responses: []apiResponse{respond(http.StatusOK, "recovery codes, shown this once", ConfirmMFAResponse{})},
+	},
+	{
+		method: http.MethodDelete, path: "/user/mfa", tag: "mfa", summary: "Disable MFA",
+		scope:     token.ScopeProfileWrite,
+		body:      MFACodeRequest{},
+		responses: []apiResponse{noContent("MFA was disabled")},
+	},
+	{
+		method: http.MethodPost, path: "/user/api_key", tag: "api keys", summary: "Create an API key",
+		scope:     token.ScopeProfileWrite,
+		body:      CreateAPIKeyRequest{},
+		responses: []apiResponse{respond(http.StatusCreated, "the key, shown this once", CreateAPIKeyResponse{})},
+	},
+	{
+		method: http.MethodGet, path: "/user/api_keys", tag: "api keys", summary: "List the API keys of the authenticated user",
+		scope:     token.ScopeProfileRead,
+		responses: []apiResponse{respond(http.StatusOK, "the keys without their secrets", []APIKeyResponse{})},
+	},
+	{
+		method: http.MethodDelete, path: "/user/api_key/:id", tag: "api keys", summary: "Revoke an API key",
+		scope:     token.ScopeProfileWrite,
+		uri:       APIKeyURIRequest{},
+		responses: []apiResponse{noContent("the key was revoked")},
+	},
+	{
+		method: http.MethodPost, path: "/user/webhook", tag: "webhooks", summary: "Subscribe a webhook to events",
+		scope:     token.ScopeWebhooksWrite,
+		body:      CreateWebhookRequest{},
+		responses: []apiResponse{respond(http.StatusCreated, "the subscription with its signing secret, shown this once", CreateWebhookResponse{})},
+	},
+	{
+		method: http.MethodGet, path: "/user/webhooks", tag: "webhooks", summary: "List the webhooks of the authenticated user",
+		scope:     token.ScopeWebhooksRead,
+		responses: []apiResponse{respond(http.StatusOK, "the subscriptions", []WebhookResponse{})},
+	},
+	{
+		method: http.MethodDelete, path: "/user/webhook/:id", tag: "webhooks", summary: "Delete a webhook",
+		scope:     token.ScopeWebhooksWrite,
+		uri:       WebhookURIRequest{},
+		responses: []apiResponse{noContent("the subscription was deleted with its deliveries")},
+	},
+	{
+		method: http.MethodGet, path: "/user/webhook/:id/deliveries", tag: "webhooks", summary: "List the deliveries of a webhook",
+		scope:     token.ScopeWebhooksRead,
+		uri:       WebhookURIRequest{},
+		query:     ListWebhookDeliveriesRequest{},
+		responses: []apiResponse{respond(http.StatusOK, "the deliveries, latest first", []db.WebhookDelivery{})},
+	},
+	{
+		method: http.MethodGet, path: "/user/webhook_delivery/:id", tag: "webhooks", summary: "Get a delivery with its attempts",
+		scope:     token.ScopeWebhooksRead,
+		uri:       WebhookURIRequest{},
+		responses: []apiResponse{respond(http.StatusOK, "the delivery", WebhookDeliveryResponse{})},
+	},
+	{
+		method: http.MethodPost, path: "/user/webhook_delivery/:id/redeliver", tag: "webhooks", summary: "Deliver an event again",
+		scope:     token.ScopeWebhooksWrite,
+		uri:       WebhookURIRequest{},
+		responses: []apiResponse{respond(http.StatusAccepted, "the delivery, due at once", db.WebhookDelivery{})},
+	},
+
+	// Account routes
+	{
+		method: http.MethodGet, path: "/account/:id", tag: "accounts", summary: "Get an account",
+		scope:     token.ScopeAccountsRead,
+		uri:       GetAccountRequest{},
+		responses: []apiResponse{respond(http.StatusOK, "the account", db.Account{})},
+	},
+	{
+		method: http.MethodGet, path: "/accounts", tag: "accounts", summary: "List accounts",
+		scope:     token.ScopeAccountsRead,
+		query:     ListAccountsRequest{},
+		responses: []apiResponse{respond(http.StatusOK, "a page of accounts", []db.Account{})},
+	},
+	{
+		method: http.MethodPost, path: "/account", tag: "accounts", summary: "Open an account",
+		scope:     token.ScopeAccountsWrite,
+		body:      CreateAccountRequest{},
+		responses: []apiResponse{respond(http.StatusOK, "the new account", db.Account{})},
+	},
+	{
+		method: http.MethodPatch, path: "/account", tag: "accounts", summary: "Set the balance of an account",
+		scope:     token.ScopeAccountsWrite,
+		body:      UpdateAccountRequest{},
+		responses: []apiResponse{noContent("the balance was set")},
+	},
+	{
+		method: http.MethodDelete, path: "/account/:id", tag: "accounts", summary: "Delete an account",
+		scope:     token.ScopeAccountsWrite,
+		uri:       DeleteAccountRequest{},
+		responses: []apiResponse{noContent("the account was deleted")},
+	},
+	{
+		method: http.MethodGet, path: "/account/:id/limits", tag: "accounts", summary: "What the transaction limits still allow",
+		scope:     token.ScopeAccountsRead,
+		uri:       AccountURIRequest{},
+		responses: []apiResponse{respond(http.StatusOK, "the allowance left by every limit applying to the account", []db.TransactionAllowance{})},
+	},
+	{
+		method: http.MethodGet, path: "/account/:id/events", tag: "accounts", summary: "Stream the entries and the balance of an account",
+		scope:  token.ScopeAccountsRead,
+		uri:    AccountURIRequest{},
+		header: accountEventsHeaders{},
+		responses: []apiResponse{{
+			status:      http.StatusOK,
+			description: "server-sent entry events with the entry id, each batch followed by a balance event",
+			body:        "",
+			contentType: "text/event-stream",
+		}},
+	},
+
+	// Transfer routes
+	{
+		method: http.MethodGet, path: "/transfer/fee", tag: "transfers", summary: "Quote the fee of a transfer",
+		scope:     token.ScopeTransfersRead,
+		query:     QuoteTransferFeeRequest{},
+		responses: []apiResponse{respond(http.StatusOK, "the fee and the total debited from the sender", db.FeeQuote{})},
+	},
+	{
+		method: http.MethodGet, path: "/transfer/:id", tag: "transfers", summary: "Get a transfer",
+		scope:     token.ScopeTransfersRead,
+		uri:       GetTransferRequest{},
+		responses: []apiResponse{respond(http.StatusOK, "the transfer", db.Transfer{})},
+	},
+	{
+		method: http.MethodGet, path: "/transfers", tag: "transfers", summary: "List transfers",
+		scope:     token.ScopeTransfersRead,
+		query:     ListTransfersRequest{},
+		responses: []apiResponse{respond(http.StatusOK, "a page of transfers", []db.Transfer{})},
+	},
+	{
+		method: http.MethodPost, path: "/transfer/:id/post", tag: "transfers", summary: "Post a pending transfer",
+		scope:     token.ScopeTransfersWrite,
+		uri:       TransferURIRequest{},
+		responses: []apiResponse{respond(http.StatusOK, "the posted transfer with its entries", db.TransferTxResult{})},
+	},
+	{
+		method: http.MethodPost, path: "/transfer/:id/void", tag: "transfers", summary: "Void a pending transfer",
+		scope:     token.ScopeTransfersWrite,
+		uri:       TransferURIRequest{},
+		responses: []apiResponse{respond(http.StatusOK, "the voided transfer with its refund entries", db.VoidTransferTxResult{})},
+	},
+	{
+		method: http.MethodDelete, path: "/transfer/:id", tag: "transfers", summary: "Delete a posted or voided transfer",
+		scope:     token.ScopeTransfersWrite,
+		uri:       DeleteTransferRequest{},
+		responses: []apiResponse{respond(http.StatusOK, "the transfer was deleted", messageResponse{})},
+	},
+	{
+		method: http.MethodGet, path: "/transfer_request/:id", tag: "transfer requests", summary: "Get a transfer request",
+		scope:     token.ScopeTransfersRead,
+		uri:       TransferRequestURIRequest{},
+		responses: []apiResponse{respond(http.StatusOK, "the transfer request", db.TransferRequest{})},
+	},
+
+	// Entry routes
+	{
+		method: http.MethodGet, path: "/entry/:id", tag: "entries", summary: "Get an entry",
+		scope:     token.ScopeEntriesRead,
+		uri:       GetEntryRequest{},
+		responses: []apiResponse{respond(http.StatusOK, "the entry", db.Entry{})},
+	},
+	{
+		method: http.MethodGet, path: "/entries", tag: "entries", summary: "List entries",
+		scope:     token.ScopeEntriesRead,
+		query:     ListEntriesRequest{},
+		responses: []apiResponse{respond(http.StatusOK, "a page of entries", []db.Entry{})},
+	},
+	{
+		method: http.MethodDelete, path: "/entry/:id", tag: "entries", summary: "Delete an entry",
+		scope:     token.ScopeEntriesWrite,
+		uri:       DeleteEntryRequest{},
+		responses: []apiResponse{respond(http.StatusOK, "the entry was deleted", messageResponse{})},
+	},
+
+	// Money movement routes
+	{
+		method: http.MethodPost, path: "/account/:id/withdraw", tag: "accounts", summary: "Withdraw from an account",
+		scope:     token.ScopeAccountsWrite,
+		uri:       AccountURIRequest{},
+		body:      WithdrawRequest{},
+		responses: []apiResponse{respond(http.StatusOK, "the account with the withdrawal entry", db.WithdrawTxResult{})},
+	},
+	{
+		method: http.MethodPost, path: "/transfer", tag: "transfers", summary: "Transfer money between accounts",
+		scope: token.ScopeTransfersWrite,
+		body:  CreateTransferRequest{},
+		responses: []apiResponse{
+			respond(http.StatusOK, "the transfer with its entries", db.TransferTxResult{}),
+			respond(http.StatusAccepted, "a transfer request waiting for an approver, for amounts above the approval threshold", db.TransferRequest{}),
+		},
+	},
+	{
+		method: http.MethodPost, path: "/transfer/reserve", tag: "transfers", summary: "Reserve a transfer to post or void later",
+		scope:     token.ScopeTransfersWrite,
+		body:      CreateTransferRequest{},
+		responses: []apiResponse{respond(http.StatusOK, "the pending transfer with the entry reserving the amount", db.TransferTxResult{})},
+	},
+	{
+		method: http.MethodPost, path: "/entry", tag: "entries", summary: "Book an entry",
+		scope:     token.ScopeEntriesWrite,
+		body:      CreateEntryRequest{},
+		responses: []apiResponse{respond(http.StatusOK, "the entry", db.Entry{})},
+	},
+
+	// Admin routes
+	{
+		method: http.MethodPut, path: "/admin/account/:id/overdraft", tag: "admin", summary: "Set the overdraft limit of an account",
+		scope: token.ScopeAdmin, role: db.RoleAdmin,
+		uri:       AccountURIRequest{},
+		body:      SetOverdraftLimitRequest{},
+		responses: []apiResponse{respond(http.StatusOK, "the account with the recorded change", db.SetOverdraftLimitTxResult{})},
+	},
+	{
+		method: http.MethodDelete, path: "/admin/account/:id/overdraft", tag: "admin", summary: "Revoke the overdraft limit of an account",
+		scope: token.ScopeAdmin, role: db.RoleAdmin,
+		uri:       AccountURIRequest{},
+		body:      RevokeOverdraftLimitRequest{},
+		responses: []apiResponse{respond(http.StatusOK, "the account with the recorded change", db.SetOverdraftLimitTxResult{})},
+	},
+	{
+		method: http.MethodGet, path: "/admin/account/:id/overdraft/history", tag: "admin", summary: "List the overdraft limit changes of an account",
+		scope: token.ScopeAdmin, role: db.RoleAdmin,
+		uri:       AccountURIRequest{},
+		query:     ListOverdraftLimitChangesRequest{},
+		responses: []apiResponse{respond(http.StatusOK, "a page of changes", []db.OverdraftLimitChange{})},
+	},
+	{
+		method: http.MethodGet, path: "/admin/transaction_limits", tag: "admin", summary: "List transaction limits",
+		scope: token.ScopeAdmin, role: db.RoleAdmin,
+		query:     ListTransactionLimitsRequest{},
+		responses: []apiResponse{respond(http.StatusOK, "a page of limits", []db.TransactionLimit{})},
+	},
+	{
+		method: http.MethodPost, path: "/admin/transaction_limit", tag: "admin", summary: "Create a transaction limit",
+		scope: token.ScopeAdmin, role: db.RoleAdmin,
+		body:      CreateTransactionLimitRequest{},
+		responses: []apiResponse{respond(http.StatusCreated, "the limit", db.TransactionLimit{})},
+	},
+	{
+		method: http.MethodPut, path: "/admin/transaction_limit/:id", tag: "admin", summary: "Update a transaction limit",
+		scope: token.ScopeAdmin, role: db.RoleAdmin,
+		uri:       TransactionLimitURIRequest{},
+		body:      UpdateTransactionLimitRequest{},
+		responses: []apiResponse{respond(http.StatusOK, "the limit", db.TransactionLimit{})},
+	},
+	{
+		method: http.MethodDelete, path: "/admin/transaction_limit/:id", tag: "admin", summary: "Delete a transaction limit",
+		scope: token.ScopeAdmin, role: db.RoleAdmin,
+		uri:       TransactionLimitURIRequest{},
+		responses: []apiResponse{respond(http.StatusOK, "the limit was deleted", messageResponse{})},
+	},
+	{
+		method: http.MethodPost, path: "/admin/user/:username/unlock", tag: "admin", summary: "Unlock a user locked out by failed logins",
+		scope: token.ScopeAdmin, role: db.RoleAdmin,
+		uri:       usernameURIRequest{},
+		responses: []apiResponse{noContent("the user can log in again")},
+	},
+	{
+		method: http.MethodGet, path: "/admin/login_events", tag: "admin", summary: "List login attempts",
+		scope: token.ScopeAdmin, role: db.RoleAdmin,
+		query:     ListLoginEventsRequest{},
+		responses: []apiResponse{respond(http.StatusOK, "a page of login events", []db.LoginEvent{})},
+	},
+	{
+		method: http.MethodGet, path: "/admin/audit_logs", tag: "admin", summary: "Search the audit log",
+		scope: token.ScopeAdmin, role: db.RoleAdmin,
+		query:     ListAuditLogsRequest{},
+		responses: []apiResponse{respond(http.StatusOK, "a page of audit log rows", []AuditLogResponse{})},
+	},
+	{
+		method: http.MethodGet, path: "/admin/audit_logs/verify", tag: "admin", summary: "Verify the hash chain of the audit log",
+		scope: token.ScopeAdmin, role: db.RoleAdmin,
+		responses: []apiResponse{respond(http.StatusOK, "whether the chain is sound, and where it breaks", db.AuditLogVerification{})},
+	},
+
+	// Approver routes
+	{
+		method: http.MethodGet, path: "/transfer_requests", tag: "transfer requests", summary: "List transfer requests",
+		scope: token.ScopeTransfersRead, role: db.RoleApprover,
+		query:     ListTransferRequestsRequest{},
+		responses: []apiResponse{respond(http.StatusOK, "a page of transfer requests", []db.TransferRequest{})},
+	},
+	{
+		method: http.MethodPost, path: "/transfer_request/:id/approve", tag: "transfer requests", summary: "Approve a transfer request",
+		scope: token.ScopeTransfersWrite, role: db.RoleApprover,
+		uri:       TransferRequestURIRequest{},
+		responses: []apiResponse{respond(http.StatusOK, "the approved request with the transfer made", db.ApproveTransferRequestTxResult{})},
+	},
+	{
+		method: http.MethodPost, path: "/transfer_request/:id/reject", tag: "transfer requests", summary: "Reject a transfer request",
+		scope: token.ScopeTransfersWrite, role: db.RoleApprover,
+		uri:       TransferRequestURIRequest{},
+		body:      RejectTransferRequestRequest{},
+		responses: []apiResponse{respond(http.StatusOK, "the rejected request", db.TransferRequest{})},
+	},
+}
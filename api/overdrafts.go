@@ -0,0 +1,91 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/primarybank/db/sqlc"
+	"github.com/primarybank/token"
+)
+
+// SetOverdraftLimit grants or changes the overdraft facility of an account
+func (s *Server) SetOverdraftLimit(ctx *gin.Context) {
+	var uri AccountURIRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	var req SetOverdraftLimitRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	s.setOverdraftLimit(ctx, uri.ID, req.OverdraftLimit, req.Reason)
+}
+
+// RevokeOverdraftLimit removes the overdraft facility of an account
+func (s *Server) RevokeOverdraftLimit(ctx *gin.Context) {
+	var uri AccountURIRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	var req RevokeOverdraftLimitRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	s.setOverdraftLimit(ctx, uri.ID, 0, req.Reason)
+}
+
+func (s *Server) setOverdraftLimit(ctx *gin.Context, accountID int64, limit int64, reason string) {
+	payload := ctx.MustGet(AuthzPayloadKey).(*token.Payload)
+
+	result, err := s.store.SetOverdraftLimitTx(ctx.Request.Context(), db.SetOverdraftLimitTxParams{
+		AccountID:      accountID,
+		OverdraftLimit: limit,
+		ChangedBy:      payload.Username,
+		Reason:         reason,
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	before := result.Account
+	before.OverdraftLimit = result.Change.PreviousLimit
+	audit(ctx, "account", accountID, before, result.Account)
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// ListOverdraftLimitChanges returns the overdraft limit history of an account, newest first
+func (s *Server) ListOverdraftLimitChanges(ctx *gin.Context) {
+	var uri AccountURIRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	var req ListOverdraftLimitChangesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	changes, err := s.store.ListOverdraftLimitChanges(ctx.Request.Context(), db.ListOverdraftLimitChangesParams{
+		AccountID: uri.ID,
+		Limit:     req.PageSize,
+		Offset:    (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, changes)
+}
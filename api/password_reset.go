@@ -0,0 +1,100 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	commonutils "github.com/primarybank/common/utils"
+	db "github.com/primarybank/db/sqlc"
+	"github.com/primarybank/mail"
+)
+
+const passwordResetTokenBytes = 32
+
+// ForgotPassword mails a one-time password reset token to the owner of the email.
+// It answers the same whether the email is known or not, so it cannot be used to find users.
+func (s *Server) ForgotPassword(ctx *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	user, err := s.store.GetUserByEmail(ctx.Request.Context(), req.Email)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			ctx.Status(http.StatusAccepted)
+			return
+		}
+		ctx.Error(err)
+		return
+	}
+
+	token, err := commonutils.RandomSecret(passwordResetTokenBytes)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ttl := s.Config.PasswordResetTokenTTL
+	_, err = s.store.CreatePasswordResetToken(ctx.Request.Context(), db.CreatePasswordResetTokenParams{
+		Username:  user.Username,
+		TokenHash: commonutils.HashSecret(token),
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	audit(ctx, "password_reset_token", user.Username, nil, nil)
+
+	// answering with a failed send would tell the email is known, the user can ask again
+	afterCommit(ctx, func() {
+		err := s.Mailer.Send(ctx.Request.Context(), mail.Message{
+			To:      []string{user.Email},
+			Subject: "Reset your password",
+			Body: fmt.Sprintf("Hi %s,\n\nUse this code to reset your password: %s\n\nIt expires in %s. If you did not ask for it, you can ignore this email.\n",
+				user.FullName, token, ttl),
+		})
+		if err != nil {
+			log.Printf("cannot send password reset email to %s: %v", user.Username, err)
+		}
+	})
+
+	ctx.Status(http.StatusAccepted)
+}
+
+// ResetPassword sets a new password for the user a reset token was issued to
+func (s *Server) ResetPassword(ctx *gin.Context) {
+	var req ResetPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	if err := s.policy.Validate(req.Password); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	// the slow hash waits for the token to check out
+	user, err := s.store.ResetPasswordTx(ctx.Request.Context(), db.ResetPasswordTxParams{
+		TokenHash: commonutils.HashSecret(req.Token),
+		HashPassword: func() (string, error) {
+			return s.hasher.Hash(req.Password)
+		},
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	audit(ctx, "user", user.Username, nil, nil)
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
@@ -0,0 +1,21 @@
+package api
+
+import (
+	"fmt"
+
+	commonerrors "github.com/primarybank/common/errors"
+	"github.com/primarybank/token"
+)
+
+// scopeFieldErrors blames every unknown scope requested in the scopes field
+func scopeFieldErrors(scopes []string) []commonerrors.FieldError {
+	var fields []commonerrors.FieldError
+
+	for _, scope := range scopes {
+		if !token.IsScope(scope) {
+			fields = append(fields, commonerrors.FieldError{Field: "scopes", Reason: fmt.Sprintf("unknown scope %q", scope)})
+		}
+	}
+
+	return fields
+}
@@ -1,11 +1,20 @@
 package api
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/primarybank/activity"
+	commonutils "github.com/primarybank/common/utils"
 	"github.com/primarybank/config"
 	db "github.com/primarybank/db/sqlc"
+	"github.com/primarybank/mail"
+	"github.com/primarybank/mfa"
 	"github.com/primarybank/token"
 )
 
@@ -14,64 +23,278 @@ type Server struct {
 	store      db.Store
 	Router     *gin.Engine
 	TokenMaker token.Maker
-	Config     config.Config
+	// keyring is nil when tokens are signed with the symmetric key
+	keyring       *token.Keyring
+	EmailVerifier *token.EmailVerifier
+	Mailer        mail.Sender
+	Config        config.Config
+	mfaCipher     *mfa.Cipher
+	webhookCipher *mfa.Cipher
+	hasher        *commonutils.PasswordHasher
+	policy        commonutils.PasswordPolicy
+	// dummyPasswordHash is checked against the password of unknown usernames
+	dummyPasswordHash func() (string, error)
+	// Activity wakes up the account event streams, it only hears from postgres while it runs
+	Activity *activity.Hub
+	// Gateway serves the routes listed in GATEWAY_ROUTES, they stay with their gin handlers while it is nil
+	Gateway       http.Handler
+	gatewayRoutes map[string]bool
+	// V2Handlers are the handlers of the routes /v2 changed, by method and v1 path as in "GET /account/:id".
+	// /v2 is mounted once it changes a route, it serves every other route of v1 as it is, behind the same
+	// middlewares.
+	V2Handlers map[string]func(*Server, *gin.Context)
+	// versions are mounted under their prefix, the unversioned routes answer like v1, deprecated since
+	// legacyDeprecatedAt, until legacySunset
+	versions           []apiVersion
+	legacyDeprecatedAt time.Time
+	legacySunset       time.Time
+	openAPIDocument    func() ([]byte, error)
 }
 
-func NewServer(cfg config.Config, store db.Store) (*Server, error) {
-	tokenMaker, err := token.NewJWTMaker(cfg.TokenSymmetricKey)
+// ServerOption changes a server before its router is set up
+type ServerOption func(*Server)
+
+func NewServer(cfg config.Config, store db.Store, opts ...ServerOption) (*Server, error) {
+	var keyring *token.Keyring
+	var tokenMaker token.Maker
+	var err error
+	if cfg.TokenSigningKey != "" {
+		keyring, err = token.ParseKeyring(cfg.TokenSigningKey, cfg.TokenVerificationKeys)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load token keyring: %w", err)
+		}
+		tokenMaker = token.NewEd25519Maker(keyring)
+	} else {
+		tokenMaker, err = token.NewJWTMaker(cfg.TokenSymmetricKey)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create token maker: %w", err)
+		}
+	}
+
+	emailVerifier, err := token.NewEmailVerifier(cfg.TokenSymmetricKey)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create email verifier: %w", err)
+	}
+
+	mailer, err := mail.NewSender(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create mail sender: %w", err)
+	}
+
+	mfaCipher, err := mfa.NewCipher(cfg.MFAEncryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create mfa cipher: %w", err)
+	}
+
+	// a leaked webhook key must not reveal the TOTP secrets
+	if strings.EqualFold(cfg.WebhookEncryptionKey, cfg.MFAEncryptionKey) {
+		return nil, errors.New("webhook encryption key must differ from the mfa encryption key")
+	}
+	webhookCipher, err := mfa.NewCipher(cfg.WebhookEncryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create webhook secret cipher: %w", err)
+	}
+
+	gatewayRoutes, err := parseGatewayRoutes(cfg.GatewayRoutes)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load gateway routes: %w", err)
+	}
+
+	legacyDeprecatedAt, err := parseLegacyRoutesDate(cfg.LegacyRoutesDeprecatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse legacy routes deprecation date: %w", err)
+	}
+	legacySunset, err := parseLegacyRoutesDate(cfg.LegacyRoutesSunset)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse legacy routes sunset: %w", err)
+	}
+
+	breached, err := commonutils.LoadBreachedPasswords(cfg.PasswordBreachedList)
 	if err != nil {
-		return nil, fmt.Errorf("cannot create token maker: %w", err)
+		return nil, fmt.Errorf("cannot load breached passwords: %w", err)
 	}
 
 	server := &Server{
-		store:      store,
-		TokenMaker: tokenMaker,
-		Config:     cfg,
+		store:         store,
+		TokenMaker:    tokenMaker,
+		keyring:       keyring,
+		EmailVerifier: emailVerifier,
+		Mailer:        mailer,
+		Config:        cfg,
+		mfaCipher:     mfaCipher,
+		webhookCipher: webhookCipher,
+		hasher: commonutils.NewPasswordHasher(commonutils.Argon2Params{
+			Memory:      cfg.Argon2Memory,
+			Iterations:  cfg.Argon2Iterations,
+			Parallelism: cfg.Argon2Parallelism,
+		}),
+		policy: commonutils.PasswordPolicy{
+			MinLength:     cfg.PasswordMinLength,
+			RequireUpper:  cfg.PasswordRequireUpper,
+			RequireLower:  cfg.PasswordRequireLower,
+			RequireDigit:  cfg.PasswordRequireDigit,
+			RequireSymbol: cfg.PasswordRequireSymbol,
+			Breached:      breached,
+		},
+		Activity:           activity.NewHub(store),
+		gatewayRoutes:      gatewayRoutes,
+		V2Handlers:         v2Handlers(),
+		legacyDeprecatedAt: legacyDeprecatedAt,
+		legacySunset:       legacySunset,
+	}
+	for _, opt := range opts {
+		opt(server)
+	}
+
+	server.versions = server.apiVersions()
+	server.openAPIDocument = newOpenAPIDocumentOnce(server.versions)
+	server.dummyPasswordHash = sync.OnceValues(func() (string, error) {
+		password, err := commonutils.RandomSecret(32)
+		if err != nil {
+			return "", err
+		}
+		return server.hasher.Hash(password)
+	})
+	if err := server.setUpRouter(); err != nil {
+		return nil, fmt.Errorf("cannot set up router: %w", err)
 	}
-	server.setUpRouter()
 
 	return server, nil
 }
 
-func (server *Server) setUpRouter() {
+func (server *Server) setUpRouter() error {
 	router := gin.Default()
-	// add routes to the routes
+	// the client IP throttles logins and is audited, it must not come from a header anyone can send
+	if err := router.SetTrustedProxies(parseTrustedProxies(server.Config.TrustedProxies)); err != nil {
+		return fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+	router.Use(RequestIDMiddleware(), ErrorHandler(), AuditMiddleware(server.store))
+
+	// keys and documents aren't versioned
+	router.GET("/.well-known/jwks.json", server.JWKS)
+	router.GET("/openapi.json", server.OpenAPI)
+	router.GET("/docs", server.SwaggerUI)
+
+	for _, version := range server.versions {
+		server.registerRoutes(router.Group(version.prefix, VersionMiddleware(version.prefix)), version)
+	}
+	if err := checkChangedRoutes(router, server.versions); err != nil {
+		return err
+	}
+
+	// the routes from before versioning serve v1 until their sunset
+	server.registerRoutes(router.Group("/", DeprecationMiddleware(server.legacyDeprecatedAt, server.legacySunset)), apiVersion{})
+
+	server.Router = router
+	return nil
+}
+
+// parseTrustedProxies reads the comma separated TRUSTED_PROXIES, none when it is empty
+func parseTrustedProxies(value string) []string {
+	var proxies []string
+	for _, proxy := range strings.Split(value, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
+// registerRoutes registers every route of the API in router for version
+func (server *Server) registerRoutes(router *gin.RouterGroup, version apiVersion) {
 	// User routes
-	router.POST("/user", server.CreateUser)
-	router.POST("/user/login", server.LoginUser)
+	publicRoutes := server.routes(router, version)
+	publicRoutes.POST("/user", server.CreateUser)
+	publicRoutes.POST("/user/login", server.LoginUser)
+	publicRoutes.POST("/user/login/mfa", server.VerifyMFALogin)
+	publicRoutes.POST("/user/password/forgot", server.ForgotPassword)
+	publicRoutes.POST("/user/password/reset", server.ResetPassword)
+	publicRoutes.GET("/user/verify_email", server.VerifyEmail)
 
-	authRoutes := router.Group("/").Use(AuthMiddleWare(server.TokenMaker))
+	authRoutes := server.routes(router.Group("/", AuthMiddleWare(server.TokenMaker, server.store)), version)
 
 	// routes require auth
-	authRoutes.GET("/user/:username", server.GetUser)
-	authRoutes.PUT("/user/:username", server.UpdateUser)
+	authRoutes.GET("/user/profile", ScopeMiddleware(token.ScopeProfileRead), server.GetUserProfile)
+	authRoutes.GET("/user/:username", ScopeMiddleware(token.ScopeProfileRead), server.viaGateway(server.GetUser))
+	authRoutes.PUT("/user/:username", ScopeMiddleware(token.ScopeProfileWrite), server.UpdateUser)
+	authRoutes.POST("/user/verify_email/resend", ScopeMiddleware(token.ScopeProfileWrite), server.ResendVerificationEmail)
+	authRoutes.POST("/user/mfa/enroll", ScopeMiddleware(token.ScopeProfileWrite), server.EnrollMFA)
+	authRoutes.POST("/user/mfa/confirm", ScopeMiddleware(token.ScopeProfileWrite), server.ConfirmMFA)
+	authRoutes.DELETE("/user/mfa", ScopeMiddleware(token.ScopeProfileWrite), server.DisableMFA)
+	authRoutes.POST("/user/api_key", ScopeMiddleware(token.ScopeProfileWrite), server.CreateAPIKey)
+	authRoutes.GET("/user/api_keys", ScopeMiddleware(token.ScopeProfileRead), server.ListAPIKeys)
+	authRoutes.DELETE("/user/api_key/:id", ScopeMiddleware(token.ScopeProfileWrite), server.RevokeAPIKey)
+	authRoutes.POST("/user/webhook", ScopeMiddleware(token.ScopeWebhooksWrite), server.CreateWebhook)
+	authRoutes.GET("/user/webhooks", ScopeMiddleware(token.ScopeWebhooksRead), server.ListWebhooks)
+	authRoutes.DELETE("/user/webhook/:id", ScopeMiddleware(token.ScopeWebhooksWrite), server.DeleteWebhook)
+	authRoutes.GET("/user/webhook/:id/deliveries", ScopeMiddleware(token.ScopeWebhooksRead), server.ListWebhookDeliveries)
+	authRoutes.GET("/user/webhook_delivery/:id", ScopeMiddleware(token.ScopeWebhooksRead), server.GetWebhookDelivery)
+	authRoutes.POST("/user/webhook_delivery/:id/redeliver", ScopeMiddleware(token.ScopeWebhooksWrite), server.RedeliverWebhook)
 
 	// Account routes
-	authRoutes.GET("/account/:id", server.GetAccount)
-	authRoutes.GET("/accounts", server.ListAccounts)
-	authRoutes.POST("/account", server.CreateAccount)
-	authRoutes.PATCH("/account", server.UpdateAccount)
-	authRoutes.DELETE("/account/:id", server.DeleteAccount)
+	authRoutes.GET("/account/:id", ScopeMiddleware(token.ScopeAccountsRead), server.viaGateway(server.GetAccount))
+	authRoutes.GET("/accounts", ScopeMiddleware(token.ScopeAccountsRead), server.viaGateway(server.ListAccounts))
+	authRoutes.POST("/account", ScopeMiddleware(token.ScopeAccountsWrite), server.viaGateway(server.CreateAccount))
+	authRoutes.PATCH("/account", ScopeMiddleware(token.ScopeAccountsWrite), server.UpdateAccount)
+	authRoutes.DELETE("/account/:id", ScopeMiddleware(token.ScopeAccountsWrite), server.DeleteAccount)
+	authRoutes.GET("/account/:id/limits", ScopeMiddleware(token.ScopeAccountsRead), server.GetTransactionAllowances)
+	authRoutes.GET("/account/:id/events", ScopeMiddleware(token.ScopeAccountsRead), server.StreamAccountEvents)
 
 	// Transfer routes
-	authRoutes.GET("/transfer/:id", server.GetTransfer)
-	authRoutes.GET("/transfers", server.ListTransfers)
-	authRoutes.POST("/transfer", server.CreateTransfer)
-	authRoutes.DELETE("/transfer/:id", server.DeleteTransfer)
+	authRoutes.GET("/transfer/fee", ScopeMiddleware(token.ScopeTransfersRead), server.QuoteTransferFee)
+	authRoutes.GET("/transfer/:id", ScopeMiddleware(token.ScopeTransfersRead), server.viaGateway(server.GetTransfer))
+	authRoutes.GET("/transfers", ScopeMiddleware(token.ScopeTransfersRead), server.viaGateway(server.ListTransfers))
+	authRoutes.POST("/transfer/:id/post", ScopeMiddleware(token.ScopeTransfersWrite), server.PostTransfer)
+	authRoutes.POST("/transfer/:id/void", ScopeMiddleware(token.ScopeTransfersWrite), server.VoidTransfer)
+	authRoutes.DELETE("/transfer/:id", ScopeMiddleware(token.ScopeTransfersWrite), server.DeleteTransfer)
+	authRoutes.GET("/transfer_request/:id", ScopeMiddleware(token.ScopeTransfersRead), server.GetTransferRequest)
 
 	// Entry routes
-	authRoutes.GET("/entry/:id", server.GetEntry)
-	authRoutes.GET("/entries", server.ListEntries)
-	authRoutes.POST("/entry", server.CreateEntry)
-	authRoutes.DELETE("/entry/:id", server.DeleteEntry)
+	authRoutes.GET("/entry/:id", ScopeMiddleware(token.ScopeEntriesRead), server.viaGateway(server.GetEntry))
+	authRoutes.GET("/entries", ScopeMiddleware(token.ScopeEntriesRead), server.viaGateway(server.ListEntries))
+	authRoutes.DELETE("/entry/:id", ScopeMiddleware(token.ScopeEntriesWrite), server.DeleteEntry)
 
-	server.Router = router
+	// Money movement routes, closed to unverified users when configured
+	moneyRoutes := server.routes(router.Group("/",
+		AuthMiddleWare(server.TokenMaker, server.store),
+		VerifiedEmailMiddleware(server.store, server.Config.EmailVerificationRequiredFor),
+	), version)
+
+	moneyRoutes.POST("/account/:id/withdraw", ScopeMiddleware(token.ScopeAccountsWrite), server.Withdraw)
+	moneyRoutes.POST("/transfer", ScopeMiddleware(token.ScopeTransfersWrite), server.CreateTransfer)
+	moneyRoutes.POST("/transfer/reserve", ScopeMiddleware(token.ScopeTransfersWrite), server.ReserveTransfer)
+	moneyRoutes.POST("/entry", ScopeMiddleware(token.ScopeEntriesWrite), server.CreateEntry)
+
+	// Admin routes
+	adminRoutes := server.routes(router.Group("/admin",
+		AuthMiddleWare(server.TokenMaker, server.store),
+		RoleMiddleware(server.store, db.RoleAdmin),
+		ScopeMiddleware(token.ScopeAdmin),
+	), version)
+
+	adminRoutes.PUT("/account/:id/overdraft", server.SetOverdraftLimit)
+	adminRoutes.DELETE("/account/:id/overdraft", server.RevokeOverdraftLimit)
+	adminRoutes.GET("/account/:id/overdraft/history", server.ListOverdraftLimitChanges)
+	adminRoutes.GET("/transaction_limits", server.ListTransactionLimits)
+	adminRoutes.POST("/transaction_limit", server.CreateTransactionLimit)
+	adminRoutes.PUT("/transaction_limit/:id", server.UpdateTransactionLimit)
+	adminRoutes.DELETE("/transaction_limit/:id", server.DeleteTransactionLimit)
+	adminRoutes.POST("/user/:username/unlock", server.UnlockUser)
+	adminRoutes.GET("/login_events", server.ListLoginEvents)
+	adminRoutes.GET("/audit_logs", server.ListAuditLogs)
+	adminRoutes.GET("/audit_logs/verify", server.VerifyAuditLog)
+
+	// Approver routes
+	approverRoutes := server.routes(router.Group("/",
+		AuthMiddleWare(server.TokenMaker, server.store),
+		RoleMiddleware(server.store, db.RoleApprover),
+	), version)
+
+	approverRoutes.GET("/transfer_requests", ScopeMiddleware(token.ScopeTransfersRead), server.ListTransferRequests)
+	approverRoutes.POST("/transfer_request/:id/approve", ScopeMiddleware(token.ScopeTransfersWrite), server.ApproveTransferRequest)
+	approverRoutes.POST("/transfer_request/:id/reject", ScopeMiddleware(token.ScopeTransfersWrite), server.RejectTransferRequest)
 }
 
 func (s *Server) Start(addr string) error {
 	return s.Router.Run(addr)
 }
-
-func errResp(err error) gin.H {
-	return gin.H{"error": err.Error()}
-}
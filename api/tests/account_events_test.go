@@ -0,0 +1,169 @@
+package tests
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/primarybank/api"
+	"github.com/primarybank/db/mocks"
+	db "github.com/primarybank/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+type streamedEvent struct {
+	id    string
+	event string
+	data  string
+}
+
+// readEvent reads the next server-sent event, skipping heartbeats
+func readEvent(t *testing.T, reader *bufio.Reader) streamedEvent {
+	var event streamedEvent
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			if event.event != "" {
+				return event
+			}
+		case strings.HasPrefix(line, "id:"):
+			event.id = strings.TrimPrefix(line, "id:")
+		case strings.HasPrefix(line, "event:"):
+			event.event = strings.TrimPrefix(line, "event:")
+		case strings.HasPrefix(line, "data:"):
+			event.data = strings.TrimPrefix(line, "data:")
+		}
+	}
+}
+
+func TestStreamAccountEvents(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+
+	account := CreateRandomAccount(t)
+	missed := db.Entry{ID: 11, AccountID: account.ID, Amount: -10}
+	booked := db.Entry{ID: 12, AccountID: account.ID, Amount: 25}
+
+	store.EXPECT().GetAccount(gomock.Any(), account.ID).Return(*account, nil).AnyTimes()
+	store.EXPECT().GetLastAccountEntry(gomock.Any(), gomock.Any()).Times(0)
+	gomock.InOrder(
+		store.EXPECT().
+			ListAccountEntriesAfter(gomock.Any(), db.ListAccountEntriesAfterParams{AccountID: account.ID, ID: 10, Limit: 100}).
+			Return([]db.Entry{missed}, nil).
+			Times(1),
+		store.EXPECT().
+			ListAccountEntriesAfter(gomock.Any(), db.ListAccountEntriesAfterParams{AccountID: account.ID, ID: 11, Limit: 100}).
+			Return([]db.Entry{booked}, nil).
+			Times(1),
+	)
+
+	httpServer := httptest.NewServer(server.Router)
+	defer httpServer.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, httpServer.URL+"/v1/account/"+strconv.FormatInt(account.ID, 10)+"/events", nil)
+	require.NoError(t, err)
+	req.Header.Set(api.LastEventIDHeader, "10")
+	addAuthz(t, server.TokenMaker, req, api.AuthType, account.Owner, time.Minute)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(resp.Body)
+
+	// the entries missed since Last-Event-ID come first
+	event := readEvent(t, reader)
+	require.Equal(t, "entry", event.event)
+	require.Equal(t, "11", event.id)
+
+	event = readEvent(t, reader)
+	require.Equal(t, "balance", event.event)
+	var balance api.AccountBalanceEvent
+	require.NoError(t, json.Unmarshal([]byte(event.data), &balance))
+	require.Equal(t, account.Balance, balance.Balance)
+
+	// then new entries as they are booked
+	server.Activity.Publish(db.AccountActivity{AccountID: account.ID, EntryID: booked.ID})
+
+	event = readEvent(t, reader)
+	require.Equal(t, "entry", event.event)
+	require.Equal(t, "12", event.id)
+
+	var entry db.Entry
+	require.NoError(t, json.Unmarshal([]byte(event.data), &entry))
+	require.Equal(t, booked.Amount, entry.Amount)
+
+	require.Equal(t, "balance", readEvent(t, reader).event)
+}
+
+func TestStreamAccountEventsRejected(t *testing.T) {
+	account := CreateRandomAccount(t)
+
+	testCases := []struct {
+		name        string
+		username    string
+		lastEventID string
+		buildStubs  func(store *mocks.MockStore)
+		checkResp   func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "Account Of Another User",
+			username: "other",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), account.ID).Return(*account, nil).Times(1)
+				store.EXPECT().ListAccountEntriesAfter(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, http.StatusNotFound, "not_found")
+			},
+		},
+		{
+			name:        "Invalid Last Event ID",
+			username:    account.Owner,
+			lastEventID: "yesterday",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				problem := requireProblem(t, recorder, http.StatusBadRequest, "invalid_request")
+				require.Equal(t, api.LastEventIDHeader, problem.InvalidParams[0].Name)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mocks.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+			tc.buildStubs(store)
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/account/"+strconv.FormatInt(account.ID, 10)+"/events", nil)
+			if tc.lastEventID != "" {
+				req.Header.Set(api.LastEventIDHeader, tc.lastEventID)
+			}
+			tc.checkResp(t, serveAs(t, server, req, tc.username))
+		})
+	}
+}
@@ -1,18 +1,15 @@
 package tests
 
 import (
-	"bytes"
-	"database/sql"
-	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
 	"testing"
 	"time"
 
-	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
 	"github.com/primarybank/api"
+	commonerrors "github.com/primarybank/common/errors"
 	commonutils "github.com/primarybank/common/utils"
 	"github.com/primarybank/db/mocks"
 	db "github.com/primarybank/db/sqlc"
@@ -51,12 +48,41 @@ func TestCreateAccount(t *testing.T) {
 			},
 			buildStubs: func(store *mocks.MockStore) {
 				store.EXPECT().
-					CreateAccount(gomock.Any(), gomock.Any()).
+					CreateAccountTx(gomock.Any(), gomock.Any()).
 					Return(*account, nil).
 					Times(1)
 			},
 			expectedCode: http.StatusOK,
 		},
+		{
+			name: "Duplicate Currency",
+			requestBody: api.CreateAccountRequest{
+				Owner:    account.Owner,
+				Currency: account.Currency,
+			},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().
+					CreateAccountTx(gomock.Any(), gomock.Any()).
+					Return(db.Account{}, alreadyExists("owner", "currency")).
+					Times(1)
+			},
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name: "Unknown Owner",
+			requestBody: api.CreateAccountRequest{
+				Owner:    account.Owner,
+				Currency: account.Currency,
+			},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().
+					CreateAccountTx(gomock.Any(), gomock.Any()).
+					Return(db.Account{}, commonerrors.New(commonerrors.KindUnprocessable, "invalid_reference", "owner does not exist").
+						WithFields(commonerrors.FieldError{Field: "owner", Reason: "does not exist"})).
+					Times(1)
+			},
+			expectedCode: http.StatusUnprocessableEntity,
+		},
 		{
 			name: "Invalid Request - Empty Owner",
 			requestBody: api.CreateAccountRequest{
@@ -64,7 +90,7 @@ func TestCreateAccount(t *testing.T) {
 				Currency: account.Currency,
 			},
 			buildStubs: func(store *mocks.MockStore) {
-				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateAccountTx(gomock.Any(), gomock.Any()).Times(0)
 			},
 			expectedCode: http.StatusBadRequest,
 		},
@@ -74,13 +100,8 @@ func TestCreateAccount(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			tc.buildStubs(store)
 
-			recorder := httptest.NewRecorder()
-			c, _ := gin.CreateTestContext(recorder)
-			body, _ := json.Marshal(tc.requestBody)
-			c.Request = httptest.NewRequest(http.MethodPost, "/accounts", bytes.NewReader(body))
-			c.Request.Header.Set("Content-Type", "application/json")
-
-			server.CreateAccount(c)
+			req := newJSONRequest(t, http.MethodPost, "/v1/account", tc.requestBody)
+			recorder := serveAs(t, server, req, account.Owner)
 
 			require.Equal(t, tc.expectedCode, recorder.Code)
 		})
@@ -116,7 +137,7 @@ func TestGetAccount(t *testing.T) {
 			name:      "Not Found",
 			accountID: "999",
 			buildStubs: func(store *mocks.MockStore) {
-				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Return(db.Account{}, sql.ErrNoRows).Times(1)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Return(db.Account{}, db.ErrNotFound).Times(1)
 			},
 			expectedCode: http.StatusNotFound,
 		},
@@ -126,12 +147,8 @@ func TestGetAccount(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			tc.buildStubs(store)
 
-			recorder := httptest.NewRecorder()
-			c, _ := gin.CreateTestContext(recorder)
-			c.Params = append(c.Params, gin.Param{Key: "id", Value: tc.accountID})
-			c.Request = httptest.NewRequest(http.MethodGet, "/accounts/"+tc.accountID, nil)
-
-			server.GetAccount(c)
+			req := httptest.NewRequest(http.MethodGet, "/v1/account/"+tc.accountID, nil)
+			recorder := serveAs(t, server, req, account.Owner)
 
 			require.Equal(t, tc.expectedCode, recorder.Code)
 		})
@@ -156,6 +173,7 @@ func TestDeleteAccount(t *testing.T) {
 			name:      "Valid Request",
 			accountID: strconv.Itoa(int(account.ID)),
 			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), account.ID).Return(*account, nil).Times(1)
 				store.EXPECT().DeleteAccount(gomock.Any(), account.ID).Return(nil).Times(1)
 			},
 			expectedCode: http.StatusNoContent,
@@ -164,7 +182,8 @@ func TestDeleteAccount(t *testing.T) {
 			name:      "Not Found",
 			accountID: "999",
 			buildStubs: func(store *mocks.MockStore) {
-				store.EXPECT().DeleteAccount(gomock.Any(), gomock.Any()).Return(sql.ErrNoRows).Times(1)
+				store.EXPECT().GetAccount(gomock.Any(), int64(999)).Return(db.Account{}, db.ErrNotFound).Times(1)
+				store.EXPECT().DeleteAccount(gomock.Any(), gomock.Any()).Times(0)
 			},
 			expectedCode: http.StatusNotFound,
 		},
@@ -174,12 +193,8 @@ func TestDeleteAccount(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			tc.buildStubs(store)
 
-			recorder := httptest.NewRecorder()
-			c, _ := gin.CreateTestContext(recorder)
-			c.Params = append(c.Params, gin.Param{Key: "id", Value: tc.accountID})
-			c.Request = httptest.NewRequest(http.MethodDelete, "/accounts/"+tc.accountID, nil)
-
-			server.DeleteAccount(c)
+			req := httptest.NewRequest(http.MethodDelete, "/v1/account/"+tc.accountID, nil)
+			recorder := serveAs(t, server, req, account.Owner)
 
 			require.Equal(t, tc.expectedCode, recorder.Code)
 		})
@@ -221,11 +236,8 @@ func TestListAccounts(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			tc.buildStubs(store)
 
-			recorder := httptest.NewRecorder()
-			c, _ := gin.CreateTestContext(recorder)
-			c.Request = httptest.NewRequest(http.MethodGet, "/accounts?"+tc.queryParams, nil)
-
-			server.ListAccounts(c)
+			req := httptest.NewRequest(http.MethodGet, "/v1/accounts?"+tc.queryParams, nil)
+			recorder := serveAs(t, server, req, "user")
 
 			require.Equal(t, tc.expectedCode, recorder.Code)
 		})
@@ -253,6 +265,7 @@ func TestUpdateAccount(t *testing.T) {
 				Balance: 500,
 			},
 			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), account.ID).Return(*account, nil).Times(1)
 				store.EXPECT().UpdateAccount(gomock.Any(), gomock.Any()).Return(nil).Times(1)
 			},
 			expectedCode: http.StatusNoContent,
@@ -264,7 +277,8 @@ func TestUpdateAccount(t *testing.T) {
 				Balance: 500,
 			},
 			buildStubs: func(store *mocks.MockStore) {
-				store.EXPECT().UpdateAccount(gomock.Any(), gomock.Any()).Return(sql.ErrNoRows).Times(1)
+				store.EXPECT().GetAccount(gomock.Any(), int64(999)).Return(db.Account{}, db.ErrNotFound).Times(1)
+				store.EXPECT().UpdateAccount(gomock.Any(), gomock.Any()).Times(0)
 			},
 			expectedCode: http.StatusNotFound,
 		},
@@ -274,13 +288,67 @@ func TestUpdateAccount(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			tc.buildStubs(store)
 
-			recorder := httptest.NewRecorder()
-			c, _ := gin.CreateTestContext(recorder)
-			body, _ := json.Marshal(tc.requestBody)
-			c.Request = httptest.NewRequest(http.MethodPut, "/accounts", bytes.NewReader(body))
-			c.Request.Header.Set("Content-Type", "application/json")
+			req := newJSONRequest(t, http.MethodPatch, "/v1/account", tc.requestBody)
+			recorder := serveAs(t, server, req, account.Owner)
+
+			require.Equal(t, tc.expectedCode, recorder.Code)
+		})
+	}
+}
+
+func TestWithdraw(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+
+	account := CreateRandomAccount(t)
+	testCases := []struct {
+		name         string
+		requestBody  api.WithdrawRequest
+		buildStubs   func(store *mocks.MockStore)
+		expectedCode int
+	}{
+		{
+			name:        "Valid Request",
+			requestBody: api.WithdrawRequest{Amount: 10},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().
+					WithdrawTx(gomock.Any(), db.WithdrawTxParams{AccountID: account.ID, Amount: 10}).
+					Return(db.WithdrawTxResult{Account: *account}, nil).
+					Times(1)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:        "Overdraft Limit Exceeded",
+			requestBody: api.WithdrawRequest{Amount: 10},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().
+					WithdrawTx(gomock.Any(), gomock.Any()).
+					Return(db.WithdrawTxResult{}, &db.OverdraftError{AccountID: account.ID}).
+					Times(1)
+			},
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:        "Invalid Request - Zero Amount",
+			requestBody: api.WithdrawRequest{Amount: 0},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().WithdrawTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.buildStubs(store)
 
-			server.UpdateAccount(c)
+			id := strconv.FormatInt(account.ID, 10)
+			req := newJSONRequest(t, http.MethodPost, "/v1/account/"+id+"/withdraw", tc.requestBody)
+			recorder := serveAs(t, server, req, account.Owner)
 
 			require.Equal(t, tc.expectedCode, recorder.Code)
 		})
@@ -0,0 +1,367 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/primarybank/api"
+	commonutils "github.com/primarybank/common/utils"
+	"github.com/primarybank/db/mocks"
+	db "github.com/primarybank/db/sqlc"
+	"github.com/primarybank/token"
+	"github.com/stretchr/testify/require"
+)
+
+// newRandomAPIKey returns a stored API key of username along with the key a client would send
+func newRandomAPIKey(t *testing.T, username string) (db.ApiKey, string) {
+	prefix := commonutils.RandomString(12)
+	secret, err := commonutils.RandomSecret(32)
+	require.NoError(t, err)
+
+	apiKey := db.ApiKey{
+		ID:         commonutils.RandomInt(1, 1000),
+		Username:   username,
+		Name:       "ci",
+		Prefix:     prefix,
+		SecretHash: commonutils.HashSecret(secret),
+		Scopes:     []string{token.ScopeAccountsRead},
+		CreatedAt:  time.Now(),
+	}
+	return apiKey, "pbk_" + prefix + "." + secret
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	username := commonutils.RandomString(8)
+
+	testCases := []struct {
+		name       string
+		body       gin.H
+		buildStubs func(store *mocks.MockStore)
+		checkResp  func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"name": "ci", "scopes": []string{token.ScopeAccountsRead, token.ScopeTransfersWrite}},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().
+					CreateAPIKey(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ any, args db.CreateAPIKeyParams) (db.ApiKey, error) {
+						require.Equal(t, username, args.Username)
+						require.Equal(t, "ci", args.Name)
+						require.Equal(t, []string{token.ScopeAccountsRead, token.ScopeTransfersWrite}, args.Scopes)
+						require.False(t, args.ExpiresAt.Valid)
+						return db.ApiKey{ID: 1, Username: args.Username, Name: args.Name, Prefix: args.Prefix, SecretHash: args.SecretHash, Scopes: args.Scopes}, nil
+					}).
+					Times(1)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+				require.NotContains(t, recorder.Body.String(), "secret_hash")
+
+				var resp api.CreateAPIKeyResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+				require.True(t, strings.HasPrefix(resp.Key, "pbk_"+resp.Prefix+"."))
+				require.Len(t, resp.Scopes, 2)
+			},
+		},
+		{
+			name: "Unknown Scope",
+			body: gin.H{"name": "ci", "scopes": []string{token.ScopeAccountsRead, "everything"}},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().CreateAPIKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				problem := requireProblem(t, recorder, http.StatusBadRequest, "invalid_request")
+				require.Equal(t, []api.InvalidParam{{Name: "scopes", Reason: `unknown scope "everything"`}}, problem.InvalidParams)
+			},
+		},
+		{
+			name: "Expiry In The Past",
+			body: gin.H{"name": "ci", "scopes": []string{token.ScopeAccountsRead}, "expires_at": time.Now().Add(-time.Hour)},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().CreateAPIKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				problem := requireProblem(t, recorder, http.StatusBadRequest, "invalid_request")
+				require.Equal(t, "expires_at", problem.InvalidParams[0].Name)
+			},
+		},
+		{
+			name: "No Scopes",
+			body: gin.H{"name": "ci", "scopes": []string{}},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().CreateAPIKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, http.StatusBadRequest, "invalid_request")
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mocks.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+			tc.buildStubs(store)
+
+			req := newJSONRequest(t, http.MethodPost, "/v1/user/api_key", tc.body)
+			tc.checkResp(t, serveAs(t, server, req, username))
+		})
+	}
+}
+
+func TestCreateAPIKeyWithAPIKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+
+	apiKey, key := newRandomAPIKey(t, commonutils.RandomString(8))
+	apiKey.Scopes = []string{token.ScopeProfileWrite}
+	store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), apiKey.Prefix).Return(apiKey, nil).Times(1)
+	store.EXPECT().TouchAPIKey(gomock.Any(), apiKey.ID).Return(nil).Times(1)
+	store.EXPECT().CreateAPIKey(gomock.Any(), gomock.Any()).Times(0)
+
+	req := newJSONRequest(t, http.MethodPost, "/v1/user/api_key", gin.H{"name": "ci", "scopes": []string{token.ScopeAdmin}})
+	req.Header.Set(api.AuthHeaderKey, "ApiKey "+key)
+	requireProblem(t, serve(t, server, req), http.StatusForbidden, "forbidden")
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	user := createRandomUser(t)
+
+	testCases := []struct {
+		name       string
+		buildKey   func(t *testing.T) (db.ApiKey, string)
+		buildStubs func(store *mocks.MockStore, apiKey db.ApiKey)
+		checkResp  func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildKey: func(t *testing.T) (db.ApiKey, string) {
+				apiKey, key := newRandomAPIKey(t, user.Username)
+				apiKey.Scopes = []string{token.ScopeProfileRead}
+				return apiKey, key
+			},
+			buildStubs: func(store *mocks.MockStore, apiKey db.ApiKey) {
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), apiKey.Prefix).Return(apiKey, nil).Times(1)
+				store.EXPECT().TouchAPIKey(gomock.Any(), apiKey.ID).Return(nil).Times(1)
+				store.EXPECT().GetUser(gomock.Any(), user.Username).Return(user, nil).Times(1)
+				store.EXPECT().ListAccountsByOwner(gomock.Any(), user.Username).Return([]db.Account{}, nil).Times(1)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "Insufficient Scope",
+			buildKey: func(t *testing.T) (db.ApiKey, string) {
+				return newRandomAPIKey(t, user.Username)
+			},
+			buildStubs: func(store *mocks.MockStore, apiKey db.ApiKey) {
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), apiKey.Prefix).Return(apiKey, nil).Times(1)
+				store.EXPECT().TouchAPIKey(gomock.Any(), apiKey.ID).Return(nil).Times(1)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, http.StatusForbidden, "insufficient_scope")
+				require.Equal(t, `Bearer error="insufficient_scope", scope="profile:read"`, recorder.Header().Get("WWW-Authenticate"))
+			},
+		},
+		{
+			name: "No Scopes",
+			buildKey: func(t *testing.T) (db.ApiKey, string) {
+				apiKey, key := newRandomAPIKey(t, user.Username)
+				apiKey.Scopes = nil
+				return apiKey, key
+			},
+			buildStubs: func(store *mocks.MockStore, apiKey db.ApiKey) {
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), apiKey.Prefix).Return(apiKey, nil).Times(1)
+				store.EXPECT().TouchAPIKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, http.StatusUnauthorized, "unauthorized")
+			},
+		},
+		{
+			name: "Wrong Secret",
+			buildKey: func(t *testing.T) (db.ApiKey, string) {
+				apiKey, _ := newRandomAPIKey(t, user.Username)
+				return apiKey, "pbk_" + apiKey.Prefix + ".guessed"
+			},
+			buildStubs: func(store *mocks.MockStore, apiKey db.ApiKey) {
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), apiKey.Prefix).Return(apiKey, nil).Times(1)
+				store.EXPECT().TouchAPIKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				problem := requireProblem(t, recorder, http.StatusUnauthorized, "unauthorized")
+				require.Equal(t, "invalid api key", problem.Detail)
+			},
+		},
+		{
+			name: "Unknown Prefix",
+			buildKey: func(t *testing.T) (db.ApiKey, string) {
+				return newRandomAPIKey(t, user.Username)
+			},
+			buildStubs: func(store *mocks.MockStore, apiKey db.ApiKey) {
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), apiKey.Prefix).Return(db.ApiKey{}, db.ErrNotFound).Times(1)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, http.StatusUnauthorized, "unauthorized")
+			},
+		},
+		{
+			name: "Malformed",
+			buildKey: func(t *testing.T) (db.ApiKey, string) {
+				return db.ApiKey{}, "not-an-api-key"
+			},
+			buildStubs: func(store *mocks.MockStore, apiKey db.ApiKey) {
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, http.StatusUnauthorized, "unauthorized")
+			},
+		},
+		{
+			name: "Revoked",
+			buildKey: func(t *testing.T) (db.ApiKey, string) {
+				apiKey, key := newRandomAPIKey(t, user.Username)
+				apiKey.RevokedAt = pgtype.Timestamptz{Time: time.Now().Add(-time.Minute), Valid: true}
+				return apiKey, key
+			},
+			buildStubs: func(store *mocks.MockStore, apiKey db.ApiKey) {
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), apiKey.Prefix).Return(apiKey, nil).Times(1)
+				store.EXPECT().TouchAPIKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				problem := requireProblem(t, recorder, http.StatusUnauthorized, "unauthorized")
+				require.Equal(t, "api key has been revoked", problem.Detail)
+			},
+		},
+		{
+			name: "Expired",
+			buildKey: func(t *testing.T) (db.ApiKey, string) {
+				apiKey, key := newRandomAPIKey(t, user.Username)
+				apiKey.ExpiresAt = pgtype.Timestamptz{Time: time.Now().Add(-time.Second), Valid: true}
+				return apiKey, key
+			},
+			buildStubs: func(store *mocks.MockStore, apiKey db.ApiKey) {
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), apiKey.Prefix).Return(apiKey, nil).Times(1)
+				store.EXPECT().TouchAPIKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				problem := requireProblem(t, recorder, http.StatusUnauthorized, "unauthorized")
+				require.Equal(t, "api key has expired", problem.Detail)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mocks.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+
+			apiKey, key := tc.buildKey(t)
+			tc.buildStubs(store, apiKey)
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/user/profile", nil)
+			req.Header.Set(api.AuthHeaderKey, "ApiKey "+key)
+			tc.checkResp(t, serve(t, server, req))
+		})
+	}
+}
+
+func TestListAPIKeys(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+
+	username := commonutils.RandomString(8)
+	apiKey, _ := newRandomAPIKey(t, username)
+	store.EXPECT().ListAPIKeys(gomock.Any(), username).Return([]db.ApiKey{apiKey}, nil).Times(1)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/user/api_keys", nil)
+	recorder := serveAs(t, server, req, username)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.NotContains(t, recorder.Body.String(), apiKey.SecretHash)
+
+	var resp []api.APIKeyResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+	require.Len(t, resp, 1)
+	require.Equal(t, apiKey.Prefix, resp[0].Prefix)
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	username := commonutils.RandomString(8)
+
+	testCases := []struct {
+		name       string
+		id         string
+		buildStubs func(store *mocks.MockStore)
+		checkResp  func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			id:   "7",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().
+					RevokeAPIKey(gomock.Any(), db.RevokeAPIKeyParams{ID: 7, Username: username}).
+					Return(db.ApiKey{ID: 7}, nil).
+					Times(1)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+			},
+		},
+		{
+			name: "Not Found Or Not Owned",
+			id:   "7",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().RevokeAPIKey(gomock.Any(), gomock.Any()).Return(db.ApiKey{}, db.ErrNotFound).Times(1)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, http.StatusNotFound, "not_found")
+			},
+		},
+		{
+			name: "Invalid ID",
+			id:   "0",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().RevokeAPIKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, http.StatusBadRequest, "invalid_request")
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mocks.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+			tc.buildStubs(store)
+
+			req := httptest.NewRequest(http.MethodDelete, "/v1/user/api_key/"+tc.id, nil)
+			tc.checkResp(t, serveAs(t, server, req, username))
+		})
+	}
+}
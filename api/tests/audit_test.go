@@ -0,0 +1,398 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/primarybank/api"
+	commonutils "github.com/primarybank/common/utils"
+	"github.com/primarybank/db/mocks"
+	db "github.com/primarybank/db/sqlc"
+	"github.com/primarybank/mail"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditMiddleware(t *testing.T) {
+	account := CreateRandomAccount(t)
+
+	testCases := []struct {
+		name       string
+		buildReq   func(t *testing.T) *http.Request
+		buildStubs func(t *testing.T, store *mocks.MockStore)
+		checkResp  func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Create",
+			buildReq: func(t *testing.T) *http.Request {
+				req := newJSONRequest(t, http.MethodPost, "/v1/account", api.CreateAccountRequest{Owner: account.Owner, Currency: account.Currency})
+				req.Header.Set(api.RequestIDHeader, "req-42")
+				return req
+			},
+			buildStubs: func(t *testing.T, store *mocks.MockStore) {
+				store.EXPECT().CreateAccountTx(gomock.Any(), gomock.Any()).Return(*account, nil).Times(1)
+				store.EXPECT().
+					AppendAuditLogTx(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ any, args db.AppendAuditLogTxParams) (db.AuditLog, error) {
+						require.Equal(t, account.Owner, args.Actor)
+						require.Equal(t, "POST /account", args.Action)
+						require.Equal(t, "account", args.ResourceType)
+						require.Equal(t, strconv.FormatInt(account.ID, 10), args.ResourceID)
+						require.Nil(t, args.Before)
+						require.JSONEq(t, toJSON(t, account), string(args.After))
+						require.Equal(t, "req-42", args.RequestID)
+						require.Equal(t, "192.0.2.1", args.ClientIP)
+						return db.AuditLog{ID: 1}, nil
+					}).
+					Times(1)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "req-42", recorder.Header().Get(api.RequestIDHeader))
+			},
+		},
+		{
+			name: "Delete",
+			buildReq: func(t *testing.T) *http.Request {
+				return httptest.NewRequest(http.MethodDelete, "/v1/account/"+strconv.FormatInt(account.ID, 10), nil)
+			},
+			buildStubs: func(t *testing.T, store *mocks.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), account.ID).Return(*account, nil).Times(1)
+				store.EXPECT().DeleteAccount(gomock.Any(), account.ID).Return(nil).Times(1)
+				store.EXPECT().
+					AppendAuditLogTx(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ any, args db.AppendAuditLogTxParams) (db.AuditLog, error) {
+						require.Equal(t, "DELETE /account/:id", args.Action)
+						require.JSONEq(t, toJSON(t, account), string(args.Before))
+						require.Nil(t, args.After)
+						// requests without an id get a generated one
+						require.NotEmpty(t, args.RequestID)
+						return db.AuditLog{ID: 1}, nil
+					}).
+					Times(1)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+				require.NotEmpty(t, recorder.Header().Get(api.RequestIDHeader))
+			},
+		},
+		{
+			name: "Audit Failed",
+			buildReq: func(t *testing.T) *http.Request {
+				return newJSONRequest(t, http.MethodPost, "/v1/account", api.CreateAccountRequest{Owner: account.Owner, Currency: account.Currency})
+			},
+			buildStubs: func(t *testing.T, store *mocks.MockStore) {
+				store.EXPECT().CreateAccountTx(gomock.Any(), gomock.Any()).Return(*account, nil).Times(1)
+				store.EXPECT().AppendAuditLogTx(gomock.Any(), gomock.Any()).Return(db.AuditLog{}, errors.New("audit log unavailable")).Times(1)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// the account was rolled back with its audit row, the response must not show it
+				requireProblem(t, recorder, http.StatusInternalServerError, "internal")
+				require.NotContains(t, recorder.Body.String(), account.Owner)
+				require.NotEmpty(t, recorder.Header().Get(api.RequestIDHeader))
+			},
+		},
+		{
+			name: "Failed Request",
+			buildReq: func(t *testing.T) *http.Request {
+				return httptest.NewRequest(http.MethodDelete, "/v1/account/"+strconv.FormatInt(account.ID, 10), nil)
+			},
+			buildStubs: func(t *testing.T, store *mocks.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), account.ID).Return(db.Account{}, db.ErrNotFound).Times(1)
+				expectNoAudit(t, store)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "Read",
+			buildReq: func(t *testing.T) *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/v1/account/"+strconv.FormatInt(account.ID, 10), nil)
+			},
+			buildStubs: func(t *testing.T, store *mocks.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), account.ID).Return(*account, nil).Times(1)
+				expectNoAudit(t, store)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			// the expectations must come before the catch-all one of the test server
+			store := mocks.NewMockStore(ctrl)
+			tc.buildStubs(t, store)
+			server := newTestServer(t, store)
+
+			tc.checkResp(t, serveAs(t, server, tc.buildReq(t), account.Owner))
+		})
+	}
+}
+
+func TestAuditedWritesJoinTx(t *testing.T) {
+	user := createRandomUser(t)
+	password := commonutils.RandomString(12)
+
+	// every write must be made with the context of the audited transaction, a write made with any other context
+	// commits on its own and fails the test as an unexpected call
+	testCases := []struct {
+		name       string
+		username   string
+		buildReq   func(t *testing.T, server *api.Server) *http.Request
+		buildStubs func(store *mocks.MockStore)
+		mailed     bool
+	}{
+		{
+			name: "Create User",
+			buildReq: func(t *testing.T, server *api.Server) *http.Request {
+				return newJSONRequest(t, http.MethodPost, "/v1/user", api.CreateUserRequest{
+					Username: user.Username,
+					Password: password,
+					FullName: user.FullName,
+					Email:    user.Email,
+				})
+			},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().CreateUserTx(mocks.InAuditedTx(), gomock.Any()).Return(user, nil).Times(1)
+			},
+			mailed: true,
+		},
+		{
+			name:     "Update User",
+			username: user.Username,
+			buildReq: func(t *testing.T, server *api.Server) *http.Request {
+				return newJSONRequest(t, http.MethodPut, "/v1/user/"+user.Username, api.UpdateUserRequest{
+					FullName: user.FullName,
+					Email:    "new." + user.Email,
+				})
+			},
+			buildStubs: func(store *mocks.MockStore) {
+				updated := user
+				updated.Email = "new." + user.Email
+				store.EXPECT().GetUser(gomock.Any(), user.Username).Return(user, nil).AnyTimes()
+				store.EXPECT().UpdateUser(mocks.InAuditedTx(), gomock.Any()).Return(updated, nil).Times(1)
+			},
+		},
+		{
+			name: "Forgot Password",
+			buildReq: func(t *testing.T, server *api.Server) *http.Request {
+				return newJSONRequest(t, http.MethodPost, "/v1/user/password/forgot", api.ForgotPasswordRequest{Email: user.Email})
+			},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().GetUserByEmail(mocks.InAuditedTx(), user.Email).Return(user, nil).Times(1)
+				store.EXPECT().CreatePasswordResetToken(mocks.InAuditedTx(), gomock.Any()).Return(db.PasswordResetToken{}, nil).Times(1)
+			},
+			mailed: true,
+		},
+		{
+			name: "Reset Password",
+			buildReq: func(t *testing.T, server *api.Server) *http.Request {
+				return newJSONRequest(t, http.MethodPost, "/v1/user/password/reset", api.ResetPasswordRequest{Token: "token", Password: password})
+			},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().ResetPasswordTx(mocks.InAuditedTx(), gomock.Any()).Return(user, nil).Times(1)
+			},
+		},
+		{
+			name: "Verify Email",
+			buildReq: func(t *testing.T, server *api.Server) *http.Request {
+				code, err := server.EmailVerifier.CreateCode(user.Username, user.Email, time.Minute)
+				require.NoError(t, err)
+				return httptest.NewRequest(http.MethodGet, "/v1/user/verify_email?code="+url.QueryEscape(code), nil)
+			},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().VerifyUserEmail(mocks.InAuditedTx(), gomock.Any()).Return(user, nil).Times(1)
+			},
+		},
+		{
+			name:     "Enroll MFA",
+			username: user.Username,
+			buildReq: func(t *testing.T, server *api.Server) *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/v1/user/mfa/enroll", nil)
+			},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().GetMFAFactor(mocks.InAuditedTx(), user.Username).Return(db.MfaFactor{}, db.ErrNotFound).Times(1)
+				store.EXPECT().UpsertMFAFactor(mocks.InAuditedTx(), gomock.Any()).Return(db.MfaFactor{}, nil).Times(1)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mocks.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			server := newTestServer(t, store)
+
+			req := tc.buildReq(t, server)
+			var recorder *httptest.ResponseRecorder
+			if tc.username != "" {
+				recorder = serveAs(t, server, req, tc.username)
+			} else {
+				recorder = serve(t, server, req)
+			}
+			require.Less(t, recorder.Code, http.StatusBadRequest, recorder.Body.String())
+
+			// mail is sent once the transaction committed
+			_, mailed := server.Mailer.(*mail.MemorySender).Last(user.Email)
+			require.Equal(t, tc.mailed, mailed)
+		})
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	server := newTestServer(t, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	req.Header.Set(api.RequestIDHeader, "not a request id\n")
+	recorder := serve(t, server, req)
+
+	requestID := recorder.Header().Get(api.RequestIDHeader)
+	require.NotEmpty(t, requestID)
+	require.NotEqual(t, "not a request id\n", requestID)
+}
+
+func TestListAuditLogs(t *testing.T) {
+	since := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	testCases := []struct {
+		name       string
+		query      string
+		buildStubs func(store *mocks.MockStore)
+		checkResp  func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: "?page_id=1&page_size=5",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().
+					ListAuditLogs(gomock.Any(), db.ListAuditLogsParams{LimitCount: 5, OffsetCount: 0}).
+					Return([]db.AuditLog{{
+						ID:           1,
+						Actor:        pgtype.Text{String: "alice", Valid: true},
+						Action:       "PATCH /account",
+						ResourceType: "account",
+						ResourceID:   "7",
+						Before:       []byte(`{"balance":10}`),
+						After:        []byte(`{"balance":20}`),
+					}}, nil).
+					Times(1)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp []api.AuditLogResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+				require.Len(t, resp, 1)
+				require.Equal(t, "alice", *resp[0].Actor)
+				require.JSONEq(t, `{"balance":10}`, string(resp[0].Before))
+				require.JSONEq(t, `{"balance":20}`, string(resp[0].After))
+			},
+		},
+		{
+			name:  "Filtered",
+			query: "?actor=alice&action=PATCH+%2Faccount&resource_type=account&resource_id=7&request_id=req-42&since=2026-01-02T03:04:05Z&page_id=2&page_size=10",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().
+					ListAuditLogs(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ any, args db.ListAuditLogsParams) ([]db.AuditLog, error) {
+						require.Equal(t, pgtype.Text{String: "alice", Valid: true}, args.Actor)
+						require.Equal(t, pgtype.Text{String: "PATCH /account", Valid: true}, args.Action)
+						require.Equal(t, pgtype.Text{String: "account", Valid: true}, args.ResourceType)
+						require.Equal(t, pgtype.Text{String: "7", Valid: true}, args.ResourceID)
+						require.Equal(t, pgtype.Text{String: "req-42", Valid: true}, args.RequestID)
+						require.True(t, args.Since.Valid)
+						require.True(t, since.Equal(args.Since.Time))
+						require.False(t, args.Until.Valid)
+						require.Equal(t, int32(10), args.LimitCount)
+						require.Equal(t, int32(10), args.OffsetCount)
+						return []db.AuditLog{}, nil
+					}).
+					Times(1)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.JSONEq(t, `[]`, recorder.Body.String())
+			},
+		},
+		{
+			name:  "Invalid Time",
+			query: "?since=yesterday&page_id=1&page_size=5",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().ListAuditLogs(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, http.StatusBadRequest, "invalid_request")
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mocks.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+			expectRole(store, "admin", db.RoleAdmin)
+			tc.buildStubs(store)
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/admin/audit_logs"+tc.query, nil)
+			tc.checkResp(t, serveAs(t, server, req, "admin"))
+		})
+	}
+}
+
+func TestVerifyAuditLog(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+	expectRole(store, "admin", db.RoleAdmin)
+	store.EXPECT().
+		VerifyAuditLog(gomock.Any()).
+		Return(db.AuditLogVerification{Rows: 3, LastHash: "abc", BrokenAt: 4, Reason: "hash does not match the row content"}, nil).
+		Times(1)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/admin/audit_logs/verify", nil)
+	recorder := serveAs(t, server, req, "admin")
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var resp db.AuditLogVerification
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+	require.False(t, resp.Valid)
+	require.Equal(t, int64(4), resp.BrokenAt)
+}
+
+// expectNoAudit fails the test on any audit, Times(0) would fall through to the catch-all of the test server
+func expectNoAudit(t *testing.T, store *mocks.MockStore) {
+	store.EXPECT().
+		AppendAuditLogTx(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ any, args db.AppendAuditLogTxParams) (db.AuditLog, error) {
+			t.Errorf("unexpected audit of %s", args.Action)
+			return db.AuditLog{}, nil
+		}).
+		AnyTimes()
+}
+
+func toJSON(t *testing.T, v any) string {
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	return string(data)
+}
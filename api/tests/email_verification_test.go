@@ -0,0 +1,257 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/primarybank/api"
+	commonutils "github.com/primarybank/common/utils"
+	"github.com/primarybank/config"
+	"github.com/primarybank/db/mocks"
+	db "github.com/primarybank/db/sqlc"
+	"github.com/primarybank/mail"
+	"github.com/stretchr/testify/require"
+)
+
+var verificationLink = regexp.MustCompile(`/v1/user/verify_email\?code=(\S+)`)
+
+// mailedVerificationCode returns the code of the last verification email sent to the address
+func mailedVerificationCode(t *testing.T, server *api.Server, email string) string {
+	msg, ok := server.Mailer.(*mail.MemorySender).Last(email)
+	require.True(t, ok, "no email sent to %s", email)
+
+	match := verificationLink.FindStringSubmatch(msg.Body)
+	require.NotNil(t, match, "no verification link in %q", msg.Body)
+
+	code, err := url.QueryUnescape(match[1])
+	require.NoError(t, err)
+	return code
+}
+
+func TestCreateUserSendsVerificationEmail(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+
+	user := createRandomUser(t)
+	store.EXPECT().CreateUserTx(gomock.Any(), gomock.Any()).Return(user, nil).Times(1)
+
+	req := newJSONRequest(t, http.MethodPost, "/v1/user", api.CreateUserRequest{
+		Username: user.Username,
+		Password: "correct horse battery",
+		FullName: user.FullName,
+		Email:    user.Email,
+	})
+	recorder := serve(t, server, req)
+	require.Equal(t, http.StatusCreated, recorder.Code)
+
+	var created api.UserResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &created))
+	require.False(t, created.EmailVerified)
+
+	verification, err := server.EmailVerifier.VerifyCode(mailedVerificationCode(t, server, user.Email))
+	require.NoError(t, err)
+	require.Equal(t, user.Username, verification.Username)
+	require.Equal(t, user.Email, verification.Email)
+}
+
+func TestVerifyEmail(t *testing.T) {
+	user := createRandomUser(t)
+
+	testCases := []struct {
+		name       string
+		code       func(server *api.Server) string
+		buildStubs func(store *mocks.MockStore)
+		checkResp  func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			code: func(server *api.Server) string {
+				code, err := server.EmailVerifier.CreateCode(user.Username, user.Email, time.Minute)
+				require.NoError(t, err)
+				return code
+			},
+			buildStubs: func(store *mocks.MockStore) {
+				verified := user
+				verified.EmailVerifiedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+
+				store.EXPECT().
+					VerifyUserEmail(gomock.Any(), db.VerifyUserEmailParams{Username: user.Username, Email: user.Email}).
+					Return(verified, nil).
+					Times(1)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var verified api.UserResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &verified))
+				require.True(t, verified.EmailVerified)
+			},
+		},
+		{
+			name: "Email Changed Since",
+			code: func(server *api.Server) string {
+				code, err := server.EmailVerifier.CreateCode(user.Username, commonutils.RandomEmail(), time.Minute)
+				require.NoError(t, err)
+				return code
+			},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().
+					VerifyUserEmail(gomock.Any(), gomock.Any()).
+					Return(db.User{}, db.ErrNotFound).
+					Times(1)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, http.StatusBadRequest, "invalid_verification_code")
+			},
+		},
+		{
+			name: "Expired Code",
+			code: func(server *api.Server) string {
+				code, err := server.EmailVerifier.CreateCode(user.Username, user.Email, -time.Minute)
+				require.NoError(t, err)
+				return code
+			},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().VerifyUserEmail(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, http.StatusBadRequest, "invalid_verification_code")
+			},
+		},
+		{
+			name: "Missing Code",
+			code: func(server *api.Server) string {
+				return ""
+			},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().VerifyUserEmail(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, http.StatusBadRequest, "invalid_request")
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mocks.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			server := newTestServer(t, store)
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/user/verify_email?code="+url.QueryEscape(tc.code(server)), nil)
+			tc.checkResp(t, serve(t, server, req))
+		})
+	}
+}
+
+func TestEmailVerificationRequired(t *testing.T) {
+	unverified := createRandomUser(t)
+	verified := createRandomUser(t)
+	verified.EmailVerifiedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+
+	testCases := []struct {
+		name         string
+		requiredFor  string
+		user         db.User
+		buildStubs   func(store *mocks.MockStore, user db.User)
+		expectedCode int
+	}{
+		{
+			name:        "Unverified Blocked",
+			requiredFor: config.EmailVerificationRequiredForMoneyMovement,
+			user:        unverified,
+			buildStubs: func(store *mocks.MockStore, user db.User) {
+				store.EXPECT().GetUser(gomock.Any(), user.Username).Return(user, nil).Times(1)
+				store.EXPECT().WithdrawTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:        "Blocked Login Blocks Money Movement",
+			requiredFor: config.EmailVerificationRequiredForLogin,
+			user:        unverified,
+			buildStubs: func(store *mocks.MockStore, user db.User) {
+				store.EXPECT().GetUser(gomock.Any(), user.Username).Return(user, nil).Times(1)
+				store.EXPECT().WithdrawTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:        "Verified Allowed",
+			requiredFor: config.EmailVerificationRequiredForMoneyMovement,
+			user:        verified,
+			buildStubs: func(store *mocks.MockStore, user db.User) {
+				store.EXPECT().GetUser(gomock.Any(), user.Username).Return(user, nil).Times(1)
+				store.EXPECT().WithdrawTx(gomock.Any(), gomock.Any()).Return(db.WithdrawTxResult{}, nil).Times(1)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:        "Not Required",
+			requiredFor: config.EmailVerificationRequiredForNothing,
+			user:        unverified,
+			buildStubs: func(store *mocks.MockStore, user db.User) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().WithdrawTx(gomock.Any(), gomock.Any()).Return(db.WithdrawTxResult{}, nil).Times(1)
+			},
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mocks.NewMockStore(ctrl)
+			tc.buildStubs(store, tc.user)
+			server := newConfiguredTestServer(t, store, func(cfg *config.Config) {
+				cfg.EmailVerificationRequiredFor = tc.requiredFor
+			})
+
+			req := newJSONRequest(t, http.MethodPost, "/v1/account/1/withdraw", api.WithdrawRequest{Amount: 10})
+			recorder := serveAs(t, server, req, tc.user.Username)
+
+			require.Equal(t, tc.expectedCode, recorder.Code)
+			if tc.expectedCode == http.StatusForbidden {
+				requireProblem(t, recorder, http.StatusForbidden, "email_not_verified")
+			}
+		})
+	}
+}
+
+func TestLoginRequiresVerifiedEmail(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockStore(ctrl)
+	server := newConfiguredTestServer(t, store, func(cfg *config.Config) {
+		cfg.EmailVerificationRequiredFor = config.EmailVerificationRequiredForLogin
+	})
+
+	user := createRandomUser(t)
+	password := commonutils.RandomString(10)
+	hashedPassword, err := commonutils.HashPassword(password)
+	require.NoError(t, err)
+	user.Password = hashedPassword
+
+	expectLoginThrottle(store, user.Username, nil)
+	store.EXPECT().GetUser(gomock.Any(), user.Username).Return(user, nil).Times(1)
+	store.EXPECT().RecordLoginAttemptTx(gomock.Any(), gomock.Any()).Times(0)
+
+	req := newJSONRequest(t, http.MethodPost, "/v1/user/login", api.LoginRequest{Username: user.Username, Password: password})
+	requireProblem(t, serve(t, server, req), http.StatusForbidden, "email_not_verified")
+}
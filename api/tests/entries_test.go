@@ -1,9 +1,6 @@
 package tests
 
 import (
-	"bytes"
-	"database/sql"
-	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
@@ -11,7 +8,6 @@ import (
 	"testing"
 	"time"
 
-	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
 	"github.com/primarybank/api"
 	commonutils "github.com/primarybank/common/utils"
@@ -49,7 +45,10 @@ func TestCreateEntry(t *testing.T) {
 			},
 			buildStubs: func(store *mocks.MockStore) {
 				entry := CreateRandomEntry(t)
-				store.EXPECT().CreateEntry(gomock.Any(), gomock.Any()).Return(*entry, nil).Times(1)
+				store.EXPECT().
+					CreateEntryTx(gomock.Any(), db.CreateEntryTxParams{AccountID: 1, Amount: 100}).
+					Return(*entry, nil).
+					Times(1)
 			},
 			expectedCode: http.StatusOK,
 		},
@@ -59,7 +58,7 @@ func TestCreateEntry(t *testing.T) {
 				AccountID: 1,
 			},
 			buildStubs: func(store *mocks.MockStore) {
-				store.EXPECT().CreateEntry(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateEntryTx(gomock.Any(), gomock.Any()).Times(0)
 			},
 			expectedCode: http.StatusBadRequest,
 		},
@@ -68,12 +67,8 @@ func TestCreateEntry(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			tc.buildStubs(store)
-			recorder := httptest.NewRecorder()
-			c, _ := gin.CreateTestContext(recorder)
-			body, _ := json.Marshal(tc.requestBody)
-			c.Request = httptest.NewRequest(http.MethodPost, "/entries", bytes.NewReader(body))
-			c.Request.Header.Set("Content-Type", "application/json")
-			server.CreateEntry(c)
+			req := newJSONRequest(t, http.MethodPost, "/v1/entry", tc.requestBody)
+			recorder := serveAs(t, server, req, "user")
 			require.Equal(t, tc.expectedCode, recorder.Code)
 		})
 	}
@@ -104,7 +99,7 @@ func TestGetEntry(t *testing.T) {
 			name:    "Entry Not Found",
 			entryID: "999",
 			buildStubs: func(store *mocks.MockStore) {
-				store.EXPECT().GetEntry(gomock.Any(), gomock.Any()).Return(db.Entry{}, sql.ErrNoRows).Times(1)
+				store.EXPECT().GetEntry(gomock.Any(), gomock.Any()).Return(db.Entry{}, db.ErrNotFound).Times(1)
 			},
 			expectedCode: http.StatusNotFound,
 		},
@@ -113,11 +108,8 @@ func TestGetEntry(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			tc.buildStubs(store)
-			recorder := httptest.NewRecorder()
-			c, _ := gin.CreateTestContext(recorder)
-			c.Params = append(c.Params, gin.Param{Key: "id", Value: tc.entryID})
-			c.Request = httptest.NewRequest(http.MethodGet, "/entries/"+tc.entryID, nil)
-			server.GetEntry(c)
+			req := httptest.NewRequest(http.MethodGet, "/v1/entry/"+tc.entryID, nil)
+			recorder := serveAs(t, server, req, "user")
 			require.Equal(t, tc.expectedCode, recorder.Code)
 		})
 	}
@@ -139,15 +131,35 @@ func TestDeleteEntry(t *testing.T) {
 			name:    "Valid Request",
 			entryID: "1",
 			buildStubs: func(store *mocks.MockStore) {
-				store.EXPECT().DeleteEntry(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+				store.EXPECT().GetEntry(gomock.Any(), int64(1)).Return(db.Entry{ID: 1}, nil).Times(1)
+				store.EXPECT().DeleteEntryTx(gomock.Any(), int64(1)).Return(nil).Times(1)
 			},
 			expectedCode: http.StatusOK,
 		},
+		{
+			name:    "Not Latest Entry",
+			entryID: "1",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().GetEntry(gomock.Any(), int64(1)).Return(db.Entry{ID: 1}, nil).Times(1)
+				store.EXPECT().DeleteEntryTx(gomock.Any(), int64(1)).Return(db.ErrEntryNotLatest).Times(1)
+			},
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:    "Chained Entry",
+			entryID: "1",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().GetEntry(gomock.Any(), int64(1)).Return(db.Entry{ID: 1}, nil).Times(1)
+				store.EXPECT().DeleteEntryTx(gomock.Any(), int64(1)).Return(db.ErrEntryChained).Times(1)
+			},
+			expectedCode: http.StatusConflict,
+		},
 		{
 			name:    "Entry Not Found",
 			entryID: "999",
 			buildStubs: func(store *mocks.MockStore) {
-				store.EXPECT().DeleteEntry(gomock.Any(), gomock.Any()).Return(sql.ErrNoRows).Times(1)
+				store.EXPECT().GetEntry(gomock.Any(), int64(999)).Return(db.Entry{}, db.ErrNotFound).Times(1)
+				store.EXPECT().DeleteEntryTx(gomock.Any(), gomock.Any()).Times(0)
 			},
 			expectedCode: http.StatusNotFound,
 		},
@@ -156,11 +168,8 @@ func TestDeleteEntry(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			tc.buildStubs(store)
-			recorder := httptest.NewRecorder()
-			c, _ := gin.CreateTestContext(recorder)
-			c.Params = append(c.Params, gin.Param{Key: "id", Value: tc.entryID})
-			c.Request = httptest.NewRequest(http.MethodDelete, "/entries/"+tc.entryID, nil)
-			server.DeleteEntry(c)
+			req := httptest.NewRequest(http.MethodDelete, "/v1/entry/"+tc.entryID, nil)
+			recorder := serveAs(t, server, req, "user")
 			require.Equal(t, tc.expectedCode, recorder.Code)
 		})
 	}
@@ -206,10 +215,8 @@ func TestListEntries(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			tc.buildStubs(store)
-			recorder := httptest.NewRecorder()
-			c, _ := gin.CreateTestContext(recorder)
-			c.Request = httptest.NewRequest(http.MethodGet, "/entries?page_size="+strconv.Itoa(int(tc.queryParams.PageSize))+"&page_id="+strconv.Itoa(int(tc.queryParams.PageID)), nil)
-			server.ListEntries(c)
+			req := httptest.NewRequest(http.MethodGet, "/v1/entries?page_size="+strconv.Itoa(int(tc.queryParams.PageSize))+"&page_id="+strconv.Itoa(int(tc.queryParams.PageID)), nil)
+			recorder := serveAs(t, server, req, "user")
 			require.Equal(t, tc.expectedCode, recorder.Code)
 		})
 	}
@@ -0,0 +1,151 @@
+package tests
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/primarybank/api"
+	commonerrors "github.com/primarybank/common/errors"
+	"github.com/primarybank/db/mocks"
+	db "github.com/primarybank/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+// alreadyExists builds the error the store returns when a unique constraint on fields is violated
+func alreadyExists(fields ...string) error {
+	fieldErrors := make([]commonerrors.FieldError, 0, len(fields))
+	for _, field := range fields {
+		fieldErrors = append(fieldErrors, commonerrors.FieldError{Field: field, Reason: "already exists"})
+	}
+
+	message := strings.Join(fields, ", ") + " already exists"
+	return commonerrors.New(commonerrors.KindConflict, "already_exists", message).WithFields(fieldErrors...)
+}
+
+func TestErrorHandler(t *testing.T) {
+	testCases := []struct {
+		name       string
+		method     string
+		url        string
+		body       any
+		authorized bool
+		buildStubs func(store *mocks.MockStore)
+		checkResp  func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:       "Not Found",
+			url:        "/v1/account/1",
+			authorized: true,
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), int64(1)).Return(db.Account{}, db.ErrNotFound).Times(1)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				problem := requireProblem(t, recorder, http.StatusNotFound, "not_found")
+				require.Equal(t, "/problems/not_found", problem.Type)
+				require.Equal(t, "/v1/account/1", problem.Instance)
+			},
+		},
+		{
+			name:       "Internal Error Hides Cause",
+			url:        "/v1/account/1",
+			authorized: true,
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Return(db.Account{}, errors.New(`ERROR: relation "accounts" does not exist (SQLSTATE 42P01)`)).
+					Times(1)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, http.StatusInternalServerError, "internal")
+				require.NotContains(t, recorder.Body.String(), "SQLSTATE")
+			},
+		},
+		{
+			name:       "Validation",
+			url:        "/v1/account/abc",
+			authorized: true,
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, http.StatusBadRequest, "invalid_request")
+			},
+		},
+		{
+			name:       "Insufficient Funds",
+			method:     http.MethodPost,
+			url:        "/v1/account/1/withdraw",
+			body:       map[string]int64{"amount": 10},
+			authorized: true,
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().
+					WithdrawTx(gomock.Any(), gomock.Any()).
+					Return(db.WithdrawTxResult{}, &db.OverdraftError{AccountID: 1, Balance: -20}).
+					Times(1)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				problem := requireProblem(t, recorder, http.StatusUnprocessableEntity, "overdraft_limit_exceeded")
+				require.Equal(t, "account 1 balance -20 would exceed overdraft limit 0", problem.Detail)
+			},
+		},
+		{
+			name:       "Field Errors",
+			method:     http.MethodPost,
+			url:        "/v1/account",
+			body:       map[string]string{"owner": "user", "currency": "USD"},
+			authorized: true,
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().
+					CreateAccountTx(gomock.Any(), gomock.Any()).
+					Return(db.Account{}, alreadyExists("owner", "currency")).
+					Times(1)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				problem := requireProblem(t, recorder, http.StatusConflict, "already_exists")
+				require.Equal(t, []api.InvalidParam{
+					{Name: "owner", Reason: "already exists"},
+					{Name: "currency", Reason: "already exists"},
+				}, problem.InvalidParams)
+			},
+		},
+		{
+			name: "Unauthorized",
+			url:  "/v1/account/1",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, http.StatusUnauthorized, "unauthorized")
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mocks.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			server := newTestServer(t, store)
+
+			method := tc.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req := newJSONRequest(t, method, tc.url, tc.body)
+
+			var recorder *httptest.ResponseRecorder
+			if tc.authorized {
+				recorder = serveAs(t, server, req, "user")
+			} else {
+				recorder = serve(t, server, req)
+			}
+			tc.checkResp(t, recorder)
+		})
+	}
+}
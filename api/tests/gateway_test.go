@@ -0,0 +1,81 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/primarybank/api"
+	"github.com/primarybank/config"
+	"github.com/primarybank/db/mocks"
+	db "github.com/primarybank/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGatewayRoutes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockStore(ctrl)
+	server := newConfiguredTestServer(t, store, func(cfg *config.Config) {
+		cfg.GatewayRoutes = "GET /account/:id, GET  /entries"
+	})
+
+	account := CreateRandomAccount(t)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+
+	// routes stay with their gin handlers until there is a gateway
+	store.EXPECT().ListEntries(gomock.Any(), gomock.Any()).Return([]db.Entry{}, nil).Times(1)
+	req := httptest.NewRequest(http.MethodGet, "/v1/entries?page_id=1&page_size=5", nil)
+	require.Equal(t, http.StatusOK, serveAs(t, server, req, account.Owner).Code)
+
+	var forwarded *http.Request
+	server.Gateway = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = r
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/account/7", nil)
+	req.Header.Set(api.RequestIDHeader, "req-42")
+	recorder := serveAs(t, server, req, account.Owner)
+	require.Equal(t, http.StatusTeapot, recorder.Code)
+	require.NotNil(t, forwarded)
+	require.Equal(t, "/v1/account/7", forwarded.URL.Path)
+	require.Equal(t, "req-42", forwarded.Header.Get(api.RequestIDHeader))
+
+	// the gateway serves the v1 paths, the unversioned routes are forwarded to them
+	forwarded = nil
+	req = httptest.NewRequest(http.MethodGet, "/account/7", nil)
+	require.Equal(t, http.StatusTeapot, serveAs(t, server, req, account.Owner).Code)
+	require.NotNil(t, forwarded)
+	require.Equal(t, "/v1/account/7", forwarded.URL.Path)
+
+	// routes that aren't listed keep their gin handlers
+	forwarded = nil
+	store.EXPECT().ListAccounts(gomock.Any(), gomock.Any()).Return([]db.Account{}, nil).Times(1)
+	req = httptest.NewRequest(http.MethodGet, "/v1/accounts?page_id=1&page_size=5", nil)
+	require.Equal(t, http.StatusOK, serveAs(t, server, req, account.Owner).Code)
+	require.Nil(t, forwarded)
+
+	// the middlewares of the route still run before the gateway
+	req = httptest.NewRequest(http.MethodGet, "/v1/account/7", nil)
+	requireProblem(t, serve(t, server, req), http.StatusUnauthorized, "unauthorized")
+	require.Nil(t, forwarded)
+}
+
+func TestGatewayRoutesUnknown(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	_, err := api.NewServer(config.Config{
+		TokenSymmetricKey:    "12345678901234567890123456789012",
+		AccessTokenDuration:  time.Minute,
+		MailSender:           "memory",
+		MFAEncryptionKey:     testMFAEncryptionKey,
+		WebhookEncryptionKey: testWebhookEncryptionKey,
+		GatewayRoutes:        "DELETE /account/:id",
+	}, mocks.NewMockStore(ctrl))
+	require.ErrorContains(t, err, "DELETE /account/:id")
+}
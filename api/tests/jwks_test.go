@@ -0,0 +1,55 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/primarybank/api"
+	"github.com/primarybank/config"
+	"github.com/primarybank/token"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWKS(t *testing.T) {
+	signingKey, _, err := token.GenerateSigningKey()
+	require.NoError(t, err)
+	_, previousKey, err := token.GenerateSigningKey()
+	require.NoError(t, err)
+
+	server := newConfiguredTestServer(t, nil, func(cfg *config.Config) {
+		cfg.TokenSigningKey = signingKey
+		cfg.TokenVerificationKeys = previousKey
+	})
+
+	recorder := serve(t, server, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.NotEmpty(t, recorder.Header().Get("Cache-Control"))
+
+	var set token.JWKSet
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &set))
+	require.Len(t, set.Keys, 2)
+
+	// tokens name the published key they were signed with
+	accessToken, err := server.TokenMaker.CreateToken("user", time.Minute)
+	require.NoError(t, err)
+	parsed, _, err := jwt.NewParser().ParseUnverified(accessToken, &token.Payload{})
+	require.NoError(t, err)
+	require.Equal(t, set.Keys[0].Kid, parsed.Header["kid"])
+}
+
+func TestJWKSWithSymmetricKey(t *testing.T) {
+	server := newTestServer(t, nil)
+
+	recorder := serve(t, server, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.JSONEq(t, `{"keys":[]}`, recorder.Body.String())
+}
+
+func TestInvalidSigningKey(t *testing.T) {
+	_, err := api.NewServer(config.Config{TokenSigningKey: "not base64"}, nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,300 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/primarybank/api"
+	"github.com/primarybank/config"
+	"github.com/primarybank/db/mocks"
+	db "github.com/primarybank/db/sqlc"
+	"github.com/primarybank/token"
+	"github.com/stretchr/testify/require"
+)
+
+// expectLoginThrottle stubs the throttle check done before a login is attempted
+func expectLoginThrottle(store *mocks.MockStore, username string, err error) {
+	store.EXPECT().
+		CheckLoginThrottle(gomock.Any(), username, gomock.Any(), gomock.Any()).
+		Return(err).
+		Times(1)
+}
+
+// expectLoginAttempt expects a single login attempt of username with the given outcome to be recorded
+func expectLoginAttempt(t *testing.T, store *mocks.MockStore, username string, outcome string) {
+	store.EXPECT().
+		RecordLoginAttemptTx(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ any, args db.RecordLoginAttemptTxParams) (db.LoginEvent, error) {
+			require.Equal(t, username, args.Username)
+			require.Equal(t, outcome, args.Outcome)
+			return db.LoginEvent{Username: args.Username, ClientIp: args.ClientIP, Outcome: args.Outcome}, nil
+		}).
+		Times(1)
+}
+
+func TestLoginThrottled(t *testing.T) {
+	username := "throttled"
+
+	testCases := []struct {
+		name         string
+		err          *db.LoginThrottledError
+		expectedCode string
+		retryAfter   string
+	}{
+		{
+			name:         "Delayed",
+			err:          &db.LoginThrottledError{Until: time.Now().Add(4 * time.Second)},
+			expectedCode: "login_throttled",
+			retryAfter:   "4",
+		},
+		{
+			name:         "Locked",
+			err:          &db.LoginThrottledError{Until: time.Now().Add(15 * time.Minute), Locked: true},
+			expectedCode: "login_locked",
+			retryAfter:   "900",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mocks.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+
+			expectLoginThrottle(store, username, tc.err)
+			expectLoginAttempt(t, store, username, db.LoginOutcomeThrottled)
+			store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+
+			req := newJSONRequest(t, http.MethodPost, "/v1/user/login", api.LoginRequest{Username: username, Password: "secret"})
+			recorder := serve(t, server, req)
+
+			requireProblem(t, recorder, http.StatusTooManyRequests, tc.expectedCode)
+			require.Equal(t, tc.retryAfter, recorder.Header().Get("Retry-After"))
+		})
+	}
+}
+
+func TestLoginPolicy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockStore(ctrl)
+	server := newConfiguredTestServer(t, store, func(cfg *config.Config) {
+		cfg.LoginMaxFailures = 5
+		cfg.LoginMaxIPFailures = 50
+		cfg.LoginFailureWindow = 15 * time.Minute
+		cfg.LoginLockoutDuration = 30 * time.Minute
+		cfg.LoginBaseDelay = time.Second
+		cfg.LoginMaxDelay = 30 * time.Second
+	})
+
+	policy := db.LoginPolicy{
+		MaxFailures:     5,
+		MaxIPFailures:   50,
+		Window:          15 * time.Minute,
+		LockoutDuration: 30 * time.Minute,
+		BaseDelay:       time.Second,
+		MaxDelay:        30 * time.Second,
+	}
+
+	store.EXPECT().CheckLoginThrottle(gomock.Any(), "unknown", "203.0.113.7", policy).Return(nil).Times(1)
+	store.EXPECT().GetUser(gomock.Any(), "unknown").Return(db.User{}, db.ErrNotFound).Times(1)
+	store.EXPECT().
+		RecordLoginAttemptTx(gomock.Any(), db.RecordLoginAttemptTxParams{
+			Username:  "unknown",
+			ClientIP:  "203.0.113.7",
+			UserAgent: "curl/8.0",
+			Outcome:   db.LoginOutcomeFailure,
+			Policy:    policy,
+		}).
+		Return(db.LoginEvent{}, nil).
+		Times(1)
+
+	req := newJSONRequest(t, http.MethodPost, "/v1/user/login", api.LoginRequest{Username: "unknown", Password: "secret"})
+	req.RemoteAddr = "203.0.113.7:4711"
+	req.Header.Set("User-Agent", "curl/8.0")
+	requireProblem(t, serve(t, server, req), http.StatusUnauthorized, "invalid_credentials")
+}
+
+func TestLoginClientIP(t *testing.T) {
+	testCases := []struct {
+		name           string
+		trustedProxies string
+		remoteAddr     string
+		expectedIP     string
+	}{
+		{
+			name:       "Forwarded For Ignored",
+			remoteAddr: "203.0.113.7:4711",
+			expectedIP: "203.0.113.7",
+		},
+		{
+			name:           "Untrusted Proxy",
+			trustedProxies: "10.0.0.0/8",
+			remoteAddr:     "203.0.113.7:4711",
+			expectedIP:     "203.0.113.7",
+		},
+		{
+			name:           "Trusted Proxy",
+			trustedProxies: "192.0.2.10, 10.0.0.0/8",
+			remoteAddr:     "10.1.2.3:4711",
+			expectedIP:     "198.51.100.23",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mocks.NewMockStore(ctrl)
+			server := newConfiguredTestServer(t, store, func(cfg *config.Config) {
+				cfg.TrustedProxies = tc.trustedProxies
+			})
+
+			store.EXPECT().CheckLoginThrottle(gomock.Any(), "unknown", tc.expectedIP, gomock.Any()).Return(nil).Times(1)
+			store.EXPECT().GetUser(gomock.Any(), "unknown").Return(db.User{}, db.ErrNotFound).Times(1)
+			store.EXPECT().
+				RecordLoginAttemptTx(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ any, args db.RecordLoginAttemptTxParams) (db.LoginEvent, error) {
+					require.Equal(t, tc.expectedIP, args.ClientIP)
+					return db.LoginEvent{}, nil
+				}).
+				Times(1)
+
+			req := newJSONRequest(t, http.MethodPost, "/v1/user/login", api.LoginRequest{Username: "unknown", Password: "secret"})
+			req.RemoteAddr = tc.remoteAddr
+			req.Header.Set("X-Forwarded-For", "198.51.100.23")
+			requireProblem(t, serve(t, server, req), http.StatusUnauthorized, "invalid_credentials")
+		})
+	}
+}
+
+func TestTrustedProxiesInvalid(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	_, err := api.NewServer(config.Config{
+		TokenSymmetricKey:    "12345678901234567890123456789012",
+		AccessTokenDuration:  time.Minute,
+		MailSender:           "memory",
+		MFAEncryptionKey:     testMFAEncryptionKey,
+		WebhookEncryptionKey: testWebhookEncryptionKey,
+		TrustedProxies:       "10.0.0.0/33",
+	}, mocks.NewMockStore(ctrl))
+	require.ErrorContains(t, err, "invalid trusted proxies")
+}
+
+func TestMFALoginThrottled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+
+	username := "mfauser"
+	challenge, err := server.TokenMaker.CreateToken(username, time.Minute, token.WithPurpose(token.PurposeMFAChallenge))
+	require.NoError(t, err)
+
+	expectLoginThrottle(store, username, &db.LoginThrottledError{Until: time.Now().Add(time.Minute), Locked: true})
+	expectLoginAttempt(t, store, username, db.LoginOutcomeThrottled)
+	store.EXPECT().GetMFAFactor(gomock.Any(), gomock.Any()).Times(0)
+
+	req := newJSONRequest(t, http.MethodPost, "/v1/user/login/mfa", api.VerifyMFALoginRequest{ChallengeToken: challenge, Code: "123456"})
+	requireProblem(t, serve(t, server, req), http.StatusTooManyRequests, "login_locked")
+}
+
+func TestUnlockUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+	expectRole(store, "admin", db.RoleAdmin)
+	expectRole(store, "depositor", db.RoleDepositor)
+
+	store.EXPECT().
+		ResetLoginThrottle(gomock.Any(), db.ResetLoginThrottleParams{Scope: db.LoginScopeUsername, Subject: "locked"}).
+		Return(int64(1), nil).
+		Times(1)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/admin/user/locked/unlock", nil)
+	require.Equal(t, http.StatusNoContent, serveAs(t, server, req, "admin").Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/v1/admin/user/locked/unlock", nil)
+	requireProblem(t, serveAs(t, server, req, "depositor"), http.StatusForbidden, "forbidden")
+}
+
+func TestListLoginEvents(t *testing.T) {
+	testCases := []struct {
+		name       string
+		query      string
+		buildStubs func(store *mocks.MockStore)
+		checkResp  func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: "?page_id=1&page_size=5",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().
+					ListLoginEvents(gomock.Any(), db.ListLoginEventsParams{LimitCount: 5, OffsetCount: 0}).
+					Return([]db.LoginEvent{{ID: 1, Username: "alice", Outcome: db.LoginOutcomeFailure}}, nil).
+					Times(1)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"username":"alice"`)
+			},
+		},
+		{
+			name:  "Filtered",
+			query: "?username=alice&client_ip=203.0.113.7&outcome=throttled&page_id=2&page_size=10",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().
+					ListLoginEvents(gomock.Any(), db.ListLoginEventsParams{
+						Username:    pgtype.Text{String: "alice", Valid: true},
+						ClientIp:    pgtype.Text{String: "203.0.113.7", Valid: true},
+						Outcome:     pgtype.Text{String: db.LoginOutcomeThrottled, Valid: true},
+						LimitCount:  10,
+						OffsetCount: 10,
+					}).
+					Return([]db.LoginEvent{}, nil).
+					Times(1)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:  "Unknown Outcome",
+			query: "?outcome=maybe&page_id=1&page_size=5",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().ListLoginEvents(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, http.StatusBadRequest, "invalid_request")
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mocks.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+			expectRole(store, "admin", db.RoleAdmin)
+			tc.buildStubs(store)
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/admin/login_events"+tc.query, nil)
+			tc.checkResp(t, serveAs(t, server, req, "admin"))
+		})
+	}
+}
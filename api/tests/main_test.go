@@ -1,27 +1,116 @@
 package tests
 
 import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
 	"github.com/primarybank/api"
 	commonutils "github.com/primarybank/common/utils"
 	"github.com/primarybank/config"
+	"github.com/primarybank/db/mocks"
 	db "github.com/primarybank/db/sqlc"
 	"github.com/stretchr/testify/require"
 )
 
-func newTestServer(t *testing.T, store db.Store) *api.Server {
+const (
+	testMFAEncryptionKey     = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+	testWebhookEncryptionKey = "1f1e1d1c1b1a191817161514131211100f0e0d0c0b0a09080706050403020100"
+)
+
+func newTestServer(t *testing.T, store db.Store, opts ...api.ServerOption) *api.Server {
+	return newConfiguredTestServer(t, store, func(cfg *config.Config) {}, opts...)
+}
+
+// newConfiguredTestServer lets configure change the test config before the server is built
+func newConfiguredTestServer(t *testing.T, store db.Store, configure func(cfg *config.Config), opts ...api.ServerOption) *api.Server {
 	cfg := config.Config{
-		TokenSymmetricKey:   commonutils.RandomString(35),
-		AccessTokenDuration: 3 * time.Minute,
+		TokenSymmetricKey:     commonutils.RandomString(35),
+		AccessTokenDuration:   3 * time.Minute,
+		MailSender:            "memory",
+		PasswordResetTokenTTL: time.Minute,
+		EmailVerificationTTL:  time.Minute,
+		MFAEncryptionKey:      testMFAEncryptionKey,
+		WebhookEncryptionKey:  testWebhookEncryptionKey,
+		MFAIssuer:             "PrimaryBank",
+		MFAChallengeDuration:  time.Minute,
+		MFAStepUpMaxAge:       time.Minute,
+		PasswordMinLength:     6,
+		// the unversioned routes are deprecated, without a sunset
+		LegacyRoutesDeprecatedAt: "2026-10-19",
+	}
+	configure(&cfg)
+
+	// every state changing request is audited, tests checking the audit log expect it before building the server
+	if mockStore, ok := store.(*mocks.MockStore); ok {
+		mockStore.EXPECT().AppendAuditLogTx(gomock.Any(), gomock.Any()).Return(db.AuditLog{}, nil).AnyTimes()
+		mocks.ExpectAuditedTx(mockStore)
 	}
 
-	server, err := api.NewServer(cfg, store)
+	server, err := api.NewServer(cfg, store, opts...)
 	require.NoError(t, err)
 
 	gin.SetMode(gin.TestMode)
 
 	return server
 }
+
+func newJSONRequest(t *testing.T, method string, url string, body any) *http.Request {
+	data, err := json.Marshal(body)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(method, url, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+// passwordHash matches the encodings of the password hashers we have used
+var passwordHash = regexp.MustCompile(`\$2[aby]\$\d{2}\$|\$argon2(id|i|d)\$`)
+
+// serve sends an anonymous request through the router.
+// Every response of every route served in the tests is checked for password hashes.
+func serve(t *testing.T, server *api.Server, req *http.Request) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	server.Router.ServeHTTP(recorder, req)
+	requireNoPasswordHash(t, recorder)
+	return recorder
+}
+
+// requireNoPasswordHash fails when a response exposes a password hash or a password field
+func requireNoPasswordHash(t *testing.T, recorder *httptest.ResponseRecorder) {
+	body := recorder.Body.String()
+	require.False(t, passwordHash.MatchString(body), "response contains a password hash: %s", body)
+	require.NotContains(t, body, `"password":`, "response contains a password field")
+}
+
+// serveAs sends a request through the router authenticated as username
+func serveAs(t *testing.T, server *api.Server, req *http.Request, username string) *httptest.ResponseRecorder {
+	addAuthz(t, server.TokenMaker, req, api.AuthType, username, time.Minute)
+	return serve(t, server, req)
+}
+
+// expectRole stubs the user lookup done by the role middleware
+func expectRole(store *mocks.MockStore, username string, role string) {
+	store.EXPECT().
+		GetUser(gomock.Any(), username).
+		Return(db.User{Username: username, Role: role}, nil).
+		AnyTimes()
+}
+
+// requireProblem checks a response is an RFC 7807 problem with the given status and code
+func requireProblem(t *testing.T, recorder *httptest.ResponseRecorder, status int, code string) api.Problem {
+	require.Equal(t, status, recorder.Code)
+	require.Equal(t, "application/problem+json", recorder.Header().Get("Content-Type"))
+
+	var problem api.Problem
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &problem))
+	require.Equal(t, status, problem.Status)
+	require.Equal(t, code, problem.Code)
+	return problem
+}
@@ -0,0 +1,487 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/primarybank/api"
+	commonutils "github.com/primarybank/common/utils"
+	"github.com/primarybank/config"
+	"github.com/primarybank/db/mocks"
+	db "github.com/primarybank/db/sqlc"
+	"github.com/primarybank/mfa"
+	"github.com/primarybank/token"
+	"github.com/stretchr/testify/require"
+)
+
+// newMFAFactor returns the stored factor of a TOTP secret, confirmed or still being enrolled
+func newMFAFactor(t *testing.T, username string, confirmed bool) (db.MfaFactor, string) {
+	enrollment, err := mfa.Enroll("PrimaryBank", username)
+	require.NoError(t, err)
+
+	cipher, err := mfa.NewCipher(testMFAEncryptionKey)
+	require.NoError(t, err)
+	ciphertext, err := cipher.Encrypt([]byte(enrollment.Secret))
+	require.NoError(t, err)
+
+	factor := db.MfaFactor{Username: username, SecretCiphertext: ciphertext}
+	if confirmed {
+		factor.ConfirmedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	}
+	return factor, enrollment.Secret
+}
+
+func currentCode(t *testing.T, secret string) string {
+	code, err := mfa.GenerateCode(secret, time.Now())
+	require.NoError(t, err)
+	return code
+}
+
+// setToken authenticates req with a token created with opts
+func setToken(t *testing.T, server *api.Server, req *http.Request, username string, opts ...token.Option) {
+	accessToken, err := server.TokenMaker.CreateToken(username, time.Minute, opts...)
+	require.NoError(t, err)
+	req.Header.Set(api.AuthHeaderKey, fmt.Sprintf("%s %s", api.AuthType, accessToken))
+}
+
+func TestEnrollMFA(t *testing.T) {
+	username := commonutils.RandomString(8)
+
+	testCases := []struct {
+		name       string
+		buildStubs func(store *mocks.MockStore)
+		checkResp  func(t *testing.T, recorder *httptest.ResponseRecorder, stored *db.UpsertMFAFactorParams)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().GetMFAFactor(gomock.Any(), username).Return(db.MfaFactor{}, db.ErrNotFound).Times(1)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder, stored *db.UpsertMFAFactorParams) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp api.EnrollMFAResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+				require.Contains(t, resp.OTPAuthURI, "secret="+resp.Secret)
+
+				// the secret is only stored encrypted
+				require.Equal(t, username, stored.Username)
+				require.NotContains(t, string(stored.SecretCiphertext), resp.Secret)
+
+				cipher, err := mfa.NewCipher(testMFAEncryptionKey)
+				require.NoError(t, err)
+				secret, err := cipher.Decrypt(stored.SecretCiphertext)
+				require.NoError(t, err)
+				require.Equal(t, resp.Secret, string(secret))
+			},
+		},
+		{
+			name: "Already Enabled",
+			buildStubs: func(store *mocks.MockStore) {
+				factor, _ := newMFAFactor(t, username, true)
+				store.EXPECT().GetMFAFactor(gomock.Any(), username).Return(factor, nil).Times(1)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder, stored *db.UpsertMFAFactorParams) {
+				requireProblem(t, recorder, http.StatusConflict, "mfa_already_enabled")
+				require.Empty(t, stored.Username)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mocks.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			var stored db.UpsertMFAFactorParams
+			store.EXPECT().
+				UpsertMFAFactor(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ any, args db.UpsertMFAFactorParams) (db.MfaFactor, error) {
+					stored = args
+					return db.MfaFactor{Username: args.Username, SecretCiphertext: args.SecretCiphertext}, nil
+				}).
+				AnyTimes()
+
+			server := newTestServer(t, store)
+			req := httptest.NewRequest(http.MethodPost, "/v1/user/mfa/enroll", nil)
+			tc.checkResp(t, serveAs(t, server, req, username), &stored)
+		})
+	}
+}
+
+func TestConfirmMFA(t *testing.T) {
+	username := commonutils.RandomString(8)
+	factor, secret := newMFAFactor(t, username, false)
+
+	testCases := []struct {
+		name       string
+		code       string
+		buildStubs func(store *mocks.MockStore)
+		checkResp  func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			code: currentCode(t, secret),
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().GetMFAFactor(gomock.Any(), username).Return(factor, nil).Times(1)
+				store.EXPECT().
+					ConfirmMFATx(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ any, args db.ConfirmMFATxParams) (db.MfaFactor, error) {
+						require.Equal(t, username, args.Username)
+						require.InDelta(t, mfa.Step(time.Now()), args.Step, 1)
+						require.Len(t, args.RecoveryCodeHashes, mfa.RecoveryCodeCount)
+						return factor, nil
+					}).
+					Times(1)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp api.ConfirmMFAResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+				require.Len(t, resp.RecoveryCodes, mfa.RecoveryCodeCount)
+			},
+		},
+		{
+			name: "Wrong Code",
+			code: "12345",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().GetMFAFactor(gomock.Any(), username).Return(factor, nil).Times(1)
+				store.EXPECT().ConfirmMFATx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, http.StatusUnauthorized, "invalid_mfa_code")
+			},
+		},
+		{
+			name: "Not Enrolled",
+			code: currentCode(t, secret),
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().GetMFAFactor(gomock.Any(), username).Return(db.MfaFactor{}, db.ErrNotFound).Times(1)
+				store.EXPECT().ConfirmMFATx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, http.StatusUnprocessableEntity, "mfa_not_enabled")
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mocks.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			server := newTestServer(t, store)
+
+			req := newJSONRequest(t, http.MethodPost, "/v1/user/mfa/confirm", api.MFACodeRequest{Code: tc.code})
+			tc.checkResp(t, serveAs(t, server, req, username))
+		})
+	}
+}
+
+func TestLoginWithMFA(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+
+	user := createRandomUser(t)
+	password := commonutils.RandomString(10)
+	hashedPassword, err := commonutils.HashPassword(password)
+	require.NoError(t, err)
+	user.Password = hashedPassword
+
+	factor, secret := newMFAFactor(t, user.Username, true)
+	store.EXPECT().GetUser(gomock.Any(), user.Username).Return(user, nil).AnyTimes()
+	store.EXPECT().GetMFAFactor(gomock.Any(), user.Username).Return(factor, nil).AnyTimes()
+	store.EXPECT().CheckLoginThrottle(gomock.Any(), user.Username, gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	// the password step only yields a challenge, the login is recorded once the second factor is in
+	store.EXPECT().RecordLoginAttemptTx(gomock.Any(), gomock.Any()).Times(0)
+	req := newJSONRequest(t, http.MethodPost, "/v1/user/login", api.LoginRequest{Username: user.Username, Password: password})
+	recorder := serve(t, server, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var login api.LoginUserResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &login))
+	require.True(t, login.MFARequired)
+	require.Empty(t, login.AccessToken)
+	require.NotEmpty(t, login.ChallengeToken)
+
+	// which is no access token
+	req = httptest.NewRequest(http.MethodGet, "/v1/user/profile", nil)
+	req.Header.Set(api.AuthHeaderKey, api.AuthType+" "+login.ChallengeToken)
+	requireProblem(t, serve(t, server, req), http.StatusUnauthorized, "unauthorized")
+
+	// access tokens are no challenge either
+	accessToken, err := server.TokenMaker.CreateToken(user.Username, time.Minute)
+	require.NoError(t, err)
+	req = newJSONRequest(t, http.MethodPost, "/v1/user/login/mfa", api.VerifyMFALoginRequest{ChallengeToken: accessToken, Code: currentCode(t, secret)})
+	requireProblem(t, serve(t, server, req), http.StatusUnauthorized, "unauthorized")
+
+	code := currentCode(t, secret)
+	store.EXPECT().
+		UseMFAStep(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ any, args db.UseMFAStepParams) (db.MfaFactor, error) {
+			require.Equal(t, user.Username, args.Username)
+			return factor, nil
+		}).
+		Times(1)
+	expectLoginAttempt(t, store, user.Username, db.LoginOutcomeSuccess)
+
+	req = newJSONRequest(t, http.MethodPost, "/v1/user/login/mfa", api.VerifyMFALoginRequest{ChallengeToken: login.ChallengeToken, Code: code})
+	recorder = serve(t, server, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var verified api.LoginUserResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &verified))
+	payload, err := server.TokenMaker.VerifyToken(verified.AccessToken)
+	require.NoError(t, err)
+	require.True(t, payload.IsAccess())
+	require.True(t, payload.MFASince(time.Now().Add(-time.Minute)))
+
+	// the same code cannot be replayed
+	store.EXPECT().UseMFAStep(gomock.Any(), gomock.Any()).Return(db.MfaFactor{}, db.ErrNotFound).Times(1)
+	expectLoginAttempt(t, store, user.Username, db.LoginOutcomeFailure)
+	req = newJSONRequest(t, http.MethodPost, "/v1/user/login/mfa", api.VerifyMFALoginRequest{ChallengeToken: login.ChallengeToken, Code: code})
+	requireProblem(t, serve(t, server, req), http.StatusUnauthorized, "invalid_mfa_code")
+
+	// recovery codes work once in place of a TOTP code
+	recoveryCode := "abcde-fghij"
+	store.EXPECT().
+		UseMFARecoveryCode(gomock.Any(), db.UseMFARecoveryCodeParams{Username: user.Username, CodeHash: commonutils.HashSecret(recoveryCode)}).
+		Return(db.MfaRecoveryCode{}, nil).
+		Times(1)
+	expectLoginAttempt(t, store, user.Username, db.LoginOutcomeSuccess)
+	req = newJSONRequest(t, http.MethodPost, "/v1/user/login/mfa", api.VerifyMFALoginRequest{ChallengeToken: login.ChallengeToken, Code: "ABCDEFGHIJ"})
+	require.Equal(t, http.StatusOK, serve(t, server, req).Code)
+}
+
+func TestTransferStepUp(t *testing.T) {
+	username := commonutils.RandomString(8)
+	factor, secret := newMFAFactor(t, username, true)
+
+	transfer := api.CreateTransferRequest{FromAccountID: 1, ToAccountID: 2, Amount: 500}
+
+	testCases := []struct {
+		name       string
+		amount     int64
+		setupAuth  func(t *testing.T, server *api.Server, req *http.Request)
+		buildStubs func(store *mocks.MockStore)
+		checkResp  func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "Below Threshold",
+			amount: 50,
+			setupAuth: func(t *testing.T, server *api.Server, req *http.Request) {
+				setToken(t, server, req, username)
+			},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Return(db.TransferTxResult{}, nil).Times(1)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "No Second Factor",
+			amount: transfer.Amount,
+			setupAuth: func(t *testing.T, server *api.Server, req *http.Request) {
+				setToken(t, server, req, username)
+			},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, http.StatusForbidden, "mfa_required")
+			},
+		},
+		{
+			name:   "Recent MFA Login",
+			amount: transfer.Amount,
+			setupAuth: func(t *testing.T, server *api.Server, req *http.Request) {
+				setToken(t, server, req, username, token.WithMFA(time.Now()))
+			},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Return(db.TransferTxResult{}, nil).Times(1)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "Stale MFA Login",
+			amount: transfer.Amount,
+			setupAuth: func(t *testing.T, server *api.Server, req *http.Request) {
+				setToken(t, server, req, username, token.WithMFA(time.Now().Add(-time.Hour)))
+			},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, http.StatusForbidden, "mfa_required")
+			},
+		},
+		{
+			name:   "Code Header",
+			amount: transfer.Amount,
+			setupAuth: func(t *testing.T, server *api.Server, req *http.Request) {
+				setToken(t, server, req, username)
+				req.Header.Set(api.MFACodeHeader, currentCode(t, secret))
+			},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().CheckLoginThrottle(gomock.Any(), username, gomock.Any(), gomock.Any()).Return(nil).Times(1)
+				store.EXPECT().GetMFAFactor(gomock.Any(), username).Return(factor, nil).Times(1)
+				store.EXPECT().UseMFAStep(gomock.Any(), gomock.Any()).Return(factor, nil).Times(1)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Return(db.TransferTxResult{}, nil).Times(1)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "Code Header Without MFA",
+			amount: transfer.Amount,
+			setupAuth: func(t *testing.T, server *api.Server, req *http.Request) {
+				setToken(t, server, req, username)
+				req.Header.Set(api.MFACodeHeader, "123456")
+			},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().CheckLoginThrottle(gomock.Any(), username, gomock.Any(), gomock.Any()).Return(nil).Times(1)
+				store.EXPECT().GetMFAFactor(gomock.Any(), username).Return(db.MfaFactor{}, db.ErrNotFound).Times(1)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, http.StatusForbidden, "mfa_required")
+			},
+		},
+		{
+			name:   "Invalid Code Header",
+			amount: transfer.Amount,
+			setupAuth: func(t *testing.T, server *api.Server, req *http.Request) {
+				setToken(t, server, req, username)
+				req.Header.Set(api.MFACodeHeader, "000000")
+			},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().CheckLoginThrottle(gomock.Any(), username, gomock.Any(), gomock.Any()).Return(nil).Times(1)
+				store.EXPECT().GetMFAFactor(gomock.Any(), username).Return(factor, nil).Times(1)
+				expectLoginAttempt(t, store, username, db.LoginOutcomeFailure)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, http.StatusUnauthorized, "invalid_mfa_code")
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mocks.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			server := newConfiguredTestServer(t, store, func(cfg *config.Config) {
+				cfg.MFAStepUpThreshold = 100
+			})
+
+			body := transfer
+			body.Amount = tc.amount
+			req := newJSONRequest(t, http.MethodPost, "/v1/transfer", body)
+			tc.setupAuth(t, server, req)
+
+			tc.checkResp(t, serve(t, server, req))
+		})
+	}
+}
+
+// TestSecondFactorLockout checks the codes of the routes taking a second factor count against the lockout of logins
+func TestSecondFactorLockout(t *testing.T) {
+	const maxFailures = 3
+
+	testCases := []struct {
+		name     string
+		buildReq func(t *testing.T, code string) *http.Request
+	}{
+		{
+			name: "Step Up",
+			buildReq: func(t *testing.T, code string) *http.Request {
+				req := newJSONRequest(t, http.MethodPost, "/v1/transfer", api.CreateTransferRequest{FromAccountID: 1, ToAccountID: 2, Amount: 500})
+				req.Header.Set(api.MFACodeHeader, code)
+				return req
+			},
+		},
+		{
+			name: "Disable",
+			buildReq: func(t *testing.T, code string) *http.Request {
+				return newJSONRequest(t, http.MethodDelete, "/v1/user/mfa", api.MFACodeRequest{Code: code})
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			username := commonutils.RandomString(8)
+			factor, secret := newMFAFactor(t, username, true)
+
+			// the store locks the username out once it recorded maxFailures failures
+			failures := 0
+			store := mocks.NewMockStore(ctrl)
+			store.EXPECT().
+				CheckLoginThrottle(gomock.Any(), username, gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ any, _ string, _ string, _ db.LoginPolicy) error {
+					if failures >= maxFailures {
+						return &db.LoginThrottledError{Until: time.Now().Add(time.Minute), Locked: true}
+					}
+					return nil
+				}).
+				AnyTimes()
+			store.EXPECT().
+				RecordLoginAttemptTx(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ any, args db.RecordLoginAttemptTxParams) (db.LoginEvent, error) {
+					require.Equal(t, username, args.Username)
+					if args.Outcome == db.LoginOutcomeFailure {
+						failures++
+					}
+					return db.LoginEvent{Username: args.Username, Outcome: args.Outcome}, nil
+				}).
+				AnyTimes()
+			store.EXPECT().GetMFAFactor(gomock.Any(), username).Return(factor, nil).Times(maxFailures)
+			store.EXPECT().UseMFAStep(gomock.Any(), gomock.Any()).Times(0)
+			store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			store.EXPECT().DisableMFATx(gomock.Any(), gomock.Any()).Times(0)
+
+			server := newConfiguredTestServer(t, store, func(cfg *config.Config) {
+				cfg.MFAStepUpThreshold = 100
+				cfg.LoginMaxFailures = maxFailures
+			})
+
+			for i := 0; i < maxFailures; i++ {
+				req := tc.buildReq(t, "000000")
+				setToken(t, server, req, username)
+				requireProblem(t, serve(t, server, req), http.StatusUnauthorized, "invalid_mfa_code")
+			}
+			require.Equal(t, maxFailures, failures)
+
+			// locked out, even the right code is refused without being checked
+			req := tc.buildReq(t, currentCode(t, secret))
+			setToken(t, server, req, username)
+			requireProblem(t, serve(t, server, req), http.StatusTooManyRequests, "login_locked")
+		})
+	}
+}
@@ -8,7 +8,11 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5"
 	"github.com/primarybank/api"
+	"github.com/primarybank/db/mocks"
+	db "github.com/primarybank/db/sqlc"
 	"github.com/primarybank/token"
 	"github.com/stretchr/testify/require"
 )
@@ -76,7 +80,7 @@ func TestAuthMiddleware(t *testing.T) {
 			server := newTestServer(t, nil)
 
 			authPath := "/auth"
-			server.Router.GET(authPath, api.AuthMiddleWare(server.TokenMaker),
+			server.Router.GET(authPath, api.AuthMiddleWare(server.TokenMaker, nil),
 				func(ctx *gin.Context) {
 					ctx.JSON(http.StatusOK, gin.H{})
 				},
@@ -92,3 +96,67 @@ func TestAuthMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestRoleMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		buildStubs func(store *mocks.MockStore)
+		checkResp  func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Allowed",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), "user").Return(db.User{Username: "user", Role: db.RoleAdmin}, nil).Times(1)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "Forbidden",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), "user").Return(db.User{Username: "user", Role: db.RoleDepositor}, nil).Times(1)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "UnknownUser",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), "user").Return(db.User{}, pgx.ErrNoRows).Times(1)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mocks.NewMockStore(ctrl)
+			tt.buildStubs(store)
+			server := newTestServer(t, store)
+
+			authPath := "/role"
+			server.Router.GET(authPath,
+				api.AuthMiddleWare(server.TokenMaker, store),
+				api.RoleMiddleware(store, db.RoleAdmin),
+				func(ctx *gin.Context) {
+					ctx.JSON(http.StatusOK, gin.H{})
+				},
+			)
+
+			recorder := httptest.NewRecorder()
+			req, err := http.NewRequest(http.MethodGet, authPath, nil)
+			require.NoError(t, err)
+
+			addAuthz(t, server.TokenMaker, req, api.AuthType, "user", time.Minute)
+			server.Router.ServeHTTP(recorder, req)
+			tt.checkResp(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/primarybank/api"
+	"github.com/primarybank/db/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+// openAPIDocument is the part of the OpenAPI document the tests look into
+type openAPIDocument struct {
+	OpenAPI    string                                     `json:"openapi"`
+	Paths      map[string]map[string]openAPIOperation     `json:"paths"`
+	Components struct{ Schemas map[string]openAPISchema } `json:"components"`
+}
+
+type openAPIOperation struct {
+	Parameters  []openAPIParameter `json:"parameters"`
+	RequestBody *struct {
+		Content map[string]struct {
+			Schema openAPISchema `json:"schema"`
+		} `json:"content"`
+	} `json:"requestBody"`
+	Responses  map[string]any        `json:"responses"`
+	Security   []map[string][]string `json:"security"`
+	Deprecated bool                  `json:"deprecated"`
+}
+
+type openAPIParameter struct {
+	Name     string        `json:"name"`
+	In       string        `json:"in"`
+	Required bool          `json:"required"`
+	Schema   openAPISchema `json:"schema"`
+}
+
+type openAPISchema struct {
+	Ref        string                   `json:"$ref"`
+	Properties map[string]openAPISchema `json:"properties"`
+	Required   []string                 `json:"required"`
+	Enum       []any                    `json:"enum"`
+	Minimum    *float64                 `json:"minimum"`
+	Maximum    *float64                 `json:"maximum"`
+	Exclusive  *float64                 `json:"exclusiveMinimum"`
+	Format     string                   `json:"format"`
+	Default    any                      `json:"default"`
+}
+
+func getOpenAPIDocument(t *testing.T, server *api.Server) openAPIDocument {
+	// not through serve, the document names the password fields of the requests
+	recorder := httptest.NewRecorder()
+	server.Router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+
+	var document openAPIDocument
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &document))
+	return document
+}
+
+func findParameter(t *testing.T, operation openAPIOperation, name string) openAPIParameter {
+	for _, parameter := range operation.Parameters {
+		if parameter.Name == name {
+			return parameter
+		}
+	}
+	require.Failf(t, "missing parameter", "no parameter %s", name)
+	return openAPIParameter{}
+}
+
+func TestOpenAPIDocumentsEveryRoute(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := newTestServer(t, mocks.NewMockStore(ctrl))
+	document := getOpenAPIDocument(t, server)
+	require.Equal(t, api.OpenAPIVersion, document.OpenAPI)
+
+	registered := make(map[string]bool)
+	for _, route := range server.Router.Routes() {
+		path := api.OpenAPIPath(route.Path)
+		method := strings.ToLower(route.Method)
+		registered[method+" "+path] = true
+
+		operation, ok := document.Paths[path][method]
+		require.Truef(t, ok, "%s %s is registered but missing from the OpenAPI document", route.Method, route.Path)
+		require.NotEmpty(t, operation.Responses, "%s %s has no responses", route.Method, route.Path)
+	}
+
+	for path, operations := range document.Paths {
+		for method := range operations {
+			require.Truef(t, registered[method+" "+path], "%s %s is documented but not registered", method, path)
+		}
+	}
+}
+
+func TestOpenAPIDocumentConstraints(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	document := getOpenAPIDocument(t, newTestServer(t, mocks.NewMockStore(ctrl)))
+
+	// query parameters carry their binding constraints
+	pageSize := findParameter(t, document.Paths["/v1/accounts"]["get"], "page_size")
+	require.Equal(t, "query", pageSize.In)
+	require.True(t, pageSize.Required)
+	require.Equal(t, float64(5), *pageSize.Schema.Minimum)
+	require.Equal(t, float64(10), *pageSize.Schema.Maximum)
+
+	status := findParameter(t, document.Paths["/v1/transfer_requests"]["get"], "status")
+	require.False(t, status.Required)
+	require.Equal(t, "pending", status.Schema.Default)
+	require.Equal(t, []any{"pending", "approved", "rejected", "expired"}, status.Schema.Enum)
+
+	id := findParameter(t, document.Paths["/v1/account/{id}"]["get"], "id")
+	require.Equal(t, "path", id.In)
+	require.Equal(t, float64(1), *id.Schema.Minimum)
+
+	// and so do the fields of request bodies
+	body := document.Paths["/v1/account"]["post"].RequestBody.Content["application/json"].Schema
+	require.Equal(t, "#/components/schemas/CreateAccountRequest", body.Ref)
+
+	createAccount := document.Components.Schemas["CreateAccountRequest"]
+	require.ElementsMatch(t, []string{"owner", "currency"}, createAccount.Required)
+	require.Equal(t, []any{"USD", "EUR", "RUP"}, createAccount.Properties["currency"].Enum)
+
+	createTransfer := document.Components.Schemas["CreateTransferRequest"]
+	require.Equal(t, float64(0), *createTransfer.Properties["amount"].Exclusive)
+
+	createUser := document.Components.Schemas["CreateUserRequest"]
+	require.Equal(t, "email", createUser.Properties["email"].Format)
+
+	// fields that aren't bound as required are optional
+	updateUser := document.Components.Schemas["UpdateUserRequest"]
+	require.NotContains(t, updateUser.Required, "password")
+
+	// failures are problems
+	require.Contains(t, document.Components.Schemas, "Problem")
+
+	// public routes aren't authenticated, the others take the scope of their route
+	require.Empty(t, document.Paths["/v1/user/login"]["post"].Security)
+	require.Contains(t, document.Paths["/v1/transfer"]["post"].Security, map[string][]string{"bearer": {"transfers:write"}})
+
+	// the unversioned paths are documented as deprecated, keys and documents aren't versioned
+	require.False(t, document.Paths["/v1/accounts"]["get"].Deprecated)
+	require.True(t, document.Paths["/accounts"]["get"].Deprecated)
+	require.False(t, document.Paths["/.well-known/jwks.json"]["get"].Deprecated)
+	require.NotContains(t, document.Paths, "/v1/openapi.json")
+}
+
+func TestSwaggerUI(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := newTestServer(t, mocks.NewMockStore(ctrl))
+	recorder := serve(t, server, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Contains(t, recorder.Header().Get("Content-Type"), "text/html")
+	require.Contains(t, recorder.Body.String(), `url: "/openapi.json"`)
+}
@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/primarybank/db/mocks"
+	db "github.com/primarybank/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetOverdraftLimit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+	expectRole(store, "admin", db.RoleAdmin)
+	account := CreateRandomAccount(t)
+
+	testCases := []struct {
+		name         string
+		accountID    string
+		requestBody  gin.H
+		buildStubs   func(store *mocks.MockStore)
+		expectedCode int
+	}{
+		{
+			name:        "Valid Request",
+			accountID:   "1",
+			requestBody: gin.H{"overdraft_limit": 500, "reason": "good standing"},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().
+					SetOverdraftLimitTx(gomock.Any(), db.SetOverdraftLimitTxParams{
+						AccountID:      1,
+						OverdraftLimit: 500,
+						ChangedBy:      "admin",
+						Reason:         "good standing",
+					}).
+					Return(db.SetOverdraftLimitTxResult{Account: *account}, nil).
+					Times(1)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:        "Account Not Found",
+			accountID:   "1",
+			requestBody: gin.H{"overdraft_limit": 500, "reason": "good standing"},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().
+					SetOverdraftLimitTx(gomock.Any(), gomock.Any()).
+					Return(db.SetOverdraftLimitTxResult{}, db.ErrNotFound).
+					Times(1)
+			},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:        "Invalid Request - Negative Limit",
+			accountID:   "1",
+			requestBody: gin.H{"overdraft_limit": -1, "reason": "good standing"},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().SetOverdraftLimitTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:        "Invalid Request - Missing Reason",
+			accountID:   "1",
+			requestBody: gin.H{"overdraft_limit": 500},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().SetOverdraftLimitTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.buildStubs(store)
+			req := newJSONRequest(t, http.MethodPut, "/v1/admin/account/"+tc.accountID+"/overdraft", tc.requestBody)
+			recorder := serveAs(t, server, req, "admin")
+			require.Equal(t, tc.expectedCode, recorder.Code)
+		})
+	}
+}
+
+func TestRevokeOverdraftLimit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+	expectRole(store, "admin", db.RoleAdmin)
+
+	store.EXPECT().
+		SetOverdraftLimitTx(gomock.Any(), db.SetOverdraftLimitTxParams{
+			AccountID:      1,
+			OverdraftLimit: 0,
+			ChangedBy:      "admin",
+			Reason:         "missed payments",
+		}).
+		Return(db.SetOverdraftLimitTxResult{}, nil).
+		Times(1)
+
+	req := newJSONRequest(t, http.MethodDelete, "/v1/admin/account/1/overdraft", gin.H{"reason": "missed payments"})
+	recorder := serveAs(t, server, req, "admin")
+	require.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestListOverdraftLimitChanges(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+	expectRole(store, "admin", db.RoleAdmin)
+
+	store.EXPECT().
+		ListOverdraftLimitChanges(gomock.Any(), db.ListOverdraftLimitChangesParams{AccountID: 1, Limit: 5, Offset: 0}).
+		Return([]db.OverdraftLimitChange{}, nil).
+		Times(1)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/admin/account/1/overdraft/history?page_id=1&page_size=5", nil)
+	recorder := serveAs(t, server, req, "admin")
+	require.Equal(t, http.StatusOK, recorder.Code)
+}
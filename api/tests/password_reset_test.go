@@ -0,0 +1,192 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/primarybank/api"
+	commonutils "github.com/primarybank/common/utils"
+	"github.com/primarybank/db/mocks"
+	db "github.com/primarybank/db/sqlc"
+	"github.com/primarybank/mail"
+	"github.com/stretchr/testify/require"
+)
+
+var resetCode = regexp.MustCompile(`reset your password: (\S+)`)
+
+func TestForgotPassword(t *testing.T) {
+	user := createRandomUser(t)
+
+	testCases := []struct {
+		name       string
+		email      string
+		buildStubs func(store *mocks.MockStore)
+		checkResp  func(t *testing.T, status int, mailer *mail.MemorySender)
+	}{
+		{
+			name:  "Known Email",
+			email: user.Email,
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), user.Email).Return(user, nil).Times(1)
+				store.EXPECT().
+					CreatePasswordResetToken(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ any, args db.CreatePasswordResetTokenParams) (db.PasswordResetToken, error) {
+						require.Equal(t, user.Username, args.Username)
+						require.WithinDuration(t, time.Now().Add(time.Minute), args.ExpiresAt, time.Second)
+						return db.PasswordResetToken{Username: args.Username, TokenHash: args.TokenHash}, nil
+					}).
+					Times(1)
+			},
+			checkResp: func(t *testing.T, status int, mailer *mail.MemorySender) {
+				require.Equal(t, http.StatusAccepted, status)
+
+				msg, ok := mailer.Last(user.Email)
+				require.True(t, ok)
+				require.Regexp(t, resetCode, msg.Body)
+			},
+		},
+		{
+			name:  "Unknown Email",
+			email: commonutils.RandomEmail(),
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Any()).Return(db.User{}, db.ErrNotFound).Times(1)
+				store.EXPECT().CreatePasswordResetToken(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, status int, mailer *mail.MemorySender) {
+				// same answer as for a known email
+				require.Equal(t, http.StatusAccepted, status)
+				require.Empty(t, mailer.Messages())
+			},
+		},
+		{
+			name:  "Invalid Email",
+			email: "not-an-email",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, status int, mailer *mail.MemorySender) {
+				require.Equal(t, http.StatusBadRequest, status)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mocks.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			server := newTestServer(t, store)
+
+			req := newJSONRequest(t, http.MethodPost, "/v1/user/password/forgot", api.ForgotPasswordRequest{Email: tc.email})
+			recorder := serve(t, server, req)
+
+			tc.checkResp(t, recorder.Code, server.Mailer.(*mail.MemorySender))
+		})
+	}
+}
+
+// failingMailer is a mail server that is down
+type failingMailer struct{}
+
+func (failingMailer) Send(context.Context, mail.Message) error {
+	return errors.New("connection refused")
+}
+
+func TestForgotPasswordMailFailed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	user := createRandomUser(t)
+	store := mocks.NewMockStore(ctrl)
+	store.EXPECT().GetUserByEmail(gomock.Any(), user.Email).Return(user, nil).Times(1)
+	store.EXPECT().CreatePasswordResetToken(gomock.Any(), gomock.Any()).Return(db.PasswordResetToken{}, nil).Times(1)
+
+	server := newTestServer(t, store)
+	server.Mailer = failingMailer{}
+
+	// a known email answers like an unknown one even when its mail cannot be sent
+	req := newJSONRequest(t, http.MethodPost, "/v1/user/password/forgot", api.ForgotPasswordRequest{Email: user.Email})
+	recorder := serve(t, server, req)
+	require.Equal(t, http.StatusAccepted, recorder.Code)
+	require.Empty(t, recorder.Body.String())
+}
+
+func TestResetPassword(t *testing.T) {
+	user := createRandomUser(t)
+	token := commonutils.RandomString(43)
+	password := commonutils.RandomString(10)
+
+	testCases := []struct {
+		name       string
+		body       api.ResetPasswordRequest
+		buildStubs func(store *mocks.MockStore)
+		checkResp  func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: api.ResetPasswordRequest{Token: token, Password: password},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ any, args db.ResetPasswordTxParams) (db.User, error) {
+						// only the hash of the token ever reaches the store
+						require.Equal(t, commonutils.HashSecret(token), args.TokenHash)
+						hashedPassword, err := args.HashPassword()
+						require.NoError(t, err)
+						require.NoError(t, commonutils.CheckPassword(password, hashedPassword))
+						return user, nil
+					}).
+					Times(1)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+			},
+		},
+		{
+			name: "Invalid Token",
+			body: api.ResetPasswordRequest{Token: token, Password: password},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Return(db.User{}, db.ErrInvalidPasswordResetToken).
+					Times(1)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, http.StatusBadRequest, "invalid_reset_token")
+			},
+		},
+		{
+			name: "Short Password",
+			body: api.ResetPasswordRequest{Token: token, Password: "abc"},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().ResetPasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				problem := requireProblem(t, recorder, http.StatusBadRequest, "weak_password")
+				require.Equal(t, "password", problem.InvalidParams[0].Name)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mocks.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			server := newTestServer(t, store)
+
+			req := newJSONRequest(t, http.MethodPost, "/v1/user/password/reset", tc.body)
+			tc.checkResp(t, serve(t, server, req))
+		})
+	}
+}
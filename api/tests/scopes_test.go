@@ -0,0 +1,172 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/primarybank/api"
+	commonutils "github.com/primarybank/common/utils"
+	"github.com/primarybank/db/mocks"
+	db "github.com/primarybank/db/sqlc"
+	"github.com/primarybank/token"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScopeMiddleware(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []token.Option
+		checkResp func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Granted",
+			opts: []token.Option{token.WithScopes(token.ScopeAccountsRead, token.ScopeAccountsWrite)},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "Legacy Token Without Scopes",
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "Missing Scope",
+			opts: []token.Option{token.WithScopes(token.ScopeAccountsRead)},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, http.StatusForbidden, "insufficient_scope")
+				require.Equal(t, `Bearer error="insufficient_scope", scope="accounts:read accounts:write"`, recorder.Header().Get("WWW-Authenticate"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t, nil)
+
+			scopePath := "/scope"
+			server.Router.GET(scopePath,
+				api.AuthMiddleWare(server.TokenMaker, nil),
+				api.ScopeMiddleware(token.ScopeAccountsRead, token.ScopeAccountsWrite),
+				func(ctx *gin.Context) {
+					ctx.JSON(http.StatusOK, gin.H{})
+				},
+			)
+
+			accessToken, err := server.TokenMaker.CreateToken("user", time.Minute, tt.opts...)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodGet, scopePath, nil)
+			req.Header.Set(api.AuthHeaderKey, api.AuthType+" "+accessToken)
+			tt.checkResp(t, serve(t, server, req))
+		})
+	}
+}
+
+func TestLoginUserScopes(t *testing.T) {
+	user := createRandomUser(t)
+	password := commonutils.RandomString(10)
+	hashedPassword, err := commonutils.HashPassword(password)
+	require.NoError(t, err)
+	user.Password = hashedPassword
+
+	testCases := []struct {
+		name       string
+		scopes     []string
+		buildStubs func(store *mocks.MockStore)
+		checkResp  func(t *testing.T, server *api.Server, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "Reduced",
+			scopes: []string{token.ScopeAccountsRead},
+			buildStubs: func(store *mocks.MockStore) {
+				expectLoginThrottle(store, user.Username, nil)
+				store.EXPECT().GetUser(gomock.Any(), user.Username).Return(user, nil).Times(1)
+				store.EXPECT().GetMFAFactor(gomock.Any(), user.Username).Return(db.MfaFactor{}, db.ErrNotFound).Times(1)
+				expectLoginAttempt(t, store, user.Username, db.LoginOutcomeSuccess)
+			},
+			checkResp: func(t *testing.T, server *api.Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp api.LoginUserResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+				require.Equal(t, token.ScopeAccountsRead, resp.Scope)
+
+				payload, err := server.TokenMaker.VerifyToken(resp.AccessToken)
+				require.NoError(t, err)
+				require.Equal(t, []string{token.ScopeAccountsRead}, payload.Scopes())
+			},
+		},
+		{
+			name: "Default",
+			buildStubs: func(store *mocks.MockStore) {
+				expectLoginThrottle(store, user.Username, nil)
+				store.EXPECT().GetUser(gomock.Any(), user.Username).Return(user, nil).Times(1)
+				store.EXPECT().GetMFAFactor(gomock.Any(), user.Username).Return(db.MfaFactor{}, db.ErrNotFound).Times(1)
+				expectLoginAttempt(t, store, user.Username, db.LoginOutcomeSuccess)
+			},
+			checkResp: func(t *testing.T, server *api.Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp api.LoginUserResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+
+				payload, err := server.TokenMaker.VerifyToken(resp.AccessToken)
+				require.NoError(t, err)
+				require.Equal(t, token.Scopes, payload.Scopes())
+			},
+		},
+		{
+			name:   "Unknown Scope",
+			scopes: []string{"everything"},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().CheckLoginThrottle(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, server *api.Server, recorder *httptest.ResponseRecorder) {
+				problem := requireProblem(t, recorder, http.StatusBadRequest, "invalid_request")
+				require.Equal(t, []api.InvalidParam{{Name: "scopes", Reason: `unknown scope "everything"`}}, problem.InvalidParams)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mocks.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+			tc.buildStubs(store)
+
+			req := newJSONRequest(t, http.MethodPost, "/v1/user/login", api.LoginRequest{
+				Username: user.Username,
+				Password: password,
+				Scopes:   tc.scopes,
+			})
+			tc.checkResp(t, server, serve(t, server, req))
+		})
+	}
+}
+
+func TestCreateAPIKeyBeyondTokenScopes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+	store.EXPECT().CreateAPIKey(gomock.Any(), gomock.Any()).Times(0)
+
+	accessToken, err := server.TokenMaker.CreateToken("user", time.Minute, token.WithScopes(token.ScopeProfileWrite, token.ScopeAccountsRead))
+	require.NoError(t, err)
+
+	req := newJSONRequest(t, http.MethodPost, "/v1/user/api_key", gin.H{"name": "ci", "scopes": []string{token.ScopeAccountsRead, token.ScopeTransfersWrite}})
+	req.Header.Set(api.AuthHeaderKey, api.AuthType+" "+accessToken)
+	requireProblem(t, serve(t, server, req), http.StatusForbidden, "insufficient_scope")
+}
@@ -0,0 +1,152 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/primarybank/api"
+	"github.com/primarybank/db/mocks"
+	db "github.com/primarybank/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetTransactionAllowances(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+	testCases := []struct {
+		name         string
+		accountID    string
+		buildStubs   func(store *mocks.MockStore)
+		expectedCode int
+	}{
+		{
+			name:      "Valid Request",
+			accountID: "1",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().
+					GetTransactionAllowances(gomock.Any(), int64(1)).
+					Return([]db.TransactionAllowance{{Scope: db.LimitScopeAccount, Period: db.LimitPeriodDaily}}, nil).
+					Times(1)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:      "Account Not Found",
+			accountID: "1",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().GetTransactionAllowances(gomock.Any(), gomock.Any()).Return(nil, db.ErrNotFound).Times(1)
+			},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:      "Invalid Account ID",
+			accountID: "0",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().GetTransactionAllowances(gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.buildStubs(store)
+			req := httptest.NewRequest(http.MethodGet, "/v1/account/"+tc.accountID+"/limits", nil)
+			recorder := serveAs(t, server, req, "user")
+			require.Equal(t, tc.expectedCode, recorder.Code)
+		})
+	}
+}
+
+func TestCreateTransactionLimit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+	expectRole(store, "admin", db.RoleAdmin)
+	testCases := []struct {
+		name         string
+		requestBody  api.CreateTransactionLimitRequest
+		buildStubs   func(store *mocks.MockStore)
+		expectedCode int
+	}{
+		{
+			name: "Account Override",
+			requestBody: api.CreateTransactionLimitRequest{
+				Scope:     db.LimitScopeAccount,
+				Period:    db.LimitPeriodDaily,
+				AccountID: 1,
+				MaxAmount: 100,
+			},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().CreateTransactionLimit(gomock.Any(), gomock.Any()).Return(db.TransactionLimit{ID: 1}, nil).Times(1)
+			},
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name: "Role Default",
+			requestBody: api.CreateTransactionLimitRequest{
+				Scope:    db.LimitScopeUser,
+				Period:   db.LimitPeriodMonthly,
+				Role:     db.RoleDepositor,
+				MaxCount: 10,
+			},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().CreateTransactionLimit(gomock.Any(), gomock.Any()).Return(db.TransactionLimit{ID: 1}, nil).Times(1)
+			},
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name: "Invalid Request - Two Subjects",
+			requestBody: api.CreateTransactionLimitRequest{
+				Scope:     db.LimitScopeAccount,
+				Period:    db.LimitPeriodDaily,
+				Role:      db.RoleDepositor,
+				AccountID: 1,
+			},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().CreateTransactionLimit(gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name: "Invalid Request - Scope Mismatch",
+			requestBody: api.CreateTransactionLimitRequest{
+				Scope:    db.LimitScopeAccount,
+				Period:   db.LimitPeriodDaily,
+				Username: "user",
+			},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().CreateTransactionLimit(gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name: "Invalid Request - Unknown Period",
+			requestBody: api.CreateTransactionLimitRequest{
+				Scope:  db.LimitScopeUser,
+				Period: "weekly",
+				Role:   db.RoleDepositor,
+			},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().CreateTransactionLimit(gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.buildStubs(store)
+			req := newJSONRequest(t, http.MethodPost, "/v1/admin/transaction_limit", tc.requestBody)
+			recorder := serveAs(t, server, req, "admin")
+			require.Equal(t, tc.expectedCode, recorder.Code)
+		})
+	}
+}
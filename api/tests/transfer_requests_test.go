@@ -0,0 +1,222 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/primarybank/api"
+	"github.com/primarybank/db/mocks"
+	db "github.com/primarybank/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateTransferApprovalThreshold(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+	server.Config.TransferApprovalThreshold = 1000
+
+	testCases := []struct {
+		name         string
+		amount       int64
+		buildStubs   func(store *mocks.MockStore)
+		expectedCode int
+	}{
+		{
+			name:   "At Threshold",
+			amount: 1000,
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Return(db.TransferTxResult{}, nil).Times(1)
+				store.EXPECT().CreateTransferRequest(gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:   "Above Threshold",
+			amount: 1001,
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().
+					CreateTransferRequest(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ any, args db.CreateTransferRequestParams) (db.TransferRequest, error) {
+						require.Equal(t, "maker", args.InitiatedBy)
+						require.Equal(t, int64(1001), args.Amount)
+						return db.TransferRequest{ID: 1, Status: db.TransferRequestPending}, nil
+					}).
+					Times(1)
+			},
+			expectedCode: http.StatusAccepted,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.buildStubs(store)
+			req := newJSONRequest(t, http.MethodPost, "/v1/transfer", api.CreateTransferRequest{FromAccountID: 1, ToAccountID: 2, Amount: tc.amount})
+			recorder := serveAs(t, server, req, "maker")
+			require.Equal(t, tc.expectedCode, recorder.Code)
+		})
+	}
+}
+
+func TestApproveTransferRequest(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+	expectRole(store, "checker", db.RoleApprover)
+	testCases := []struct {
+		name         string
+		err          error
+		expectedCode int
+	}{
+		{
+			name:         "Valid Request",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "Not Found",
+			err:          db.ErrNotFound,
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "Self Review",
+			err:          db.ErrSelfReview,
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "Already Reviewed",
+			err:          db.ErrTransferRequestNotPending,
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:         "Expired",
+			err:          db.ErrTransferRequestExpired,
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:         "Overdraft Limit Exceeded",
+			err:          &db.OverdraftError{AccountID: 1},
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store.EXPECT().
+				GetTransferRequest(gomock.Any(), int64(1)).
+				Return(db.TransferRequest{ID: 1, Status: db.TransferRequestPending}, nil).
+				Times(1)
+			store.EXPECT().
+				ApproveTransferRequestTx(gomock.Any(), db.ReviewTransferRequestTxParams{ID: 1, ReviewedBy: "checker"}).
+				Return(db.ApproveTransferRequestTxResult{}, tc.err).
+				Times(1)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/transfer_request/1/approve", nil)
+			recorder := serveAs(t, server, req, "checker")
+			require.Equal(t, tc.expectedCode, recorder.Code)
+		})
+	}
+}
+
+func TestRejectTransferRequest(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+	expectRole(store, "checker", db.RoleApprover)
+	testCases := []struct {
+		name         string
+		requestBody  gin.H
+		buildStubs   func(store *mocks.MockStore)
+		expectedCode int
+	}{
+		{
+			name:        "Valid Request",
+			requestBody: gin.H{"reason": "unknown beneficiary"},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().
+					GetTransferRequest(gomock.Any(), int64(1)).
+					Return(db.TransferRequest{ID: 1, Status: db.TransferRequestPending}, nil).
+					Times(1)
+				store.EXPECT().
+					RejectTransferRequestTx(gomock.Any(), db.ReviewTransferRequestTxParams{
+						ID:         1,
+						ReviewedBy: "checker",
+						Reason:     "unknown beneficiary",
+					}).
+					Return(db.TransferRequest{ID: 1, Status: db.TransferRequestRejected}, nil).
+					Times(1)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:        "Invalid Request - Missing Reason",
+			requestBody: gin.H{},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().RejectTransferRequestTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.buildStubs(store)
+			req := newJSONRequest(t, http.MethodPost, "/v1/transfer_request/1/reject", tc.requestBody)
+			recorder := serveAs(t, server, req, "checker")
+			require.Equal(t, tc.expectedCode, recorder.Code)
+		})
+	}
+}
+
+func TestListTransferRequests(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+	expectRole(store, "checker", db.RoleApprover)
+	testCases := []struct {
+		name         string
+		query        string
+		buildStubs   func(store *mocks.MockStore)
+		expectedCode int
+	}{
+		{
+			name:  "Pending By Default",
+			query: "page_id=1&page_size=5",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().
+					ListTransferRequests(gomock.Any(), db.ListTransferRequestsParams{Status: db.TransferRequestPending, Limit: 5, Offset: 0}).
+					Return([]db.TransferRequest{}, nil).
+					Times(1)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:  "Invalid Status",
+			query: "status=posted&page_id=1&page_size=5",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().ListTransferRequests(gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.buildStubs(store)
+			req := httptest.NewRequest(http.MethodGet, "/v1/transfer_requests?"+tc.query, nil)
+			recorder := serveAs(t, server, req, "checker")
+			require.Equal(t, tc.expectedCode, recorder.Code)
+		})
+	}
+}
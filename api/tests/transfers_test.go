@@ -1,14 +1,11 @@
 package tests
 
 import (
-	"bytes"
-	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
-	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
 	"github.com/primarybank/api"
 	"github.com/primarybank/db/mocks"
@@ -47,10 +44,34 @@ func TestCreateTransfer(t *testing.T) {
 				Amount:        100,
 			},
 			buildStubs: func(store *mocks.MockStore) {
-				store.EXPECT().CreateTransfer(gomock.Any(), gomock.Any()).Return(*transfer, nil).Times(1)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Return(db.TransferTxResult{Transfer: *transfer}, nil).Times(1)
 			},
 			expectedCode: http.StatusOK,
 		},
+		{
+			name: "Overdraft Limit Exceeded",
+			requestBody: api.CreateTransferRequest{
+				FromAccountID: 1,
+				ToAccountID:   2,
+				Amount:        100,
+			},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Return(db.TransferTxResult{}, &db.OverdraftError{AccountID: 1}).Times(1)
+			},
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "Transaction Limit Exceeded",
+			requestBody: api.CreateTransferRequest{
+				FromAccountID: 1,
+				ToAccountID:   2,
+				Amount:        100,
+			},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Return(db.TransferTxResult{}, &db.TransactionLimitError{AccountID: 1}).Times(1)
+			},
+			expectedCode: http.StatusUnprocessableEntity,
+		},
 		{
 			name: "Invalid Request - Negative Amount",
 			requestBody: api.CreateTransferRequest{
@@ -59,7 +80,58 @@ func TestCreateTransfer(t *testing.T) {
 				Amount:        -100,
 			},
 			buildStubs: func(store *mocks.MockStore) {
-				store.EXPECT().CreateTransfer(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.buildStubs(store)
+			req := newJSONRequest(t, http.MethodPost, "/v1/transfer", tc.requestBody)
+			recorder := serveAs(t, server, req, "user")
+			require.Equal(t, tc.expectedCode, recorder.Code)
+		})
+	}
+}
+
+func TestQuoteTransferFee(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+	testCases := []struct {
+		name         string
+		queryParams  string
+		buildStubs   func(store *mocks.MockStore)
+		expectedCode int
+	}{
+		{
+			name:        "Valid Request",
+			queryParams: "from_account_id=1&to_account_id=2&amount=100",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().
+					QuoteTransferFee(gomock.Any(), db.TransferTxParams{FromAccountID: 1, ToAccountID: 2, Amount: 100}).
+					Return(db.FeeQuote{Amount: 100, Fee: 2, Total: 102}, nil).
+					Times(1)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:        "Account Not Found",
+			queryParams: "from_account_id=1&to_account_id=2&amount=100",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().QuoteTransferFee(gomock.Any(), gomock.Any()).Return(db.FeeQuote{}, db.ErrNotFound).Times(1)
+			},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:        "Invalid Request - Missing Amount",
+			queryParams: "from_account_id=1&to_account_id=2",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().QuoteTransferFee(gomock.Any(), gomock.Any()).Times(0)
 			},
 			expectedCode: http.StatusBadRequest,
 		},
@@ -68,12 +140,8 @@ func TestCreateTransfer(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			tc.buildStubs(store)
-			recorder := httptest.NewRecorder()
-			c, _ := gin.CreateTestContext(recorder)
-			body, _ := json.Marshal(tc.requestBody)
-			c.Request = httptest.NewRequest(http.MethodPost, "/transfers", bytes.NewReader(body))
-			c.Request.Header.Set("Content-Type", "application/json")
-			server.CreateTransfer(c)
+			req := httptest.NewRequest(http.MethodGet, "/v1/transfer/fee?"+tc.queryParams, nil)
+			recorder := serveAs(t, server, req, "user")
 			require.Equal(t, tc.expectedCode, recorder.Code)
 		})
 	}
@@ -105,11 +173,8 @@ func TestGetTransfer(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			tc.buildStubs(store)
-			recorder := httptest.NewRecorder()
-			c, _ := gin.CreateTestContext(recorder)
-			c.Params = append(c.Params, gin.Param{Key: "id", Value: tc.transferID})
-			c.Request = httptest.NewRequest(http.MethodGet, "/transfers/"+tc.transferID, nil)
-			server.GetTransfer(c)
+			req := httptest.NewRequest(http.MethodGet, "/v1/transfer/"+tc.transferID, nil)
+			recorder := serveAs(t, server, req, "user")
 			require.Equal(t, tc.expectedCode, recorder.Code)
 		})
 	}
@@ -131,20 +196,27 @@ func TestDeleteTransfer(t *testing.T) {
 			name:       "Valid Request",
 			transferID: "1",
 			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), int64(1)).Return(db.Transfer{ID: 1, Status: db.TransferPosted}, nil).Times(1)
 				store.EXPECT().DeleteTransfer(gomock.Any(), gomock.Any()).Return(nil).Times(1)
 			},
 			expectedCode: http.StatusOK,
 		},
+		{
+			name:       "Pending",
+			transferID: "2",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), int64(2)).Return(db.Transfer{ID: 2, Status: db.TransferPending}, nil).Times(1)
+				store.EXPECT().DeleteTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedCode: http.StatusConflict,
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			tc.buildStubs(store)
-			recorder := httptest.NewRecorder()
-			c, _ := gin.CreateTestContext(recorder)
-			c.Params = append(c.Params, gin.Param{Key: "id", Value: tc.transferID})
-			c.Request = httptest.NewRequest(http.MethodDelete, "/transfers/"+tc.transferID, nil)
-			server.DeleteTransfer(c)
+			req := httptest.NewRequest(http.MethodDelete, "/v1/transfer/"+tc.transferID, nil)
+			recorder := serveAs(t, server, req, "user")
 			require.Equal(t, tc.expectedCode, recorder.Code)
 		})
 	}
@@ -175,10 +247,136 @@ func TestListTransfers(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			tc.buildStubs(store)
-			recorder := httptest.NewRecorder()
-			c, _ := gin.CreateTestContext(recorder)
-			c.Request = httptest.NewRequest(http.MethodGet, "/transfers?"+tc.queryParams, nil)
-			server.ListTransfers(c)
+			req := httptest.NewRequest(http.MethodGet, "/v1/transfers?"+tc.queryParams, nil)
+			recorder := serveAs(t, server, req, "user")
+			require.Equal(t, tc.expectedCode, recorder.Code)
+		})
+	}
+}
+
+func TestReserveTransfer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+	server.Config.TransferApprovalThreshold = 1000
+
+	transfer := CreateRandomTransfer()
+	transfer.Status = db.TransferPending
+	testCases := []struct {
+		name         string
+		amount       int64
+		buildStubs   func(store *mocks.MockStore)
+		expectedCode int
+	}{
+		{
+			name:   "Valid Request",
+			amount: 100,
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().
+					ReserveTransferTx(gomock.Any(), db.TransferTxParams{FromAccountID: 1, ToAccountID: 2, Amount: 100}).
+					Return(db.TransferTxResult{Transfer: *transfer}, nil).
+					Times(1)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:   "Overdraft Limit Exceeded",
+			amount: 100,
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().ReserveTransferTx(gomock.Any(), gomock.Any()).Return(db.TransferTxResult{}, &db.OverdraftError{AccountID: 1}).Times(1)
+			},
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "Above Approval Threshold",
+			amount: 1001,
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().ReserveTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.buildStubs(store)
+			req := newJSONRequest(t, http.MethodPost, "/v1/transfer/reserve", api.CreateTransferRequest{FromAccountID: 1, ToAccountID: 2, Amount: tc.amount})
+			recorder := serveAs(t, server, req, "user")
+			require.Equal(t, tc.expectedCode, recorder.Code)
+		})
+	}
+}
+
+func TestPostTransfer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+	testCases := []struct {
+		name         string
+		err          error
+		expectedCode int
+	}{
+		{
+			name:         "Valid Request",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "Transfer Not Found",
+			err:          db.ErrNotFound,
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "Already Voided",
+			err:          &db.TransferStatusError{TransferID: 1, Status: db.TransferVoided},
+			expectedCode: http.StatusConflict,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store.EXPECT().GetTransfer(gomock.Any(), int64(1)).Return(db.Transfer{ID: 1, Status: db.TransferPending}, nil).Times(1)
+			store.EXPECT().PostTransferTx(gomock.Any(), int64(1)).Return(db.TransferTxResult{}, tc.err).Times(1)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/transfer/1/post", nil)
+			recorder := serveAs(t, server, req, "user")
+			require.Equal(t, tc.expectedCode, recorder.Code)
+		})
+	}
+}
+
+func TestVoidTransfer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+	testCases := []struct {
+		name         string
+		err          error
+		expectedCode int
+	}{
+		{
+			name:         "Valid Request",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "Already Posted",
+			err:          &db.TransferStatusError{TransferID: 1, Status: db.TransferPosted},
+			expectedCode: http.StatusConflict,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store.EXPECT().GetTransfer(gomock.Any(), int64(1)).Return(db.Transfer{ID: 1, Status: db.TransferPending}, nil).Times(1)
+			store.EXPECT().VoidTransferTx(gomock.Any(), int64(1)).Return(db.VoidTransferTxResult{}, tc.err).Times(1)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/transfer/1/void", nil)
+			recorder := serveAs(t, server, req, "user")
 			require.Equal(t, tc.expectedCode, recorder.Code)
 		})
 	}
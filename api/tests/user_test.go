@@ -1,22 +1,22 @@
 package tests
 
 import (
-	"bytes"
-	"database/sql"
 	"encoding/json"
 	"errors"
+	"math"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
-	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
 	"github.com/primarybank/api"
 	commonutils "github.com/primarybank/common/utils"
 	"github.com/primarybank/db/mocks"
 	db "github.com/primarybank/db/sqlc"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func createRandomUser(t *testing.T) db.User {
@@ -59,12 +59,45 @@ func TestCreateUser(t *testing.T) {
 			},
 			buildStubs: func(store *mocks.MockStore) {
 				store.EXPECT().
-					CreateUser(gomock.Any(), gomock.Any()).
-					Return(user, nil).
+					CreateUserTx(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ any, args db.CreateUserParams) (db.User, error) {
+						require.True(t, strings.HasPrefix(args.Password, "$argon2id$"))
+						require.NoError(t, commonutils.CheckPassword(user.Password, args.Password))
+						return user, nil
+					}).
 					Times(1)
 			},
 			expectedCode: http.StatusCreated,
 		},
+		{
+			name: "Weak Password",
+			requestBody: api.CreateUserRequest{
+				Username: user.Username,
+				Password: "Password1",
+				FullName: user.FullName,
+				Email:    user.Email,
+			},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().CreateUserTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name: "Duplicate Username",
+			requestBody: api.CreateUserRequest{
+				Username: user.Username,
+				Password: user.Password,
+				FullName: user.FullName,
+				Email:    user.Email,
+			},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().
+					CreateUserTx(gomock.Any(), gomock.Any()).
+					Return(db.User{}, alreadyExists("username")).
+					Times(1)
+			},
+			expectedCode: http.StatusConflict,
+		},
 		{
 			name: "Invalid Request - Empty Username",
 			requestBody: api.CreateUserRequest{
@@ -74,7 +107,7 @@ func TestCreateUser(t *testing.T) {
 				Email:    user.Email,
 			},
 			buildStubs: func(store *mocks.MockStore) {
-				store.EXPECT().CreateUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateUserTx(gomock.Any(), gomock.Any()).Times(0)
 			},
 			expectedCode: http.StatusBadRequest,
 		},
@@ -87,7 +120,7 @@ func TestCreateUser(t *testing.T) {
 				Email:    "invalid-email",
 			},
 			buildStubs: func(store *mocks.MockStore) {
-				store.EXPECT().CreateUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateUserTx(gomock.Any(), gomock.Any()).Times(0)
 			},
 			expectedCode: http.StatusBadRequest,
 		},
@@ -97,14 +130,8 @@ func TestCreateUser(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			tc.buildStubs(store)
 
-			recorder := httptest.NewRecorder()
-			c, _ := gin.CreateTestContext(recorder)
-
-			body, _ := json.Marshal(tc.requestBody)
-			c.Request = httptest.NewRequest(http.MethodPost, "/users", bytes.NewReader(body))
-			c.Request.Header.Set("Content-Type", "application/json")
-
-			server.CreateUser(c)
+			req := newJSONRequest(t, http.MethodPost, "/v1/user", tc.requestBody)
+			recorder := serve(t, server, req)
 
 			require.Equal(t, tc.expectedCode, recorder.Code)
 		})
@@ -191,7 +218,7 @@ func TestUpdateUser(t *testing.T) {
 			buildStubs: func(store *mocks.MockStore) {
 				store.EXPECT().
 					GetUser(gomock.Any(), "non_existing_user").
-					Return(db.User{}, sql.ErrNoRows).
+					Return(db.User{}, db.ErrNotFound).
 					Times(1)
 
 				store.EXPECT().
@@ -247,17 +274,8 @@ func TestUpdateUser(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			tc.buildStubs(store)
 
-			recorder := httptest.NewRecorder()
-			c, _ := gin.CreateTestContext(recorder)
-
-			body, _ := json.Marshal(tc.requestBody)
-			req := httptest.NewRequest(http.MethodPut, "/users/"+tc.username, bytes.NewReader(body))
-			req.Header.Set("Content-Type", "application/json")
-			c.Request = req
-
-			c.Params = gin.Params{{Key: "username", Value: tc.username}}
-
-			server.UpdateUser(c)
+			req := newJSONRequest(t, http.MethodPut, "/v1/user/"+tc.username, tc.requestBody)
+			recorder := serveAs(t, server, req, "user")
 
 			require.Equal(t, tc.expectedCode, recorder.Code)
 		})
@@ -290,10 +308,50 @@ func TestLoginUser(t *testing.T) {
 				Password: password,
 			},
 			buildStubs: func() {
+				expectLoginThrottle(store, user.Username, nil)
 				store.EXPECT().
 					GetUser(gomock.Any(), gomock.Any()).
 					Return(user, nil).
 					Times(1)
+				store.EXPECT().
+					GetMFAFactor(gomock.Any(), user.Username).
+					Return(db.MfaFactor{}, db.ErrNotFound).
+					Times(1)
+				expectLoginAttempt(t, store, user.Username, db.LoginOutcomeSuccess)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name: "LegacyHashRehashed",
+			requestBody: api.LoginRequest{
+				Username: user.Username,
+				Password: password,
+			},
+			buildStubs: func() {
+				bcryptHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+				require.NoError(t, err)
+				legacyUser := user
+				legacyUser.Password = string(bcryptHash)
+
+				expectLoginThrottle(store, user.Username, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Return(legacyUser, nil).
+					Times(1)
+				store.EXPECT().
+					UpdateUserPassword(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ any, args db.UpdateUserPasswordParams) (db.User, error) {
+						require.Equal(t, user.Username, args.Username)
+						require.True(t, strings.HasPrefix(args.Password, "$argon2id$"))
+						require.NoError(t, commonutils.CheckPassword(password, args.Password))
+						return user, nil
+					}).
+					Times(1)
+				store.EXPECT().
+					GetMFAFactor(gomock.Any(), user.Username).
+					Return(db.MfaFactor{}, db.ErrNotFound).
+					Times(1)
+				expectLoginAttempt(t, store, user.Username, db.LoginOutcomeSuccess)
 			},
 			expectedCode: http.StatusOK,
 		},
@@ -304,10 +362,12 @@ func TestLoginUser(t *testing.T) {
 				Password: password,
 			},
 			buildStubs: func() {
+				expectLoginThrottle(store, user.Username, nil)
 				store.EXPECT().
 					GetUser(gomock.Any(), gomock.Any()).
-					Return(db.User{}, sql.ErrNoRows).
+					Return(db.User{}, db.ErrNotFound).
 					Times(1)
+				expectLoginAttempt(t, store, user.Username, db.LoginOutcomeFailure)
 			},
 			expectedCode: http.StatusUnauthorized,
 		},
@@ -318,10 +378,12 @@ func TestLoginUser(t *testing.T) {
 				Password: "wrongpassword",
 			},
 			buildStubs: func() {
+				expectLoginThrottle(store, user.Username, nil)
 				store.EXPECT().
 					GetUser(gomock.Any(), gomock.Any()).
 					Return(user, nil).
 					Times(1)
+				expectLoginAttempt(t, store, user.Username, db.LoginOutcomeFailure)
 			},
 			expectedCode: http.StatusUnauthorized,
 		},
@@ -331,16 +393,96 @@ func TestLoginUser(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			tc.buildStubs()
 
-			recorder := httptest.NewRecorder()
-			c, _ := gin.CreateTestContext(recorder)
+			req := newJSONRequest(t, http.MethodPost, "/v1/user/login", tc.requestBody)
+			recorder := serve(t, server, req)
+			require.Equal(t, tc.expectedCode, recorder.Code)
+		})
+	}
+}
 
-			body, _ := json.Marshal(tc.requestBody)
-			req := httptest.NewRequest(http.MethodPost, "/user/login", bytes.NewReader(body))
-			req.Header.Set("Content-Type", "application/json")
-			c.Request = req
+func TestLoginUnknownUserTiming(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
 
-			server.LoginUser(c)
-			require.Equal(t, tc.expectedCode, recorder.Code)
+	store := mocks.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+
+	user := createRandomUser(t)
+	store.EXPECT().CheckLoginThrottle(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	store.EXPECT().RecordLoginAttemptTx(gomock.Any(), gomock.Any()).Return(db.LoginEvent{}, nil).AnyTimes()
+	store.EXPECT().GetUser(gomock.Any(), user.Username).Return(user, nil).AnyTimes()
+	store.EXPECT().GetUser(gomock.Any(), "unknown").Return(db.User{}, db.ErrNotFound).AnyTimes()
+
+	// the fastest of a few logins, refused for a wrong password or an unknown username
+	login := func(username string) time.Duration {
+		fastest := time.Duration(math.MaxInt64)
+		for i := 0; i < 3; i++ {
+			req := newJSONRequest(t, http.MethodPost, "/v1/user/login", api.LoginRequest{Username: username, Password: "wrong password"})
+			start := time.Now()
+			requireProblem(t, serve(t, server, req), http.StatusUnauthorized, "invalid_credentials")
+			fastest = min(fastest, time.Since(start))
+		}
+		return fastest
+	}
+
+	// an unknown username is checked against a password hash too, so it doesn't answer any faster
+	wrongPassword := login(user.Username)
+	unknownUser := login("unknown")
+	require.Greater(t, unknownUser, wrongPassword/2)
+}
+
+func TestGetUserProfile(t *testing.T) {
+	user := createRandomUser(t)
+	accounts := []db.Account{
+		{ID: 1, Owner: user.Username, Currency: "USD"},
+		{ID: 2, Owner: user.Username, Currency: "EUR"},
+	}
+
+	testCases := []struct {
+		name       string
+		buildStubs func(store *mocks.MockStore)
+		checkResp  func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), user.Username).Return(user, nil).Times(1)
+				store.EXPECT().ListAccountsByOwner(gomock.Any(), user.Username).Return(accounts, nil).Times(1)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var profile api.UserProfileResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &profile))
+				require.Equal(t, user.Username, profile.Username)
+				require.Equal(t, user.Email, profile.Email)
+				require.Len(t, profile.Accounts, len(accounts))
+				require.Equal(t, accounts[1].Currency, profile.Accounts[1].Currency)
+			},
+		},
+		{
+			name: "User Not Found",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), user.Username).Return(db.User{}, db.ErrNotFound).Times(1)
+				store.EXPECT().ListAccountsByOwner(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, http.StatusNotFound, "not_found")
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mocks.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			server := newTestServer(t, store)
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/user/profile", nil)
+			tc.checkResp(t, serveAs(t, server, req, user.Username))
 		})
 	}
 }
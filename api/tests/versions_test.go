@@ -0,0 +1,198 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/primarybank/api"
+	"github.com/primarybank/config"
+	"github.com/primarybank/db/mocks"
+	db "github.com/primarybank/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+// changeV2Route makes handler the /v2 handler of route in the server built with it
+func changeV2Route(route string, handler func(*api.Server, *gin.Context)) api.ServerOption {
+	return api.WithV2Handlers(map[string]func(*api.Server, *gin.Context){route: handler})
+}
+
+func TestVersionedRoutes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockStore(ctrl)
+	server := newConfiguredTestServer(t, store, func(cfg *config.Config) {
+		cfg.LegacyRoutesDeprecatedAt = "2026-10-19"
+		cfg.LegacyRoutesSunset = "2027-04-30"
+	})
+
+	account := CreateRandomAccount(t)
+	store.EXPECT().ListAccounts(gomock.Any(), gomock.Any()).Return([]db.Account{*account}, nil).Times(2)
+
+	// v1 is the current contract
+	req := httptest.NewRequest(http.MethodGet, "/v1/accounts?page_id=1&page_size=5", nil)
+	recorder := serveAs(t, server, req, account.Owner)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Empty(t, recorder.Header().Get(api.DeprecationHeader))
+	require.Empty(t, recorder.Header().Get(api.SunsetHeader))
+	v1Body := recorder.Body.String()
+
+	// the unversioned routes answer like v1 and say they are going away
+	req = httptest.NewRequest(http.MethodGet, "/accounts?page_id=1&page_size=5", nil)
+	recorder = serveAs(t, server, req, account.Owner)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, v1Body, recorder.Body.String())
+
+	deprecatedAt := time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+	require.Equal(t, "@"+strconv.FormatInt(deprecatedAt.Unix(), 10), recorder.Header().Get(api.DeprecationHeader))
+	require.Equal(t, "Fri, 30 Apr 2027 00:00:00 GMT", recorder.Header().Get(api.SunsetHeader))
+	require.Equal(t, `</v1/accounts>; rel="successor-version"`, recorder.Header().Get(api.LinkHeader))
+
+	// failures of the unversioned routes are marked too
+	req = httptest.NewRequest(http.MethodGet, "/accounts?page_id=1&page_size=5", nil)
+	recorder = serve(t, server, req)
+	requireProblem(t, recorder, http.StatusUnauthorized, "unauthorized")
+	require.NotEmpty(t, recorder.Header().Get(api.DeprecationHeader))
+
+	// there is no v2 until it changes a route
+	req = httptest.NewRequest(http.MethodGet, "/v2/accounts?page_id=1&page_size=5", nil)
+	require.Equal(t, http.StatusNotFound, serveAs(t, server, req, account.Owner).Code)
+
+	// keys and documents aren't versioned
+	recorder = serve(t, server, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Empty(t, recorder.Header().Get(api.DeprecationHeader))
+}
+
+func TestLegacyRoutesWithoutSunset(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := newTestServer(t, mocks.NewMockStore(ctrl))
+
+	req := httptest.NewRequest(http.MethodGet, "/accounts?page_id=1&page_size=5", nil)
+	recorder := serve(t, server, req)
+	require.NotEmpty(t, recorder.Header().Get(api.DeprecationHeader))
+	require.Empty(t, recorder.Header().Get(api.SunsetHeader))
+}
+
+func TestLegacyRoutesWithoutDeprecation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := newConfiguredTestServer(t, mocks.NewMockStore(ctrl), func(cfg *config.Config) {
+		cfg.LegacyRoutesDeprecatedAt = ""
+	})
+
+	// the unversioned routes still point at their successor
+	req := httptest.NewRequest(http.MethodGet, "/accounts?page_id=1&page_size=5", nil)
+	recorder := serve(t, server, req)
+	require.Empty(t, recorder.Header().Get(api.DeprecationHeader))
+	require.Equal(t, `</v1/accounts>; rel="successor-version"`, recorder.Header().Get(api.LinkHeader))
+}
+
+func TestLegacyRoutesDeprecatedAtInvalid(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	_, err := api.NewServer(config.Config{
+		TokenSymmetricKey:        "12345678901234567890123456789012",
+		AccessTokenDuration:      time.Minute,
+		MailSender:               "memory",
+		MFAEncryptionKey:         testMFAEncryptionKey,
+		WebhookEncryptionKey:     testWebhookEncryptionKey,
+		LegacyRoutesDeprecatedAt: "19/10/2026",
+	}, mocks.NewMockStore(ctrl))
+	require.ErrorContains(t, err, "deprecation date")
+}
+
+func TestLegacyRoutesSunsetInvalid(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	_, err := api.NewServer(config.Config{
+		TokenSymmetricKey:    "12345678901234567890123456789012",
+		AccessTokenDuration:  time.Minute,
+		MailSender:           "memory",
+		MFAEncryptionKey:     testMFAEncryptionKey,
+		WebhookEncryptionKey: testWebhookEncryptionKey,
+		LegacyRoutesSunset:   "30/04/2027",
+	}, mocks.NewMockStore(ctrl))
+	require.ErrorContains(t, err, "sunset")
+}
+
+func TestV2Routes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockStore(ctrl)
+	server := newTestServer(t, store, changeV2Route("GET /account/:id", func(server *api.Server, ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{"version": 2, "id": ctx.Param("id")})
+	}))
+	account := CreateRandomAccount(t)
+
+	// the changed route gets the v2 handler
+	req := httptest.NewRequest(http.MethodGet, "/v2/account/7", nil)
+	recorder := serveAs(t, server, req, account.Owner)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.JSONEq(t, `{"version": 2, "id": "7"}`, recorder.Body.String())
+
+	// behind the middlewares of the v1 route
+	req = httptest.NewRequest(http.MethodGet, "/v2/account/7", nil)
+	requireProblem(t, serve(t, server, req), http.StatusUnauthorized, "unauthorized")
+
+	// v1 and the unversioned route keep the v1 handler
+	store.EXPECT().GetAccount(gomock.Any(), account.ID).Return(*account, nil).Times(2)
+	for _, path := range []string{"/v1/account/", "/account/"} {
+		req = httptest.NewRequest(http.MethodGet, path+strconv.FormatInt(account.ID, 10), nil)
+		recorder = serveAs(t, server, req, account.Owner)
+		require.Equal(t, http.StatusOK, recorder.Code)
+		require.Contains(t, recorder.Body.String(), `"owner":"`+account.Owner+`"`)
+	}
+
+	// the routes v2 didn't change are served as in v1
+	store.EXPECT().ListAccounts(gomock.Any(), gomock.Any()).Return([]db.Account{*account}, nil).Times(1)
+	req = httptest.NewRequest(http.MethodGet, "/v2/accounts?page_id=1&page_size=5", nil)
+	recorder = serveAs(t, server, req, account.Owner)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Empty(t, recorder.Header().Get(api.DeprecationHeader))
+
+	// and documented under the prefix of both versions
+	document := getOpenAPIDocument(t, server)
+	require.Contains(t, document.Paths, "/v2/accounts")
+	require.Contains(t, document.Paths, "/v1/accounts")
+}
+
+func TestV2RoutesUnknown(t *testing.T) {
+	testCases := []struct {
+		name  string
+		route string
+	}{
+		{name: "Unknown Path", route: "GET /account/:id/statement"},
+		{name: "Unknown Method", route: "PUT /account/:id"},
+		{name: "Versioned Path", route: "GET /v1/account/:id"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			// a route v1 lacks keeps the server from starting
+			_, err := api.NewServer(config.Config{
+				TokenSymmetricKey:    "12345678901234567890123456789012",
+				AccessTokenDuration:  time.Minute,
+				MailSender:           "memory",
+				MFAEncryptionKey:     testMFAEncryptionKey,
+				WebhookEncryptionKey: testWebhookEncryptionKey,
+			}, mocks.NewMockStore(ctrl), changeV2Route(tc.route, func(server *api.Server, ctx *gin.Context) {}))
+			require.ErrorContains(t, err, fmt.Sprintf("route %q changed by /v2 is not a v1 route", tc.route))
+		})
+	}
+}
@@ -0,0 +1,338 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/primarybank/api"
+	commonutils "github.com/primarybank/common/utils"
+	"github.com/primarybank/config"
+	"github.com/primarybank/db/mocks"
+	db "github.com/primarybank/db/sqlc"
+	"github.com/primarybank/mfa"
+	"github.com/stretchr/testify/require"
+)
+
+func newRandomWebhook(username string) db.WebhookSubscription {
+	return db.WebhookSubscription{
+		ID:               commonutils.RandomInt(1, 1000),
+		Username:         username,
+		Url:              "https://example.com/hooks",
+		EventTypes:       []string{db.EventTransferCompleted},
+		SecretCiphertext: []byte("ciphertext"),
+		CreatedAt:        time.Now(),
+	}
+}
+
+func TestCreateWebhook(t *testing.T) {
+	username := commonutils.RandomString(8)
+	cipher, err := mfa.NewCipher(testWebhookEncryptionKey)
+	require.NoError(t, err)
+
+	var ciphertext []byte
+	testCases := []struct {
+		name       string
+		body       gin.H
+		buildStubs func(store *mocks.MockStore)
+		checkResp  func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"url": "https://example.com/hooks", "event_types": []string{db.EventTransferCompleted, db.EventAccountCreated}},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().
+					CreateWebhookSubscription(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ any, args db.CreateWebhookSubscriptionParams) (db.WebhookSubscription, error) {
+						require.Equal(t, username, args.Username)
+						require.Equal(t, "https://example.com/hooks", args.Url)
+						require.Equal(t, []string{db.EventTransferCompleted, db.EventAccountCreated}, args.EventTypes)
+						ciphertext = args.SecretCiphertext
+						return db.WebhookSubscription{ID: 1, Username: args.Username, Url: args.Url, EventTypes: args.EventTypes, SecretCiphertext: args.SecretCiphertext}, nil
+					}).
+					Times(1)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+				require.NotContains(t, recorder.Body.String(), "secret_ciphertext")
+
+				var resp api.CreateWebhookResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+				require.True(t, strings.HasPrefix(resp.Secret, "whsec_"))
+
+				// only the encrypted secret is stored
+				secret, err := cipher.Decrypt(ciphertext)
+				require.NoError(t, err)
+				require.Equal(t, resp.Secret, string(secret))
+			},
+		},
+		{
+			name: "Unknown Event Type",
+			body: gin.H{"url": "https://example.com/hooks", "event_types": []string{"account.robbed"}},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().CreateWebhookSubscription(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				problem := requireProblem(t, recorder, http.StatusBadRequest, "invalid_request")
+				require.Equal(t, []api.InvalidParam{{Name: "event_types", Reason: `unknown event type "account.robbed"`}}, problem.InvalidParams)
+			},
+		},
+		{
+			name: "Relative URL",
+			body: gin.H{"url": "/hooks", "event_types": []string{db.EventTransferCompleted}},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().CreateWebhookSubscription(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				problem := requireProblem(t, recorder, http.StatusBadRequest, "invalid_request")
+				require.Equal(t, "url", problem.InvalidParams[0].Name)
+			},
+		},
+		{
+			name: "No Event Types",
+			body: gin.H{"url": "https://example.com/hooks", "event_types": []string{}},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().CreateWebhookSubscription(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, http.StatusBadRequest, "invalid_request")
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mocks.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+			tc.buildStubs(store)
+
+			req := newJSONRequest(t, http.MethodPost, "/v1/user/webhook", tc.body)
+			tc.checkResp(t, serveAs(t, server, req, username))
+		})
+	}
+}
+
+func TestListWebhooks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+
+	username := commonutils.RandomString(8)
+	webhook := newRandomWebhook(username)
+	store.EXPECT().ListWebhookSubscriptions(gomock.Any(), username).Return([]db.WebhookSubscription{webhook}, nil).Times(1)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/user/webhooks", nil)
+	recorder := serveAs(t, server, req, username)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.NotContains(t, recorder.Body.String(), "secret")
+
+	var resp []api.WebhookResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+	require.Len(t, resp, 1)
+	require.Equal(t, webhook.ID, resp[0].ID)
+	require.Equal(t, webhook.Url, resp[0].URL)
+}
+
+func TestDeleteWebhook(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+
+	username := commonutils.RandomString(8)
+	webhook := newRandomWebhook(username)
+	testCases := []struct {
+		name         string
+		err          error
+		expectedCode int
+	}{
+		{
+			name:         "Valid Request",
+			expectedCode: http.StatusNoContent,
+		},
+		{
+			name:         "Not Found",
+			err:          db.ErrNotFound,
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store.EXPECT().
+				DeleteWebhookSubscription(gomock.Any(), db.DeleteWebhookSubscriptionParams{ID: webhook.ID, Username: username}).
+				Return(webhook, tc.err).
+				Times(1)
+
+			req := httptest.NewRequest(http.MethodDelete, "/v1/user/webhook/"+strconv.FormatInt(webhook.ID, 10), nil)
+			recorder := serveAs(t, server, req, username)
+			require.Equal(t, tc.expectedCode, recorder.Code)
+		})
+	}
+}
+
+func TestListWebhookDeliveries(t *testing.T) {
+	username := commonutils.RandomString(8)
+	webhook := newRandomWebhook(username)
+
+	testCases := []struct {
+		name         string
+		owner        string
+		buildStubs   func(store *mocks.MockStore)
+		expectedCode int
+	}{
+		{
+			name:  "Valid Request",
+			owner: username,
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().
+					ListWebhookDeliveries(gomock.Any(), db.ListWebhookDeliveriesParams{SubscriptionID: webhook.ID, Limit: 5, Offset: 5}).
+					Return([]db.WebhookDelivery{}, nil).
+					Times(1)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:  "Webhook Of Another User",
+			owner: "other",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().ListWebhookDeliveries(gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mocks.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+
+			owned := webhook
+			owned.Username = tc.owner
+			store.EXPECT().GetWebhookSubscription(gomock.Any(), webhook.ID).Return(owned, nil).Times(1)
+			tc.buildStubs(store)
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/user/webhook/"+strconv.FormatInt(webhook.ID, 10)+"/deliveries?page_id=2&page_size=5", nil)
+			recorder := serveAs(t, server, req, username)
+			require.Equal(t, tc.expectedCode, recorder.Code)
+		})
+	}
+}
+
+func TestGetWebhookDelivery(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+
+	username := commonutils.RandomString(8)
+	webhook := newRandomWebhook(username)
+	delivery := db.WebhookDelivery{ID: 7, SubscriptionID: webhook.ID, EventType: db.EventTransferCompleted, Status: db.WebhookDeliveryPending, Attempts: 1}
+	attempt := db.WebhookDeliveryAttempt{ID: 1, DeliveryID: delivery.ID, ResponseStatus: pgtype.Int4{Int32: 500, Valid: true}}
+
+	store.EXPECT().GetWebhookDelivery(gomock.Any(), delivery.ID).Return(delivery, nil).Times(1)
+	store.EXPECT().GetWebhookSubscription(gomock.Any(), webhook.ID).Return(webhook, nil).Times(1)
+	store.EXPECT().ListWebhookDeliveryAttempts(gomock.Any(), delivery.ID).Return([]db.WebhookDeliveryAttempt{attempt}, nil).Times(1)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/user/webhook_delivery/7", nil)
+	recorder := serveAs(t, server, req, username)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var resp api.WebhookDeliveryResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+	require.Equal(t, delivery.ID, resp.ID)
+	require.Len(t, resp.Attempts, 1)
+	require.Equal(t, int32(500), resp.Attempts[0].ResponseStatus.Int32)
+}
+
+func TestRedeliverWebhook(t *testing.T) {
+	username := commonutils.RandomString(8)
+	webhook := newRandomWebhook(username)
+	delivery := db.WebhookDelivery{ID: 7, SubscriptionID: webhook.ID, EventType: db.EventTransferCompleted, Status: db.WebhookDeliveryDead, Attempts: 10}
+
+	testCases := []struct {
+		name       string
+		owner      string
+		buildStubs func(store *mocks.MockStore)
+		checkResp  func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			owner: username,
+			buildStubs: func(store *mocks.MockStore) {
+				redelivered := delivery
+				redelivered.Status = db.WebhookDeliveryPending
+				redelivered.Attempts = 0
+				store.EXPECT().RedeliverWebhookDelivery(gomock.Any(), delivery.ID).Return(redelivered, nil).Times(1)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+
+				var resp db.WebhookDelivery
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+				require.Equal(t, db.WebhookDeliveryPending, resp.Status)
+				require.Zero(t, resp.Attempts)
+			},
+		},
+		{
+			name:  "Delivery Of Another User",
+			owner: "other",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().RedeliverWebhookDelivery(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mocks.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+
+			owned := webhook
+			owned.Username = tc.owner
+			store.EXPECT().GetWebhookDelivery(gomock.Any(), delivery.ID).Return(delivery, nil).Times(1)
+			store.EXPECT().GetWebhookSubscription(gomock.Any(), webhook.ID).Return(owned, nil).Times(1)
+			tc.buildStubs(store)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/user/webhook_delivery/7/redeliver", nil)
+			tc.checkResp(t, serveAs(t, server, req, username))
+		})
+	}
+}
+
+func TestWebhookEncryptionKeyReused(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	_, err := api.NewServer(config.Config{
+		TokenSymmetricKey:    "12345678901234567890123456789012",
+		AccessTokenDuration:  time.Minute,
+		MailSender:           "memory",
+		MFAEncryptionKey:     testMFAEncryptionKey,
+		WebhookEncryptionKey: strings.ToUpper(testMFAEncryptionKey),
+	}, mocks.NewMockStore(ctrl))
+	require.ErrorContains(t, err, "webhook encryption key")
+}
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/primarybank/db/sqlc"
+)
+
+// GetTransactionAllowances returns the remaining allowance of every limit applying to an account,
+// so clients can show it before a transfer is submitted
+func (s *Server) GetTransactionAllowances(ctx *gin.Context) {
+	var uri AccountURIRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	allowances, err := s.store.GetTransactionAllowances(ctx.Request.Context(), uri.ID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, allowances)
+}
+
+// CreateTransactionLimit adds a role default or an account/user override
+func (s *Server) CreateTransactionLimit(ctx *gin.Context) {
+	var req CreateTransactionLimitRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	args := db.CreateTransactionLimitParams{
+		Scope:     req.Scope,
+		Period:    req.Period,
+		Role:      pgtype.Text{String: req.Role, Valid: req.Role != ""},
+		Username:  pgtype.Text{String: req.Username, Valid: req.Username != ""},
+		AccountID: pgtype.Int8{Int64: req.AccountID, Valid: req.AccountID != 0},
+		MaxAmount: req.MaxAmount,
+		MaxCount:  req.MaxCount,
+	}
+
+	if err := validateLimitSubject(args); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	limit, err := s.store.CreateTransactionLimit(ctx.Request.Context(), args)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	audit(ctx, "transaction_limit", limit.ID, nil, limit)
+
+	ctx.JSON(http.StatusCreated, limit)
+}
+
+// validateLimitSubject checks a limit targets exactly one of a role, a user or an account matching its scope
+func validateLimitSubject(args db.CreateTransactionLimitParams) error {
+	subjects := 0
+	for _, valid := range []bool{args.Role.Valid, args.Username.Valid, args.AccountID.Valid} {
+		if valid {
+			subjects++
+		}
+	}
+
+	switch {
+	case subjects != 1:
+		return errors.New("exactly one of role, username or account_id must be set")
+	case args.AccountID.Valid && args.Scope != db.LimitScopeAccount:
+		return errors.New("account overrides must use the account scope")
+	case args.Username.Valid && args.Scope != db.LimitScopeUser:
+		return errors.New("user overrides must use the user scope")
+	}
+
+	return nil
+}
+
+func (s *Server) UpdateTransactionLimit(ctx *gin.Context) {
+	var uri TransactionLimitURIRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	var req UpdateTransactionLimitRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	before, err := s.store.GetTransactionLimit(ctx.Request.Context(), uri.ID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	limit, err := s.store.UpdateTransactionLimit(ctx.Request.Context(), db.UpdateTransactionLimitParams{
+		ID:        uri.ID,
+		MaxAmount: req.MaxAmount,
+		MaxCount:  req.MaxCount,
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	audit(ctx, "transaction_limit", uri.ID, before, limit)
+
+	ctx.JSON(http.StatusOK, limit)
+}
+
+func (s *Server) DeleteTransactionLimit(ctx *gin.Context) {
+	var uri TransactionLimitURIRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	before, err := s.store.GetTransactionLimit(ctx.Request.Context(), uri.ID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	err = s.store.DeleteTransactionLimit(ctx.Request.Context(), uri.ID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	audit(ctx, "transaction_limit", uri.ID, before, nil)
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "transaction limit deleted"})
+}
+
+func (s *Server) ListTransactionLimits(ctx *gin.Context) {
+	var req ListTransactionLimitsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	limits, err := s.store.ListTransactionLimits(ctx.Request.Context(), db.ListTransactionLimitsParams{
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, limits)
+}
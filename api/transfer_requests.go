@@ -0,0 +1,134 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/primarybank/db/sqlc"
+	"github.com/primarybank/token"
+)
+
+// requestTransferApproval stores a transfer above the approval threshold until an approver reviews it
+func (s *Server) requestTransferApproval(ctx *gin.Context, req CreateTransferRequest) {
+	payload := ctx.MustGet(AuthzPayloadKey).(*token.Payload)
+
+	request, err := s.store.CreateTransferRequest(ctx.Request.Context(), db.CreateTransferRequestParams{
+		FromAccountID: req.FromAccountID,
+		ToAccountID:   req.ToAccountID,
+		Amount:        req.Amount,
+		InitiatedBy:   payload.Username,
+		ExpiresAt:     time.Now().Add(s.Config.TransferApprovalTTL),
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	audit(ctx, "transfer_request", request.ID, nil, request)
+
+	ctx.JSON(http.StatusAccepted, request)
+}
+
+func (s *Server) GetTransferRequest(ctx *gin.Context) {
+	var req TransferRequestURIRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	request, err := s.store.GetTransferRequest(ctx.Request.Context(), req.ID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, request)
+}
+
+func (s *Server) ListTransferRequests(ctx *gin.Context) {
+	var req ListTransferRequestsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	requests, err := s.store.ListTransferRequests(ctx.Request.Context(), db.ListTransferRequestsParams{
+		Status: req.Status,
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, requests)
+}
+
+// ApproveTransferRequest executes a pending transfer request on behalf of its initiator
+func (s *Server) ApproveTransferRequest(ctx *gin.Context) {
+	var uri TransferRequestURIRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	payload := ctx.MustGet(AuthzPayloadKey).(*token.Payload)
+
+	before, err := s.store.GetTransferRequest(ctx.Request.Context(), uri.ID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	result, err := s.store.ApproveTransferRequestTx(ctx.Request.Context(), db.ReviewTransferRequestTxParams{
+		ID:         uri.ID,
+		ReviewedBy: payload.Username,
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	audit(ctx, "transfer_request", uri.ID, before, result.Request)
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// RejectTransferRequest declines a pending transfer request, the reason is kept on the request
+func (s *Server) RejectTransferRequest(ctx *gin.Context) {
+	var uri TransferRequestURIRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	var req RejectTransferRequestRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	payload := ctx.MustGet(AuthzPayloadKey).(*token.Payload)
+
+	before, err := s.store.GetTransferRequest(ctx.Request.Context(), uri.ID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	request, err := s.store.RejectTransferRequestTx(ctx.Request.Context(), db.ReviewTransferRequestTxParams{
+		ID:         uri.ID,
+		ReviewedBy: payload.Username,
+		Reason:     req.Reason,
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	audit(ctx, "transfer_request", uri.ID, before, request)
+
+	ctx.JSON(http.StatusOK, request)
+}
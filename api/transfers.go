@@ -1,8 +1,6 @@
 package api
 
 import (
-	"database/sql"
-	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -13,39 +11,154 @@ import (
 func (s *Server) CreateTransfer(ctx *gin.Context) {
 	var req CreateTransferRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errResp(err))
+		ctx.Error(invalidRequest(err))
 		return
 	}
 
-	args := db.CreateTransferParams{
+	if err := s.requireStepUp(ctx, req.Amount); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	if s.Config.TransferApprovalThreshold > 0 && req.Amount > s.Config.TransferApprovalThreshold {
+		s.requestTransferApproval(ctx, req)
+		return
+	}
+
+	args := db.TransferTxParams{
 		FromAccountID: req.FromAccountID,
 		ToAccountID:   req.ToAccountID,
 		Amount:        req.Amount,
 	}
 
-	transfer, err := s.store.CreateTransfer(ctx.Request.Context(), args)
+	result, err := s.store.TransferTx(ctx.Request.Context(), args)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errResp(err))
+		ctx.Error(err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, transfer)
+	audit(ctx, "transfer", result.Transfer.ID, nil, result.Transfer)
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// ReserveTransfer debits the sender and keeps the transfer pending until it is posted or voided.
+// Amounts needing approval can't be reserved as approval executes the transfer at once.
+func (s *Server) ReserveTransfer(ctx *gin.Context) {
+	var req CreateTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	if err := s.requireStepUp(ctx, req.Amount); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	if s.Config.TransferApprovalThreshold > 0 && req.Amount > s.Config.TransferApprovalThreshold {
+		ctx.Error(ErrApprovalRequired)
+		return
+	}
+
+	result, err := s.store.ReserveTransferTx(ctx.Request.Context(), db.TransferTxParams{
+		FromAccountID: req.FromAccountID,
+		ToAccountID:   req.ToAccountID,
+		Amount:        req.Amount,
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	audit(ctx, "transfer", result.Transfer.ID, nil, result.Transfer)
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// PostTransfer completes a pending transfer
+func (s *Server) PostTransfer(ctx *gin.Context) {
+	var req TransferURIRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	before, err := s.store.GetTransfer(ctx.Request.Context(), req.ID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	result, err := s.store.PostTransferTx(ctx.Request.Context(), req.ID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	audit(ctx, "transfer", req.ID, before, result.Transfer)
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// VoidTransfer cancels a pending transfer and refunds the sender
+func (s *Server) VoidTransfer(ctx *gin.Context) {
+	var req TransferURIRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	before, err := s.store.GetTransfer(ctx.Request.Context(), req.ID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	result, err := s.store.VoidTransferTx(ctx.Request.Context(), req.ID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	audit(ctx, "transfer", req.ID, before, result.Transfer)
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// QuoteTransferFee returns the fee a transfer would be charged without executing it
+func (s *Server) QuoteTransferFee(ctx *gin.Context) {
+	var req QuoteTransferFeeRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	args := db.TransferTxParams{
+		FromAccountID: req.FromAccountID,
+		ToAccountID:   req.ToAccountID,
+		Amount:        req.Amount,
+	}
+
+	quote, err := s.store.QuoteTransferFee(ctx.Request.Context(), args)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, quote)
 }
 
 func (s *Server) GetTransfer(ctx *gin.Context) {
 	var req GetTransferRequest
 	if err := ctx.ShouldBindUri(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errResp(err))
+		ctx.Error(invalidRequest(err))
 		return
 	}
 
 	transfer, err := s.store.GetTransfer(ctx.Request.Context(), req.ID)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			ctx.JSON(http.StatusNotFound, errResp(err))
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, errResp(err))
+		ctx.Error(err)
 		return
 	}
 
@@ -55,27 +168,37 @@ func (s *Server) GetTransfer(ctx *gin.Context) {
 func (s *Server) DeleteTransfer(ctx *gin.Context) {
 	var req DeleteTransferRequest
 	if err := ctx.ShouldBindUri(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errResp(err))
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	before, err := s.store.GetTransfer(ctx.Request.Context(), req.ID)
+	if err != nil {
+		ctx.Error(err)
 		return
 	}
 
-	err := s.store.DeleteTransfer(ctx.Request.Context(), req.ID)
+	// the entry debiting the reservation would be left without the transfer voiding it could refund
+	if before.Status == db.TransferPending {
+		ctx.Error(errTransferPending)
+		return
+	}
+
+	err = s.store.DeleteTransfer(ctx.Request.Context(), req.ID)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			ctx.JSON(http.StatusNotFound, errResp(err))
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, errResp(err))
+		ctx.Error(err)
 		return
 	}
 
+	audit(ctx, "transfer", req.ID, before, nil)
+
 	ctx.JSON(http.StatusOK, gin.H{"message": "transfer deleted"})
 }
 
 func (s *Server) ListTransfers(ctx *gin.Context) {
 	var req ListTransfersRequest
 	if err := ctx.ShouldBindQuery(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errResp(err))
+		ctx.Error(invalidRequest(err))
 		return
 	}
 
@@ -86,7 +209,7 @@ func (s *Server) ListTransfers(ctx *gin.Context) {
 
 	transfers, err := s.store.ListTransfers(ctx.Request.Context(), args)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errResp(err))
+		ctx.Error(err)
 		return
 	}
 
@@ -1,25 +1,29 @@
 package api
 
 import (
-	"database/sql"
+	"context"
+	"errors"
+	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	commonutils "github.com/primarybank/common/utils"
+	"github.com/primarybank/config"
 	db "github.com/primarybank/db/sqlc"
+	"github.com/primarybank/token"
 )
 
 func (server *Server) CreateUser(ctx *gin.Context) {
 	var req CreateUserRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		ctx.Error(invalidRequest(err))
 		return
 	}
 
-	hashedPassword, err := commonutils.HashPassword(req.Password)
+	hashedPassword, err := server.hashNewPassword(req.Password)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
@@ -30,55 +34,52 @@ func (server *Server) CreateUser(ctx *gin.Context) {
 		Email:    req.Email,
 	}
 
-	user, err := server.store.CreateUser(ctx, arg)
+	user, err := server.store.CreateUserTx(ctx.Request.Context(), arg)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create user"})
+		ctx.Error(err)
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, user)
+	audit(ctx, "user", user.Username, nil, newUserResponse(user))
+
+	// the user exists either way, a lost email can be sent again
+	server.sendVerificationEmailAfterCommit(ctx, user)
+
+	ctx.JSON(http.StatusCreated, newUserResponse(user))
 }
 
 func (server *Server) GetUser(ctx *gin.Context) {
 	username := ctx.Param("username")
 
-	user, err := server.store.GetUser(ctx, username)
+	user, err := server.store.GetUser(ctx.Request.Context(), username)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get user"})
+		ctx.Error(err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, user)
+	ctx.JSON(http.StatusOK, newUserResponse(user))
 }
 
 func (server *Server) UpdateUser(ctx *gin.Context) {
 	username := ctx.Param("username")
 
-	user, err := server.store.GetUser(ctx, username)
+	user, err := server.store.GetUser(ctx.Request.Context(), username)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get user"})
+		ctx.Error(err)
 		return
 	}
 
 	var req UpdateUserRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		ctx.Error(invalidRequest(err))
 		return
 	}
 
 	var hashedPassword *string
 	if req.Password != "" {
-		hash, err := commonutils.HashPassword(req.Password)
+		hash, err := server.hashNewPassword(req.Password)
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to hash password"})
+			ctx.Error(err)
 			return
 		}
 		hashedPassword = &hash
@@ -96,46 +97,168 @@ func (server *Server) UpdateUser(ctx *gin.Context) {
 		arg.Password = *hashedPassword
 	}
 
-	user, err = server.store.UpdateUser(ctx, arg)
+	before := newUserResponse(user)
+
+	user, err = server.store.UpdateUser(ctx.Request.Context(), arg)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update user"})
+		ctx.Error(err)
+		return
+	}
+
+	audit(ctx, "user", username, before, newUserResponse(user))
+
+	if user.Email != before.Email {
+		server.sendVerificationEmailAfterCommit(ctx, user)
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+// GetUserProfile returns the authenticated user along with the accounts they own
+func (s *Server) GetUserProfile(ctx *gin.Context) {
+	payload := ctx.MustGet(AuthzPayloadKey).(*token.Payload)
+
+	user, err := s.store.GetUser(ctx.Request.Context(), payload.Username)
+	if err != nil {
+		ctx.Error(err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, user)
+	accounts, err := s.store.ListAccountsByOwner(ctx.Request.Context(), user.Username)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, UserProfileResponse{
+		UserResponse: newUserResponse(user),
+		Accounts:     accounts,
+	})
 }
 
 func (s *Server) LoginUser(ctx *gin.Context) {
 	var req LoginRequest
 
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		ctx.Error(invalidRequest(err))
 		return
 	}
 
-	user, err := s.store.GetUser(ctx, req.Username)
+	if fields := scopeFieldErrors(req.Scopes); len(fields) > 0 {
+		ctx.Error(errInvalidFields.WithFields(fields...))
+		return
+	}
+
+	scopes := req.Scopes
+	if len(scopes) == 0 {
+		scopes = token.Scopes
+	}
+
+	if err := s.checkLoginThrottle(ctx, req.Username); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	var rehash bool
+	user, err := s.store.GetUser(ctx.Request.Context(), req.Username)
+	if err == nil {
+		rehash, err = s.hasher.Verify(req.Password, user.Password)
+	} else if errors.Is(err, db.ErrNotFound) {
+		s.verifyUnknownUserPassword(req.Password)
+	}
 	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		if err := s.recordLoginAttempt(ctx, req.Username, db.LoginOutcomeFailure); err != nil {
+			ctx.Error(err)
+			return
+		}
+		ctx.Error(errInvalidCredentials)
 		return
 	}
 
-	if err := commonutils.CheckPassword(req.Password, user.Password); err != nil {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+	if rehash {
+		s.rehashPassword(ctx.Request.Context(), user, req.Password)
+	}
+
+	required := emailVerificationRequired(s.Config.EmailVerificationRequiredFor, config.EmailVerificationRequiredForLogin)
+	if required && !user.EmailVerifiedAt.Valid {
+		ctx.Error(ErrEmailNotVerified)
+		return
+	}
+
+	mfaEnabled, err := s.mfaEnabled(ctx.Request.Context(), user.Username)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	audit(ctx, "user", user.Username, nil, nil)
+
+	if mfaEnabled {
+		expirationTime := s.Config.MFAChallengeDuration
+		challenge, err := s.TokenMaker.CreateToken(user.Username, expirationTime, token.WithPurpose(token.PurposeMFAChallenge), token.WithScopes(scopes...))
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, LoginUserResponse{
+			MFARequired:    true,
+			ChallengeToken: challenge,
+			ExpiresIn:      time.Now().Add(expirationTime).Unix(),
+		})
+		return
+	}
+
+	if err := s.recordLoginAttempt(ctx, user.Username, db.LoginOutcomeSuccess); err != nil {
+		ctx.Error(err)
 		return
 	}
 
 	expirationTime := s.Config.AccessTokenDuration
-	token, err := s.TokenMaker.CreateToken(user.Username, expirationTime)
+	accessToken, err := s.TokenMaker.CreateToken(user.Username, expirationTime, token.WithScopes(scopes...))
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create token"})
+		ctx.Error(err)
+		return
 	}
 
 	ctx.JSON(http.StatusOK, LoginUserResponse{
-		AccessToken: token,
+		AccessToken: accessToken,
+		Scope:       strings.Join(scopes, " "),
 		ExpiresIn:   time.Now().Add(expirationTime).Unix(),
 	})
 }
+
+// hashNewPassword checks a password chosen by a user against the password policy before hashing it
+func (s *Server) hashNewPassword(password string) (string, error) {
+	if err := s.policy.Validate(password); err != nil {
+		return "", err
+	}
+
+	return s.hasher.Hash(password)
+}
+
+// verifyUnknownUserPassword checks the password of a username that doesn't exist against a hash of no one's password,
+// so that it takes as long to refuse as a wrong password and the response time doesn't tell which usernames exist
+func (s *Server) verifyUnknownUserPassword(password string) {
+	hashedPassword, err := s.dummyPasswordHash()
+	if err != nil {
+		log.Printf("cannot hash the password of unknown users: %v", err)
+		return
+	}
+	s.hasher.Verify(password, hashedPassword)
+}
+
+// rehashPassword upgrades the hash of a password that was just verified to the current hasher.
+// The old hash keeps working, so failures are only logged.
+func (s *Server) rehashPassword(ctx context.Context, user db.User, password string) {
+	hashedPassword, err := s.hasher.Hash(password)
+	if err == nil {
+		_, err = s.store.UpdateUserPassword(ctx, db.UpdateUserPasswordParams{
+			Username: user.Username,
+			Password: hashedPassword,
+		})
+	}
+	if err != nil {
+		log.Printf("cannot rehash password of %s: %v", user.Username, err)
+	}
+}
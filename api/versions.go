@@ -0,0 +1,169 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// prefixes of the versions of the API
+const (
+	V1Prefix = "/v1"
+	V2Prefix = "/v2"
+)
+
+// headers sent on the unversioned routes, see RFC 9745 and RFC 8594
+const (
+	DeprecationHeader = "Deprecation"
+	SunsetHeader      = "Sunset"
+	LinkHeader        = "Link"
+)
+
+// apiVersionKey holds the prefix of the version serving the request, empty on the unversioned routes
+const apiVersionKey = "api_version"
+
+// apiVersion is a version of the API, mounted under prefix
+type apiVersion struct {
+	prefix string
+	// changed replaces the v1 handlers of the routes the version changed
+	changed map[string]func(*Server, *gin.Context)
+}
+
+// versionRoutes registers the routes of a version, a route the version changed gets the handler of the
+// version in place of the last handler given, the one of v1
+type versionRoutes struct {
+	*gin.RouterGroup
+	server  *Server
+	version apiVersion
+}
+
+// routes registers the routes of group for version
+func (server *Server) routes(group *gin.RouterGroup, version apiVersion) versionRoutes {
+	return versionRoutes{RouterGroup: group, server: server, version: version}
+}
+
+func (r versionRoutes) handle(method, relativePath string, handlers []gin.HandlerFunc) gin.IRoutes {
+	route := method + " " + strings.TrimPrefix(path.Join(r.BasePath(), relativePath), r.version.prefix)
+	if handler, ok := r.version.changed[route]; ok {
+		handlers = append(slices.Clone(handlers[:len(handlers)-1]), func(ctx *gin.Context) {
+			handler(r.server, ctx)
+		})
+	}
+	return r.RouterGroup.Handle(method, relativePath, handlers...)
+}
+
+func (r versionRoutes) GET(relativePath string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	return r.handle(http.MethodGet, relativePath, handlers)
+}
+
+func (r versionRoutes) POST(relativePath string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	return r.handle(http.MethodPost, relativePath, handlers)
+}
+
+func (r versionRoutes) PUT(relativePath string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	return r.handle(http.MethodPut, relativePath, handlers)
+}
+
+func (r versionRoutes) PATCH(relativePath string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	return r.handle(http.MethodPatch, relativePath, handlers)
+}
+
+func (r versionRoutes) DELETE(relativePath string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	return r.handle(http.MethodDelete, relativePath, handlers)
+}
+
+// v2Handlers are the handlers of the routes /v2 changed, see Server.V2Handlers
+func v2Handlers() map[string]func(*Server, *gin.Context) {
+	return map[string]func(*Server, *gin.Context){}
+}
+
+// WithV2Handlers replaces the handlers of the routes /v2 changed. /v2 stays unmounted while no route is changed,
+// clients only see it once it differs from v1. Every route changed must be a v1 route written without the version,
+// like "GET /account/:id", NewServer fails on any other.
+func WithV2Handlers(handlers map[string]func(*Server, *gin.Context)) ServerOption {
+	return func(server *Server) {
+		server.V2Handlers = handlers
+	}
+}
+
+// apiVersions are the versions mounted, /v2 only once it changed a route
+func (server *Server) apiVersions() []apiVersion {
+	versions := []apiVersion{{prefix: V1Prefix}}
+	if len(server.V2Handlers) > 0 {
+		versions = append(versions, apiVersion{prefix: V2Prefix, changed: server.V2Handlers})
+	}
+	return versions
+}
+
+// checkChangedRoutes fails when a version changes a route v1 doesn't have
+func checkChangedRoutes(router *gin.Engine, versions []apiVersion) error {
+	v1Routes := make(map[string]bool)
+	for _, route := range router.Routes() {
+		if strings.HasPrefix(route.Path, V1Prefix+"/") {
+			v1Routes[route.Method+" "+strings.TrimPrefix(route.Path, V1Prefix)] = true
+		}
+	}
+
+	for _, version := range versions {
+		for route := range version.changed {
+			if !v1Routes[route] {
+				return fmt.Errorf("route %q changed by %s is not a v1 route", route, version.prefix)
+			}
+		}
+	}
+	return nil
+}
+
+// parseLegacyRoutesDate reads LEGACY_ROUTES_DEPRECATED_AT and LEGACY_ROUTES_SUNSET, the zero time when empty
+func parseLegacyRoutesDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
+
+// VersionMiddleware records the version serving the routes of its group
+func VersionMiddleware(prefix string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Set(apiVersionKey, prefix)
+		ctx.Next()
+	}
+}
+
+// DeprecationMiddleware marks the unversioned routes deprecated, pointing at their /v1 path.
+// The Deprecation and Sunset headers are left out when their time is zero.
+func DeprecationMiddleware(deprecatedAt time.Time, sunset time.Time) gin.HandlerFunc {
+	deprecation := "@" + strconv.FormatInt(deprecatedAt.Unix(), 10)
+	return func(ctx *gin.Context) {
+		if !deprecatedAt.IsZero() {
+			ctx.Header(DeprecationHeader, deprecation)
+		}
+		if !sunset.IsZero() {
+			ctx.Header(SunsetHeader, sunset.UTC().Format(http.TimeFormat))
+		}
+		ctx.Header(LinkHeader, fmt.Sprintf(`<%s%s>; rel="successor-version"`, V1Prefix, ctx.Request.URL.Path))
+		ctx.Next()
+	}
+}
+
+// routePath is the path of the route serving the request without its version, as in "/account/:id"
+func routePath(ctx *gin.Context) string {
+	return strings.TrimPrefix(ctx.FullPath(), ctx.GetString(apiVersionKey))
+}
+
+// unversionedPath is a route path without the prefix of its version, for the middlewares running before
+// VersionMiddleware
+func unversionedPath(fullPath string) string {
+	for _, prefix := range []string{V1Prefix, V2Prefix} {
+		if rest, ok := strings.CutPrefix(fullPath, prefix+"/"); ok {
+			return "/" + rest
+		}
+	}
+	return fullPath
+}
@@ -0,0 +1,242 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	commonerrors "github.com/primarybank/common/errors"
+	commonutils "github.com/primarybank/common/utils"
+	db "github.com/primarybank/db/sqlc"
+	"github.com/primarybank/token"
+)
+
+// webhook secrets look like whsec_<secret>
+const (
+	webhookSecretMarker = "whsec_"
+	webhookSecretBytes  = 32
+)
+
+// CreateWebhook subscribes a url of the authenticated user to domain events.
+// The signing secret is only ever shown in this response.
+func (s *Server) CreateWebhook(ctx *gin.Context) {
+	payload := ctx.MustGet(AuthzPayloadKey).(*token.Payload)
+
+	var req CreateWebhookRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	if err := validateWebhookRequest(req); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	secret, err := commonutils.RandomSecret(webhookSecretBytes)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	secret = webhookSecretMarker + secret
+
+	ciphertext, err := s.webhookCipher.Encrypt([]byte(secret))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	subscription, err := s.store.CreateWebhookSubscription(ctx.Request.Context(), db.CreateWebhookSubscriptionParams{
+		Username:         payload.Username,
+		Url:              req.URL,
+		EventTypes:       req.EventTypes,
+		SecretCiphertext: ciphertext,
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	audit(ctx, "webhook", subscription.ID, nil, newWebhookResponse(subscription))
+	ctx.JSON(http.StatusCreated, CreateWebhookResponse{
+		WebhookResponse: newWebhookResponse(subscription),
+		Secret:          secret,
+	})
+}
+
+// ListWebhooks returns the webhook subscriptions of the authenticated user
+func (s *Server) ListWebhooks(ctx *gin.Context) {
+	payload := ctx.MustGet(AuthzPayloadKey).(*token.Payload)
+
+	subscriptions, err := s.store.ListWebhookSubscriptions(ctx.Request.Context(), payload.Username)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	resp := make([]WebhookResponse, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		resp = append(resp, newWebhookResponse(subscription))
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// DeleteWebhook unsubscribes a webhook of the authenticated user, its deliveries go with it
+func (s *Server) DeleteWebhook(ctx *gin.Context) {
+	var uri WebhookURIRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	payload := ctx.MustGet(AuthzPayloadKey).(*token.Payload)
+
+	subscription, err := s.store.DeleteWebhookSubscription(ctx.Request.Context(), db.DeleteWebhookSubscriptionParams{
+		ID:       uri.ID,
+		Username: payload.Username,
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	audit(ctx, "webhook", uri.ID, newWebhookResponse(subscription), nil)
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+// ListWebhookDeliveries returns the deliveries of a webhook of the authenticated user, newest first
+func (s *Server) ListWebhookDeliveries(ctx *gin.Context) {
+	var uri WebhookURIRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	var req ListWebhookDeliveriesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	if _, err := s.ownedWebhook(ctx, uri.ID); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	deliveries, err := s.store.ListWebhookDeliveries(ctx.Request.Context(), db.ListWebhookDeliveriesParams{
+		SubscriptionID: uri.ID,
+		Limit:          req.PageSize,
+		Offset:         (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, deliveries)
+}
+
+// GetWebhookDelivery returns a delivery of a webhook of the authenticated user along with the log of its attempts
+func (s *Server) GetWebhookDelivery(ctx *gin.Context) {
+	var uri WebhookURIRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	delivery, err := s.ownedWebhookDelivery(ctx, uri.ID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	attempts, err := s.store.ListWebhookDeliveryAttempts(ctx.Request.Context(), delivery.ID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, WebhookDeliveryResponse{
+		WebhookDelivery: delivery,
+		Attempts:        attempts,
+	})
+}
+
+// RedeliverWebhook sends a delivery again as soon as possible, with a fresh set of attempts
+func (s *Server) RedeliverWebhook(ctx *gin.Context) {
+	var uri WebhookURIRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	before, err := s.ownedWebhookDelivery(ctx, uri.ID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	delivery, err := s.store.RedeliverWebhookDelivery(ctx.Request.Context(), uri.ID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	audit(ctx, "webhook_delivery", uri.ID, before, delivery)
+	ctx.JSON(http.StatusAccepted, delivery)
+}
+
+// ownedWebhook hides the webhooks of other users as if they didn't exist
+func (s *Server) ownedWebhook(ctx *gin.Context, id int64) (db.WebhookSubscription, error) {
+	payload := ctx.MustGet(AuthzPayloadKey).(*token.Payload)
+
+	subscription, err := s.store.GetWebhookSubscription(ctx.Request.Context(), id)
+	if err != nil {
+		return subscription, err
+	}
+	if subscription.Username != payload.Username {
+		return db.WebhookSubscription{}, db.ErrNotFound
+	}
+
+	return subscription, nil
+}
+
+func (s *Server) ownedWebhookDelivery(ctx *gin.Context, id int64) (db.WebhookDelivery, error) {
+	delivery, err := s.store.GetWebhookDelivery(ctx.Request.Context(), id)
+	if err != nil {
+		return delivery, err
+	}
+
+	if _, err := s.ownedWebhook(ctx, delivery.SubscriptionID); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return db.WebhookDelivery{}, db.ErrNotFound
+		}
+		return db.WebhookDelivery{}, err
+	}
+
+	return delivery, nil
+}
+
+func validateWebhookRequest(req CreateWebhookRequest) error {
+	var fields []commonerrors.FieldError
+
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		fields = append(fields, commonerrors.FieldError{Field: "url", Reason: "must be an absolute http or https url"})
+	}
+
+	for _, eventType := range req.EventTypes {
+		if !slices.Contains(db.EventTypes, eventType) {
+			fields = append(fields, commonerrors.FieldError{Field: "event_types", Reason: fmt.Sprintf("unknown event type %q", eventType)})
+		}
+	}
+
+	if len(fields) > 0 {
+		return errInvalidFields.WithFields(fields...)
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+// Command tokenkey generates an Ed25519 key for signing access tokens
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/primarybank/token"
+)
+
+func main() {
+	signingKey, publicKey, err := token.GenerateSigningKey()
+	if err != nil {
+		log.Fatal("cannot generate signing key: ", err)
+	}
+
+	fmt.Printf("TOKEN_SIGNING_KEY=%s\n", signingKey)
+	fmt.Printf("# public key, add to TOKEN_VERIFICATION_KEYS ahead of a rotation and after it\n")
+	fmt.Printf("# %s\n", publicKey)
+}
@@ -0,0 +1,86 @@
+// Command verifyledger walks the hash chain of account entries and reports the first broken link of each account.
+// It exits with status 1 when an account's entries were altered out of band.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/primarybank/config"
+	db "github.com/primarybank/db/sqlc"
+)
+
+// accounts are listed in pages of this size when every account is verified
+const accountPageSize = 100
+
+func main() {
+	accountID := flag.Int64("account", 0, "id of the account to verify, every account when unset")
+	flag.Parse()
+
+	cfg, err := config.Load(".")
+	if err != nil {
+		log.Fatal("cannot load config: ", err)
+	}
+
+	ctx := context.Background()
+	conn, err := pgxpool.New(ctx, cfg.DBSource)
+	if err != nil {
+		log.Fatal("cannot connect to db: ", err)
+	}
+	defer conn.Close()
+
+	store := db.NewStore(conn)
+
+	accountIDs := []int64{*accountID}
+	if *accountID == 0 {
+		accountIDs, err = listAccountIDs(ctx, store)
+		if err != nil {
+			log.Fatal("cannot list accounts: ", err)
+		}
+	}
+
+	broken := 0
+	for _, id := range accountIDs {
+		verification, err := store.VerifyEntryChain(ctx, id)
+		if err != nil {
+			log.Fatalf("cannot verify account %d: %v", id, err)
+		}
+
+		if !verification.Valid {
+			broken++
+			fmt.Printf("account %d: broken at entry %d: %s\n", id, verification.BrokenAt, verification.Reason)
+			continue
+		}
+		fmt.Printf("account %d: ok, %d chained entries, %d from before the chain\n", id, verification.Entries, verification.Unchained)
+	}
+
+	if broken > 0 {
+		fmt.Printf("%d of %d accounts have a broken chain\n", broken, len(accountIDs))
+		os.Exit(1)
+	}
+}
+
+func listAccountIDs(ctx context.Context, store db.Store) ([]int64, error) {
+	var ids []int64
+
+	for offset := int32(0); ; offset += accountPageSize {
+		accounts, err := store.ListAccounts(ctx, db.ListAccountsParams{
+			Limit:  accountPageSize,
+			Offset: offset,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, account := range accounts {
+			ids = append(ids, account.ID)
+		}
+		if len(accounts) < accountPageSize {
+			return ids, nil
+		}
+	}
+}
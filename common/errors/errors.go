@@ -0,0 +1,89 @@
+package commonerrors
+
+import "errors"
+
+// Kind classifies an error independently of the transport it is reported through
+type Kind string
+
+const (
+	KindNotFound          Kind = "not_found"
+	KindConflict          Kind = "conflict"
+	KindInsufficientFunds Kind = "insufficient_funds"
+	KindUnprocessable     Kind = "unprocessable"
+	KindForbidden         Kind = "forbidden"
+	KindUnauthorized      Kind = "unauthorized"
+	KindTooManyRequests   Kind = "too_many_requests"
+	KindValidation        Kind = "validation"
+	KindInternal          Kind = "internal"
+)
+
+// FieldError points at the input field an error is about
+type FieldError struct {
+	Field  string
+	Reason string
+}
+
+// Error is a typed domain error.
+// Its message is safe to show to clients, the wrapped cause is kept for logging only.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Fields  []FieldError
+	Err     error
+}
+
+// New creates an error of the given kind with a machine readable code
+func New(kind Kind, code string, message string) *Error {
+	return &Error{
+		Kind:    kind,
+		Code:    code,
+		Message: message,
+	}
+}
+
+// Wrap classifies err, keeping it as the cause
+func Wrap(err error, kind Kind, code string, message string) *Error {
+	return &Error{
+		Kind:    kind,
+		Code:    code,
+		Message: message,
+		Err:     err,
+	}
+}
+
+// WithFields returns a copy of the error blaming the given fields
+func (e *Error) WithFields(fields ...FieldError) *Error {
+	copied := *e
+	copied.Fields = fields
+	return &copied
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports errors of the same kind and code as equal, so errors.Is can be used against sentinels
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Kind == e.Kind && t.Code == e.Code
+}
+
+// As returns the first typed error in the chain of err
+func As(err error) (*Error, bool) {
+	var e *Error
+	ok := errors.As(err, &e)
+	return e, ok
+}
+
+// KindOf returns the kind of err, errors that were never classified are internal
+func KindOf(err error) Kind {
+	if e, ok := As(err); ok {
+		return e.Kind
+	}
+	return KindInternal
+}
@@ -0,0 +1,69 @@
+package commonerrors
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKindOf(t *testing.T) {
+	cause := errors.New("ERROR: relation \"accounts\" does not exist (SQLSTATE 42P01)")
+
+	testCases := []struct {
+		name     string
+		err      error
+		expected Kind
+	}{
+		{
+			name:     "Typed",
+			err:      New(KindNotFound, "not_found", "resource not found"),
+			expected: KindNotFound,
+		},
+		{
+			name:     "Wrapped Cause",
+			err:      Wrap(cause, KindConflict, "already_exists", "resource already exists"),
+			expected: KindConflict,
+		},
+		{
+			name:     "Wrapped By Caller",
+			err:      fmt.Errorf("cannot transfer: %w", New(KindInsufficientFunds, "overdraft_limit_exceeded", "overdraft limit exceeded")),
+			expected: KindInsufficientFunds,
+		},
+		{
+			name:     "Unclassified",
+			err:      cause,
+			expected: KindInternal,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, KindOf(tc.err))
+		})
+	}
+}
+
+func TestErrorIs(t *testing.T) {
+	sentinel := New(KindNotFound, "not_found", "resource not found")
+	cause := errors.New("no rows in result set")
+	err := Wrap(cause, KindNotFound, "not_found", "account not found")
+
+	require.ErrorIs(t, err, sentinel)
+	require.ErrorIs(t, err, cause)
+	require.NotErrorIs(t, err, New(KindNotFound, "user_not_found", "user not found"))
+
+	// the cause is never part of the message
+	require.Equal(t, "account not found", err.Error())
+}
+
+func TestWithFields(t *testing.T) {
+	sentinel := New(KindConflict, "already_exists", "resource already exists")
+	err := sentinel.WithFields(FieldError{Field: "email", Reason: "already exists"})
+
+	require.Equal(t, []FieldError{{Field: "email", Reason: "already exists"}}, err.Fields)
+	require.ErrorIs(t, err, sentinel)
+	// the sentinel itself is never modified
+	require.Empty(t, sentinel.Fields)
+}
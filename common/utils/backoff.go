@@ -0,0 +1,21 @@
+package commonutils
+
+import "time"
+
+// Backoff returns the wait after the given number of consecutive failures, doubling from base up to max.
+// No failures or a zero base mean no wait, and without a max the wait stays at base.
+func Backoff(base time.Duration, max time.Duration, failures int32) time.Duration {
+	if base <= 0 || failures <= 0 {
+		return 0
+	}
+
+	delay := base
+	for i := int32(1); i < failures && delay < max; i++ {
+		delay *= 2
+	}
+
+	if max > 0 && delay > max {
+		return max
+	}
+	return delay
+}
@@ -0,0 +1,18 @@
+package commonutils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackoff(t *testing.T) {
+	require.Zero(t, Backoff(time.Second, time.Minute, 0))
+	require.Zero(t, Backoff(0, time.Minute, 3))
+	require.Equal(t, time.Second, Backoff(time.Second, time.Minute, 1))
+	require.Equal(t, 8*time.Second, Backoff(time.Second, time.Minute, 4))
+	require.Equal(t, time.Minute, Backoff(time.Second, time.Minute, 7))
+	require.Equal(t, time.Minute, Backoff(time.Second, time.Minute, 1000))
+	require.Equal(t, time.Second, Backoff(time.Second, 0, 3))
+}
@@ -1,18 +1,145 @@
 package commonutils
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
 
-// HashPassword returns the becrypt hash of the password
-func HashPassword(password string) (string, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrPasswordMismatch is returned when a password does not match its hash, whatever scheme made it
+var ErrPasswordMismatch = errors.New("password does not match")
+
+var errUnknownPasswordHash = errors.New("unknown password hash format")
+
+// Argon2Params tunes the cost of Argon2id password hashes, memory is in KiB
+type Argon2Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2Params follow the RFC 9106 recommendation for memory constrained environments
+var DefaultArgon2Params = Argon2Params{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 2,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// PasswordHasher hashes passwords with Argon2id and verifies hashes of every scheme we have used
+type PasswordHasher struct {
+	params Argon2Params
+}
+
+// NewPasswordHasher creates a hasher, zero parameters take their default value
+func NewPasswordHasher(params Argon2Params) *PasswordHasher {
+	if params.Memory == 0 {
+		params.Memory = DefaultArgon2Params.Memory
+	}
+	if params.Iterations == 0 {
+		params.Iterations = DefaultArgon2Params.Iterations
+	}
+	if params.Parallelism == 0 {
+		params.Parallelism = DefaultArgon2Params.Parallelism
+	}
+	if params.SaltLength == 0 {
+		params.SaltLength = DefaultArgon2Params.SaltLength
+	}
+	if params.KeyLength == 0 {
+		params.KeyLength = DefaultArgon2Params.KeyLength
+	}
+
+	return &PasswordHasher{params: params}
+}
+
+var defaultPasswordHasher = NewPasswordHasher(DefaultArgon2Params)
+
+// Hash returns the Argon2id hash of the password in the PHC string format
+func (h *PasswordHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
 		return "", err
 	}
 
-	return string(hashedPassword), err
+	key := argon2.IDKey([]byte(password), salt, h.params.Iterations, h.params.Memory, h.params.Parallelism, h.params.KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.params.Memory, h.params.Iterations, h.params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Verify checks the password against its hash. A matching hash made by bcrypt or
+// with other Argon2id parameters than the hasher's is reported as due for a rehash.
+func (h *PasswordHasher) Verify(password string, hashedPassword string) (rehash bool, err error) {
+	if strings.HasPrefix(hashedPassword, "$argon2id$") {
+		return h.verifyArgon2id(password, hashedPassword)
+	}
+
+	// bcrypt hashes the users signed up with before Argon2id
+	err = bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, ErrPasswordMismatch
+	}
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", errUnknownPasswordHash, err)
+	}
+	return true, nil
+}
+
+func (h *PasswordHasher) verifyArgon2id(password string, hashedPassword string) (bool, error) {
+	parts := strings.Split(hashedPassword, "$")
+	if len(parts) != 6 {
+		return false, errUnknownPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, errUnknownPasswordHash
+	}
+
+	var params Argon2Params
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return false, errUnknownPasswordHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, errUnknownPasswordHash
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return false, errUnknownPasswordHash
+	}
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+
+	candidate := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+	if subtle.ConstantTimeCompare(key, candidate) != 1 {
+		return false, ErrPasswordMismatch
+	}
+
+	return params != h.params, nil
+}
+
+// HashPassword returns the Argon2id hash of the password made with the default parameters
+func HashPassword(password string) (string, error) {
+	return defaultPasswordHasher.Hash(password)
 }
 
 // CheckPassword checks if the input password is correct or not
 func CheckPassword(password string, hashedPassword string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	_, err := defaultPasswordHasher.Verify(password, hashedPassword)
+	return err
 }
@@ -0,0 +1,110 @@
+package commonutils
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+
+	commonerrors "github.com/primarybank/common/errors"
+)
+
+// ErrWeakPassword carries a field error for every rule of the policy a password breaks
+var ErrWeakPassword = commonerrors.New(commonerrors.KindValidation, "weak_password", "password does not meet the password policy")
+
+//go:embed breached_passwords.txt
+var bundledBreachedPasswords []byte
+
+// PasswordPolicy lists the rules new passwords have to follow
+type PasswordPolicy struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// Breached holds known breached passwords in lower case
+	Breached map[string]struct{}
+}
+
+// LoadBreachedPasswords reads a list of breached passwords, one per line.
+// Without a path the list bundled with the application is used.
+func LoadBreachedPasswords(path string) (map[string]struct{}, error) {
+	if path == "" {
+		return readBreachedPasswords(bytes.NewReader(bundledBreachedPasswords))
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open breached password list: %w", err)
+	}
+	defer file.Close()
+
+	return readBreachedPasswords(file)
+}
+
+func readBreachedPasswords(r io.Reader) (map[string]struct{}, error) {
+	breached := make(map[string]struct{})
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		breached[strings.ToLower(line)] = struct{}{}
+	}
+
+	return breached, scanner.Err()
+}
+
+// Validate returns ErrWeakPassword when the password breaks any rule of the policy
+func (p PasswordPolicy) Validate(password string) error {
+	var fields []commonerrors.FieldError
+	broken := func(reason string) {
+		fields = append(fields, commonerrors.FieldError{Field: "password", Reason: reason})
+	}
+
+	if len([]rune(password)) < p.MinLength {
+		broken(fmt.Sprintf("must be at least %d characters long", p.MinLength))
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+
+	if p.RequireUpper && !upper {
+		broken("must contain an upper case letter")
+	}
+	if p.RequireLower && !lower {
+		broken("must contain a lower case letter")
+	}
+	if p.RequireDigit && !digit {
+		broken("must contain a digit")
+	}
+	if p.RequireSymbol && !symbol {
+		broken("must contain a symbol")
+	}
+
+	if _, ok := p.Breached[strings.ToLower(password)]; ok {
+		broken("is a known breached password")
+	}
+
+	if len(fields) > 0 {
+		return ErrWeakPassword.WithFields(fields...)
+	}
+	return nil
+}
@@ -1,8 +1,12 @@
 package commonutils
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	commonerrors "github.com/primarybank/common/errors"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -13,11 +17,122 @@ func TestPassword(t *testing.T) {
 	hashedPassword, err := HashPassword(password)
 	require.NoError(t, err)
 	require.NotEmpty(t, hashedPassword)
+	require.True(t, strings.HasPrefix(hashedPassword, "$argon2id$v=19$m=65536,t=3,p=2$"))
 
 	err = CheckPassword(password, hashedPassword)
 	require.NoError(t, err)
 
 	wrongPassword := RandomString(8)
 	err = CheckPassword(wrongPassword, hashedPassword)
-	require.EqualError(t, err, bcrypt.ErrMismatchedHashAndPassword.Error())
+	require.ErrorIs(t, err, ErrPasswordMismatch)
+
+	// salts differ between hashes of the same password
+	otherHash, err := HashPassword(password)
+	require.NoError(t, err)
+	require.NotEqual(t, hashedPassword, otherHash)
+}
+
+func TestPasswordRehash(t *testing.T) {
+	password := RandomString(8)
+	hasher := NewPasswordHasher(Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 1})
+
+	hashedPassword, err := hasher.Hash(password)
+	require.NoError(t, err)
+
+	rehash, err := hasher.Verify(password, hashedPassword)
+	require.NoError(t, err)
+	require.False(t, rehash)
+
+	// hashes made with other parameters still verify but are due for a rehash
+	stronger := NewPasswordHasher(Argon2Params{Memory: 2048, Iterations: 1, Parallelism: 1})
+	rehash, err = stronger.Verify(password, hashedPassword)
+	require.NoError(t, err)
+	require.True(t, rehash)
+
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	rehash, err = hasher.Verify(password, string(bcryptHash))
+	require.NoError(t, err)
+	require.True(t, rehash)
+
+	_, err = hasher.Verify(RandomString(8), string(bcryptHash))
+	require.ErrorIs(t, err, ErrPasswordMismatch)
+
+	_, err = hasher.Verify(password, "plaintext")
+	require.Error(t, err)
+	require.NotErrorIs(t, err, ErrPasswordMismatch)
+
+	_, err = hasher.Verify(password, "$argon2id$v=19$m=1024,t=1$c2FsdA$a2V5")
+	require.Error(t, err)
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	breached, err := LoadBreachedPasswords("")
+	require.NoError(t, err)
+	require.Contains(t, breached, "p@ssw0rd")
+
+	policy := PasswordPolicy{
+		MinLength:     10,
+		RequireUpper:  true,
+		RequireLower:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+		Breached:      breached,
+	}
+
+	require.NoError(t, policy.Validate("correct Horse 9 battery"))
+
+	testCases := []struct {
+		name     string
+		password string
+		reasons  []string
+	}{
+		{
+			name:     "Too Short",
+			password: "aB3$",
+			reasons:  []string{"must be at least 10 characters long"},
+		},
+		{
+			name:     "Missing Classes",
+			password: "alllowercaseletters",
+			reasons:  []string{"must contain an upper case letter", "must contain a digit", "must contain a symbol"},
+		},
+		{
+			name:     "Breached",
+			password: "PASSWORD123!",
+			reasons:  []string{"must contain a lower case letter", "is a known breached password"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := policy.Validate(tc.password)
+			require.ErrorIs(t, err, ErrWeakPassword)
+
+			typed, ok := commonerrors.As(err)
+			require.True(t, ok)
+
+			var reasons []string
+			for _, field := range typed.Fields {
+				require.Equal(t, "password", field.Field)
+				reasons = append(reasons, field.Reason)
+			}
+			require.Equal(t, tc.reasons, reasons)
+		})
+	}
+}
+
+func TestLoadBreachedPasswords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	require.NoError(t, os.WriteFile(path, []byte("# comment\nHunter2\n\n  tr0ub4dor  \n"), 0o600))
+
+	breached, err := LoadBreachedPasswords(path)
+	require.NoError(t, err)
+	require.Len(t, breached, 2)
+	require.Contains(t, breached, "hunter2")
+	require.Contains(t, breached, "tr0ub4dor")
+
+	_, err = LoadBreachedPasswords(filepath.Join(t.TempDir(), "missing.txt"))
+	require.Error(t, err)
 }
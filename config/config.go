@@ -12,10 +12,102 @@ type Config struct {
 	DBDriver            string        `mapstructure:"DB_DRIVER"`
 	DBSource            string        `mapstructure:"DB_SOURCE"`
 	ServerAddr          string        `mapstructure:"SERVER_ADDR"`
+	GRPCServerAddr      string        `mapstructure:"GRPC_SERVER_ADDR"`
 	TokenSymmetricKey   string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
 	AccessTokenDuration time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	// access tokens are signed with the base64 Ed25519 TOKEN_SIGNING_KEY and published at /.well-known/jwks.json
+	// along with the comma separated base64 public TOKEN_VERIFICATION_KEYS they are still accepted from.
+	// To rotate, publish the new public key as a verification key, then swap it in as the signing key and keep
+	// the old public key for ACCESS_TOKEN_DURATION. Without a signing key tokens are signed with TOKEN_SYMMETRIC_KEY.
+	TokenSigningKey       string `mapstructure:"TOKEN_SIGNING_KEY"`
+	TokenVerificationKeys string `mapstructure:"TOKEN_VERIFICATION_KEYS"`
+	// transfers above the threshold wait for an approver, zero disables approvals
+	TransferApprovalThreshold int64         `mapstructure:"TRANSFER_APPROVAL_THRESHOLD"`
+	TransferApprovalTTL       time.Duration `mapstructure:"TRANSFER_APPROVAL_TTL"`
+	// mail is delivered by smtp, written to MAIL_DIR by file, or kept in memory
+	MailSender            string        `mapstructure:"MAIL_SENDER"`
+	MailFrom              string        `mapstructure:"MAIL_FROM"`
+	MailDir               string        `mapstructure:"MAIL_DIR"`
+	SMTPHost              string        `mapstructure:"SMTP_HOST"`
+	SMTPPort              string        `mapstructure:"SMTP_PORT"`
+	SMTPUsername          string        `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword          string        `mapstructure:"SMTP_PASSWORD"`
+	PasswordResetTokenTTL time.Duration `mapstructure:"PASSWORD_RESET_TOKEN_TTL"`
+	// links mailed to users point at PUBLIC_BASE_URL
+	PublicBaseURL        string        `mapstructure:"PUBLIC_BASE_URL"`
+	EmailVerificationTTL time.Duration `mapstructure:"EMAIL_VERIFICATION_TTL"`
+	// what unverified users are kept from doing, see the EmailVerificationRequiredFor constants
+	EmailVerificationRequiredFor string `mapstructure:"EMAIL_VERIFICATION_REQUIRED_FOR"`
+	// TOTP secrets are encrypted with MFA_ENCRYPTION_KEY, 32 bytes hex encoded
+	MFAEncryptionKey     string        `mapstructure:"MFA_ENCRYPTION_KEY"`
+	MFAIssuer            string        `mapstructure:"MFA_ISSUER"`
+	MFAChallengeDuration time.Duration `mapstructure:"MFA_CHALLENGE_DURATION"`
+	// transfers above the threshold need a second factor passed within MFA_STEP_UP_MAX_AGE, zero disables step-up
+	MFAStepUpThreshold int64         `mapstructure:"MFA_STEP_UP_THRESHOLD"`
+	MFAStepUpMaxAge    time.Duration `mapstructure:"MFA_STEP_UP_MAX_AGE"`
+	// failed logins within LOGIN_FAILURE_WINDOW lock a username or a client IP out for LOGIN_LOCKOUT_DURATION,
+	// every failure delays the next attempt from LOGIN_BASE_DELAY up to LOGIN_MAX_DELAY, zero disables each
+	LoginMaxFailures     int32         `mapstructure:"LOGIN_MAX_FAILURES"`
+	LoginMaxIPFailures   int32         `mapstructure:"LOGIN_MAX_IP_FAILURES"`
+	LoginFailureWindow   time.Duration `mapstructure:"LOGIN_FAILURE_WINDOW"`
+	LoginLockoutDuration time.Duration `mapstructure:"LOGIN_LOCKOUT_DURATION"`
+	LoginBaseDelay       time.Duration `mapstructure:"LOGIN_BASE_DELAY"`
+	LoginMaxDelay        time.Duration `mapstructure:"LOGIN_MAX_DELAY"`
+	// logins are throttled and requests audited by client IP, taken from X-Forwarded-For only when the request comes
+	// from one of the comma separated IPs and CIDRs of TRUSTED_PROXIES, by default from no proxy at all
+	TrustedProxies string `mapstructure:"TRUSTED_PROXIES"`
+	// rules for new passwords, PASSWORD_BREACHED_LIST defaults to the bundled list of common passwords
+	PasswordMinLength     int    `mapstructure:"PASSWORD_MIN_LENGTH"`
+	PasswordRequireUpper  bool   `mapstructure:"PASSWORD_REQUIRE_UPPER"`
+	PasswordRequireLower  bool   `mapstructure:"PASSWORD_REQUIRE_LOWER"`
+	PasswordRequireDigit  bool   `mapstructure:"PASSWORD_REQUIRE_DIGIT"`
+	PasswordRequireSymbol bool   `mapstructure:"PASSWORD_REQUIRE_SYMBOL"`
+	PasswordBreachedList  string `mapstructure:"PASSWORD_BREACHED_LIST"`
+	// Argon2id cost of password hashes, ARGON2_MEMORY is in KiB and zero values take the defaults
+	Argon2Memory      uint32 `mapstructure:"ARGON2_MEMORY"`
+	Argon2Iterations  uint32 `mapstructure:"ARGON2_ITERATIONS"`
+	Argon2Parallelism uint8  `mapstructure:"ARGON2_PARALLELISM"`
+	// domain events are relayed from the outbox every OUTBOX_RELAY_INTERVAL to the log, to the http EVENT_WEBHOOK_URL,
+	// or kept in memory, failed deliveries are retried after a delay doubling from OUTBOX_RETRY_BASE_DELAY up to
+	// OUTBOX_RETRY_MAX_DELAY
+	EventPublisher       string        `mapstructure:"EVENT_PUBLISHER"`
+	EventWebhookURL      string        `mapstructure:"EVENT_WEBHOOK_URL"`
+	OutboxRelayInterval  time.Duration `mapstructure:"OUTBOX_RELAY_INTERVAL"`
+	OutboxBatchSize      int32         `mapstructure:"OUTBOX_BATCH_SIZE"`
+	OutboxRetryBaseDelay time.Duration `mapstructure:"OUTBOX_RETRY_BASE_DELAY"`
+	OutboxRetryMaxDelay  time.Duration `mapstructure:"OUTBOX_RETRY_MAX_DELAY"`
+	// webhooks are dispatched every WEBHOOK_DISPATCH_INTERVAL, a delivery that fails WEBHOOK_MAX_ATTEMPTS times is dead,
+	// until then it is retried after a delay doubling from WEBHOOK_RETRY_BASE_DELAY up to WEBHOOK_RETRY_MAX_DELAY
+	WebhookDispatchInterval time.Duration `mapstructure:"WEBHOOK_DISPATCH_INTERVAL"`
+	WebhookBatchSize        int32         `mapstructure:"WEBHOOK_BATCH_SIZE"`
+	WebhookTimeout          time.Duration `mapstructure:"WEBHOOK_TIMEOUT"`
+	WebhookMaxAttempts      int32         `mapstructure:"WEBHOOK_MAX_ATTEMPTS"`
+	WebhookRetryBaseDelay   time.Duration `mapstructure:"WEBHOOK_RETRY_BASE_DELAY"`
+	WebhookRetryMaxDelay    time.Duration `mapstructure:"WEBHOOK_RETRY_MAX_DELAY"`
+	// webhooks are never delivered to loopback, private or link-local addresses, except those in the comma separated
+	// CIDRs of WEBHOOK_ALLOWED_NETWORKS
+	WebhookAllowedNetworks string `mapstructure:"WEBHOOK_ALLOWED_NETWORKS"`
+	// webhook signing secrets are encrypted with WEBHOOK_ENCRYPTION_KEY, 32 bytes hex encoded like MFA_ENCRYPTION_KEY
+	// and different from it
+	WebhookEncryptionKey string `mapstructure:"WEBHOOK_ENCRYPTION_KEY"`
+	// comma separated routes, written "GET /account/:id" as in the router without the version, that the gateway
+	// generated from the protobuf service serves instead of their gin handlers. The URLs and the responses stay
+	// the same, so routes can move over one at a time.
+	GatewayRoutes string `mapstructure:"GATEWAY_ROUTES"`
+	// the routes are served under /v1, and at their old unversioned paths, deprecated since
+	// LEGACY_ROUTES_DEPRECATED_AT, until LEGACY_ROUTES_SUNSET, both dates written 2006-01-02. The Deprecation and
+	// Sunset headers are left out while their date is empty.
+	LegacyRoutesDeprecatedAt string `mapstructure:"LEGACY_ROUTES_DEPRECATED_AT"`
+	LegacyRoutesSunset       string `mapstructure:"LEGACY_ROUTES_SUNSET"`
 }
 
+// values of EMAIL_VERIFICATION_REQUIRED_FOR, blocking login also blocks money movement
+const (
+	EmailVerificationRequiredForNothing       = "none"
+	EmailVerificationRequiredForLogin         = "login"
+	EmailVerificationRequiredForMoneyMovement = "money_movement"
+)
+
 // Load reads configuration from a file or env variables.
 func Load(path string) (cfg Config, err error) {
 	viper.AddConfigPath(path)
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS transfers_from_account_id_created_at_idx;
+ALTER TABLE entries DROP COLUMN IF EXISTS fee_rule_id;
+ALTER TABLE transfers DROP COLUMN IF EXISTS fee_rule_id;
+ALTER TABLE transfers DROP COLUMN IF EXISTS fee;
+DROP TABLE IF EXISTS fee_rules;
\ No newline at end of file
@@ -0,0 +1,30 @@
+CREATE TABLE fee_rules (
+  id bigserial PRIMARY KEY,
+  name varchar NOT NULL,
+  currency varchar NOT NULL,
+  flat_fee bigint NOT NULL DEFAULT 0,
+  percentage_bps int NOT NULL DEFAULT 0,
+  min_fee bigint NOT NULL DEFAULT 0,
+  max_fee bigint NOT NULL DEFAULT 0,
+  free_transfers_per_month int NOT NULL DEFAULT 0,
+  fee_account_id bigint NOT NULL REFERENCES accounts (id),
+  is_active boolean NOT NULL DEFAULT true,
+  created_at timestamptz NOT NULL DEFAULT (now()),
+  CHECK (flat_fee >= 0 AND percentage_bps >= 0 AND min_fee >= 0 AND max_fee >= 0 AND free_transfers_per_month >= 0)
+);
+
+COMMENT ON COLUMN fee_rules.percentage_bps IS 'percentage fee in basis points, 100 = 1%';
+
+COMMENT ON COLUMN fee_rules.max_fee IS '0 means the fee is not capped';
+
+-- only one schedule can be charged per currency at a time
+CREATE UNIQUE INDEX idx_fee_rules_active_currency ON fee_rules (currency) WHERE is_active;
+
+ALTER TABLE transfers
+ADD COLUMN fee bigint NOT NULL DEFAULT 0,
+ADD COLUMN fee_rule_id bigint REFERENCES fee_rules (id);
+
+ALTER TABLE entries
+ADD COLUMN fee_rule_id bigint REFERENCES fee_rules (id);
+
+CREATE INDEX ON transfers (from_account_id, created_at);
\ No newline at end of file
@@ -0,0 +1,19 @@
+ALTER TABLE users
+ADD COLUMN role varchar NOT NULL DEFAULT 'depositor';
+
+ALTER TABLE accounts
+ADD COLUMN overdraft_limit bigint NOT NULL DEFAULT 0 CHECK (overdraft_limit >= 0);
+
+COMMENT ON COLUMN accounts.overdraft_limit IS 'how far below zero the balance may go';
+
+CREATE TABLE overdraft_limit_changes (
+  id bigserial PRIMARY KEY,
+  account_id bigint NOT NULL REFERENCES accounts (id) ON DELETE CASCADE,
+  previous_limit bigint NOT NULL,
+  new_limit bigint NOT NULL,
+  changed_by varchar NOT NULL REFERENCES users (username),
+  reason varchar NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON overdraft_limit_changes (account_id);
\ No newline at end of file
@@ -0,0 +1,35 @@
+CREATE TABLE transaction_limits (
+  id bigserial PRIMARY KEY,
+  scope varchar NOT NULL CHECK (scope IN ('account', 'user')),
+  period varchar NOT NULL CHECK (period IN ('daily', 'monthly')),
+  role varchar,
+  username varchar REFERENCES users (username) ON DELETE CASCADE,
+  account_id bigint REFERENCES accounts (id) ON DELETE CASCADE,
+  max_amount bigint NOT NULL DEFAULT 0 CHECK (max_amount >= 0),
+  max_count bigint NOT NULL DEFAULT 0 CHECK (max_count >= 0),
+  created_at timestamptz NOT NULL DEFAULT (now()),
+  updated_at timestamptz NOT NULL DEFAULT (now()),
+  CHECK (num_nonnulls(role, username, account_id) = 1),
+  CHECK (account_id IS NULL OR scope = 'account'),
+  CHECK (username IS NULL OR scope = 'user')
+);
+
+COMMENT ON COLUMN transaction_limits.role IS 'default limit for every user with this role';
+
+COMMENT ON COLUMN transaction_limits.max_amount IS '0 means the amount is not capped';
+
+COMMENT ON COLUMN transaction_limits.max_count IS '0 means the count is not capped';
+
+CREATE UNIQUE INDEX idx_transaction_limits_role ON transaction_limits (scope, period, role) WHERE role IS NOT NULL;
+
+CREATE UNIQUE INDEX idx_transaction_limits_username ON transaction_limits (period, username) WHERE username IS NOT NULL;
+
+CREATE UNIQUE INDEX idx_transaction_limits_account ON transaction_limits (period, account_id) WHERE account_id IS NOT NULL;
+
+CREATE INDEX ON entries (account_id, created_at);
+
+INSERT INTO transaction_limits (scope, period, role, max_amount, max_count) VALUES
+  ('account', 'daily', 'depositor', 1000000, 100),
+  ('account', 'monthly', 'depositor', 10000000, 1000),
+  ('user', 'daily', 'depositor', 2000000, 200),
+  ('user', 'monthly', 'depositor', 20000000, 2000);
\ No newline at end of file
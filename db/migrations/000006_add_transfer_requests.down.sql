@@ -0,0 +1 @@
+DROP TABLE IF EXISTS transfer_requests;
\ No newline at end of file
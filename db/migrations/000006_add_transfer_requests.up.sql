@@ -0,0 +1,20 @@
+CREATE TABLE transfer_requests (
+  id bigserial PRIMARY KEY,
+  from_account_id bigint NOT NULL REFERENCES accounts (id),
+  to_account_id bigint NOT NULL REFERENCES accounts (id),
+  amount bigint NOT NULL CHECK (amount > 0),
+  status varchar NOT NULL DEFAULT 'pending',
+  initiated_by varchar NOT NULL REFERENCES users (username),
+  reviewed_by varchar REFERENCES users (username),
+  review_reason varchar,
+  transfer_id bigint REFERENCES transfers (id),
+  expires_at timestamptz NOT NULL,
+  reviewed_at timestamptz,
+  created_at timestamptz NOT NULL DEFAULT (now()),
+  CHECK (status IN ('pending', 'approved', 'rejected', 'expired')),
+  CHECK (reviewed_by IS NULL OR reviewed_by <> initiated_by)
+);
+
+COMMENT ON COLUMN transfer_requests.status IS 'pending, approved, rejected or expired';
+
+CREATE INDEX ON transfer_requests (status, expires_at);
\ No newline at end of file
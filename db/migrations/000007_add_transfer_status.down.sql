@@ -0,0 +1,4 @@
+ALTER TABLE transfers
+DROP COLUMN IF EXISTS voided_at,
+DROP COLUMN IF EXISTS posted_at,
+DROP COLUMN IF EXISTS status;
\ No newline at end of file
@@ -0,0 +1,11 @@
+ALTER TABLE transfers
+ADD COLUMN status varchar NOT NULL DEFAULT 'posted' CHECK (status IN ('pending', 'posted', 'voided')),
+ADD COLUMN posted_at timestamptz,
+ADD COLUMN voided_at timestamptz;
+
+COMMENT ON COLUMN transfers.status IS 'pending transfers are reserved on the sender and still have to be posted or voided';
+
+-- every transfer made before two-phase transfers was posted immediately
+UPDATE transfers SET posted_at = created_at;
+
+CREATE INDEX ON transfers (status) WHERE status = 'pending';
\ No newline at end of file
@@ -0,0 +1,7 @@
+ALTER TABLE users
+ADD COLUMN email_verified_at timestamptz;
+
+COMMENT ON COLUMN users.email_verified_at IS 'null until the user proves they own the email address';
+
+-- users registered before verification existed are trusted
+UPDATE users SET email_verified_at = created_at;
\ No newline at end of file
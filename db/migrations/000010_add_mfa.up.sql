@@ -0,0 +1,20 @@
+CREATE TABLE mfa_factors (
+  username varchar PRIMARY KEY REFERENCES users (username) ON DELETE CASCADE,
+  secret_ciphertext bytea NOT NULL,
+  confirmed_at timestamptz,
+  last_used_step bigint NOT NULL DEFAULT 0,
+  created_at timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON COLUMN mfa_factors.secret_ciphertext IS 'TOTP secret encrypted with MFA_ENCRYPTION_KEY';
+
+COMMENT ON COLUMN mfa_factors.last_used_step IS 'TOTP time step of the last accepted code, codes of earlier steps are replays';
+
+CREATE TABLE mfa_recovery_codes (
+  id bigserial PRIMARY KEY,
+  username varchar NOT NULL REFERENCES users (username) ON DELETE CASCADE,
+  code_hash varchar NOT NULL,
+  used_at timestamptz,
+  created_at timestamptz NOT NULL DEFAULT (now()),
+  UNIQUE (username, code_hash)
+);
\ No newline at end of file
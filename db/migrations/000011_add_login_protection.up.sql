@@ -0,0 +1,27 @@
+CREATE TABLE login_events (
+  id bigserial PRIMARY KEY,
+  username varchar NOT NULL,
+  client_ip varchar NOT NULL,
+  user_agent varchar NOT NULL DEFAULT '',
+  outcome varchar NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT (now()),
+  CHECK (outcome IN ('success', 'failure', 'throttled'))
+);
+
+COMMENT ON COLUMN login_events.username IS 'as typed by the client, attempts on unknown usernames are recorded too';
+
+CREATE INDEX ON login_events (username, created_at);
+
+CREATE INDEX ON login_events (client_ip, created_at);
+
+CREATE TABLE login_throttles (
+  scope varchar NOT NULL,
+  subject varchar NOT NULL,
+  failed_attempts integer NOT NULL DEFAULT 0,
+  last_failed_at timestamptz NOT NULL DEFAULT (now()),
+  locked_until timestamptz,
+  PRIMARY KEY (scope, subject),
+  CHECK (scope IN ('username', 'client_ip'))
+);
+
+COMMENT ON COLUMN login_throttles.failed_attempts IS 'consecutive failures, counting restarts once a failure window passes without any';
\ No newline at end of file
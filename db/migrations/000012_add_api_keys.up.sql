@@ -0,0 +1,16 @@
+CREATE TABLE api_keys (
+  id bigserial PRIMARY KEY,
+  username varchar NOT NULL REFERENCES users (username) ON DELETE CASCADE,
+  name varchar NOT NULL,
+  prefix varchar UNIQUE NOT NULL,
+  secret_hash varchar NOT NULL,
+  scopes varchar[] NOT NULL DEFAULT '{}',
+  expires_at timestamptz,
+  last_used_at timestamptz,
+  revoked_at timestamptz,
+  created_at timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON COLUMN api_keys.prefix IS 'public part of the key used to look it up, the secret part is only stored as sha256';
+
+CREATE INDEX ON api_keys (username);
\ No newline at end of file
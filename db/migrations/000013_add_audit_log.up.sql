@@ -0,0 +1,36 @@
+CREATE TABLE audit_log (
+  id bigserial PRIMARY KEY,
+  actor varchar,
+  action varchar NOT NULL,
+  resource_type varchar NOT NULL DEFAULT '',
+  resource_id varchar NOT NULL DEFAULT '',
+  before json,
+  after json,
+  request_id varchar NOT NULL DEFAULT '',
+  client_ip varchar NOT NULL DEFAULT '',
+  prev_hash varchar NOT NULL,
+  hash varchar UNIQUE NOT NULL,
+  created_at timestamptz NOT NULL
+);
+
+COMMENT ON COLUMN audit_log.actor IS 'username of the authenticated caller, null for anonymous requests';
+
+COMMENT ON COLUMN audit_log.before IS 'json rather than jsonb so the snapshots keep the exact bytes they were hashed with';
+
+COMMENT ON COLUMN audit_log.hash IS 'sha256 of prev_hash and every other column but id, chaining each row to the previous one';
+
+CREATE INDEX ON audit_log (actor, created_at);
+
+CREATE INDEX ON audit_log (resource_type, resource_id);
+
+CREATE INDEX ON audit_log (request_id);
+
+CREATE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'audit_log is append only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_append_only
+BEFORE UPDATE OR DELETE OR TRUNCATE ON audit_log
+FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
\ No newline at end of file
@@ -0,0 +1,12 @@
+ALTER TABLE entries ADD COLUMN prev_hash varchar NOT NULL DEFAULT '';
+
+ALTER TABLE entries ADD COLUMN hash varchar NOT NULL DEFAULT '';
+
+-- entries written before the chain existed keep an empty hash, new ones must bring their own
+ALTER TABLE entries ALTER COLUMN prev_hash DROP DEFAULT;
+
+ALTER TABLE entries ALTER COLUMN hash DROP DEFAULT;
+
+COMMENT ON COLUMN entries.hash IS 'sha256 of prev_hash and every other column but id, chaining each entry to the previous entry of its account';
+
+CREATE INDEX ON entries (account_id, id);
\ No newline at end of file
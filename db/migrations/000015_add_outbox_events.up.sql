@@ -0,0 +1,20 @@
+CREATE TABLE outbox_events (
+  id bigserial PRIMARY KEY,
+  event_type varchar NOT NULL,
+  aggregate_type varchar NOT NULL,
+  aggregate_id varchar NOT NULL,
+  payload jsonb NOT NULL,
+  attempts integer NOT NULL DEFAULT 0,
+  last_error varchar NOT NULL DEFAULT '',
+  next_attempt_at timestamptz NOT NULL DEFAULT (now()),
+  published_at timestamptz,
+  created_at timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON TABLE outbox_events IS 'domain events written in the transaction of the change they describe, delivered at least once by the relay';
+
+COMMENT ON COLUMN outbox_events.published_at IS 'null until the event was handed to the publisher';
+
+CREATE INDEX ON outbox_events (next_attempt_at) WHERE published_at IS NULL;
+
+CREATE INDEX ON outbox_events (aggregate_type, aggregate_id);
\ No newline at end of file
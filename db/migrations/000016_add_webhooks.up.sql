@@ -0,0 +1,44 @@
+CREATE TABLE webhook_subscriptions (
+  id bigserial PRIMARY KEY,
+  username varchar NOT NULL REFERENCES users (username) ON DELETE CASCADE,
+  url varchar NOT NULL,
+  event_types varchar[] NOT NULL,
+  secret_ciphertext bytea NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON COLUMN webhook_subscriptions.secret_ciphertext IS 'signing secret encrypted with the webhook encryption key, it is needed in the clear to sign deliveries';
+
+CREATE INDEX ON webhook_subscriptions (username);
+
+CREATE TABLE webhook_deliveries (
+  id bigserial PRIMARY KEY,
+  subscription_id bigint NOT NULL REFERENCES webhook_subscriptions (id) ON DELETE CASCADE,
+  event_id bigint NOT NULL REFERENCES outbox_events (id),
+  event_type varchar NOT NULL,
+  status varchar NOT NULL DEFAULT 'pending',
+  attempts integer NOT NULL DEFAULT 0,
+  next_attempt_at timestamptz NOT NULL DEFAULT (now()),
+  delivered_at timestamptz,
+  created_at timestamptz NOT NULL DEFAULT (now()),
+  UNIQUE (subscription_id, event_id)
+);
+
+COMMENT ON COLUMN webhook_deliveries.status IS 'pending, succeeded, or dead once every attempt failed';
+
+COMMENT ON COLUMN webhook_deliveries.next_attempt_at IS 'moved ahead while a dispatcher holds the delivery, so it is retried if the dispatcher dies';
+
+CREATE INDEX ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+
+CREATE TABLE webhook_delivery_attempts (
+  id bigserial PRIMARY KEY,
+  delivery_id bigint NOT NULL REFERENCES webhook_deliveries (id) ON DELETE CASCADE,
+  response_status integer,
+  error varchar NOT NULL DEFAULT '',
+  duration_ms bigint NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON COLUMN webhook_delivery_attempts.response_status IS 'null when the receiver could not be reached';
+
+CREATE INDEX ON webhook_delivery_attempts (delivery_id);
\ No newline at end of file
@@ -0,0 +1,45 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/golang/mock/gomock"
+	db "github.com/primarybank/db/sqlc"
+)
+
+// auditedTxKey marks the contexts of the units of work run by ExpectAuditedTx
+type auditedTxKey struct{}
+
+// ExpectAuditedTx lets every ExecAuditedTx of store run its unit of work, appending the audit row it returns with
+// AppendAuditLogTx so tests check the rows through the expectations of AppendAuditLogTx
+func ExpectAuditedTx(store *MockStore) {
+	store.EXPECT().
+		ExecAuditedTx(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, fn func(ctx context.Context) (*db.AppendAuditLogTxParams, error)) error {
+			ctx = context.WithValue(ctx, auditedTxKey{}, true)
+			args, err := fn(ctx)
+			if err != nil || args == nil {
+				return err
+			}
+			_, err = store.AppendAuditLogTx(ctx, *args)
+			return err
+		}).
+		AnyTimes()
+}
+
+// InAuditedTx matches the contexts of the units of work run by ExpectAuditedTx, the queries made with any other
+// context would not join the audited transaction
+func InAuditedTx() gomock.Matcher {
+	return inAuditedTx{}
+}
+
+type inAuditedTx struct{}
+
+func (inAuditedTx) Matches(x interface{}) bool {
+	ctx, ok := x.(context.Context)
+	return ok && ctx.Value(auditedTxKey{}) != nil
+}
+
+func (inAuditedTx) String() string {
+	return "is a context of an audited transaction"
+}
@@ -23,6 +23,11 @@ ORDER BY id
 LIMIT $1
 OFFSET $2;
 
+-- name: ListAccountsByOwner :many
+SELECT * FROM accounts
+WHERE owner = $1
+ORDER BY id;
+
 -- name: UpdateAccount :exec
 UPDATE accounts 
 SET balance = $2
@@ -35,5 +40,11 @@ SET balance = balance + sqlc.arg(amount)
 WHERE id = sqlc.arg(id)
 RETURNING *;
 
+-- name: SetAccountOverdraftLimit :one
+UPDATE accounts
+SET overdraft_limit = $2
+WHERE id = $1
+RETURNING *;
+
 -- name: DeleteAccount :exec
 DELETE FROM accounts WHERE id = $1;
\ No newline at end of file
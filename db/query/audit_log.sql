@@ -0,0 +1,44 @@
+-- name: LockAuditLog :exec
+-- appends are serialized so that every row chains to the one before it
+LOCK TABLE audit_log IN EXCLUSIVE MODE;
+
+-- name: GetLastAuditLog :one
+SELECT * FROM audit_log
+ORDER BY id DESC
+LIMIT 1;
+
+-- name: CreateAuditLog :one
+INSERT INTO audit_log (
+    actor,
+    action,
+    resource_type,
+    resource_id,
+    before,
+    after,
+    request_id,
+    client_ip,
+    prev_hash,
+    hash,
+    created_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+) RETURNING *;
+
+-- name: ListAuditLogs :many
+SELECT * FROM audit_log
+WHERE (sqlc.narg(actor)::varchar IS NULL OR actor = sqlc.narg(actor))
+  AND (sqlc.narg(action)::varchar IS NULL OR action = sqlc.narg(action))
+  AND (sqlc.narg(resource_type)::varchar IS NULL OR resource_type = sqlc.narg(resource_type))
+  AND (sqlc.narg(resource_id)::varchar IS NULL OR resource_id = sqlc.narg(resource_id))
+  AND (sqlc.narg(request_id)::varchar IS NULL OR request_id = sqlc.narg(request_id))
+  AND (sqlc.narg(since)::timestamptz IS NULL OR created_at >= sqlc.narg(since))
+  AND (sqlc.narg(until)::timestamptz IS NULL OR created_at < sqlc.narg(until))
+ORDER BY id DESC
+LIMIT sqlc.arg(limit_count)
+OFFSET sqlc.arg(offset_count);
+
+-- name: ListAuditLogsAfter :many
+SELECT * FROM audit_log
+WHERE id > $1
+ORDER BY id
+LIMIT $2;
\ No newline at end of file
@@ -1,20 +1,80 @@
 -- name: CreateEntry :one
 INSERT INTO entries (
     account_id,
-    amount
+    amount,
+    fee_rule_id,
+    prev_hash,
+    hash,
+    created_at
 ) VALUES (
-    $1, $2
+    $1, $2, $3, $4, $5, $6
 ) RETURNING *;
 
 -- name: GetEntry :one
 SELECT * FROM entries
 WHERE id = $1 LIMIT 1;
 
+-- name: GetLastAccountEntry :one
+SELECT * FROM entries
+WHERE account_id = $1
+ORDER BY id DESC
+LIMIT 1;
+
+-- name: ListAccountEntriesAfter :many
+SELECT * FROM entries
+WHERE account_id = $1
+AND id > $2
+ORDER BY id
+LIMIT $3;
+
 -- name: ListEntries :many
 SELECT * FROM entries
 ORDER BY id
 LIMIT $1
 OFFSET $2;
 
+-- name: GetAccountOutflowSince :one
+-- voided transfers gave their amount back, their debits don't count
+WITH debits AS (
+    SELECT COALESCE(SUM(-amount), 0)::bigint AS amount, count(*) AS count
+    FROM entries
+    WHERE account_id = sqlc.arg(account_id)
+    AND amount < 0
+    AND fee_rule_id IS NULL
+    AND created_at >= sqlc.arg(since)
+), voided AS (
+    SELECT COALESCE(SUM(amount), 0)::bigint AS amount, count(*) AS count
+    FROM transfers
+    WHERE from_account_id = sqlc.arg(account_id)
+    AND status = 'voided'
+    AND created_at >= sqlc.arg(since)
+)
+SELECT (debits.amount - voided.amount)::bigint AS total_amount, (debits.count - voided.count)::bigint AS total_count
+FROM debits, voided;
+
+-- name: GetUserOutflowSince :one
+WITH debits AS (
+    SELECT COALESCE(SUM(-e.amount), 0)::bigint AS amount, count(*) AS count
+    FROM entries e
+    JOIN accounts a ON a.id = e.account_id
+    WHERE a.owner = sqlc.arg(owner)
+    AND e.amount < 0
+    AND e.fee_rule_id IS NULL
+    AND e.created_at >= sqlc.arg(since)
+), voided AS (
+    SELECT COALESCE(SUM(t.amount), 0)::bigint AS amount, count(*) AS count
+    FROM transfers t
+    JOIN accounts a ON a.id = t.from_account_id
+    WHERE a.owner = sqlc.arg(owner)
+    AND t.status = 'voided'
+    AND t.created_at >= sqlc.arg(since)
+)
+SELECT (debits.amount - voided.amount)::bigint AS total_amount, (debits.count - voided.count)::bigint AS total_count
+FROM debits, voided;
+
 -- name: DeleteEntry :exec
-DELETE FROM entries WHERE id = $1;
\ No newline at end of file
+DELETE FROM entries WHERE id = $1;
+
+-- name: NotifyAccountActivity :exec
+-- delivered to listeners of account_activity once the transaction commits
+SELECT pg_notify('account_activity', sqlc.arg(payload)::text);
\ No newline at end of file
@@ -0,0 +1,33 @@
+-- name: CreateFeeRule :one
+INSERT INTO fee_rules (
+    name,
+    currency,
+    flat_fee,
+    percentage_bps,
+    min_fee,
+    max_fee,
+    free_transfers_per_month,
+    fee_account_id
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8
+) RETURNING *;
+
+-- name: GetFeeRule :one
+SELECT * FROM fee_rules
+WHERE id = $1 LIMIT 1;
+
+-- name: GetActiveFeeRule :one
+SELECT * FROM fee_rules
+WHERE currency = $1 AND is_active
+LIMIT 1;
+
+-- name: ListFeeRules :many
+SELECT * FROM fee_rules
+ORDER BY id
+LIMIT $1
+OFFSET $2;
+
+-- name: DeactivateFeeRule :exec
+UPDATE fee_rules
+SET is_active = false
+WHERE id = $1;
\ No newline at end of file
@@ -0,0 +1,66 @@
+-- name: CreateLoginEvent :one
+INSERT INTO login_events (
+    username,
+    client_ip,
+    user_agent,
+    outcome
+) VALUES (
+    $1, $2, $3, $4
+) RETURNING *;
+
+-- name: ListLoginEvents :many
+SELECT * FROM login_events
+WHERE (sqlc.narg(username)::varchar IS NULL OR username = sqlc.narg(username))
+  AND (sqlc.narg(client_ip)::varchar IS NULL OR client_ip = sqlc.narg(client_ip))
+  AND (sqlc.narg(outcome)::varchar IS NULL OR outcome = sqlc.narg(outcome))
+ORDER BY id DESC
+LIMIT sqlc.arg(limit_count)
+OFFSET sqlc.arg(offset_count);
+
+-- name: GetLoginThrottles :many
+SELECT * FROM login_throttles
+WHERE (scope = 'username' AND subject = sqlc.arg(username))
+   OR (scope = 'client_ip' AND subject = sqlc.arg(client_ip));
+
+-- name: GetLoginThrottlesForUpdate :many
+-- creates the missing throttles with no failures so that both rows can be locked until the transaction ends,
+-- the client IP always first so that concurrent logins lock in the same order
+INSERT INTO login_throttles (
+    scope,
+    subject
+) VALUES (
+    'client_ip', sqlc.arg(client_ip)
+), (
+    'username', sqlc.arg(username)
+)
+ON CONFLICT (scope, subject) DO UPDATE
+SET failed_attempts = login_throttles.failed_attempts
+RETURNING *;
+
+-- name: RecordLoginFailure :one
+-- failures older than the window no longer count
+INSERT INTO login_throttles (
+    scope,
+    subject,
+    failed_attempts,
+    last_failed_at
+) VALUES (
+    sqlc.arg(scope), sqlc.arg(subject), 1, now()
+)
+ON CONFLICT (scope, subject) DO UPDATE
+SET failed_attempts = CASE
+        WHEN login_throttles.last_failed_at < sqlc.arg(window_start) THEN 1
+        ELSE login_throttles.failed_attempts + 1
+    END,
+    last_failed_at = now()
+RETURNING *;
+
+-- name: LockLogin :one
+UPDATE login_throttles
+SET locked_until = $3
+WHERE scope = $1 AND subject = $2
+RETURNING *;
+
+-- name: ResetLoginThrottle :execrows
+DELETE FROM login_throttles
+WHERE scope = $1 AND subject = $2;
\ No newline at end of file
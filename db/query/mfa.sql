@@ -0,0 +1,58 @@
+-- name: UpsertMFAFactor :one
+-- starting over an enrollment replaces the unconfirmed secret
+INSERT INTO mfa_factors (
+    username,
+    secret_ciphertext
+) VALUES (
+    $1, $2
+)
+ON CONFLICT (username) DO UPDATE
+SET secret_ciphertext = EXCLUDED.secret_ciphertext,
+    confirmed_at = NULL,
+    last_used_step = 0,
+    created_at = now()
+RETURNING *;
+
+-- name: GetMFAFactor :one
+SELECT * FROM mfa_factors
+WHERE username = $1 LIMIT 1;
+
+-- name: GetMFAFactorForUpdate :one
+SELECT * FROM mfa_factors
+WHERE username = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: ConfirmMFAFactor :one
+UPDATE mfa_factors
+SET confirmed_at = now(), last_used_step = $2
+WHERE username = $1
+RETURNING *;
+
+-- name: UseMFAStep :one
+-- fails with no rows when the step was already used, which is how replays are caught
+UPDATE mfa_factors
+SET last_used_step = sqlc.arg(step)
+WHERE username = sqlc.arg(username)
+  AND confirmed_at IS NOT NULL
+  AND last_used_step < sqlc.arg(step)
+RETURNING *;
+
+-- name: DeleteMFAFactor :exec
+DELETE FROM mfa_factors WHERE username = $1;
+
+-- name: CreateMFARecoveryCode :exec
+INSERT INTO mfa_recovery_codes (
+    username,
+    code_hash
+) VALUES (
+    $1, $2
+);
+
+-- name: UseMFARecoveryCode :one
+UPDATE mfa_recovery_codes
+SET used_at = now()
+WHERE username = $1 AND code_hash = $2 AND used_at IS NULL
+RETURNING *;
+
+-- name: DeleteMFARecoveryCodes :exec
+DELETE FROM mfa_recovery_codes WHERE username = $1;
\ No newline at end of file
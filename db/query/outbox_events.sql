@@ -0,0 +1,47 @@
+-- name: CreateOutboxEvent :one
+INSERT INTO outbox_events (
+    event_type,
+    aggregate_type,
+    aggregate_id,
+    payload
+) VALUES (
+    $1, $2, $3, $4
+) RETURNING *;
+
+-- name: ClaimPendingOutboxEvents :many
+-- claimed events are not due again until lease_until, unless they are marked before
+UPDATE outbox_events
+SET next_attempt_at = sqlc.arg(lease_until)
+WHERE id IN (
+    SELECT id FROM outbox_events
+    WHERE published_at IS NULL
+    AND next_attempt_at <= now()
+    ORDER BY id
+    LIMIT sqlc.arg(limit_count)
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: MarkOutboxEventPublished :exec
+UPDATE outbox_events
+SET published_at = now(),
+    attempts = attempts + 1,
+    last_error = ''
+WHERE id = $1;
+
+-- name: MarkOutboxEventFailed :exec
+UPDATE outbox_events
+SET attempts = attempts + 1,
+    last_error = sqlc.arg(last_error),
+    next_attempt_at = sqlc.arg(next_attempt_at)
+WHERE id = sqlc.arg(id);
+
+-- name: ListAggregateOutboxEvents :many
+SELECT * FROM outbox_events
+WHERE aggregate_type = $1
+AND aggregate_id = $2
+ORDER BY id;
+
+-- name: GetOutboxEvent :one
+SELECT * FROM outbox_events
+WHERE id = $1 LIMIT 1;
\ No newline at end of file
@@ -0,0 +1,18 @@
+-- name: CreatePasswordResetToken :one
+INSERT INTO password_reset_tokens (
+    username,
+    token_hash,
+    expires_at
+) VALUES (
+    $1, $2, $3
+) RETURNING *;
+
+-- name: GetPasswordResetTokenForUpdate :one
+SELECT * FROM password_reset_tokens
+WHERE token_hash = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: InvalidatePasswordResetTokens :exec
+UPDATE password_reset_tokens
+SET used_at = now()
+WHERE username = $1 AND used_at IS NULL;
\ No newline at end of file
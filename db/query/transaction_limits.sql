@@ -0,0 +1,38 @@
+-- name: CreateTransactionLimit :one
+INSERT INTO transaction_limits (
+    scope,
+    period,
+    role,
+    username,
+    account_id,
+    max_amount,
+    max_count
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+) RETURNING *;
+
+-- name: GetTransactionLimit :one
+SELECT * FROM transaction_limits
+WHERE id = $1 LIMIT 1;
+
+-- name: ListTransactionLimits :many
+SELECT * FROM transaction_limits
+ORDER BY id
+LIMIT $1
+OFFSET $2;
+
+-- name: ListApplicableTransactionLimits :many
+SELECT * FROM transaction_limits
+WHERE account_id = sqlc.arg(account_id)
+OR username = sqlc.arg(username)
+OR role = sqlc.arg(role)
+ORDER BY id;
+
+-- name: UpdateTransactionLimit :one
+UPDATE transaction_limits
+SET max_amount = $2, max_count = $3, updated_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteTransactionLimit :exec
+DELETE FROM transaction_limits WHERE id = $1;
\ No newline at end of file
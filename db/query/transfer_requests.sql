@@ -0,0 +1,43 @@
+-- name: CreateTransferRequest :one
+INSERT INTO transfer_requests (
+    from_account_id,
+    to_account_id,
+    amount,
+    initiated_by,
+    expires_at
+) VALUES (
+    $1, $2, $3, $4, $5
+) RETURNING *;
+
+-- name: GetTransferRequest :one
+SELECT * FROM transfer_requests
+WHERE id = $1 LIMIT 1;
+
+-- name: GetTransferRequestForUpdate :one
+SELECT * FROM transfer_requests
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: ListTransferRequests :many
+SELECT * FROM transfer_requests
+WHERE status = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3;
+
+-- name: ReviewTransferRequest :one
+UPDATE transfer_requests
+SET
+    status = sqlc.arg(status),
+    reviewed_by = sqlc.arg(reviewed_by),
+    review_reason = sqlc.narg(review_reason),
+    transfer_id = sqlc.narg(transfer_id),
+    reviewed_at = now()
+WHERE id = sqlc.arg(id) AND status = 'pending'
+RETURNING *;
+
+-- name: ExpireTransferRequests :many
+UPDATE transfer_requests
+SET status = 'expired'
+WHERE status = 'pending' AND expires_at <= now()
+RETURNING *;
\ No newline at end of file
@@ -2,20 +2,47 @@
 INSERT INTO transfers (
     from_account_id,
     to_account_id,
-    amount
+    amount,
+    fee,
+    fee_rule_id,
+    status,
+    posted_at
 ) VALUES (
-    $1, $2, $3
+    $1, $2, $3, $4, $5, $6, $7
 ) RETURNING *;
 
 -- name: GetTransfer :one
 SELECT * FROM transfers
 WHERE id = $1 LIMIT 1;
 
+-- name: GetTransferForUpdate :one
+SELECT * FROM transfers
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: PostTransfer :one
+UPDATE transfers
+SET status = 'posted', posted_at = now()
+WHERE id = $1 AND status = 'pending'
+RETURNING *;
+
+-- name: VoidTransfer :one
+UPDATE transfers
+SET status = 'voided', voided_at = now()
+WHERE id = $1 AND status = 'pending'
+RETURNING *;
+
 -- name: ListTransfers :many
 SELECT * FROM transfers
 ORDER BY id
 LIMIT $1
 OFFSET $2;
 
+-- name: CountTransfersSince :one
+SELECT count(*) FROM transfers
+WHERE from_account_id = sqlc.arg(from_account_id)
+AND status <> 'voided'
+AND created_at >= sqlc.arg(since);
+
 -- name: DeleteTransfer :exec
 DELETE FROM transfers WHERE id = $1;
\ No newline at end of file
@@ -1,13 +1,46 @@
 -- name: GetUser :one
 SELECT * FROM users WHERE username = $1 LIMIT 1;
 
+-- name: GetUserForUpdate :one
+SELECT * FROM users
+WHERE username = $1
+LIMIT 1
+FOR NO KEY UPDATE;
+
 -- name: CreateUser :one
 INSERT INTO users (username, password, full_name, email)
 VALUES ($1, $2, $3, $4)
 RETURNING *;
 
 -- name: UpdateUser :one
+-- a changed email has to be verified again
+UPDATE users
+SET full_name = $2,
+    email = $3,
+    password = COALESCE($4, password),
+    email_verified_at = CASE WHEN email = $3 THEN email_verified_at END,
+    updated_at = now()
+WHERE username = $1
+RETURNING *;
+
+-- name: UpdateUserRole :one
 UPDATE users
-SET full_name = $2, email = $3, password = COALESCE($4, password), updated_at = now()
+SET role = $2, updated_at = now()
 WHERE username = $1
-RETURNING username, password, full_name, email, created_at, updated_at;
+RETURNING *;
+
+-- name: GetUserByEmail :one
+SELECT * FROM users WHERE email = $1 LIMIT 1;
+
+-- name: UpdateUserPassword :one
+UPDATE users
+SET password = $2, updated_at = now()
+WHERE username = $1
+RETURNING *;
+
+
+-- name: VerifyUserEmail :one
+UPDATE users
+SET email_verified_at = COALESCE(email_verified_at, now()), updated_at = now()
+WHERE username = $1 AND email = $2
+RETURNING *;
\ No newline at end of file
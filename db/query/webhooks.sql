@@ -0,0 +1,90 @@
+-- name: CreateWebhookSubscription :one
+INSERT INTO webhook_subscriptions (
+    username,
+    url,
+    event_types,
+    secret_ciphertext
+) VALUES (
+    $1, $2, $3, $4
+) RETURNING *;
+
+-- name: GetWebhookSubscription :one
+SELECT * FROM webhook_subscriptions
+WHERE id = $1 LIMIT 1;
+
+-- name: ListWebhookSubscriptions :many
+SELECT * FROM webhook_subscriptions
+WHERE username = $1
+ORDER BY id;
+
+-- name: DeleteWebhookSubscription :one
+DELETE FROM webhook_subscriptions
+WHERE id = $1 AND username = $2
+RETURNING *;
+
+-- name: EnqueueWebhookDeliveries :many
+-- an event relayed more than once is only delivered once to every subscription
+INSERT INTO webhook_deliveries (subscription_id, event_id, event_type)
+SELECT s.id, sqlc.arg(event_id)::bigint, sqlc.arg(event_type)::varchar
+FROM webhook_subscriptions s
+WHERE s.username = ANY(sqlc.arg(usernames)::varchar[])
+AND sqlc.arg(event_type)::varchar = ANY(s.event_types)
+ON CONFLICT (subscription_id, event_id) DO NOTHING
+RETURNING *;
+
+-- name: ClaimWebhookDeliveries :many
+-- claimed deliveries are not due again until lease_until, unless they are recorded before
+UPDATE webhook_deliveries
+SET next_attempt_at = sqlc.arg(lease_until)
+WHERE id IN (
+    SELECT id FROM webhook_deliveries
+    WHERE status = 'pending'
+    AND next_attempt_at <= now()
+    ORDER BY id
+    LIMIT sqlc.arg(limit_count)
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: UpdateWebhookDelivery :one
+UPDATE webhook_deliveries
+SET status = sqlc.arg(status),
+    attempts = attempts + 1,
+    next_attempt_at = sqlc.arg(next_attempt_at),
+    delivered_at = sqlc.narg(delivered_at)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: RedeliverWebhookDelivery :one
+UPDATE webhook_deliveries
+SET status = 'pending',
+    attempts = 0,
+    next_attempt_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: GetWebhookDelivery :one
+SELECT * FROM webhook_deliveries
+WHERE id = $1 LIMIT 1;
+
+-- name: ListWebhookDeliveries :many
+SELECT * FROM webhook_deliveries
+WHERE subscription_id = $1
+ORDER BY id DESC
+LIMIT $2
+OFFSET $3;
+
+-- name: CreateWebhookDeliveryAttempt :one
+INSERT INTO webhook_delivery_attempts (
+    delivery_id,
+    response_status,
+    error,
+    duration_ms
+) VALUES (
+    $1, $2, $3, $4
+) RETURNING *;
+
+-- name: ListWebhookDeliveryAttempts :many
+SELECT * FROM webhook_delivery_attempts
+WHERE delivery_id = $1
+ORDER BY id;
\ No newline at end of file
@@ -0,0 +1,64 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// AccountActivityChannel is the postgres notification channel entries are announced on
+const AccountActivityChannel = "account_activity"
+
+// AccountActivity tells listeners a new entry was booked on an account, and so its balance changed
+type AccountActivity struct {
+	AccountID int64 `json:"account_id"`
+	EntryID   int64 `json:"entry_id"`
+}
+
+// announceEntry must run inside the db transaction that books the entry,
+// postgres only delivers the notification once that transaction commits
+func announceEntry(ctx context.Context, queries *Queries, entry Entry) error {
+	payload, err := json.Marshal(AccountActivity{
+		AccountID: entry.AccountID,
+		EntryID:   entry.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot encode account activity: %w", err)
+	}
+
+	return queries.NotifyAccountActivity(ctx, string(payload))
+}
+
+// ListenAccountActivity holds a connection of its own listening on AccountActivityChannel, calls listening once
+// it listens and then handle with every notification, until ctx is done or the connection fails.
+// Notifications sent while nobody listens are lost, listeners catch up from the entries themselves.
+func (s *SQLStore) ListenAccountActivity(ctx context.Context, listening func(), handle func(AccountActivity)) error {
+	pooled, err := s.db.Acquire(ctx)
+	if err != nil {
+		return mapError(err)
+	}
+
+	// a listening connection must not go back to the pool
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{AccountActivityChannel}.Sanitize()); err != nil {
+		return mapError(err)
+	}
+	listening()
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return mapError(err)
+		}
+
+		var activity AccountActivity
+		if err := json.Unmarshal([]byte(notification.Payload), &activity); err != nil {
+			continue
+		}
+		handle(activity)
+	}
+}
@@ -0,0 +1,56 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenAccountActivity(t *testing.T) {
+	account1 := createFundedAccount(t, 1000)
+	account2 := CreateRandomAccount(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	listening := make(chan struct{})
+	received := make(chan AccountActivity, 100)
+	done := make(chan error, 1)
+	go func() {
+		done <- testStore.ListenAccountActivity(ctx, func() { close(listening) }, func(activity AccountActivity) {
+			received <- activity
+		})
+	}()
+
+	// transfers made once the listener is up are heard of
+	select {
+	case <-listening:
+	case <-time.After(5 * time.Second):
+		t.Fatal("not listening")
+	}
+
+	result, err := testStore.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        1,
+	})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		for {
+			select {
+			case activity := <-received:
+				if activity.AccountID == account2.ID && activity.EntryID == result.ToEntry.ID {
+					return true
+				}
+			default:
+				return false
+			}
+		}
+	}, 5*time.Second, 100*time.Millisecond)
+
+	cancel()
+	require.Error(t, <-done)
+}
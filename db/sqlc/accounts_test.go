@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	commonerrors "github.com/primarybank/common/errors"
 	commonutils "github.com/primarybank/common/utils"
 	"github.com/stretchr/testify/require"
 )
@@ -35,6 +36,22 @@ func TestCreateAccount(t *testing.T) {
 	CreateRandomAccount(t)
 }
 
+func TestCreateAccountConstraintViolations(t *testing.T) {
+	account := CreateRandomAccount(t)
+
+	_, err := testStore.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    account.Owner,
+		Currency: account.Currency,
+	})
+	requireFieldError(t, err, commonerrors.KindConflict, "already_exists", "owner", "currency")
+
+	_, err = testStore.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    commonutils.RandomString(12),
+		Currency: account.Currency,
+	})
+	requireFieldError(t, err, commonerrors.KindUnprocessable, "invalid_reference", "owner")
+}
+
 func TestGetAccount(t *testing.T) {
 	account1 := CreateRandomAccount(t)
 	account2, err := testStore.GetAccount(context.Background(), account1.ID)
@@ -117,3 +134,12 @@ func TestAddAccountBalanceAndGetAccountForUpdate(t *testing.T) {
 	require.Equal(t, updatedAccount.Owner, fetchedAccount.Owner)
 	require.Equal(t, updatedAccount.Currency, fetchedAccount.Currency)
 }
+
+func TestListAccountsByOwner(t *testing.T) {
+	account := CreateRandomAccount(t)
+
+	accounts, err := testStore.ListAccountsByOwner(context.Background(), account.Owner)
+	require.NoError(t, err)
+	require.Len(t, accounts, 1)
+	require.Equal(t, account.ID, accounts[0].ID)
+}
@@ -0,0 +1,64 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	commonutils "github.com/primarybank/common/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomAPIKey(t *testing.T, user User) ApiKey {
+	args := CreateAPIKeyParams{
+		Username:   user.Username,
+		Name:       commonutils.RandomString(6),
+		Prefix:     commonutils.RandomString(12),
+		SecretHash: commonutils.HashSecret(commonutils.RandomString(32)),
+		Scopes:     []string{"accounts:read", "transfers:write"},
+		ExpiresAt:  pgtype.Timestamptz{Time: time.Now().Add(time.Hour), Valid: true},
+	}
+
+	apiKey, err := testStore.CreateAPIKey(context.Background(), args)
+	require.NoError(t, err)
+	require.Equal(t, args.Prefix, apiKey.Prefix)
+	require.Equal(t, args.Scopes, apiKey.Scopes)
+	require.False(t, apiKey.LastUsedAt.Valid)
+	require.False(t, apiKey.RevokedAt.Valid)
+
+	return apiKey
+}
+
+func TestAPIKeys(t *testing.T) {
+	ctx := context.Background()
+	user := CreateRandomUser(t)
+	apiKey := createRandomAPIKey(t, user)
+	createRandomAPIKey(t, user)
+
+	found, err := testStore.GetAPIKeyByPrefix(ctx, apiKey.Prefix)
+	require.NoError(t, err)
+	require.Equal(t, apiKey.ID, found.ID)
+	require.Equal(t, apiKey.SecretHash, found.SecretHash)
+
+	apiKeys, err := testStore.ListAPIKeys(ctx, user.Username)
+	require.NoError(t, err)
+	require.Len(t, apiKeys, 2)
+
+	require.NoError(t, testStore.TouchAPIKey(ctx, apiKey.ID))
+	found, err = testStore.GetAPIKeyByPrefix(ctx, apiKey.Prefix)
+	require.NoError(t, err)
+	require.True(t, found.LastUsedAt.Valid)
+
+	// keys can only be revoked once and only by their owner
+	other := CreateRandomUser(t)
+	_, err = testStore.RevokeAPIKey(ctx, RevokeAPIKeyParams{ID: apiKey.ID, Username: other.Username})
+	require.ErrorIs(t, err, ErrNotFound)
+
+	revoked, err := testStore.RevokeAPIKey(ctx, RevokeAPIKeyParams{ID: apiKey.ID, Username: user.Username})
+	require.NoError(t, err)
+	require.True(t, revoked.RevokedAt.Valid)
+
+	_, err = testStore.RevokeAPIKey(ctx, RevokeAPIKeyParams{ID: apiKey.ID, Username: user.Username})
+	require.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,184 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// audit log rows are read back in batches of this size when the chain is verified
+const auditLogVerifyBatch = 1000
+
+// actions of the audit log rows of the background jobs, which have no actor
+const (
+	AuditActionExpireTransferRequest = "expire transfer request"
+	AuditActionRelayOutboxEvent      = "relay outbox event"
+	AuditActionRecordWebhookAttempt  = "record webhook attempt"
+)
+
+// AppendAuditLogTx appends a row to the audit log, chained to the row before it by its hash
+func (s *SQLStore) AppendAuditLogTx(ctx context.Context, args AppendAuditLogTxParams) (AuditLog, error) {
+	var retval AuditLog
+
+	err := s.execTx(ctx, func(queries *Queries) error {
+		var err error
+		retval, err = appendAuditLog(ctx, queries, args)
+		return err
+	})
+
+	return retval, err
+}
+
+// appendAuditLog must run inside a db transaction, which holds the audit log lock until it commits
+func appendAuditLog(ctx context.Context, queries *Queries, args AppendAuditLogTxParams) (AuditLog, error) {
+	if err := queries.LockAuditLog(ctx); err != nil {
+		return AuditLog{}, err
+	}
+
+	var prevHash string
+	last, err := queries.GetLastAuditLog(ctx)
+	switch {
+	case err == nil:
+		prevHash = last.Hash
+	case !errors.Is(err, ErrNotFound):
+		return AuditLog{}, err
+	}
+
+	row := AuditLog{
+		Actor:        pgtype.Text{String: args.Actor, Valid: args.Actor != ""},
+		Action:       args.Action,
+		ResourceType: args.ResourceType,
+		ResourceID:   args.ResourceID,
+		Before:       args.Before,
+		After:        args.After,
+		RequestID:    args.RequestID,
+		ClientIp:     args.ClientIP,
+		PrevHash:     prevHash,
+		// postgres keeps microseconds, the hash must be computed over what is read back
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+	}
+
+	return queries.CreateAuditLog(ctx, CreateAuditLogParams{
+		Actor:        row.Actor,
+		Action:       row.Action,
+		ResourceType: row.ResourceType,
+		ResourceID:   row.ResourceID,
+		Before:       row.Before,
+		After:        row.After,
+		RequestID:    row.RequestID,
+		ClientIp:     row.ClientIp,
+		PrevHash:     row.PrevHash,
+		Hash:         AuditLogHash(row),
+		CreatedAt:    row.CreatedAt,
+	})
+}
+
+// auditJob appends a change made by a background job to the audit log, with the queries of the db transaction
+// making the change so both commit together
+func auditJob(ctx context.Context, queries *Queries, action string, resourceType string, resourceID any, before any, after any) error {
+	args := AppendAuditLogTxParams{
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   fmt.Sprint(resourceID),
+	}
+
+	var err error
+	if args.Before, err = auditJobSnapshot(before); err != nil {
+		return err
+	}
+	if args.After, err = auditJobSnapshot(after); err != nil {
+		return err
+	}
+
+	_, err = appendAuditLog(ctx, queries, args)
+	return err
+}
+
+func auditJobSnapshot(resource any) ([]byte, error) {
+	if resource == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return nil, fmt.Errorf("cannot snapshot %T for the audit log: %w", resource, err)
+	}
+	return data, nil
+}
+
+// AuditLogHash returns the hash of an audit log row, covering its previous hash and every other column but the id
+func AuditLogHash(row AuditLog) string {
+	h := sha256.New()
+
+	writeHashField(h, row.PrevHash)
+	writeHashField(h, strconv.FormatBool(row.Actor.Valid))
+	writeHashField(h, row.Actor.String)
+	writeHashField(h, row.Action)
+	writeHashField(h, row.ResourceType)
+	writeHashField(h, row.ResourceID)
+	writeHashField(h, string(row.Before))
+	writeHashField(h, string(row.After))
+	writeHashField(h, row.RequestID)
+	writeHashField(h, row.ClientIp)
+	writeHashField(h, row.CreatedAt.UTC().Format(time.RFC3339Nano))
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// writeHashField length prefixes every field so that no two rows hash the same input
+func writeHashField(h hash.Hash, field string) {
+	fmt.Fprintf(h, "%d:%s", len(field), field)
+}
+
+// VerifyAuditLog walks the audit log from its first row and reports the first row that was altered,
+// removed or inserted out of band
+func (s *SQLStore) VerifyAuditLog(ctx context.Context) (AuditLogVerification, error) {
+	var retval AuditLogVerification
+	var afterID int64
+
+	for {
+		rows, err := s.ListAuditLogsAfter(ctx, ListAuditLogsAfterParams{
+			ID:    afterID,
+			Limit: auditLogVerifyBatch,
+		})
+		if err != nil {
+			return retval, err
+		}
+
+		for _, row := range rows {
+			if reason := verifyAuditLogRow(row, retval.LastHash); reason != "" {
+				retval.BrokenAt = row.ID
+				retval.Reason = reason
+				return retval, nil
+			}
+
+			retval.Rows++
+			retval.LastHash = row.Hash
+			afterID = row.ID
+		}
+
+		if len(rows) < auditLogVerifyBatch {
+			retval.Valid = true
+			return retval, nil
+		}
+	}
+}
+
+// verifyAuditLogRow explains why a row doesn't follow prevHash or its own hash, it returns nothing for a sound row
+func verifyAuditLogRow(row AuditLog, prevHash string) string {
+	if row.PrevHash != prevHash {
+		return "previous hash does not match the row before"
+	}
+	if AuditLogHash(row) != row.Hash {
+		return "hash does not match the row content"
+	}
+	return ""
+}
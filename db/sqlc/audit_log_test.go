@@ -0,0 +1,222 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	commonutils "github.com/primarybank/common/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditLogHash(t *testing.T) {
+	row := AuditLog{
+		Actor:        pgtype.Text{String: "alice", Valid: true},
+		Action:       "PATCH /account",
+		ResourceType: "account",
+		ResourceID:   "7",
+		Before:       []byte(`{"balance":10}`),
+		After:        []byte(`{"balance":20}`),
+		RequestID:    "req-42",
+		ClientIp:     "192.0.2.1",
+		PrevHash:     "genesis",
+		CreatedAt:    time.Date(2026, 1, 2, 3, 4, 5, 6000, time.UTC),
+	}
+	row.Hash = AuditLogHash(row)
+	require.Len(t, row.Hash, 64)
+	require.Empty(t, verifyAuditLogRow(row, "genesis"))
+
+	// the time zone a row is read back in does not change its hash
+	local := row
+	local.CreatedAt = row.CreatedAt.In(time.FixedZone("test", 3600))
+	require.Equal(t, row.Hash, AuditLogHash(local))
+
+	require.NotEmpty(t, verifyAuditLogRow(row, "other"))
+
+	tampered := row
+	tampered.After = []byte(`{"balance":2000}`)
+	require.NotEmpty(t, verifyAuditLogRow(tampered, "genesis"))
+
+	// moving bytes between fields is caught too
+	shifted := row
+	shifted.ResourceType, shifted.ResourceID = "account7", ""
+	require.NotEqual(t, row.Hash, AuditLogHash(shifted))
+
+	anonymous := row
+	anonymous.Actor = pgtype.Text{}
+	require.NotEqual(t, row.Hash, AuditLogHash(anonymous))
+}
+
+func appendRandomAuditLog(t *testing.T) AuditLog {
+	args := AppendAuditLogTxParams{
+		Actor:        commonutils.RandomOwner(),
+		Action:       "POST /account",
+		ResourceType: "account",
+		ResourceID:   commonutils.RandomString(6),
+		After:        []byte(`{"balance":0}`),
+		RequestID:    commonutils.RandomString(12),
+		ClientIP:     "192.0.2.1",
+	}
+
+	row, err := testStore.AppendAuditLogTx(context.Background(), args)
+	require.NoError(t, err)
+	require.Equal(t, args.Actor, row.Actor.String)
+	require.Equal(t, args.ResourceID, row.ResourceID)
+	require.Nil(t, row.Before)
+	require.Equal(t, args.After, row.After)
+	require.Equal(t, AuditLogHash(row), row.Hash)
+	return row
+}
+
+func TestAppendAuditLog(t *testing.T) {
+	first := appendRandomAuditLog(t)
+	second := appendRandomAuditLog(t)
+
+	// rows appended by other tests may sit between the two
+	require.Greater(t, second.ID, first.ID)
+	if second.ID == first.ID+1 {
+		require.Equal(t, first.Hash, second.PrevHash)
+	}
+
+	logs, err := testStore.ListAuditLogs(context.Background(), ListAuditLogsParams{
+		RequestID:  pgtype.Text{String: second.RequestID, Valid: true},
+		LimitCount: 5,
+	})
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	require.Equal(t, second.Hash, logs[0].Hash)
+}
+
+func TestAuditLogAppendOnly(t *testing.T) {
+	row := appendRandomAuditLog(t)
+	ctx := context.Background()
+	pool := testStore.(*SQLStore).db
+
+	_, err := pool.Exec(ctx, "UPDATE audit_log SET action = 'tampered' WHERE id = $1", row.ID)
+	require.ErrorContains(t, err, "append only")
+
+	_, err = pool.Exec(ctx, "DELETE FROM audit_log WHERE id = $1", row.ID)
+	require.ErrorContains(t, err, "append only")
+}
+
+func TestVerifyAuditLog(t *testing.T) {
+	appendRandomAuditLog(t)
+	last := appendRandomAuditLog(t)
+
+	verification, err := testStore.VerifyAuditLog(context.Background())
+	require.NoError(t, err)
+	require.True(t, verification.Valid)
+	require.Zero(t, verification.BrokenAt)
+	require.GreaterOrEqual(t, verification.Rows, int64(2))
+	require.NotEmpty(t, verification.LastHash)
+	require.NotEmpty(t, last.Hash)
+}
+
+// auditLogsOfRequest returns the audit log rows of a request id
+func auditLogsOfRequest(t *testing.T, requestID string) []AuditLog {
+	logs, err := testStore.ListAuditLogs(context.Background(), ListAuditLogsParams{
+		RequestID:  pgtype.Text{String: requestID, Valid: true},
+		LimitCount: 5,
+	})
+	require.NoError(t, err)
+	return logs
+}
+
+func TestExecAuditedTx(t *testing.T) {
+	user := CreateRandomUser(t)
+
+	testCases := []struct {
+		name  string
+		audit bool
+		err   error
+		check func(t *testing.T, account Account, logs []AuditLog, err error)
+	}{
+		{
+			name:  "Committed With Audit Row",
+			audit: true,
+			check: func(t *testing.T, account Account, logs []AuditLog, err error) {
+				require.NoError(t, err)
+				_, getErr := testStore.GetAccount(context.Background(), account.ID)
+				require.NoError(t, getErr)
+				require.Len(t, logs, 1)
+				require.Equal(t, fmt.Sprint(account.ID), logs[0].ResourceID)
+			},
+		},
+		{
+			name: "Committed Without Audit Row",
+			check: func(t *testing.T, account Account, logs []AuditLog, err error) {
+				require.NoError(t, err)
+				_, getErr := testStore.GetAccount(context.Background(), account.ID)
+				require.NoError(t, getErr)
+				require.Empty(t, logs)
+			},
+		},
+		{
+			name:  "Rolled Back",
+			audit: true,
+			err:   errors.New("handler failed"),
+			check: func(t *testing.T, account Account, logs []AuditLog, err error) {
+				require.ErrorContains(t, err, "handler failed")
+				_, getErr := testStore.GetAccount(context.Background(), account.ID)
+				require.ErrorIs(t, getErr, ErrNotFound)
+				require.Empty(t, logs)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			requestID := commonutils.RandomString(12)
+			var account Account
+
+			err := testStore.ExecAuditedTx(context.Background(), func(ctx context.Context) (*AppendAuditLogTxParams, error) {
+				// a transaction of the store and a query outside of one both join the unit of work
+				var err error
+				account, err = testStore.CreateAccountTx(ctx, CreateAccountParams{Owner: user.Username, Currency: commonutils.RandomCurrency()})
+				if err != nil {
+					return nil, err
+				}
+				if account, err = testStore.AddAccountBalance(ctx, AddAccountBalanceParams{ID: account.ID, Amount: 10}); err != nil {
+					return nil, err
+				}
+
+				if !tc.audit {
+					return nil, tc.err
+				}
+				return &AppendAuditLogTxParams{
+					Action:       "POST /account",
+					ResourceType: "account",
+					ResourceID:   fmt.Sprint(account.ID),
+					RequestID:    requestID,
+				}, tc.err
+			})
+
+			tc.check(t, account, auditLogsOfRequest(t, requestID), err)
+		})
+	}
+}
+
+func TestExpireTransferRequestsTxAudited(t *testing.T) {
+	account1 := createFundedAccount(t, 1000)
+	account2 := CreateRandomAccount(t)
+	request := CreateRandomTransferRequest(t, account1, account2, 100, -time.Minute)
+
+	expired, err := testStore.ExpireTransferRequestsTx(context.Background())
+	require.NoError(t, err)
+	require.Contains(t, transferRequestIDs(expired), request.ID)
+
+	logs, err := testStore.ListAuditLogs(context.Background(), ListAuditLogsParams{
+		Action:       pgtype.Text{String: AuditActionExpireTransferRequest, Valid: true},
+		ResourceType: pgtype.Text{String: "transfer_request", Valid: true},
+		ResourceID:   pgtype.Text{String: fmt.Sprint(request.ID), Valid: true},
+		LimitCount:   5,
+	})
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	require.False(t, logs[0].Actor.Valid)
+	require.Contains(t, string(logs[0].Before), `"status":"pending"`)
+	require.Contains(t, string(logs[0].After), `"status":"expired"`)
+}
@@ -0,0 +1,128 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// auditedTxKey holds the *auditedTx the queries of a context join
+type auditedTxKey struct{}
+
+// auditedTx is the db transaction of a unit of work audited by ExecAuditedTx, begun by its first query so units
+// of work that never reach the db don't hold a connection
+type auditedTx struct {
+	pool *pgxpool.Pool
+	tx   pgx.Tx
+}
+
+func (a *auditedTx) begin(ctx context.Context) (pgx.Tx, error) {
+	if a.tx == nil {
+		tx, err := a.pool.BeginTx(ctx, pgx.TxOptions{})
+		if err != nil {
+			return nil, err
+		}
+		a.tx = tx
+	}
+	return a.tx, nil
+}
+
+// ExecAuditedTx runs fn as one db transaction: every query and transaction of the store run with the context
+// given to fn joins it, the transactions as savepoints. The audit log row fn returns is appended with the same
+// transaction before it commits, so a change and its audit row commit together or not at all.
+// A nil row commits the changes without auditing them, an error rolls them back.
+func (s *SQLStore) ExecAuditedTx(ctx context.Context, fn func(ctx context.Context) (*AppendAuditLogTxParams, error)) error {
+	unit := &auditedTx{pool: s.db}
+
+	args, err := fn(context.WithValue(ctx, auditedTxKey{}, unit))
+	if err == nil && args != nil {
+		err = appendUnitAuditLog(ctx, unit, *args)
+	}
+
+	if unit.tx == nil {
+		return err
+	}
+	if err != nil {
+		if rbErr := unit.tx.Rollback(ctx); rbErr != nil {
+			return fmt.Errorf("tx err: %v, rb err: %v", err, rbErr)
+		}
+		return err
+	}
+	return mapError(unit.tx.Commit(ctx))
+}
+
+func appendUnitAuditLog(ctx context.Context, unit *auditedTx, args AppendAuditLogTxParams) error {
+	tx, err := unit.begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	if _, err = appendAuditLog(ctx, New(errorMappingDB{tx}), args); err != nil {
+		return fmt.Errorf("cannot append %s to the audit log: %w", args.Action, err)
+	}
+	return nil
+}
+
+// beginTx begins a transaction, a savepoint of the audited transaction of ctx if there is one
+func (s *SQLStore) beginTx(ctx context.Context) (pgx.Tx, error) {
+	unit, ok := ctx.Value(auditedTxKey{}).(*auditedTx)
+	if !ok {
+		return s.db.BeginTx(ctx, pgx.TxOptions{})
+	}
+
+	tx, err := unit.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return tx.Begin(ctx)
+}
+
+// contextDB runs the queries of the store outside its transactions in the audited transaction of their context,
+// if there is one, and on the pool otherwise
+type contextDB struct {
+	pool *pgxpool.Pool
+}
+
+func (d contextDB) conn(ctx context.Context) (DBTX, error) {
+	unit, ok := ctx.Value(auditedTxKey{}).(*auditedTx)
+	if !ok {
+		return d.pool, nil
+	}
+	return unit.begin(ctx)
+}
+
+func (d contextDB) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	conn, err := d.conn(ctx)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	return conn.Exec(ctx, sql, args...)
+}
+
+func (d contextDB) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	conn, err := d.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return conn.Query(ctx, sql, args...)
+}
+
+func (d contextDB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	conn, err := d.conn(ctx)
+	if err != nil {
+		return errRow{err}
+	}
+	return conn.QueryRow(ctx, sql, args...)
+}
+
+// errRow is the row of a query that could not be sent
+type errRow struct {
+	err error
+}
+
+func (r errRow) Scan(...any) error {
+	return r.err
+}
@@ -7,30 +7,51 @@ package db
 
 import (
 	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const createEntry = `-- name: CreateEntry :one
 INSERT INTO entries (
     account_id,
-    amount
+    amount,
+    fee_rule_id,
+    prev_hash,
+    hash,
+    created_at
 ) VALUES (
-    $1, $2
-) RETURNING id, account_id, amount, created_at
+    $1, $2, $3, $4, $5, $6
+) RETURNING id, account_id, amount, created_at, fee_rule_id, prev_hash, hash
 `
 
 type CreateEntryParams struct {
-	AccountID int64 `json:"account_id"`
-	Amount    int64 `json:"amount"`
+	AccountID int64       `json:"account_id"`
+	Amount    int64       `json:"amount"`
+	FeeRuleID pgtype.Int8 `json:"fee_rule_id"`
+	PrevHash  string      `json:"prev_hash"`
+	Hash      string      `json:"hash"`
+	CreatedAt time.Time   `json:"created_at"`
 }
 
 func (q *Queries) CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error) {
-	row := q.db.QueryRow(ctx, createEntry, arg.AccountID, arg.Amount)
+	row := q.db.QueryRow(ctx, createEntry,
+		arg.AccountID,
+		arg.Amount,
+		arg.FeeRuleID,
+		arg.PrevHash,
+		arg.Hash,
+		arg.CreatedAt,
+	)
 	var i Entry
 	err := row.Scan(
 		&i.ID,
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.FeeRuleID,
+		&i.PrevHash,
+		&i.Hash,
 	)
 	return i, err
 }
@@ -44,8 +65,45 @@ func (q *Queries) DeleteEntry(ctx context.Context, id int64) error {
 	return err
 }
 
+const getAccountOutflowSince = `-- name: GetAccountOutflowSince :one
+WITH debits AS (
+    SELECT COALESCE(SUM(-amount), 0)::bigint AS amount, count(*) AS count
+    FROM entries
+    WHERE account_id = $1
+    AND amount < 0
+    AND fee_rule_id IS NULL
+    AND created_at >= $2
+), voided AS (
+    SELECT COALESCE(SUM(amount), 0)::bigint AS amount, count(*) AS count
+    FROM transfers
+    WHERE from_account_id = $1
+    AND status = 'voided'
+    AND created_at >= $2
+)
+SELECT (debits.amount - voided.amount)::bigint AS total_amount, (debits.count - voided.count)::bigint AS total_count
+FROM debits, voided
+`
+
+type GetAccountOutflowSinceParams struct {
+	AccountID int64     `json:"account_id"`
+	Since     time.Time `json:"since"`
+}
+
+type GetAccountOutflowSinceRow struct {
+	TotalAmount int64 `json:"total_amount"`
+	TotalCount  int64 `json:"total_count"`
+}
+
+// voided transfers gave their amount back, their debits don't count
+func (q *Queries) GetAccountOutflowSince(ctx context.Context, arg GetAccountOutflowSinceParams) (GetAccountOutflowSinceRow, error) {
+	row := q.db.QueryRow(ctx, getAccountOutflowSince, arg.AccountID, arg.Since)
+	var i GetAccountOutflowSinceRow
+	err := row.Scan(&i.TotalAmount, &i.TotalCount)
+	return i, err
+}
+
 const getEntry = `-- name: GetEntry :one
-SELECT id, account_id, amount, created_at FROM entries
+SELECT id, account_id, amount, created_at, fee_rule_id, prev_hash, hash FROM entries
 WHERE id = $1 LIMIT 1
 `
 
@@ -57,12 +115,117 @@ func (q *Queries) GetEntry(ctx context.Context, id int64) (Entry, error) {
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.FeeRuleID,
+		&i.PrevHash,
+		&i.Hash,
 	)
 	return i, err
 }
 
+const getLastAccountEntry = `-- name: GetLastAccountEntry :one
+SELECT id, account_id, amount, created_at, fee_rule_id, prev_hash, hash FROM entries
+WHERE account_id = $1
+ORDER BY id DESC
+LIMIT 1
+`
+
+func (q *Queries) GetLastAccountEntry(ctx context.Context, accountID int64) (Entry, error) {
+	row := q.db.QueryRow(ctx, getLastAccountEntry, accountID)
+	var i Entry
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.FeeRuleID,
+		&i.PrevHash,
+		&i.Hash,
+	)
+	return i, err
+}
+
+const getUserOutflowSince = `-- name: GetUserOutflowSince :one
+WITH debits AS (
+    SELECT COALESCE(SUM(-e.amount), 0)::bigint AS amount, count(*) AS count
+    FROM entries e
+    JOIN accounts a ON a.id = e.account_id
+    WHERE a.owner = $1
+    AND e.amount < 0
+    AND e.fee_rule_id IS NULL
+    AND e.created_at >= $2
+), voided AS (
+    SELECT COALESCE(SUM(t.amount), 0)::bigint AS amount, count(*) AS count
+    FROM transfers t
+    JOIN accounts a ON a.id = t.from_account_id
+    WHERE a.owner = $1
+    AND t.status = 'voided'
+    AND t.created_at >= $2
+)
+SELECT (debits.amount - voided.amount)::bigint AS total_amount, (debits.count - voided.count)::bigint AS total_count
+FROM debits, voided
+`
+
+type GetUserOutflowSinceParams struct {
+	Owner string    `json:"owner"`
+	Since time.Time `json:"since"`
+}
+
+type GetUserOutflowSinceRow struct {
+	TotalAmount int64 `json:"total_amount"`
+	TotalCount  int64 `json:"total_count"`
+}
+
+func (q *Queries) GetUserOutflowSince(ctx context.Context, arg GetUserOutflowSinceParams) (GetUserOutflowSinceRow, error) {
+	row := q.db.QueryRow(ctx, getUserOutflowSince, arg.Owner, arg.Since)
+	var i GetUserOutflowSinceRow
+	err := row.Scan(&i.TotalAmount, &i.TotalCount)
+	return i, err
+}
+
+const listAccountEntriesAfter = `-- name: ListAccountEntriesAfter :many
+SELECT id, account_id, amount, created_at, fee_rule_id, prev_hash, hash FROM entries
+WHERE account_id = $1
+AND id > $2
+ORDER BY id
+LIMIT $3
+`
+
+type ListAccountEntriesAfterParams struct {
+	AccountID int64 `json:"account_id"`
+	ID        int64 `json:"id"`
+	Limit     int32 `json:"limit"`
+}
+
+func (q *Queries) ListAccountEntriesAfter(ctx context.Context, arg ListAccountEntriesAfterParams) ([]Entry, error) {
+	rows, err := q.db.Query(ctx, listAccountEntriesAfter, arg.AccountID, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Entry{}
+	for rows.Next() {
+		var i Entry
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.FeeRuleID,
+			&i.PrevHash,
+			&i.Hash,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listEntries = `-- name: ListEntries :many
-SELECT id, account_id, amount, created_at FROM entries
+SELECT id, account_id, amount, created_at, fee_rule_id, prev_hash, hash FROM entries
 ORDER BY id
 LIMIT $1
 OFFSET $2
@@ -87,6 +250,9 @@ func (q *Queries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Ent
 			&i.AccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.FeeRuleID,
+			&i.PrevHash,
+			&i.Hash,
 		); err != nil {
 			return nil, err
 		}
@@ -97,3 +263,13 @@ func (q *Queries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Ent
 	}
 	return items, nil
 }
+
+const notifyAccountActivity = `-- name: NotifyAccountActivity :exec
+SELECT pg_notify('account_activity', $1::text)
+`
+
+// delivered to listeners of account_activity once the transaction commits
+func (q *Queries) NotifyAccountActivity(ctx context.Context, payload string) error {
+	_, err := q.db.Exec(ctx, notifyAccountActivity, payload)
+	return err
+}
@@ -10,17 +10,18 @@ import (
 )
 
 func CreateRandomEntry(t *testing.T, account Account) Entry {
-	args := CreateEntryParams{
+	args := CreateEntryTxParams{
 		AccountID: account.ID,
 		Amount:    commonutils.RandomMoney(),
 	}
 
-	entry, err := testStore.CreateEntry(context.Background(), args)
+	entry, err := testStore.CreateEntryTx(context.Background(), args)
 	require.NoError(t, err)
 	require.NotEmpty(t, entry)
 	require.Equal(t, args.AccountID, entry.AccountID)
 	require.Equal(t, args.Amount, entry.Amount)
 	require.NotZero(t, entry.ID)
+	require.Equal(t, EntryHash(entry), entry.Hash)
 
 	return entry
 }
@@ -42,11 +43,24 @@ func TestGetEntry(t *testing.T) {
 	require.WithinDuration(t, entry1.CreatedAt, entry2.CreatedAt, time.Second)
 }
 
+// createUnchainedEntry books an entry like those from before the hash chain was introduced
+func createUnchainedEntry(t *testing.T, account Account) Entry {
+	entry, err := testStore.CreateEntry(context.Background(), CreateEntryParams{
+		AccountID: account.ID,
+		Amount:    commonutils.RandomMoney(),
+		CreatedAt: time.Now(),
+	})
+	require.NoError(t, err)
+	require.Empty(t, entry.Hash)
+
+	return entry
+}
+
 func TestDeleteEntry(t *testing.T) {
 	account := CreateRandomAccount(t)
-	entry := CreateRandomEntry(t, account)
+	entry := createUnchainedEntry(t, account)
 
-	err := testStore.DeleteEntry(context.Background(), entry.ID)
+	err := testStore.DeleteEntryTx(context.Background(), entry.ID)
 	require.NoError(t, err)
 
 	deletedEntry, err := testStore.GetEntry(context.Background(), entry.ID)
@@ -54,6 +68,40 @@ func TestDeleteEntry(t *testing.T) {
 	require.Empty(t, deletedEntry)
 }
 
+func TestDeleteEntryNotLatest(t *testing.T) {
+	account := CreateRandomAccount(t)
+	first := createUnchainedEntry(t, account)
+	createUnchainedEntry(t, account)
+
+	err := testStore.DeleteEntryTx(context.Background(), first.ID)
+	require.ErrorIs(t, err, ErrEntryNotLatest)
+
+	_, err = testStore.GetEntry(context.Background(), first.ID)
+	require.NoError(t, err)
+}
+
+func TestDeleteEntryChained(t *testing.T) {
+	ctx := context.Background()
+	account := CreateRandomAccount(t)
+	legacy := createUnchainedEntry(t, account)
+	CreateRandomEntry(t, account)
+	last := CreateRandomEntry(t, account)
+
+	// neither the head of the chain nor the entries before it can go
+	for _, entry := range []Entry{last, legacy} {
+		err := testStore.DeleteEntryTx(ctx, entry.ID)
+		require.ErrorIs(t, err, ErrEntryChained)
+
+		_, err = testStore.GetEntry(ctx, entry.ID)
+		require.NoError(t, err)
+	}
+
+	verification, err := testStore.VerifyEntryChain(ctx, account.ID)
+	require.NoError(t, err)
+	require.True(t, verification.Valid)
+	require.Equal(t, last.Hash, verification.LastHash)
+}
+
 func TestListEntries(t *testing.T) {
 	account := CreateRandomAccount(t)
 	for i := 0; i < 10; i++ {
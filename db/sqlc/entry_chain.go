@@ -0,0 +1,160 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// account entries are read back in batches of this size when a chain is verified
+const entryChainVerifyBatch = 1000
+
+// CreateEntryTx books an entry on an account outside of a transfer, chained to the account's previous entry
+func (s *SQLStore) CreateEntryTx(ctx context.Context, args CreateEntryTxParams) (Entry, error) {
+	var retval Entry
+
+	err := s.execTx(ctx, func(queries *Queries) error {
+		var err error
+		retval, err = appendEntry(ctx, queries, CreateEntryParams{
+			AccountID: args.AccountID,
+			Amount:    args.Amount,
+		})
+		return err
+	})
+
+	return retval, err
+}
+
+// DeleteEntryTx deletes the latest entry of an account booked before the hash chain was introduced.
+// Once an account has chained entries none of them is deleted, the chain could not tell a removed latest entry
+// from one never booked.
+func (s *SQLStore) DeleteEntryTx(ctx context.Context, entryID int64) error {
+	return s.execTx(ctx, func(queries *Queries) error {
+		entry, err := queries.GetEntry(ctx, entryID)
+		if err != nil {
+			return err
+		}
+
+		if _, err = queries.GetAccountForUpdate(ctx, entry.AccountID); err != nil {
+			return err
+		}
+
+		last, err := queries.GetLastAccountEntry(ctx, entry.AccountID)
+		if err != nil {
+			return err
+		}
+		if last.Hash != "" {
+			return ErrEntryChained
+		}
+		if last.ID != entry.ID {
+			return ErrEntryNotLatest
+		}
+
+		return queries.DeleteEntry(ctx, entryID)
+	})
+}
+
+// appendEntry must run inside a db transaction. It locks the account of the entry, which is a no-op
+// for accounts the caller already locked, so that no other entry can be chained to the same previous entry.
+// Listeners of AccountActivityChannel are told about the entry once the transaction commits.
+func appendEntry(ctx context.Context, queries *Queries, args CreateEntryParams) (Entry, error) {
+	if _, err := queries.GetAccountForUpdate(ctx, args.AccountID); err != nil {
+		return Entry{}, err
+	}
+
+	last, err := queries.GetLastAccountEntry(ctx, args.AccountID)
+	switch {
+	case err == nil:
+		args.PrevHash = last.Hash
+	case !errors.Is(err, ErrNotFound):
+		return Entry{}, err
+	}
+
+	// postgres keeps microseconds, the hash must be computed over what is read back
+	args.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	args.Hash = EntryHash(Entry{
+		AccountID: args.AccountID,
+		Amount:    args.Amount,
+		CreatedAt: args.CreatedAt,
+		FeeRuleID: args.FeeRuleID,
+		PrevHash:  args.PrevHash,
+	})
+
+	entry, err := queries.CreateEntry(ctx, args)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	return entry, announceEntry(ctx, queries, entry)
+}
+
+// EntryHash returns the hash of an entry, covering its previous hash and every other column but the id
+func EntryHash(entry Entry) string {
+	h := sha256.New()
+
+	writeHashField(h, entry.PrevHash)
+	writeHashField(h, strconv.FormatInt(entry.AccountID, 10))
+	writeHashField(h, strconv.FormatInt(entry.Amount, 10))
+	writeHashField(h, strconv.FormatBool(entry.FeeRuleID.Valid))
+	writeHashField(h, strconv.FormatInt(entry.FeeRuleID.Int64, 10))
+	writeHashField(h, entry.CreatedAt.UTC().Format(time.RFC3339Nano))
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// VerifyEntryChain walks the entries of an account from its first one and reports the first entry that was altered,
+// removed or inserted out of band. Entries booked before the chain was introduced have no hash and are only counted.
+func (s *SQLStore) VerifyEntryChain(ctx context.Context, accountID int64) (EntryChainVerification, error) {
+	retval := EntryChainVerification{AccountID: accountID}
+	var afterID int64
+	chained := false
+
+	for {
+		entries, err := s.ListAccountEntriesAfter(ctx, ListAccountEntriesAfterParams{
+			AccountID: accountID,
+			ID:        afterID,
+			Limit:     entryChainVerifyBatch,
+		})
+		if err != nil {
+			return retval, err
+		}
+
+		for _, entry := range entries {
+			afterID = entry.ID
+
+			if !chained && entry.Hash == "" && entry.PrevHash == "" {
+				retval.Unchained++
+				continue
+			}
+			chained = true
+
+			if reason := verifyEntry(entry, retval.LastHash); reason != "" {
+				retval.BrokenAt = entry.ID
+				retval.Reason = reason
+				return retval, nil
+			}
+
+			retval.Entries++
+			retval.LastHash = entry.Hash
+		}
+
+		if len(entries) < entryChainVerifyBatch {
+			retval.Valid = true
+			return retval, nil
+		}
+	}
+}
+
+// verifyEntry explains why an entry doesn't follow prevHash or its own hash, it returns nothing for a sound entry
+func verifyEntry(entry Entry, prevHash string) string {
+	if entry.PrevHash != prevHash {
+		return "previous hash does not match the entry before"
+	}
+	if EntryHash(entry) != entry.Hash {
+		return "hash does not match the entry content"
+	}
+	return ""
+}
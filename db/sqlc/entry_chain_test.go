@@ -0,0 +1,112 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEntryHash(t *testing.T) {
+	entry := Entry{
+		AccountID: 7,
+		Amount:    -250,
+		FeeRuleID: pgtype.Int8{Int64: 3, Valid: true},
+		PrevHash:  "genesis",
+		CreatedAt: time.Date(2026, 1, 2, 3, 4, 5, 6000, time.UTC),
+	}
+	entry.Hash = EntryHash(entry)
+	require.Len(t, entry.Hash, 64)
+	require.Empty(t, verifyEntry(entry, "genesis"))
+	require.NotEmpty(t, verifyEntry(entry, "other"))
+
+	tampered := entry
+	tampered.Amount = 250
+	require.NotEmpty(t, verifyEntry(tampered, "genesis"))
+
+	moved := entry
+	moved.AccountID = 8
+	require.NotEmpty(t, verifyEntry(moved, "genesis"))
+
+	withoutFee := entry
+	withoutFee.FeeRuleID = pgtype.Int8{Int64: 3}
+	require.NotEqual(t, entry.Hash, EntryHash(withoutFee))
+}
+
+func TestEntryChain(t *testing.T) {
+	ctx := context.Background()
+	account1 := createFundedAccount(t, 1000)
+	account2 := CreateRandomAccount(t)
+
+	var results []TransferTxResult
+	for i := 0; i < 3; i++ {
+		result, err := testStore.TransferTx(ctx, TransferTxParams{
+			FromAccountID: account1.ID,
+			ToAccountID:   account2.ID,
+			Amount:        10,
+		})
+		require.NoError(t, err)
+		results = append(results, result)
+	}
+
+	// fee entries may sit between the sender's entries, the receiver only gets the amounts
+	require.Empty(t, results[0].ToEntry.PrevHash)
+	for i := 1; i < len(results); i++ {
+		require.Equal(t, results[i-1].ToEntry.Hash, results[i].ToEntry.PrevHash)
+	}
+
+	verification, err := testStore.VerifyEntryChain(ctx, account1.ID)
+	require.NoError(t, err)
+	require.True(t, verification.Valid)
+
+	verification, err = testStore.VerifyEntryChain(ctx, account2.ID)
+	require.NoError(t, err)
+	require.True(t, verification.Valid)
+	require.Equal(t, int64(3), verification.Entries)
+	require.Zero(t, verification.Unchained)
+	require.Equal(t, results[2].ToEntry.Hash, verification.LastHash)
+}
+
+func TestVerifyEntryChainTampered(t *testing.T) {
+	ctx := context.Background()
+	account := CreateRandomAccount(t)
+	first := CreateRandomEntry(t, account)
+	second := CreateRandomEntry(t, account)
+	CreateRandomEntry(t, account)
+
+	pool := testStore.(*SQLStore).db
+	_, err := pool.Exec(ctx, "UPDATE entries SET amount = amount + 1 WHERE id = $1", second.ID)
+	require.NoError(t, err)
+
+	verification, err := testStore.VerifyEntryChain(ctx, account.ID)
+	require.NoError(t, err)
+	require.False(t, verification.Valid)
+	require.Equal(t, second.ID, verification.BrokenAt)
+	require.Equal(t, int64(1), verification.Entries)
+	require.Equal(t, first.Hash, verification.LastHash)
+	require.NotEmpty(t, verification.Reason)
+}
+
+func TestVerifyEntryChainUnchained(t *testing.T) {
+	ctx := context.Background()
+	account := CreateRandomAccount(t)
+
+	// entries booked before the chain was introduced have no hash
+	_, err := testStore.CreateEntry(ctx, CreateEntryParams{
+		AccountID: account.ID,
+		Amount:    10,
+		CreatedAt: time.Now(),
+	})
+	require.NoError(t, err)
+	CreateRandomEntry(t, account)
+
+	verification, err := testStore.VerifyEntryChain(ctx, account.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), verification.Unchained)
+
+	// the first chained entry follows the legacy one, which has no hash to chain to
+	require.True(t, verification.Valid)
+	require.Equal(t, int64(1), verification.Entries)
+}
@@ -0,0 +1,150 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	commonerrors "github.com/primarybank/common/errors"
+)
+
+// postgres error codes translated into domain errors
+const (
+	foreignKeyViolation = "23503"
+	uniqueViolation     = "23505"
+	checkViolation      = "23514"
+	deadlockDetected    = "40P01"
+)
+
+// keyDetail matches the columns in the detail of key violations, e.g.
+// Key (owner, currency)=(bob, USD) already exists.
+var keyDetail = regexp.MustCompile(`^Key \((.+?)\)=`)
+
+var ErrNotFound = commonerrors.New(commonerrors.KindNotFound, "not_found", "resource not found")
+
+// errorMappingDB translates the errors of the driver into domain errors so that
+// callers of the store never have to know about pgx or postgres error codes
+type errorMappingDB struct {
+	db DBTX
+}
+
+func (d errorMappingDB) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	tag, err := d.db.Exec(ctx, sql, args...)
+	return tag, mapError(err)
+}
+
+func (d errorMappingDB) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	rows, err := d.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return errorMappingRows{rows}, nil
+}
+
+func (d errorMappingDB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return errorMappingRow{d.db.QueryRow(ctx, sql, args...)}
+}
+
+type errorMappingRow struct {
+	pgx.Row
+}
+
+func (r errorMappingRow) Scan(dest ...any) error {
+	return mapError(r.Row.Scan(dest...))
+}
+
+type errorMappingRows struct {
+	pgx.Rows
+}
+
+func (r errorMappingRows) Scan(dest ...any) error {
+	return mapError(r.Rows.Scan(dest...))
+}
+
+func (r errorMappingRows) Err() error {
+	return mapError(r.Rows.Err())
+}
+
+// mapError classifies a driver error, keeping it as the cause so errors.Is and errors.As still see it.
+// Deadlocks are left alone to be retried by the transactions.
+func mapError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if _, ok := commonerrors.As(err); ok {
+		return err
+	}
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return commonerrors.Wrap(err, ErrNotFound.Kind, ErrNotFound.Code, ErrNotFound.Message)
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case uniqueViolation:
+			return uniqueViolationError(err, pgErr)
+		case foreignKeyViolation:
+			return foreignKeyViolationError(err, pgErr)
+		case checkViolation:
+			return commonerrors.Wrap(err, commonerrors.KindValidation, "constraint_violation", "value violates a constraint")
+		}
+	}
+
+	return err
+}
+
+// uniqueViolationError names the fields whose values are already taken
+func uniqueViolationError(err error, pgErr *pgconn.PgError) error {
+	columns := keyColumns(pgErr)
+	if len(columns) == 0 {
+		return commonerrors.Wrap(err, commonerrors.KindConflict, "already_exists", "resource already exists")
+	}
+
+	message := fmt.Sprintf("%s already exists", strings.Join(columns, ", "))
+	return commonerrors.Wrap(err, commonerrors.KindConflict, "already_exists", message).
+		WithFields(fieldErrors(columns, "already exists")...)
+}
+
+// foreignKeyViolationError tells a reference to a missing row, which the client can fix,
+// from a delete of a row that is still referenced
+func foreignKeyViolationError(err error, pgErr *pgconn.PgError) error {
+	if strings.Contains(pgErr.Detail, "is still referenced") {
+		return commonerrors.Wrap(err, commonerrors.KindConflict, "still_referenced", "resource is still referenced by other resources")
+	}
+
+	columns := keyColumns(pgErr)
+	if len(columns) == 0 {
+		return commonerrors.Wrap(err, commonerrors.KindUnprocessable, "invalid_reference", "referenced resource does not exist")
+	}
+
+	message := fmt.Sprintf("%s does not exist", strings.Join(columns, ", "))
+	return commonerrors.Wrap(err, commonerrors.KindUnprocessable, "invalid_reference", message).
+		WithFields(fieldErrors(columns, "does not exist")...)
+}
+
+func keyColumns(pgErr *pgconn.PgError) []string {
+	match := keyDetail.FindStringSubmatch(pgErr.Detail)
+	if match == nil {
+		return nil
+	}
+
+	columns := strings.Split(match[1], ",")
+	for i := range columns {
+		columns[i] = strings.Trim(strings.TrimSpace(columns[i]), `"`)
+	}
+	return columns
+}
+
+func fieldErrors(columns []string, reason string) []commonerrors.FieldError {
+	fields := make([]commonerrors.FieldError, 0, len(columns))
+	for _, column := range columns {
+		fields = append(fields, commonerrors.FieldError{Field: column, Reason: reason})
+	}
+	return fields
+}
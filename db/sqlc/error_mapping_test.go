@@ -0,0 +1,96 @@
+package db
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	commonerrors "github.com/primarybank/common/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMapError(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		expected commonerrors.Kind
+		fields   []commonerrors.FieldError
+	}{
+		{
+			name:     "No Rows",
+			err:      pgx.ErrNoRows,
+			expected: commonerrors.KindNotFound,
+		},
+		{
+			name:     "Unique Violation",
+			err:      &pgconn.PgError{Code: uniqueViolation},
+			expected: commonerrors.KindConflict,
+		},
+		{
+			name: "Unique Violation On Columns",
+			err: &pgconn.PgError{
+				Code:   uniqueViolation,
+				Detail: "Key (owner, currency)=(bob, USD) already exists.",
+			},
+			expected: commonerrors.KindConflict,
+			fields: []commonerrors.FieldError{
+				{Field: "owner", Reason: "already exists"},
+				{Field: "currency", Reason: "already exists"},
+			},
+		},
+		{
+			name: "Missing Reference",
+			err: &pgconn.PgError{
+				Code:   foreignKeyViolation,
+				Detail: `Key (owner)=(bob) is not present in table "users".`,
+			},
+			expected: commonerrors.KindUnprocessable,
+			fields: []commonerrors.FieldError{
+				{Field: "owner", Reason: "does not exist"},
+			},
+		},
+		{
+			name: "Still Referenced",
+			err: &pgconn.PgError{
+				Code:   foreignKeyViolation,
+				Detail: `Key (id)=(1) is still referenced from table "entries".`,
+			},
+			expected: commonerrors.KindConflict,
+		},
+		{
+			name:     "Check Violation",
+			err:      &pgconn.PgError{Code: checkViolation},
+			expected: commonerrors.KindValidation,
+		},
+		{
+			name:     "Domain Error",
+			err:      &OverdraftError{AccountID: 1},
+			expected: commonerrors.KindInsufficientFunds,
+		},
+		{
+			name:     "Unknown",
+			err:      errors.New("conn closed"),
+			expected: commonerrors.KindInternal,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mapped := mapError(tc.err)
+			require.Equal(t, tc.expected, commonerrors.KindOf(mapped))
+			// the driver error is still reachable for the store itself
+			require.ErrorIs(t, mapped, tc.err)
+
+			if typed, ok := commonerrors.As(mapped); ok {
+				require.Equal(t, tc.fields, typed.Fields)
+			}
+		})
+	}
+
+	require.NoError(t, mapError(nil))
+
+	// deadlocks must stay retryable
+	deadlock := mapError(&pgconn.PgError{Code: deadlockDetected})
+	require.True(t, isRetryableError(deadlock))
+}
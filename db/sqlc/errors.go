@@ -0,0 +1,122 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	commonerrors "github.com/primarybank/common/errors"
+)
+
+var ErrOverdraftLimitExceeded = commonerrors.New(commonerrors.KindInsufficientFunds, "overdraft_limit_exceeded", "overdraft limit exceeded")
+
+// OverdraftError is returned when a debit would take an account below its overdraft limit
+type OverdraftError struct {
+	AccountID      int64
+	Balance        int64
+	OverdraftLimit int64
+}
+
+func (e *OverdraftError) Error() string {
+	return fmt.Sprintf("account %d balance %d would exceed overdraft limit %d", e.AccountID, e.Balance, e.OverdraftLimit)
+}
+
+func (e *OverdraftError) Unwrap() error {
+	return ErrOverdraftLimitExceeded
+}
+
+// checkOverdraft validates an account balance after it has been debited
+func checkOverdraft(account Account) error {
+	if account.Balance < -account.OverdraftLimit {
+		return &OverdraftError{
+			AccountID:      account.ID,
+			Balance:        account.Balance,
+			OverdraftLimit: account.OverdraftLimit,
+		}
+	}
+	return nil
+}
+
+var ErrTransactionLimitExceeded = commonerrors.New(commonerrors.KindUnprocessable, "transaction_limit_exceeded", "transaction limit exceeded")
+
+// TransactionLimitError is returned when an outgoing transaction would exceed a velocity limit
+type TransactionLimitError struct {
+	AccountID int64
+	Allowance TransactionAllowance
+	Amount    int64
+}
+
+func (e *TransactionLimitError) Error() string {
+	return fmt.Sprintf("%s %s limit of account %d does not allow an outgoing transaction of %d",
+		e.Allowance.Period, e.Allowance.Scope, e.AccountID, e.Amount)
+}
+
+func (e *TransactionLimitError) Unwrap() error {
+	return ErrTransactionLimitExceeded
+}
+
+var (
+	ErrSelfReview                = commonerrors.New(commonerrors.KindForbidden, "self_review", "transfer request cannot be reviewed by its initiator")
+	ErrTransferRequestNotPending = commonerrors.New(commonerrors.KindConflict, "transfer_request_not_pending", "transfer request is not pending")
+	ErrTransferRequestExpired    = commonerrors.New(commonerrors.KindConflict, "transfer_request_expired", "transfer request has expired")
+)
+
+// ErrFeeAccountCurrency is returned when a fee rule pays its fees into an account of another currency
+var ErrFeeAccountCurrency = commonerrors.New(commonerrors.KindInternal, "fee_account_currency", "fee account currency differs from the transfer currency")
+
+var ErrTransferNotPending = commonerrors.New(commonerrors.KindConflict, "transfer_not_pending", "transfer is not pending")
+
+// TransferStatusError is returned when a transfer that was already posted or voided is resolved again
+type TransferStatusError struct {
+	TransferID int64
+	Status     string
+}
+
+func (e *TransferStatusError) Error() string {
+	return fmt.Sprintf("transfer %d is already %s", e.TransferID, e.Status)
+}
+
+func (e *TransferStatusError) Unwrap() error {
+	return ErrTransferNotPending
+}
+
+// ErrInvalidPasswordResetToken does not tell unknown, used and expired tokens apart on purpose
+var ErrInvalidPasswordResetToken = commonerrors.New(commonerrors.KindValidation, "invalid_reset_token", "password reset token is invalid or has expired")
+
+var ErrMFAAlreadyEnabled = commonerrors.New(commonerrors.KindConflict, "mfa_already_enabled", "two-factor authentication is already enabled")
+
+var (
+	ErrLoginThrottled = commonerrors.New(commonerrors.KindTooManyRequests, "login_throttled", "too many failed login attempts")
+	ErrLoginLocked    = commonerrors.New(commonerrors.KindTooManyRequests, "login_locked", "login is temporarily locked")
+)
+
+// LoginThrottledError is returned for logins attempted before the delay earned by previous failures ran out
+// or while a username or client IP is locked out
+type LoginThrottledError struct {
+	Until  time.Time
+	Locked bool
+}
+
+func (e *LoginThrottledError) Error() string {
+	if e.Locked {
+		return fmt.Sprintf("login is locked after too many failed attempts until %s", e.Until.UTC().Format(time.RFC3339))
+	}
+	return fmt.Sprintf("too many failed login attempts, try again after %s", e.Until.UTC().Format(time.RFC3339))
+}
+
+func (e *LoginThrottledError) Unwrap() error {
+	if e.Locked {
+		return ErrLoginLocked
+	}
+	return ErrLoginThrottled
+}
+
+// RetryAfter tells how long the client has to wait before trying again
+func (e *LoginThrottledError) RetryAfter() time.Duration {
+	return time.Until(e.Until)
+}
+
+// ErrEntryNotLatest is returned when deleting an entry that other entries of its account were booked after
+var ErrEntryNotLatest = commonerrors.New(commonerrors.KindConflict, "entry_not_latest", "only the latest entry of an account can be deleted")
+
+// ErrEntryChained is returned when deleting an entry of an account whose entries are hash chained
+var ErrEntryChained = commonerrors.New(commonerrors.KindConflict, "entry_chained", "entries of a hash chained account cannot be deleted, book a correcting entry instead")
@@ -3,18 +3,16 @@ package db
 import (
 	"context"
 	"fmt"
-
-	"github.com/jackc/pgx/v5"
 )
 
-// execTx executes a func in a db transaction
+// execTx executes a func in a db transaction, a savepoint when ctx is part of an ExecAuditedTx
 func (s *SQLStore) execTx(ctx context.Context, fn func(queries *Queries) error) error {
-	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	tx, err := s.beginTx(ctx)
 	if err != nil {
-		return err
+		return mapError(err)
 	}
 
-	q := New(tx)
+	q := New(errorMappingDB{tx})
 	err = fn(q)
 	if err != nil {
 		if tErr := tx.Rollback(ctx); tErr != nil {
@@ -23,5 +21,5 @@ func (s *SQLStore) execTx(ctx context.Context, fn func(queries *Queries) error)
 		return err
 	}
 
-	return tx.Commit(ctx)
+	return mapError(tx.Commit(ctx))
 }
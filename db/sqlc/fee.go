@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const bpsDenominator = 10000
+
+// Calculate prices a transfer of amount according to the rule.
+// The free monthly allowance is not taken into account here.
+func (r FeeRule) Calculate(amount int64) int64 {
+	// split the multiplication so large amounts don't overflow
+	fee := r.FlatFee +
+		(amount/bpsDenominator)*int64(r.PercentageBps) +
+		(amount%bpsDenominator)*int64(r.PercentageBps)/bpsDenominator
+
+	if fee < r.MinFee {
+		fee = r.MinFee
+	}
+	if r.MaxFee > 0 && fee > r.MaxFee {
+		fee = r.MaxFee
+	}
+
+	return fee
+}
+
+// QuoteTransferFee returns the fee that TransferTx would charge right now for the given transfer
+func (s *SQLStore) QuoteTransferFee(ctx context.Context, args TransferTxParams) (FeeQuote, error) {
+	account, err := s.GetAccount(ctx, args.FromAccountID)
+	if err != nil {
+		return FeeQuote{}, err
+	}
+
+	return quoteFee(ctx, s.Queries, account, args.Amount, time.Now())
+}
+
+// quoteFee looks up the active fee rule for the account currency and prices the transfer,
+// waiving the fee while the account is still within its free monthly allowance
+func quoteFee(ctx context.Context, q *Queries, account Account, amount int64, now time.Time) (FeeQuote, error) {
+	quote := FeeQuote{
+		Amount:   amount,
+		Currency: account.Currency,
+		Total:    amount,
+	}
+
+	rule, err := q.GetActiveFeeRule(ctx, account.Currency)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return quote, nil
+		}
+		return quote, err
+	}
+	quote.FeeRuleID = pgtype.Int8{Int64: rule.ID, Valid: true}
+	quote.FeeAccountID = rule.FeeAccountID
+
+	// the fee is credited as it was charged, an account of another currency would take it at face value
+	feeAccount, err := q.GetAccount(ctx, rule.FeeAccountID)
+	if err != nil {
+		return quote, err
+	}
+	if feeAccount.Currency != account.Currency {
+		return quote, fmt.Errorf("%w: fee rule %d pays %s fees into account %d of %s",
+			ErrFeeAccountCurrency, rule.ID, account.Currency, feeAccount.ID, feeAccount.Currency)
+	}
+
+	if rule.FreeTransfersPerMonth > 0 {
+		used, err := q.CountTransfersSince(ctx, CountTransfersSinceParams{
+			FromAccountID: account.ID,
+			Since:         startOfMonth(now),
+		})
+		if err != nil {
+			return quote, err
+		}
+
+		if used < int64(rule.FreeTransfersPerMonth) {
+			return quote, nil
+		}
+	}
+
+	quote.Fee = rule.Calculate(amount)
+	quote.Total = amount + quote.Fee
+	return quote, nil
+}
+
+func startOfMonth(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,192 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	commonutils "github.com/primarybank/common/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func CreateRandomFeeRule(t *testing.T, currency string, args CreateFeeRuleParams) FeeRule {
+	ctx := context.Background()
+
+	// only one rule can be active per currency
+	active, err := testStore.GetActiveFeeRule(ctx, currency)
+	if err == nil {
+		require.NoError(t, testStore.DeactivateFeeRule(ctx, active.ID))
+	} else {
+		require.True(t, errors.Is(err, pgx.ErrNoRows))
+	}
+
+	args.Name = commonutils.RandomString(8)
+	args.Currency = currency
+	if args.FeeAccountID == 0 {
+		args.FeeAccountID = createAccountInCurrency(t, currency).ID
+	}
+
+	rule, err := testStore.CreateFeeRule(ctx, args)
+	require.NoError(t, err)
+	require.NotZero(t, rule.ID)
+	require.Equal(t, args.Currency, rule.Currency)
+	require.Equal(t, args.FlatFee, rule.FlatFee)
+	require.Equal(t, args.PercentageBps, rule.PercentageBps)
+	require.Equal(t, args.FeeAccountID, rule.FeeAccountID)
+	require.True(t, rule.IsActive)
+
+	// don't leak fees into the other transfer tests
+	t.Cleanup(func() {
+		require.NoError(t, testStore.DeactivateFeeRule(ctx, rule.ID))
+	})
+
+	return rule
+}
+
+func createAccountInCurrency(t *testing.T, currency string) Account {
+	account, err := testStore.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    CreateRandomUser(t).Username,
+		Currency: currency,
+	})
+	require.NoError(t, err)
+	return account
+}
+
+func TestCreateFeeRule(t *testing.T) {
+	CreateRandomFeeRule(t, commonutils.RandomCurrency(), CreateFeeRuleParams{FlatFee: 5})
+}
+
+func TestFeeRuleCalculate(t *testing.T) {
+	testCases := []struct {
+		name     string
+		rule     FeeRule
+		amount   int64
+		expected int64
+	}{
+		{
+			name:     "Flat",
+			rule:     FeeRule{FlatFee: 25},
+			amount:   1000,
+			expected: 25,
+		},
+		{
+			name:     "Percentage",
+			rule:     FeeRule{PercentageBps: 150},
+			amount:   1000,
+			expected: 15,
+		},
+		{
+			name:     "FlatAndPercentage",
+			rule:     FeeRule{FlatFee: 10, PercentageBps: 100},
+			amount:   1000,
+			expected: 20,
+		},
+		{
+			name:     "Min",
+			rule:     FeeRule{PercentageBps: 100, MinFee: 50},
+			amount:   1000,
+			expected: 50,
+		},
+		{
+			name:     "Max",
+			rule:     FeeRule{PercentageBps: 100, MaxFee: 5},
+			amount:   1000,
+			expected: 5,
+		},
+		{
+			name:     "LargeAmount",
+			rule:     FeeRule{PercentageBps: 10000},
+			amount:   1 << 62,
+			expected: 1 << 62,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, tc.rule.Calculate(tc.amount))
+		})
+	}
+}
+
+func TestTransferTxWithFee(t *testing.T) {
+	ctx := context.Background()
+
+	account1 := createFundedAccount(t, 1000)
+	account2 := CreateRandomAccount(t)
+	rule := CreateRandomFeeRule(t, account1.Currency, CreateFeeRuleParams{
+		FlatFee:               1,
+		PercentageBps:         100,
+		MinFee:                2,
+		MaxFee:                50,
+		FreeTransfersPerMonth: 1,
+	})
+
+	args := TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        100,
+	}
+
+	// the first transfer of the month is covered by the free allowance
+	retval, err := testStore.TransferTx(ctx, args)
+	require.NoError(t, err)
+	require.Zero(t, retval.Transfer.Fee)
+	require.Equal(t, rule.ID, retval.Transfer.FeeRuleID.Int64)
+	require.Nil(t, retval.FeeEntry)
+	require.Equal(t, account1.Balance-args.Amount, retval.FromAccount.Balance)
+
+	quote, err := testStore.QuoteTransferFee(ctx, args)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), quote.Fee)
+	require.Equal(t, args.Amount+quote.Fee, quote.Total)
+
+	retval, err = testStore.TransferTx(ctx, args)
+	require.NoError(t, err)
+	require.Equal(t, quote.Fee, retval.Transfer.Fee)
+	require.Equal(t, rule.ID, retval.Transfer.FeeRuleID.Int64)
+	require.Equal(t, account1.Balance-2*args.Amount-quote.Fee, retval.FromAccount.Balance)
+	require.Equal(t, account2.Balance+2*args.Amount, retval.ToAccount.Balance)
+
+	require.NotNil(t, retval.FeeEntry)
+	require.Equal(t, account1.ID, retval.FeeEntry.AccountID)
+	require.Equal(t, -quote.Fee, retval.FeeEntry.Amount)
+	require.Equal(t, rule.ID, retval.FeeEntry.FeeRuleID.Int64)
+
+	require.NotNil(t, retval.FeeIncomeEntry)
+	require.Equal(t, rule.FeeAccountID, retval.FeeIncomeEntry.AccountID)
+	require.Equal(t, quote.Fee, retval.FeeIncomeEntry.Amount)
+}
+
+func TestTransferTxFeeAccountCurrency(t *testing.T) {
+	ctx := context.Background()
+
+	account1 := createFundedAccount(t, 1000)
+	account2 := CreateRandomAccount(t)
+
+	otherCurrency := "USD"
+	if account1.Currency == otherCurrency {
+		otherCurrency = "EUR"
+	}
+	CreateRandomFeeRule(t, account1.Currency, CreateFeeRuleParams{
+		FlatFee:      5,
+		FeeAccountID: createAccountInCurrency(t, otherCurrency).ID,
+	})
+
+	args := TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	}
+
+	_, err := testStore.QuoteTransferFee(ctx, args)
+	require.ErrorIs(t, err, ErrFeeAccountCurrency)
+
+	// nothing is booked
+	_, err = testStore.TransferTx(ctx, args)
+	require.ErrorIs(t, err, ErrFeeAccountCurrency)
+
+	sender, err := testStore.GetAccount(ctx, account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, sender.Balance)
+}
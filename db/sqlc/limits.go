@@ -0,0 +1,176 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// transaction limit scopes and periods
+const (
+	LimitScopeAccount = "account"
+	LimitScopeUser    = "user"
+
+	LimitPeriodDaily   = "daily"
+	LimitPeriodMonthly = "monthly"
+)
+
+var limitScopes = []string{LimitScopeAccount, LimitScopeUser}
+var limitPeriods = []string{LimitPeriodDaily, LimitPeriodMonthly}
+
+// GetTransactionAllowances returns what is left of every outgoing limit that applies to the account
+func (s *SQLStore) GetTransactionAllowances(ctx context.Context, accountID int64) ([]TransactionAllowance, error) {
+	account, err := s.GetAccount(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	return transactionAllowances(ctx, s.Queries, account, time.Now())
+}
+
+// checkOutgoingLimits locks the owner of an already locked account and evaluates its transaction limits.
+// Holding the owner lock keeps concurrent debits from the owner's other accounts from both passing user wide limits.
+func checkOutgoingLimits(ctx context.Context, q *Queries, account Account, amount int64) error {
+	if _, err := q.GetUserForUpdate(ctx, account.Owner); err != nil {
+		return err
+	}
+
+	return checkTransactionLimits(ctx, q, account, amount, time.Now())
+}
+
+// checkTransactionLimits rejects an outgoing amount that would exceed any limit applying to the account.
+// The account, and its owner for user wide limits, must be locked by the calling transaction.
+func checkTransactionLimits(ctx context.Context, q *Queries, account Account, amount int64, now time.Time) error {
+	allowances, err := transactionAllowances(ctx, q, account, now)
+	if err != nil {
+		return err
+	}
+
+	for _, allowance := range allowances {
+		if !allowance.Allows(amount) {
+			return &TransactionLimitError{
+				AccountID: account.ID,
+				Allowance: allowance,
+				Amount:    amount,
+			}
+		}
+	}
+
+	return nil
+}
+
+func transactionAllowances(ctx context.Context, q *Queries, account Account, now time.Time) ([]TransactionAllowance, error) {
+	owner, err := q.GetUser(ctx, account.Owner)
+	if err != nil {
+		return nil, err
+	}
+
+	limits, err := q.ListApplicableTransactionLimits(ctx, ListApplicableTransactionLimitsParams{
+		AccountID: pgtype.Int8{Int64: account.ID, Valid: true},
+		Username:  pgtype.Text{String: owner.Username, Valid: true},
+		Role:      pgtype.Text{String: owner.Role, Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	effective := effectiveTransactionLimits(limits)
+
+	allowances := []TransactionAllowance{}
+	for _, scope := range limitScopes {
+		for _, period := range limitPeriods {
+			limit, ok := effective[scope+"/"+period]
+			if !ok || (limit.MaxAmount == 0 && limit.MaxCount == 0) {
+				continue
+			}
+
+			since, resetsAt := limitWindow(period, now)
+
+			var usedAmount, usedCount int64
+			if scope == LimitScopeAccount {
+				usage, err := q.GetAccountOutflowSince(ctx, GetAccountOutflowSinceParams{
+					AccountID: account.ID,
+					Since:     since,
+				})
+				if err != nil {
+					return nil, err
+				}
+				usedAmount, usedCount = usage.TotalAmount, usage.TotalCount
+			} else {
+				usage, err := q.GetUserOutflowSince(ctx, GetUserOutflowSinceParams{
+					Owner: owner.Username,
+					Since: since,
+				})
+				if err != nil {
+					return nil, err
+				}
+				usedAmount, usedCount = usage.TotalAmount, usage.TotalCount
+			}
+
+			allowances = append(allowances, newTransactionAllowance(limit, usedAmount, usedCount, resetsAt))
+		}
+	}
+
+	return allowances, nil
+}
+
+// effectiveTransactionLimits picks, for every scope and period, the admin override over the role default
+func effectiveTransactionLimits(limits []TransactionLimit) map[string]TransactionLimit {
+	effective := make(map[string]TransactionLimit)
+
+	for _, limit := range limits {
+		key := limit.Scope + "/" + limit.Period
+		if current, ok := effective[key]; ok && !current.Role.Valid {
+			continue
+		}
+		effective[key] = limit
+	}
+
+	return effective
+}
+
+// limitWindow returns when the current period started and when it resets
+func limitWindow(period string, now time.Time) (since time.Time, resetsAt time.Time) {
+	now = now.UTC()
+	if period == LimitPeriodDaily {
+		since = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		return since, since.AddDate(0, 0, 1)
+	}
+
+	since = startOfMonth(now)
+	return since, since.AddDate(0, 1, 0)
+}
+
+func newTransactionAllowance(limit TransactionLimit, usedAmount int64, usedCount int64, resetsAt time.Time) TransactionAllowance {
+	allowance := TransactionAllowance{
+		LimitID:    limit.ID,
+		Scope:      limit.Scope,
+		Period:     limit.Period,
+		MaxAmount:  limit.MaxAmount,
+		MaxCount:   limit.MaxCount,
+		UsedAmount: usedAmount,
+		UsedCount:  usedCount,
+		ResetsAt:   resetsAt,
+	}
+
+	if limit.MaxAmount > 0 {
+		allowance.RemainingAmount = max(limit.MaxAmount-usedAmount, 0)
+	}
+	if limit.MaxCount > 0 {
+		allowance.RemainingCount = max(limit.MaxCount-usedCount, 0)
+	}
+
+	return allowance
+}
+
+// Allows reports whether one more outgoing transaction of amount fits in the allowance
+func (a TransactionAllowance) Allows(amount int64) bool {
+	if a.MaxAmount > 0 && amount > a.RemainingAmount {
+		return false
+	}
+	if a.MaxCount > 0 && a.RemainingCount < 1 {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,185 @@
+package db
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func CreateAccountTransactionLimit(t *testing.T, account Account, period string, maxAmount int64, maxCount int64) TransactionLimit {
+	limit, err := testStore.CreateTransactionLimit(context.Background(), CreateTransactionLimitParams{
+		Scope:     LimitScopeAccount,
+		Period:    period,
+		AccountID: pgtype.Int8{Int64: account.ID, Valid: true},
+		MaxAmount: maxAmount,
+		MaxCount:  maxCount,
+	})
+	require.NoError(t, err)
+	require.Equal(t, account.ID, limit.AccountID.Int64)
+	require.False(t, limit.Role.Valid)
+
+	return limit
+}
+
+func TestTransferTxCountLimit(t *testing.T) {
+	ctx := context.Background()
+	account1 := createFundedAccount(t, 1000)
+	account2 := CreateRandomAccount(t)
+	limit := CreateAccountTransactionLimit(t, account1, LimitPeriodDaily, 0, 1)
+
+	args := TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	}
+
+	_, err := testStore.TransferTx(ctx, args)
+	require.NoError(t, err)
+
+	_, err = testStore.TransferTx(ctx, args)
+	require.ErrorIs(t, err, ErrTransactionLimitExceeded)
+
+	allowances, err := testStore.GetTransactionAllowances(ctx, account1.ID)
+	require.NoError(t, err)
+
+	var found bool
+	for _, allowance := range allowances {
+		if allowance.LimitID == limit.ID {
+			found = true
+			require.Equal(t, int64(1), allowance.UsedCount)
+			require.Zero(t, allowance.RemainingCount)
+		}
+	}
+	require.True(t, found)
+}
+
+func TestTransferTxAmountLimit(t *testing.T) {
+	ctx := context.Background()
+	account1 := createFundedAccount(t, 1000)
+	account2 := CreateRandomAccount(t)
+	CreateAccountTransactionLimit(t, account1, LimitPeriodMonthly, 100, 0)
+
+	_, err := testStore.TransferTx(ctx, TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        60,
+	})
+	require.NoError(t, err)
+
+	_, err = testStore.TransferTx(ctx, TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        60,
+	})
+	require.ErrorIs(t, err, ErrTransactionLimitExceeded)
+
+	_, err = testStore.WithdrawTx(ctx, WithdrawTxParams{
+		AccountID: account1.ID,
+		Amount:    40,
+	})
+	require.NoError(t, err)
+}
+
+func TestTransferTxLimitsVoided(t *testing.T) {
+	ctx := context.Background()
+	account1 := createFundedAccount(t, 1000)
+	account2 := CreateRandomAccount(t)
+	accountLimit := CreateAccountTransactionLimit(t, account1, LimitPeriodDaily, 100, 1)
+	userLimit, err := testStore.CreateTransactionLimit(ctx, CreateTransactionLimitParams{
+		Scope:     LimitScopeUser,
+		Period:    LimitPeriodMonthly,
+		Username:  pgtype.Text{String: account1.Owner, Valid: true},
+		MaxAmount: 100,
+		MaxCount:  1,
+	})
+	require.NoError(t, err)
+
+	args := TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        80,
+	}
+
+	reserved, err := testStore.ReserveTransferTx(ctx, args)
+	require.NoError(t, err)
+	_, err = testStore.VoidTransferTx(ctx, reserved.Transfer.ID)
+	require.NoError(t, err)
+
+	// the voided reservation gave its amount back, it uses none of the limits
+	requireLimitsUsed(t, account1.ID, 0, 0, accountLimit.ID, userLimit.ID)
+
+	_, err = testStore.TransferTx(ctx, args)
+	require.NoError(t, err)
+
+	requireLimitsUsed(t, account1.ID, 80, 1, accountLimit.ID, userLimit.ID)
+
+	_, err = testStore.TransferTx(ctx, args)
+	require.ErrorIs(t, err, ErrTransactionLimitExceeded)
+}
+
+// requireLimitsUsed checks the allowances of the limits of an account, leaving out the others applying to it
+func requireLimitsUsed(t *testing.T, accountID int64, amount int64, count int64, limitIDs ...int64) {
+	allowances, err := testStore.GetTransactionAllowances(context.Background(), accountID)
+	require.NoError(t, err)
+
+	found := 0
+	for _, allowance := range allowances {
+		if !slices.Contains(limitIDs, allowance.LimitID) {
+			continue
+		}
+		found++
+		require.Equal(t, amount, allowance.UsedAmount)
+		require.Equal(t, count, allowance.UsedCount)
+	}
+	require.Equal(t, len(limitIDs), found)
+}
+
+func TestEffectiveTransactionLimits(t *testing.T) {
+	roleDefault := TransactionLimit{
+		ID:        1,
+		Scope:     LimitScopeAccount,
+		Period:    LimitPeriodDaily,
+		Role:      pgtype.Text{String: RoleDepositor, Valid: true},
+		MaxAmount: 100,
+	}
+	override := TransactionLimit{
+		ID:        2,
+		Scope:     LimitScopeAccount,
+		Period:    LimitPeriodDaily,
+		AccountID: pgtype.Int8{Int64: 1, Valid: true},
+		MaxAmount: 500,
+	}
+	userDefault := TransactionLimit{
+		ID:        3,
+		Scope:     LimitScopeUser,
+		Period:    LimitPeriodDaily,
+		Role:      pgtype.Text{String: RoleDepositor, Valid: true},
+		MaxAmount: 1000,
+	}
+
+	for _, limits := range [][]TransactionLimit{
+		{roleDefault, override, userDefault},
+		{override, roleDefault, userDefault},
+	} {
+		effective := effectiveTransactionLimits(limits)
+		require.Len(t, effective, 2)
+		require.Equal(t, override.ID, effective[LimitScopeAccount+"/"+LimitPeriodDaily].ID)
+		require.Equal(t, userDefault.ID, effective[LimitScopeUser+"/"+LimitPeriodDaily].ID)
+	}
+}
+
+func TestLimitWindow(t *testing.T) {
+	now := time.Date(2024, time.February, 29, 15, 4, 5, 0, time.UTC)
+
+	since, resetsAt := limitWindow(LimitPeriodDaily, now)
+	require.Equal(t, time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC), since)
+	require.Equal(t, time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC), resetsAt)
+
+	since, resetsAt = limitWindow(LimitPeriodMonthly, now)
+	require.Equal(t, time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC), since)
+	require.Equal(t, time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC), resetsAt)
+}
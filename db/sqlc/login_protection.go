@@ -0,0 +1,154 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	commonutils "github.com/primarybank/common/utils"
+)
+
+// failed logins are counted per username and per client IP
+const (
+	LoginScopeUsername = "username"
+	LoginScopeClientIP = "client_ip"
+)
+
+// outcomes of recorded login attempts
+const (
+	LoginOutcomeSuccess   = "success"
+	LoginOutcomeFailure   = "failure"
+	LoginOutcomeThrottled = "throttled"
+)
+
+// LoginPolicy decides how failed logins slow down and lock out further attempts.
+// A zero field disables the matching protection.
+type LoginPolicy struct {
+	// failures within Window after which a username or a client IP is locked for LockoutDuration
+	MaxFailures     int32
+	MaxIPFailures   int32
+	Window          time.Duration
+	LockoutDuration time.Duration
+	// every failure doubles the wait before the next attempt, starting at BaseDelay and capped at MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// Delay returns how long after the last of the given consecutive failures the next attempt is refused
+func (p LoginPolicy) Delay(failures int32) time.Duration {
+	return commonutils.Backoff(p.BaseDelay, p.MaxDelay, failures)
+}
+
+func (p LoginPolicy) maxFailures(scope string) int32 {
+	if scope == LoginScopeClientIP {
+		return p.MaxIPFailures
+	}
+	return p.MaxFailures
+}
+
+// CheckLoginThrottle returns a LoginThrottledError when the username or the client IP is locked out
+// or still waiting out the delay of its last failure.
+// The throttles stay locked until the transaction of ctx ends, a login checked and recorded in one transaction
+// holds back the concurrent attempts on its username and client IP until its outcome is counted.
+func (s *SQLStore) CheckLoginThrottle(ctx context.Context, username string, clientIP string, policy LoginPolicy) error {
+	throttles, err := s.GetLoginThrottlesForUpdate(ctx, GetLoginThrottlesForUpdateParams{
+		ClientIp: clientIP,
+		Username: username,
+	})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	var retval *LoginThrottledError
+	for _, throttle := range throttles {
+		if throttle.LockedUntil.Valid && throttle.LockedUntil.Time.After(now) {
+			if retval == nil || !retval.Locked || throttle.LockedUntil.Time.After(retval.Until) {
+				retval = &LoginThrottledError{Until: throttle.LockedUntil.Time, Locked: true}
+			}
+			continue
+		}
+
+		if retval != nil && retval.Locked {
+			continue
+		}
+
+		until := throttle.LastFailedAt.Add(policy.Delay(throttle.FailedAttempts))
+		if until.After(now) && (retval == nil || until.After(retval.Until)) {
+			retval = &LoginThrottledError{Until: until}
+		}
+	}
+
+	if retval != nil {
+		return retval
+	}
+	return nil
+}
+
+// RecordLoginAttemptTx logs a login attempt. Failures count against the username and the client IP,
+// locking them out once the policy allows no more, and a success clears the failures of the username.
+func (s *SQLStore) RecordLoginAttemptTx(ctx context.Context, args RecordLoginAttemptTxParams) (LoginEvent, error) {
+	var retval LoginEvent
+
+	err := s.execTx(ctx, func(queries *Queries) error {
+		var err error
+
+		retval, err = queries.CreateLoginEvent(ctx, CreateLoginEventParams{
+			Username:  args.Username,
+			ClientIp:  args.ClientIP,
+			UserAgent: args.UserAgent,
+			Outcome:   args.Outcome,
+		})
+		if err != nil {
+			return err
+		}
+
+		switch args.Outcome {
+		case LoginOutcomeFailure:
+			if err := countLoginFailure(ctx, queries, LoginScopeUsername, args.Username, args.Policy); err != nil {
+				return err
+			}
+			return countLoginFailure(ctx, queries, LoginScopeClientIP, args.ClientIP, args.Policy)
+		case LoginOutcomeSuccess:
+			_, err = queries.ResetLoginThrottle(ctx, ResetLoginThrottleParams{
+				Scope:   LoginScopeUsername,
+				Subject: args.Username,
+			})
+			return err
+		}
+
+		return nil
+	})
+
+	return retval, err
+}
+
+func countLoginFailure(ctx context.Context, q *Queries, scope string, subject string, policy LoginPolicy) error {
+	// without a window failures keep counting until a successful login
+	var windowStart time.Time
+	if policy.Window > 0 {
+		windowStart = time.Now().Add(-policy.Window)
+	}
+
+	throttle, err := q.RecordLoginFailure(ctx, RecordLoginFailureParams{
+		Scope:       scope,
+		Subject:     subject,
+		WindowStart: windowStart,
+	})
+	if err != nil {
+		return err
+	}
+
+	maxFailures := policy.maxFailures(scope)
+	if maxFailures == 0 || policy.LockoutDuration == 0 || throttle.FailedAttempts < maxFailures {
+		return nil
+	}
+
+	_, err = q.LockLogin(ctx, LockLoginParams{
+		Scope:       scope,
+		Subject:     subject,
+		LockedUntil: pgtype.Timestamptz{Time: time.Now().Add(policy.LockoutDuration), Valid: true},
+	})
+	return err
+}
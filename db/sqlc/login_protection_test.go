@@ -0,0 +1,192 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	commonerrors "github.com/primarybank/common/errors"
+	commonutils "github.com/primarybank/common/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoginPolicyDelay(t *testing.T) {
+	policy := LoginPolicy{BaseDelay: time.Second, MaxDelay: 30 * time.Second}
+
+	require.Zero(t, policy.Delay(0))
+	require.Equal(t, time.Second, policy.Delay(1))
+	require.Equal(t, 2*time.Second, policy.Delay(2))
+	require.Equal(t, 16*time.Second, policy.Delay(5))
+	require.Equal(t, 30*time.Second, policy.Delay(6))
+	require.Equal(t, 30*time.Second, policy.Delay(1000))
+
+	// no delays without a base, and no growth without a cap
+	require.Zero(t, LoginPolicy{MaxDelay: time.Minute}.Delay(3))
+	require.Equal(t, time.Second, LoginPolicy{BaseDelay: time.Second}.Delay(3))
+}
+
+func TestLoginThrottledError(t *testing.T) {
+	err := error(&LoginThrottledError{Until: time.Now().Add(time.Minute), Locked: true})
+	require.ErrorIs(t, err, ErrLoginLocked)
+	require.Equal(t, commonerrors.KindTooManyRequests, commonerrors.KindOf(err))
+
+	err = &LoginThrottledError{Until: time.Now().Add(time.Minute)}
+	require.ErrorIs(t, err, ErrLoginThrottled)
+	require.InDelta(t, time.Minute, err.(*LoginThrottledError).RetryAfter(), float64(time.Second))
+}
+
+func recordLoginAttempt(t *testing.T, username string, clientIP string, outcome string, policy LoginPolicy) {
+	event, err := testStore.RecordLoginAttemptTx(context.Background(), RecordLoginAttemptTxParams{
+		Username:  username,
+		ClientIP:  clientIP,
+		UserAgent: "test",
+		Outcome:   outcome,
+		Policy:    policy,
+	})
+	require.NoError(t, err)
+	require.Equal(t, username, event.Username)
+	require.Equal(t, outcome, event.Outcome)
+}
+
+func TestLoginLockout(t *testing.T) {
+	ctx := context.Background()
+	username := commonutils.RandomString(8)
+	clientIP := "198.51.100." + commonutils.RandomString(3)
+	policy := LoginPolicy{
+		MaxFailures:     3,
+		MaxIPFailures:   100,
+		Window:          time.Minute,
+		LockoutDuration: time.Hour,
+	}
+
+	require.NoError(t, testStore.CheckLoginThrottle(ctx, username, clientIP, policy))
+
+	for i := 0; i < 2; i++ {
+		recordLoginAttempt(t, username, clientIP, LoginOutcomeFailure, policy)
+		require.NoError(t, testStore.CheckLoginThrottle(ctx, username, clientIP, policy))
+	}
+
+	recordLoginAttempt(t, username, clientIP, LoginOutcomeFailure, policy)
+
+	err := testStore.CheckLoginThrottle(ctx, username, clientIP, policy)
+	var throttled *LoginThrottledError
+	require.True(t, errors.As(err, &throttled))
+	require.True(t, throttled.Locked)
+	require.WithinDuration(t, time.Now().Add(time.Hour), throttled.Until, time.Minute)
+
+	// the lockout follows the username to other clients
+	err = testStore.CheckLoginThrottle(ctx, username, "192.0.2.1", policy)
+	require.ErrorIs(t, err, ErrLoginLocked)
+
+	rows, err := testStore.ResetLoginThrottle(ctx, ResetLoginThrottleParams{Scope: LoginScopeUsername, Subject: username})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), rows)
+	require.NoError(t, testStore.CheckLoginThrottle(ctx, username, clientIP, policy))
+
+	// the client IP kept counting, the check left the username a throttle without failures
+	throttles, err := testStore.GetLoginThrottles(ctx, GetLoginThrottlesParams{Username: username, ClientIp: clientIP})
+	require.NoError(t, err)
+	require.Len(t, throttles, 2)
+	for _, throttle := range throttles {
+		if throttle.Scope == LoginScopeClientIP {
+			require.Equal(t, int32(3), throttle.FailedAttempts)
+		} else {
+			require.Zero(t, throttle.FailedAttempts)
+		}
+	}
+}
+
+func TestLoginLockoutConcurrent(t *testing.T) {
+	username := commonutils.RandomString(8)
+	clientIP := "198.51.100." + commonutils.RandomString(3)
+	policy := LoginPolicy{
+		MaxFailures:     3,
+		MaxIPFailures:   100,
+		Window:          time.Minute,
+		LockoutDuration: time.Hour,
+	}
+
+	// every attempt checks and records its failure in one transaction, like a login request
+	n := 10
+	var passed atomic.Int32
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			errs <- testStore.ExecAuditedTx(context.Background(), func(ctx context.Context) (*AppendAuditLogTxParams, error) {
+				err := testStore.CheckLoginThrottle(ctx, username, clientIP, policy)
+				if errors.Is(err, ErrLoginLocked) {
+					return nil, nil
+				}
+				if err != nil {
+					return nil, err
+				}
+
+				passed.Add(1)
+				_, err = testStore.RecordLoginAttemptTx(ctx, RecordLoginAttemptTxParams{
+					Username: username,
+					ClientIP: clientIP,
+					Outcome:  LoginOutcomeFailure,
+					Policy:   policy,
+				})
+				return nil, err
+			})
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		require.NoError(t, <-errs)
+	}
+	require.Equal(t, policy.MaxFailures, passed.Load())
+}
+
+func TestLoginDelay(t *testing.T) {
+	ctx := context.Background()
+	username := commonutils.RandomString(8)
+	clientIP := "198.51.100." + commonutils.RandomString(3)
+	policy := LoginPolicy{BaseDelay: time.Minute, MaxDelay: time.Hour}
+
+	recordLoginAttempt(t, username, clientIP, LoginOutcomeFailure, policy)
+	recordLoginAttempt(t, username, clientIP, LoginOutcomeFailure, policy)
+
+	err := testStore.CheckLoginThrottle(ctx, username, clientIP, policy)
+	var throttled *LoginThrottledError
+	require.True(t, errors.As(err, &throttled))
+	require.False(t, throttled.Locked)
+	require.WithinDuration(t, time.Now().Add(2*time.Minute), throttled.Until, 10*time.Second)
+
+	// a success clears the username but not the client IP
+	recordLoginAttempt(t, username, clientIP, LoginOutcomeSuccess, policy)
+	require.NoError(t, testStore.CheckLoginThrottle(ctx, username, "192.0.2.1", policy))
+	require.ErrorIs(t, testStore.CheckLoginThrottle(ctx, username, clientIP, policy), ErrLoginThrottled)
+}
+
+func TestListLoginEvents(t *testing.T) {
+	ctx := context.Background()
+	username := commonutils.RandomString(8)
+	clientIP := "198.51.100." + commonutils.RandomString(3)
+
+	recordLoginAttempt(t, username, clientIP, LoginOutcomeFailure, LoginPolicy{})
+	recordLoginAttempt(t, username, clientIP, LoginOutcomeThrottled, LoginPolicy{})
+	recordLoginAttempt(t, username, clientIP, LoginOutcomeSuccess, LoginPolicy{})
+
+	events, err := testStore.ListLoginEvents(ctx, ListLoginEventsParams{
+		Username:   pgtype.Text{String: username, Valid: true},
+		LimitCount: 10,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	require.Equal(t, LoginOutcomeSuccess, events[0].Outcome)
+	require.Equal(t, LoginOutcomeFailure, events[2].Outcome)
+
+	events, err = testStore.ListLoginEvents(ctx, ListLoginEventsParams{
+		ClientIp:   pgtype.Text{String: clientIP, Valid: true},
+		Outcome:    pgtype.Text{String: LoginOutcomeThrottled, Valid: true},
+		LimitCount: 10,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, username, events[0].Username)
+}
@@ -0,0 +1,60 @@
+package db
+
+import "context"
+
+// ConfirmMFATx enables the enrolled TOTP factor of a user and replaces their recovery codes
+func (s *SQLStore) ConfirmMFATx(ctx context.Context, args ConfirmMFATxParams) (MfaFactor, error) {
+	var retval MfaFactor
+
+	err := s.execTx(ctx, func(queries *Queries) error {
+		factor, err := queries.GetMFAFactorForUpdate(ctx, args.Username)
+		if err != nil {
+			return err
+		}
+
+		if factor.ConfirmedAt.Valid {
+			return ErrMFAAlreadyEnabled
+		}
+
+		retval, err = queries.ConfirmMFAFactor(ctx, ConfirmMFAFactorParams{
+			Username:     args.Username,
+			LastUsedStep: args.Step,
+		})
+		if err != nil {
+			return err
+		}
+
+		return replaceRecoveryCodes(ctx, queries, args.Username, args.RecoveryCodeHashes)
+	})
+
+	return retval, err
+}
+
+// DisableMFATx removes the TOTP factor of a user along with their recovery codes
+func (s *SQLStore) DisableMFATx(ctx context.Context, username string) error {
+	return s.execTx(ctx, func(queries *Queries) error {
+		if err := queries.DeleteMFARecoveryCodes(ctx, username); err != nil {
+			return err
+		}
+
+		return queries.DeleteMFAFactor(ctx, username)
+	})
+}
+
+func replaceRecoveryCodes(ctx context.Context, q *Queries, username string, codeHashes []string) error {
+	if err := q.DeleteMFARecoveryCodes(ctx, username); err != nil {
+		return err
+	}
+
+	for _, codeHash := range codeHashes {
+		err := q.CreateMFARecoveryCode(ctx, CreateMFARecoveryCodeParams{
+			Username: username,
+			CodeHash: codeHash,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
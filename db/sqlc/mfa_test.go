@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	commonutils "github.com/primarybank/common/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func enrollRandomMFAFactor(t *testing.T, user User) MfaFactor {
+	factor, err := testStore.UpsertMFAFactor(context.Background(), UpsertMFAFactorParams{
+		Username:         user.Username,
+		SecretCiphertext: []byte(commonutils.RandomString(32)),
+	})
+	require.NoError(t, err)
+	require.False(t, factor.ConfirmedAt.Valid)
+
+	return factor
+}
+
+func TestConfirmMFATx(t *testing.T) {
+	ctx := context.Background()
+	user := CreateRandomUser(t)
+	enrollRandomMFAFactor(t, user)
+
+	// codes of unconfirmed factors are never accepted
+	_, err := testStore.UseMFAStep(ctx, UseMFAStepParams{Username: user.Username, Step: 100})
+	require.ErrorIs(t, err, ErrNotFound)
+
+	recoveryCode := commonutils.RandomString(10)
+	factor, err := testStore.ConfirmMFATx(ctx, ConfirmMFATxParams{
+		Username:           user.Username,
+		Step:               100,
+		RecoveryCodeHashes: []string{commonutils.HashSecret(recoveryCode), commonutils.HashSecret("other")},
+	})
+	require.NoError(t, err)
+	require.True(t, factor.ConfirmedAt.Valid)
+	require.Equal(t, int64(100), factor.LastUsedStep)
+
+	_, err = testStore.ConfirmMFATx(ctx, ConfirmMFATxParams{Username: user.Username, Step: 101})
+	require.ErrorIs(t, err, ErrMFAAlreadyEnabled)
+
+	// the confirming step and earlier ones are replays
+	_, err = testStore.UseMFAStep(ctx, UseMFAStepParams{Username: user.Username, Step: 100})
+	require.ErrorIs(t, err, ErrNotFound)
+
+	factor, err = testStore.UseMFAStep(ctx, UseMFAStepParams{Username: user.Username, Step: 101})
+	require.NoError(t, err)
+	require.Equal(t, int64(101), factor.LastUsedStep)
+
+	// recovery codes are single use
+	args := UseMFARecoveryCodeParams{Username: user.Username, CodeHash: commonutils.HashSecret(recoveryCode)}
+	_, err = testStore.UseMFARecoveryCode(ctx, args)
+	require.NoError(t, err)
+	_, err = testStore.UseMFARecoveryCode(ctx, args)
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestDisableMFATx(t *testing.T) {
+	ctx := context.Background()
+	user := CreateRandomUser(t)
+	enrollRandomMFAFactor(t, user)
+
+	_, err := testStore.ConfirmMFATx(ctx, ConfirmMFATxParams{
+		Username:           user.Username,
+		Step:               1,
+		RecoveryCodeHashes: []string{commonutils.HashSecret("code")},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, testStore.DisableMFATx(ctx, user.Username))
+
+	_, err = testStore.GetMFAFactor(ctx, user.Username)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	_, err = testStore.UseMFARecoveryCode(ctx, UseMFARecoveryCodeParams{Username: user.Username, CodeHash: commonutils.HashSecret("code")})
+	require.ErrorIs(t, err, ErrNotFound)
+}
@@ -6,6 +6,8 @@ package db
 
 import (
 	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type Account struct {
@@ -14,24 +16,187 @@ type Account struct {
 	Balance   int64     `json:"balance"`
 	Currency  string    `json:"currency"`
 	CreatedAt time.Time `json:"created_at"`
+	// how far below zero the balance may go
+	OverdraftLimit int64 `json:"overdraft_limit"`
+}
+
+type ApiKey struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	Name     string `json:"name"`
+	// public part of the key used to look it up, the secret part is only stored as sha256
+	Prefix     string             `json:"prefix"`
+	SecretHash string             `json:"secret_hash"`
+	Scopes     []string           `json:"scopes"`
+	ExpiresAt  pgtype.Timestamptz `json:"expires_at"`
+	LastUsedAt pgtype.Timestamptz `json:"last_used_at"`
+	RevokedAt  pgtype.Timestamptz `json:"revoked_at"`
+	CreatedAt  time.Time          `json:"created_at"`
+}
+
+type AuditLog struct {
+	ID int64 `json:"id"`
+	// username of the authenticated caller, null for anonymous requests
+	Actor        pgtype.Text `json:"actor"`
+	Action       string      `json:"action"`
+	ResourceType string      `json:"resource_type"`
+	ResourceID   string      `json:"resource_id"`
+	// json rather than jsonb so the snapshots keep the exact bytes they were hashed with
+	Before    []byte `json:"before"`
+	After     []byte `json:"after"`
+	RequestID string `json:"request_id"`
+	ClientIp  string `json:"client_ip"`
+	PrevHash  string `json:"prev_hash"`
+	// sha256 of prev_hash and every other column but id, chaining each row to the previous one
+	Hash      string    `json:"hash"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type Entry struct {
 	ID        int64 `json:"id"`
 	AccountID int64 `json:"account_id"`
 	// can be negative or positive
-	Amount    int64     `json:"amount"`
+	Amount    int64       `json:"amount"`
+	CreatedAt time.Time   `json:"created_at"`
+	FeeRuleID pgtype.Int8 `json:"fee_rule_id"`
+	PrevHash  string      `json:"prev_hash"`
+	// sha256 of prev_hash and every other column but id, chaining each entry to the previous entry of its account
+	Hash string `json:"hash"`
+}
+
+type FeeRule struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	Currency string `json:"currency"`
+	FlatFee  int64  `json:"flat_fee"`
+	// percentage fee in basis points, 100 = 1%
+	PercentageBps int32 `json:"percentage_bps"`
+	MinFee        int64 `json:"min_fee"`
+	// 0 means the fee is not capped
+	MaxFee                int64     `json:"max_fee"`
+	FreeTransfersPerMonth int32     `json:"free_transfers_per_month"`
+	FeeAccountID          int64     `json:"fee_account_id"`
+	IsActive              bool      `json:"is_active"`
+	CreatedAt             time.Time `json:"created_at"`
+}
+
+type LoginEvent struct {
+	ID int64 `json:"id"`
+	// as typed by the client, attempts on unknown usernames are recorded too
+	Username  string    `json:"username"`
+	ClientIp  string    `json:"client_ip"`
+	UserAgent string    `json:"user_agent"`
+	Outcome   string    `json:"outcome"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
-type Transfer struct {
+type LoginThrottle struct {
+	Scope   string `json:"scope"`
+	Subject string `json:"subject"`
+	// consecutive failures, counting restarts once a failure window passes without any
+	FailedAttempts int32              `json:"failed_attempts"`
+	LastFailedAt   time.Time          `json:"last_failed_at"`
+	LockedUntil    pgtype.Timestamptz `json:"locked_until"`
+}
+
+type MfaFactor struct {
+	Username string `json:"username"`
+	// TOTP secret encrypted with MFA_ENCRYPTION_KEY
+	SecretCiphertext []byte             `json:"secret_ciphertext"`
+	ConfirmedAt      pgtype.Timestamptz `json:"confirmed_at"`
+	// TOTP time step of the last accepted code, codes of earlier steps are replays
+	LastUsedStep int64     `json:"last_used_step"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type MfaRecoveryCode struct {
+	ID        int64              `json:"id"`
+	Username  string             `json:"username"`
+	CodeHash  string             `json:"code_hash"`
+	UsedAt    pgtype.Timestamptz `json:"used_at"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+type OutboxEvent struct {
+	ID            int64     `json:"id"`
+	EventType     string    `json:"event_type"`
+	AggregateType string    `json:"aggregate_type"`
+	AggregateID   string    `json:"aggregate_id"`
+	Payload       []byte    `json:"payload"`
+	Attempts      int32     `json:"attempts"`
+	LastError     string    `json:"last_error"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	// null until the event was handed to the publisher
+	PublishedAt pgtype.Timestamptz `json:"published_at"`
+	CreatedAt   time.Time          `json:"created_at"`
+}
+
+type OverdraftLimitChange struct {
 	ID            int64     `json:"id"`
-	FromAccountID int64     `json:"from_account_id"`
-	ToAccountID   int64     `json:"to_account_id"`
-	Amount        int64     `json:"amount"`
+	AccountID     int64     `json:"account_id"`
+	PreviousLimit int64     `json:"previous_limit"`
+	NewLimit      int64     `json:"new_limit"`
+	ChangedBy     string    `json:"changed_by"`
+	Reason        string    `json:"reason"`
 	CreatedAt     time.Time `json:"created_at"`
 }
 
+type PasswordResetToken struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	// sha256 of the token mailed to the user, the token itself is never stored
+	TokenHash string             `json:"token_hash"`
+	ExpiresAt time.Time          `json:"expires_at"`
+	UsedAt    pgtype.Timestamptz `json:"used_at"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+type TransactionLimit struct {
+	ID     int64  `json:"id"`
+	Scope  string `json:"scope"`
+	Period string `json:"period"`
+	// default limit for every user with this role
+	Role      pgtype.Text `json:"role"`
+	Username  pgtype.Text `json:"username"`
+	AccountID pgtype.Int8 `json:"account_id"`
+	// 0 means the amount is not capped
+	MaxAmount int64 `json:"max_amount"`
+	// 0 means the count is not capped
+	MaxCount  int64     `json:"max_count"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type Transfer struct {
+	ID            int64       `json:"id"`
+	FromAccountID int64       `json:"from_account_id"`
+	ToAccountID   int64       `json:"to_account_id"`
+	Amount        int64       `json:"amount"`
+	CreatedAt     time.Time   `json:"created_at"`
+	Fee           int64       `json:"fee"`
+	FeeRuleID     pgtype.Int8 `json:"fee_rule_id"`
+	// pending transfers are reserved on the sender and still have to be posted or voided
+	Status   string             `json:"status"`
+	PostedAt pgtype.Timestamptz `json:"posted_at"`
+	VoidedAt pgtype.Timestamptz `json:"voided_at"`
+}
+
+type TransferRequest struct {
+	ID            int64 `json:"id"`
+	FromAccountID int64 `json:"from_account_id"`
+	ToAccountID   int64 `json:"to_account_id"`
+	Amount        int64 `json:"amount"`
+	// pending, approved, rejected or expired
+	Status       string             `json:"status"`
+	InitiatedBy  string             `json:"initiated_by"`
+	ReviewedBy   pgtype.Text        `json:"reviewed_by"`
+	ReviewReason pgtype.Text        `json:"review_reason"`
+	TransferID   pgtype.Int8        `json:"transfer_id"`
+	ExpiresAt    time.Time          `json:"expires_at"`
+	ReviewedAt   pgtype.Timestamptz `json:"reviewed_at"`
+	CreatedAt    time.Time          `json:"created_at"`
+}
+
 type User struct {
 	Username  string    `json:"username"`
 	Password  string    `json:"password"`
@@ -39,4 +204,41 @@ type User struct {
 	Email     string    `json:"email"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	Role      string    `json:"role"`
+	// null until the user proves they own the email address
+	EmailVerifiedAt pgtype.Timestamptz `json:"email_verified_at"`
+}
+
+type WebhookDelivery struct {
+	ID             int64  `json:"id"`
+	SubscriptionID int64  `json:"subscription_id"`
+	EventID        int64  `json:"event_id"`
+	EventType      string `json:"event_type"`
+	// pending, succeeded, or dead once every attempt failed
+	Status   string `json:"status"`
+	Attempts int32  `json:"attempts"`
+	// moved ahead while a dispatcher holds the delivery, so it is retried if the dispatcher dies
+	NextAttemptAt time.Time          `json:"next_attempt_at"`
+	DeliveredAt   pgtype.Timestamptz `json:"delivered_at"`
+	CreatedAt     time.Time          `json:"created_at"`
+}
+
+type WebhookDeliveryAttempt struct {
+	ID         int64 `json:"id"`
+	DeliveryID int64 `json:"delivery_id"`
+	// null when the receiver could not be reached
+	ResponseStatus pgtype.Int4 `json:"response_status"`
+	Error          string      `json:"error"`
+	DurationMs     int64       `json:"duration_ms"`
+	CreatedAt      time.Time   `json:"created_at"`
+}
+
+type WebhookSubscription struct {
+	ID         int64    `json:"id"`
+	Username   string   `json:"username"`
+	Url        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	// signing secret encrypted with the webhook encryption key, it is needed in the clear to sign deliveries
+	SecretCiphertext []byte    `json:"secret_ciphertext"`
+	CreatedAt        time.Time `json:"created_at"`
 }
@@ -1,9 +1,189 @@
 package db
 
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type TransferTxParams struct {
+	FromAccountID int64 `json:"from_account_id"`
+	ToAccountID   int64 `json:"to_account_id"`
+	Amount        int64 `json:"amount"`
+}
+
 type TransferTxResult struct {
 	Transfer    Transfer `json:"transfer"`
 	FromAccount Account  `json:"from_account"`
 	ToAccount   Account  `json:"to_account"`
 	FromEntry   Entry    `json:"from_entry"`
 	ToEntry     Entry    `json:"to_entry"`
+	// fee entries are only created when a non zero fee is charged
+	FeeEntry       *Entry `json:"fee_entry,omitempty"`
+	FeeIncomeEntry *Entry `json:"fee_income_entry,omitempty"`
+}
+
+type VoidTransferTxResult struct {
+	Transfer       Transfer `json:"transfer"`
+	FromAccount    Account  `json:"from_account"`
+	RefundEntry    Entry    `json:"refund_entry"`
+	FeeRefundEntry *Entry   `json:"fee_refund_entry,omitempty"`
+}
+
+type FeeQuote struct {
+	Amount       int64       `json:"amount"`
+	Fee          int64       `json:"fee"`
+	Total        int64       `json:"total"`
+	Currency     string      `json:"currency"`
+	FeeRuleID    pgtype.Int8 `json:"fee_rule_id"`
+	FeeAccountID int64       `json:"-"`
+}
+
+type SetOverdraftLimitTxParams struct {
+	AccountID      int64  `json:"account_id"`
+	OverdraftLimit int64  `json:"overdraft_limit"`
+	ChangedBy      string `json:"changed_by"`
+	Reason         string `json:"reason"`
+}
+
+type SetOverdraftLimitTxResult struct {
+	Account Account              `json:"account"`
+	Change  OverdraftLimitChange `json:"change"`
+}
+
+type WithdrawTxParams struct {
+	AccountID int64 `json:"account_id"`
+	Amount    int64 `json:"amount"`
+}
+
+type WithdrawTxResult struct {
+	Account Account `json:"account"`
+	Entry   Entry   `json:"entry"`
+}
+
+type TransactionAllowance struct {
+	LimitID         int64     `json:"limit_id"`
+	Scope           string    `json:"scope"`
+	Period          string    `json:"period"`
+	MaxAmount       int64     `json:"max_amount"`
+	MaxCount        int64     `json:"max_count"`
+	UsedAmount      int64     `json:"used_amount"`
+	UsedCount       int64     `json:"used_count"`
+	RemainingAmount int64     `json:"remaining_amount"`
+	RemainingCount  int64     `json:"remaining_count"`
+	ResetsAt        time.Time `json:"resets_at"`
+}
+
+type ReviewTransferRequestTxParams struct {
+	ID         int64  `json:"id"`
+	ReviewedBy string `json:"reviewed_by"`
+	Reason     string `json:"reason"`
+}
+
+type ApproveTransferRequestTxResult struct {
+	Request  TransferRequest  `json:"request"`
+	Transfer TransferTxResult `json:"transfer"`
+}
+
+type ResetPasswordTxParams struct {
+	TokenHash string `json:"token_hash"`
+	// HashPassword hashes the new password once the token is known to be valid, an error keeps the token unused
+	HashPassword func() (string, error) `json:"-"`
+}
+
+type ConfirmMFATxParams struct {
+	Username string `json:"username"`
+	// Step is the TOTP time step of the code that confirmed the enrollment
+	Step               int64    `json:"step"`
+	RecoveryCodeHashes []string `json:"recovery_code_hashes"`
+}
+
+type RecordLoginAttemptTxParams struct {
+	Username  string      `json:"username"`
+	ClientIP  string      `json:"client_ip"`
+	UserAgent string      `json:"user_agent"`
+	Outcome   string      `json:"outcome"`
+	Policy    LoginPolicy `json:"-"`
+}
+
+// AppendAuditLogTxParams describes one change made by Actor, an empty actor records an anonymous change.
+// Before and After are JSON snapshots of the resource, nil when it did not exist.
+type AppendAuditLogTxParams struct {
+	Actor        string `json:"actor"`
+	Action       string `json:"action"`
+	ResourceType string `json:"resource_type"`
+	ResourceID   string `json:"resource_id"`
+	Before       []byte `json:"before"`
+	After        []byte `json:"after"`
+	RequestID    string `json:"request_id"`
+	ClientIP     string `json:"client_ip"`
+}
+
+// AuditLogVerification is the outcome of walking the audit log hash chain
+type AuditLogVerification struct {
+	Valid bool  `json:"valid"`
+	Rows  int64 `json:"rows"`
+	// hash of the last sound row, new rows chain to it
+	LastHash string `json:"last_hash"`
+	// id of the first row breaking the chain and why, unset when the chain is valid
+	BrokenAt int64  `json:"broken_at,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+type CreateEntryTxParams struct {
+	AccountID int64 `json:"account_id"`
+	Amount    int64 `json:"amount"`
+}
+
+// EntryChainVerification is the outcome of walking the hash chain of an account's entries
+type EntryChainVerification struct {
+	AccountID int64 `json:"account_id"`
+	Valid     bool  `json:"valid"`
+	// chained entries that were verified and entries booked before the chain existed
+	Entries   int64 `json:"entries"`
+	Unchained int64 `json:"unchained"`
+	// hash of the last sound entry, the next entry of the account chains to it
+	LastHash string `json:"last_hash"`
+	// id of the first entry breaking the chain and why, unset when the chain is valid
+	BrokenAt int64  `json:"broken_at,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// UserRegisteredEvent is the payload of UserRegistered events, which leave the password hash out
+type UserRegisteredEvent struct {
+	Username  string    `json:"username"`
+	FullName  string    `json:"full_name"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type RelayOutboxTxParams struct {
+	Limit int32
+	// Lease is how long the claimed events are left to the relay before other relays may publish them again
+	Lease time.Duration
+	// Publish delivers an event, an error leaves it in the outbox to be tried again
+	Publish func(ctx context.Context, event OutboxEvent) error
+	// Record writes the rows a published event leads to, like webhook deliveries, with the queries of the
+	// transaction marking it published. It may be nil, and may run more than once for an event.
+	Record func(ctx context.Context, queries Querier, event OutboxEvent) error
+	// RetryDelay is the wait before an event is tried again after the given number of attempts
+	RetryDelay func(attempts int32) time.Duration
+}
+
+type RelayOutboxTxResult struct {
+	Published int `json:"published"`
+	Failed    int `json:"failed"`
+}
+
+type RecordWebhookAttemptTxParams struct {
+	DeliveryID int64 `json:"delivery_id"`
+	// unset when the receiver could not be reached, Error says why
+	ResponseStatus pgtype.Int4   `json:"response_status"`
+	Error          string        `json:"error"`
+	Duration       time.Duration `json:"duration"`
+	AttemptedAt    time.Time     `json:"attempted_at"`
+	// status of the delivery after the attempt, and when it is tried again if it is still pending
+	Status        string    `json:"status"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
 }
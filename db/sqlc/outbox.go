@@ -0,0 +1,167 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// types of the domain events written to the outbox
+const (
+	EventUserRegistered      = "UserRegistered"
+	EventAccountCreated      = "AccountCreated"
+	EventWithdrawalCompleted = "WithdrawalCompleted"
+	EventTransferCompleted   = "TransferCompleted"
+	EventTransferReserved    = "TransferReserved"
+	EventTransferVoided      = "TransferVoided"
+)
+
+// EventTypes lists every type of domain event, in the order they are listed above
+var EventTypes = []string{
+	EventUserRegistered,
+	EventAccountCreated,
+	EventWithdrawalCompleted,
+	EventTransferCompleted,
+	EventTransferReserved,
+	EventTransferVoided,
+}
+
+// aggregates the domain events are about
+const (
+	AggregateUser     = "user"
+	AggregateAccount  = "account"
+	AggregateTransfer = "transfer"
+)
+
+// CreateUserTx creates a user and announces it with a UserRegistered event
+func (s *SQLStore) CreateUserTx(ctx context.Context, args CreateUserParams) (User, error) {
+	var retval User
+
+	err := s.execTx(ctx, func(queries *Queries) error {
+		var err error
+		retval, err = queries.CreateUser(ctx, args)
+		if err != nil {
+			return err
+		}
+
+		return recordEvent(ctx, queries, EventUserRegistered, AggregateUser, retval.Username, UserRegisteredEvent{
+			Username:  retval.Username,
+			FullName:  retval.FullName,
+			Email:     retval.Email,
+			CreatedAt: retval.CreatedAt,
+		})
+	})
+
+	return retval, err
+}
+
+// CreateAccountTx creates an account and announces it with an AccountCreated event
+func (s *SQLStore) CreateAccountTx(ctx context.Context, args CreateAccountParams) (Account, error) {
+	var retval Account
+
+	err := s.execTx(ctx, func(queries *Queries) error {
+		var err error
+		retval, err = queries.CreateAccount(ctx, args)
+		if err != nil {
+			return err
+		}
+
+		return recordEvent(ctx, queries, EventAccountCreated, AggregateAccount, retval.ID, retval)
+	})
+
+	return retval, err
+}
+
+// recordEvent writes a domain event to the outbox, it must run in the db transaction of the change it describes
+// so that the event is published if and only if the change is committed
+func recordEvent(ctx context.Context, queries *Queries, eventType string, aggregateType string, aggregateID any, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("cannot encode %s event: %w", eventType, err)
+	}
+
+	_, err = queries.CreateOutboxEvent(ctx, CreateOutboxEventParams{
+		EventType:     eventType,
+		AggregateType: aggregateType,
+		AggregateID:   fmt.Sprint(aggregateID),
+		Payload:       data,
+	})
+	return err
+}
+
+// relayedOutboxEvent is the audit snapshot of an event the relay handled
+type relayedOutboxEvent struct {
+	EventType string `json:"event_type"`
+	Published bool   `json:"published"`
+	Attempts  int32  `json:"attempts"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// RelayOutboxTx claims the due events of the outbox, hands them to args.Publish in id order and marks them.
+// Events that fail are tried again after args.RetryDelay of their attempts, so later events may overtake them.
+// The claim is committed before the events are published, so no lock is held while a publisher is waiting:
+// the events are leased for args.Lease, relays running side by side skip each other's events, and events a relay
+// could not mark before its lease ran out are published again: delivery is at least once.
+// Each event is marked in a transaction of its own, args.Record and the audit of the event run in it.
+func (s *SQLStore) RelayOutboxTx(ctx context.Context, args RelayOutboxTxParams) (RelayOutboxTxResult, error) {
+	var retval RelayOutboxTxResult
+
+	events, err := s.ClaimPendingOutboxEvents(ctx, ClaimPendingOutboxEventsParams{
+		LeaseUntil: time.Now().Add(args.Lease),
+		LimitCount: args.Limit,
+	})
+	if err != nil {
+		return retval, err
+	}
+	slices.SortFunc(events, func(a, b OutboxEvent) int { return cmp.Compare(a.ID, b.ID) })
+
+	for _, event := range events {
+		pubErr := args.Publish(ctx, event)
+
+		err = s.execTx(ctx, func(queries *Queries) error {
+			return markRelayedEvent(ctx, queries, args, event, pubErr)
+		})
+		if err != nil {
+			return retval, err
+		}
+
+		if pubErr != nil {
+			retval.Failed++
+		} else {
+			retval.Published++
+		}
+	}
+
+	return retval, nil
+}
+
+// markRelayedEvent marks an event published, or failed with pubErr, records it and audits it
+func markRelayedEvent(ctx context.Context, queries *Queries, args RelayOutboxTxParams, event OutboxEvent, pubErr error) error {
+	relayed := relayedOutboxEvent{EventType: event.EventType, Attempts: event.Attempts + 1}
+
+	var err error
+	if pubErr != nil {
+		relayed.LastError = pubErr.Error()
+		err = queries.MarkOutboxEventFailed(ctx, MarkOutboxEventFailedParams{
+			ID:            event.ID,
+			LastError:     pubErr.Error(),
+			NextAttemptAt: time.Now().Add(args.RetryDelay(event.Attempts + 1)),
+		})
+	} else {
+		relayed.Published = true
+		if args.Record != nil {
+			if err = args.Record(ctx, queries, event); err != nil {
+				return fmt.Errorf("cannot record event %d: %w", event.ID, err)
+			}
+		}
+		err = queries.MarkOutboxEventPublished(ctx, event.ID)
+	}
+	if err != nil {
+		return err
+	}
+
+	return auditJob(ctx, queries, AuditActionRelayOutboxEvent, "outbox_event", event.ID, nil, relayed)
+}
@@ -0,0 +1,183 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	commonutils "github.com/primarybank/common/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func listAggregateEvents(t *testing.T, aggregateType string, aggregateID string) []OutboxEvent {
+	events, err := testStore.ListAggregateOutboxEvents(context.Background(), ListAggregateOutboxEventsParams{
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+	})
+	require.NoError(t, err)
+	return events
+}
+
+func TestCreateUserTxEvent(t *testing.T) {
+	user, err := testStore.CreateUserTx(context.Background(), CreateUserParams{
+		Username: commonutils.RandomString(10),
+		Password: "hashed",
+		FullName: commonutils.RandomString(10),
+		Email:    commonutils.RandomEmail(),
+	})
+	require.NoError(t, err)
+
+	events := listAggregateEvents(t, AggregateUser, user.Username)
+	require.Len(t, events, 1)
+	require.Equal(t, EventUserRegistered, events[0].EventType)
+	require.NotContains(t, string(events[0].Payload), "hashed")
+
+	var payload UserRegisteredEvent
+	require.NoError(t, json.Unmarshal(events[0].Payload, &payload))
+	require.Equal(t, user.Email, payload.Email)
+}
+
+func TestCreateAccountTxEvent(t *testing.T) {
+	user := CreateRandomUser(t)
+	args := CreateAccountParams{Owner: user.Username, Currency: commonutils.RandomCurrency()}
+
+	account, err := testStore.CreateAccountTx(context.Background(), args)
+	require.NoError(t, err)
+
+	events := listAggregateEvents(t, AggregateAccount, strconv.FormatInt(account.ID, 10))
+	require.Len(t, events, 1)
+	require.Equal(t, EventAccountCreated, events[0].EventType)
+	require.False(t, events[0].PublishedAt.Valid)
+}
+
+func TestTransferTxEvent(t *testing.T) {
+	account1 := createFundedAccount(t, 1000)
+	account2 := CreateRandomAccount(t)
+
+	result, err := testStore.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	events := listAggregateEvents(t, AggregateTransfer, strconv.FormatInt(result.Transfer.ID, 10))
+	require.Len(t, events, 1)
+	require.Equal(t, EventTransferCompleted, events[0].EventType)
+
+	var transfer Transfer
+	require.NoError(t, json.Unmarshal(events[0].Payload, &transfer))
+	require.Equal(t, result.Transfer.ID, transfer.ID)
+	require.Equal(t, account2.ID, transfer.ToAccountID)
+}
+
+func TestRelayOutboxTx(t *testing.T) {
+	ctx := context.Background()
+	account := CreateRandomAccount(t)
+	accountID := strconv.FormatInt(account.ID, 10)
+
+	withdrawal, err := testStore.WithdrawTx(ctx, WithdrawTxParams{AccountID: account.ID, Amount: 1})
+	require.NoError(t, err)
+	require.NotZero(t, withdrawal.Entry.ID)
+
+	isOurs := func(event OutboxEvent) bool {
+		return event.AggregateType == AggregateAccount && event.AggregateID == accountID
+	}
+
+	// other tests write events too, only the ones of this account fail
+	_, err = testStore.RelayOutboxTx(ctx, RelayOutboxTxParams{
+		Limit: 10000,
+		Publish: func(_ context.Context, event OutboxEvent) error {
+			if isOurs(event) {
+				return errors.New("unavailable")
+			}
+			return nil
+		},
+		RetryDelay: func(attempts int32) time.Duration { return 0 },
+	})
+	require.NoError(t, err)
+
+	events := listAggregateEvents(t, AggregateAccount, accountID)
+	require.Len(t, events, 1)
+	require.Equal(t, EventWithdrawalCompleted, events[0].EventType)
+	require.Equal(t, int32(1), events[0].Attempts)
+	require.Equal(t, "unavailable", events[0].LastError)
+	require.False(t, events[0].PublishedAt.Valid)
+
+	var published []OutboxEvent
+	_, err = testStore.RelayOutboxTx(ctx, RelayOutboxTxParams{
+		Limit: 10000,
+		Publish: func(_ context.Context, event OutboxEvent) error {
+			if isOurs(event) {
+				published = append(published, event)
+			}
+			return nil
+		},
+		RetryDelay: func(attempts int32) time.Duration { return 0 },
+	})
+	require.NoError(t, err)
+	require.Len(t, published, 1)
+
+	events = listAggregateEvents(t, AggregateAccount, accountID)
+	require.Equal(t, int32(2), events[0].Attempts)
+	require.Empty(t, events[0].LastError)
+	require.True(t, events[0].PublishedAt.Valid)
+}
+
+func TestRelayOutboxTxRecordsWebhookDeliveries(t *testing.T) {
+	// a relay waiting on a lock of its events would hang, not fail
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user := CreateRandomUser(t)
+	subscription := createRandomWebhook(t, user.Username, EventTransferCompleted)
+	event := createRandomOutboxEvent(t, EventTransferCompleted)
+
+	var deliveries []WebhookDelivery
+	_, err := testStore.RelayOutboxTx(ctx, RelayOutboxTxParams{
+		Limit: 10000,
+		Lease: time.Minute,
+		Publish: func(ctx context.Context, row OutboxEvent) error {
+			if row.ID != event.ID {
+				return nil
+			}
+
+			// the claim is committed before the event is published, other connections see the lease
+			claimed, err := testStore.GetOutboxEvent(ctx, row.ID)
+			if err != nil {
+				return err
+			}
+			require.True(t, claimed.NextAttemptAt.After(time.Now()))
+			return nil
+		},
+		Record: func(ctx context.Context, queries Querier, row OutboxEvent) error {
+			if row.ID != event.ID {
+				return nil
+			}
+
+			// the deliveries reference the event marked in the same transaction
+			var err error
+			deliveries, err = queries.EnqueueWebhookDeliveries(ctx, EnqueueWebhookDeliveriesParams{
+				EventID:   row.ID,
+				EventType: row.EventType,
+				Usernames: []string{user.Username},
+			})
+			return err
+		},
+		RetryDelay: func(attempts int32) time.Duration { return 0 },
+	})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	require.Equal(t, subscription.ID, deliveries[0].SubscriptionID)
+
+	published, err := testStore.GetOutboxEvent(ctx, event.ID)
+	require.NoError(t, err)
+	require.True(t, published.PublishedAt.Valid)
+
+	delivery, err := testStore.GetWebhookDelivery(ctx, deliveries[0].ID)
+	require.NoError(t, err)
+	require.Equal(t, event.ID, delivery.EventID)
+}
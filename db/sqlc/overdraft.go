@@ -0,0 +1,77 @@
+package db
+
+import (
+	"context"
+)
+
+// SetOverdraftLimitTx changes the overdraft limit of an account and records the change in its history.
+// A limit of zero revokes the facility.
+func (s *SQLStore) SetOverdraftLimitTx(ctx context.Context, args SetOverdraftLimitTxParams) (SetOverdraftLimitTxResult, error) {
+	var retval SetOverdraftLimitTxResult
+
+	err := s.execTx(ctx, func(queries *Queries) error {
+		account, err := queries.GetAccountForUpdate(ctx, args.AccountID)
+		if err != nil {
+			return err
+		}
+
+		retval.Account, err = queries.SetAccountOverdraftLimit(ctx, SetAccountOverdraftLimitParams{
+			ID:             args.AccountID,
+			OverdraftLimit: args.OverdraftLimit,
+		})
+		if err != nil {
+			return err
+		}
+
+		retval.Change, err = queries.CreateOverdraftLimitChange(ctx, CreateOverdraftLimitChangeParams{
+			AccountID:     args.AccountID,
+			PreviousLimit: account.OverdraftLimit,
+			NewLimit:      args.OverdraftLimit,
+			ChangedBy:     args.ChangedBy,
+			Reason:        args.Reason,
+		})
+		return err
+	})
+
+	return retval, err
+}
+
+// WithdrawTx takes money out of an account, rejecting withdrawals that exceed its overdraft or transaction limits
+func (s *SQLStore) WithdrawTx(ctx context.Context, args WithdrawTxParams) (WithdrawTxResult, error) {
+	var retval WithdrawTxResult
+
+	err := s.execTx(ctx, func(queries *Queries) error {
+		account, err := queries.GetAccountForUpdate(ctx, args.AccountID)
+		if err != nil {
+			return err
+		}
+
+		if err = checkOutgoingLimits(ctx, queries, account, args.Amount); err != nil {
+			return err
+		}
+
+		retval.Entry, err = appendEntry(ctx, queries, CreateEntryParams{
+			AccountID: args.AccountID,
+			Amount:    -args.Amount,
+		})
+		if err != nil {
+			return err
+		}
+
+		retval.Account, err = queries.AddAccountBalance(ctx, AddAccountBalanceParams{
+			ID:     args.AccountID,
+			Amount: -args.Amount,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err = checkOverdraft(retval.Account); err != nil {
+			return err
+		}
+
+		return recordEvent(ctx, queries, EventWithdrawalCompleted, AggregateAccount, args.AccountID, retval.Entry)
+	})
+
+	return retval, err
+}
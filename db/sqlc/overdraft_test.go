@@ -0,0 +1,106 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetOverdraftLimitTx(t *testing.T) {
+	ctx := context.Background()
+	admin := CreateRandomUser(t)
+	account := CreateRandomAccount(t)
+	require.Zero(t, account.OverdraftLimit)
+
+	result, err := testStore.SetOverdraftLimitTx(ctx, SetOverdraftLimitTxParams{
+		AccountID:      account.ID,
+		OverdraftLimit: 500,
+		ChangedBy:      admin.Username,
+		Reason:         "granted",
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(500), result.Account.OverdraftLimit)
+	require.Equal(t, int64(0), result.Change.PreviousLimit)
+	require.Equal(t, int64(500), result.Change.NewLimit)
+	require.Equal(t, admin.Username, result.Change.ChangedBy)
+
+	result, err = testStore.SetOverdraftLimitTx(ctx, SetOverdraftLimitTxParams{
+		AccountID:      account.ID,
+		OverdraftLimit: 0,
+		ChangedBy:      admin.Username,
+		Reason:         "revoked",
+	})
+	require.NoError(t, err)
+	require.Zero(t, result.Account.OverdraftLimit)
+	require.Equal(t, int64(500), result.Change.PreviousLimit)
+
+	changes, err := testStore.ListOverdraftLimitChanges(ctx, ListOverdraftLimitChangesParams{
+		AccountID: account.ID,
+		Limit:     5,
+		Offset:    0,
+	})
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+	require.Equal(t, "revoked", changes[0].Reason)
+	require.Equal(t, "granted", changes[1].Reason)
+}
+
+func TestTransferTxOverdraft(t *testing.T) {
+	ctx := context.Background()
+	admin := CreateRandomUser(t)
+	account1 := CreateRandomAccount(t)
+	account2 := CreateRandomAccount(t)
+
+	_, err := testStore.SetOverdraftLimitTx(ctx, SetOverdraftLimitTxParams{
+		AccountID:      account1.ID,
+		OverdraftLimit: 100,
+		ChangedBy:      admin.Username,
+		Reason:         "test",
+	})
+	require.NoError(t, err)
+
+	_, err = testStore.TransferTx(ctx, TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        account1.Balance + 101,
+	})
+	require.ErrorIs(t, err, ErrOverdraftLimitExceeded)
+
+	var overdraftErr *OverdraftError
+	require.True(t, errors.As(err, &overdraftErr))
+	require.Equal(t, account1.ID, overdraftErr.AccountID)
+
+	// nothing was moved
+	unchanged, err := testStore.GetAccount(ctx, account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, unchanged.Balance)
+
+	result, err := testStore.TransferTx(ctx, TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        account1.Balance + 100,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(-100), result.FromAccount.Balance)
+}
+
+func TestWithdrawTx(t *testing.T) {
+	ctx := context.Background()
+	account := CreateRandomAccount(t)
+
+	_, err := testStore.WithdrawTx(ctx, WithdrawTxParams{
+		AccountID: account.ID,
+		Amount:    account.Balance + 1,
+	})
+	require.ErrorIs(t, err, ErrOverdraftLimitExceeded)
+
+	result, err := testStore.WithdrawTx(ctx, WithdrawTxParams{
+		AccountID: account.ID,
+		Amount:    account.Balance,
+	})
+	require.NoError(t, err)
+	require.Zero(t, result.Account.Balance)
+	require.Equal(t, -account.Balance, result.Entry.Amount)
+}
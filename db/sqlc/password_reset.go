@@ -0,0 +1,45 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ResetPasswordTx redeems a password reset token and sets the new password in the same db transaction.
+// Every other outstanding token of the user is invalidated so a token can only ever be used once.
+// The new password is only hashed for a valid token, a made up one doesn't cost a password hash.
+func (s *SQLStore) ResetPasswordTx(ctx context.Context, args ResetPasswordTxParams) (User, error) {
+	var retval User
+
+	err := s.execTx(ctx, func(queries *Queries) error {
+		token, err := queries.GetPasswordResetTokenForUpdate(ctx, args.TokenHash)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return ErrInvalidPasswordResetToken
+			}
+			return err
+		}
+
+		if token.UsedAt.Valid || !token.ExpiresAt.After(time.Now()) {
+			return ErrInvalidPasswordResetToken
+		}
+
+		hashedPassword, err := args.HashPassword()
+		if err != nil {
+			return err
+		}
+
+		retval, err = queries.UpdateUserPassword(ctx, UpdateUserPasswordParams{
+			Username: token.Username,
+			Password: hashedPassword,
+		})
+		if err != nil {
+			return err
+		}
+
+		return queries.InvalidatePasswordResetTokens(ctx, token.Username)
+	})
+
+	return retval, err
+}
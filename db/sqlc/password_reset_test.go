@@ -0,0 +1,104 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	commonutils "github.com/primarybank/common/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomPasswordResetToken(t *testing.T, user User, expiresAt time.Time) string {
+	token := commonutils.RandomString(32)
+
+	_, err := testStore.CreatePasswordResetToken(context.Background(), CreatePasswordResetTokenParams{
+		Username:  user.Username,
+		TokenHash: commonutils.HashSecret(token),
+		ExpiresAt: expiresAt,
+	})
+	require.NoError(t, err)
+
+	return token
+}
+
+// hashPassword returns a HashPassword that hashes to hash
+func hashPassword(hash string) func() (string, error) {
+	return func() (string, error) {
+		return hash, nil
+	}
+}
+
+func TestResetPasswordTx(t *testing.T) {
+	ctx := context.Background()
+	user := CreateRandomUser(t)
+
+	token := createRandomPasswordResetToken(t, user, time.Now().Add(time.Hour))
+	other := createRandomPasswordResetToken(t, user, time.Now().Add(time.Hour))
+
+	args := ResetPasswordTxParams{
+		TokenHash:    commonutils.HashSecret(token),
+		HashPassword: hashPassword("new-hash"),
+	}
+
+	updated, err := testStore.ResetPasswordTx(ctx, args)
+	require.NoError(t, err)
+	require.Equal(t, user.Username, updated.Username)
+	require.Equal(t, "new-hash", updated.Password)
+
+	// a token is single use
+	_, err = testStore.ResetPasswordTx(ctx, args)
+	require.ErrorIs(t, err, ErrInvalidPasswordResetToken)
+
+	// and resetting invalidates every other outstanding token
+	_, err = testStore.ResetPasswordTx(ctx, ResetPasswordTxParams{
+		TokenHash:    commonutils.HashSecret(other),
+		HashPassword: hashPassword("another-hash"),
+	})
+	require.ErrorIs(t, err, ErrInvalidPasswordResetToken)
+}
+
+func TestResetPasswordTxInvalidToken(t *testing.T) {
+	ctx := context.Background()
+	user := CreateRandomUser(t)
+
+	expired := createRandomPasswordResetToken(t, user, time.Now().Add(-time.Minute))
+
+	for _, token := range []string{expired, commonutils.RandomString(32)} {
+		_, err := testStore.ResetPasswordTx(ctx, ResetPasswordTxParams{
+			TokenHash: commonutils.HashSecret(token),
+			HashPassword: func() (string, error) {
+				t.Fatal("password hashed for an invalid token")
+				return "", nil
+			},
+		})
+		require.ErrorIs(t, err, ErrInvalidPasswordResetToken)
+	}
+
+	unchanged, err := testStore.GetUser(ctx, user.Username)
+	require.NoError(t, err)
+	require.Equal(t, user.Password, unchanged.Password)
+}
+
+func TestResetPasswordTxHashFailed(t *testing.T) {
+	ctx := context.Background()
+	user := CreateRandomUser(t)
+	token := createRandomPasswordResetToken(t, user, time.Now().Add(time.Hour))
+
+	_, err := testStore.ResetPasswordTx(ctx, ResetPasswordTxParams{
+		TokenHash: commonutils.HashSecret(token),
+		HashPassword: func() (string, error) {
+			return "", errors.New("hash failed")
+		},
+	})
+	require.ErrorContains(t, err, "hash failed")
+
+	// the token is still good for another try
+	updated, err := testStore.ResetPasswordTx(ctx, ResetPasswordTxParams{
+		TokenHash:    commonutils.HashSecret(token),
+		HashPassword: hashPassword("new-hash"),
+	})
+	require.NoError(t, err)
+	require.Equal(t, "new-hash", updated.Password)
+}
@@ -10,23 +10,117 @@ import (
 
 type Querier interface {
 	AddAccountBalance(ctx context.Context, arg AddAccountBalanceParams) (Account, error)
+	// claimed events are not due again until lease_until, unless they are marked before
+	ClaimPendingOutboxEvents(ctx context.Context, arg ClaimPendingOutboxEventsParams) ([]OutboxEvent, error)
+	// claimed deliveries are not due again until lease_until, unless they are recorded before
+	ClaimWebhookDeliveries(ctx context.Context, arg ClaimWebhookDeliveriesParams) ([]WebhookDelivery, error)
+	ConfirmMFAFactor(ctx context.Context, arg ConfirmMFAFactorParams) (MfaFactor, error)
+	CountTransfersSince(ctx context.Context, arg CountTransfersSinceParams) (int64, error)
+	CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (ApiKey, error)
 	CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error)
+	CreateAuditLog(ctx context.Context, arg CreateAuditLogParams) (AuditLog, error)
 	CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error)
+	CreateFeeRule(ctx context.Context, arg CreateFeeRuleParams) (FeeRule, error)
+	CreateLoginEvent(ctx context.Context, arg CreateLoginEventParams) (LoginEvent, error)
+	CreateMFARecoveryCode(ctx context.Context, arg CreateMFARecoveryCodeParams) error
+	CreateOutboxEvent(ctx context.Context, arg CreateOutboxEventParams) (OutboxEvent, error)
+	CreateOverdraftLimitChange(ctx context.Context, arg CreateOverdraftLimitChangeParams) (OverdraftLimitChange, error)
+	CreatePasswordResetToken(ctx context.Context, arg CreatePasswordResetTokenParams) (PasswordResetToken, error)
+	CreateTransactionLimit(ctx context.Context, arg CreateTransactionLimitParams) (TransactionLimit, error)
 	CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error)
+	CreateTransferRequest(ctx context.Context, arg CreateTransferRequestParams) (TransferRequest, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	CreateWebhookDeliveryAttempt(ctx context.Context, arg CreateWebhookDeliveryAttemptParams) (WebhookDeliveryAttempt, error)
+	CreateWebhookSubscription(ctx context.Context, arg CreateWebhookSubscriptionParams) (WebhookSubscription, error)
+	DeactivateFeeRule(ctx context.Context, id int64) error
 	DeleteAccount(ctx context.Context, id int64) error
 	DeleteEntry(ctx context.Context, id int64) error
+	DeleteMFAFactor(ctx context.Context, username string) error
+	DeleteMFARecoveryCodes(ctx context.Context, username string) error
+	DeleteTransactionLimit(ctx context.Context, id int64) error
 	DeleteTransfer(ctx context.Context, id int64) error
+	DeleteWebhookSubscription(ctx context.Context, arg DeleteWebhookSubscriptionParams) (WebhookSubscription, error)
+	// an event relayed more than once is only delivered once to every subscription
+	EnqueueWebhookDeliveries(ctx context.Context, arg EnqueueWebhookDeliveriesParams) ([]WebhookDelivery, error)
+	ExpireTransferRequests(ctx context.Context) ([]TransferRequest, error)
+	GetAPIKeyByPrefix(ctx context.Context, prefix string) (ApiKey, error)
 	GetAccount(ctx context.Context, id int64) (Account, error)
 	GetAccountForUpdate(ctx context.Context, id int64) (Account, error)
+	GetAccountOutflowSince(ctx context.Context, arg GetAccountOutflowSinceParams) (GetAccountOutflowSinceRow, error)
+	GetActiveFeeRule(ctx context.Context, currency string) (FeeRule, error)
 	GetEntry(ctx context.Context, id int64) (Entry, error)
+	GetFeeRule(ctx context.Context, id int64) (FeeRule, error)
+	GetLastAccountEntry(ctx context.Context, accountID int64) (Entry, error)
+	GetLastAuditLog(ctx context.Context) (AuditLog, error)
+	GetLoginThrottles(ctx context.Context, arg GetLoginThrottlesParams) ([]LoginThrottle, error)
+	// creates the missing throttles with no failures so that both rows can be locked until the transaction ends,
+	// the client IP always first so that concurrent logins lock in the same order
+	GetLoginThrottlesForUpdate(ctx context.Context, arg GetLoginThrottlesForUpdateParams) ([]LoginThrottle, error)
+	GetMFAFactor(ctx context.Context, username string) (MfaFactor, error)
+	GetMFAFactorForUpdate(ctx context.Context, username string) (MfaFactor, error)
+	GetOutboxEvent(ctx context.Context, id int64) (OutboxEvent, error)
+	GetPasswordResetTokenForUpdate(ctx context.Context, tokenHash string) (PasswordResetToken, error)
+	GetTransactionLimit(ctx context.Context, id int64) (TransactionLimit, error)
 	GetTransfer(ctx context.Context, id int64) (Transfer, error)
+	GetTransferForUpdate(ctx context.Context, id int64) (Transfer, error)
+	GetTransferRequest(ctx context.Context, id int64) (TransferRequest, error)
+	GetTransferRequestForUpdate(ctx context.Context, id int64) (TransferRequest, error)
 	GetUser(ctx context.Context, username string) (User, error)
+	GetUserByEmail(ctx context.Context, email string) (User, error)
+	GetUserForUpdate(ctx context.Context, username string) (User, error)
+	GetUserOutflowSince(ctx context.Context, arg GetUserOutflowSinceParams) (GetUserOutflowSinceRow, error)
+	GetWebhookDelivery(ctx context.Context, id int64) (WebhookDelivery, error)
+	GetWebhookSubscription(ctx context.Context, id int64) (WebhookSubscription, error)
+	InvalidatePasswordResetTokens(ctx context.Context, username string) error
+	ListAPIKeys(ctx context.Context, username string) ([]ApiKey, error)
+	ListAccountEntriesAfter(ctx context.Context, arg ListAccountEntriesAfterParams) ([]Entry, error)
 	ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error)
+	ListAccountsByOwner(ctx context.Context, owner string) ([]Account, error)
+	ListAggregateOutboxEvents(ctx context.Context, arg ListAggregateOutboxEventsParams) ([]OutboxEvent, error)
+	ListApplicableTransactionLimits(ctx context.Context, arg ListApplicableTransactionLimitsParams) ([]TransactionLimit, error)
+	ListAuditLogs(ctx context.Context, arg ListAuditLogsParams) ([]AuditLog, error)
+	ListAuditLogsAfter(ctx context.Context, arg ListAuditLogsAfterParams) ([]AuditLog, error)
 	ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error)
+	ListFeeRules(ctx context.Context, arg ListFeeRulesParams) ([]FeeRule, error)
+	ListLoginEvents(ctx context.Context, arg ListLoginEventsParams) ([]LoginEvent, error)
+	ListOverdraftLimitChanges(ctx context.Context, arg ListOverdraftLimitChangesParams) ([]OverdraftLimitChange, error)
+	ListTransactionLimits(ctx context.Context, arg ListTransactionLimitsParams) ([]TransactionLimit, error)
+	ListTransferRequests(ctx context.Context, arg ListTransferRequestsParams) ([]TransferRequest, error)
 	ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error)
+	ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]WebhookDelivery, error)
+	ListWebhookDeliveryAttempts(ctx context.Context, deliveryID int64) ([]WebhookDeliveryAttempt, error)
+	ListWebhookSubscriptions(ctx context.Context, username string) ([]WebhookSubscription, error)
+	// appends are serialized so that every row chains to the one before it
+	LockAuditLog(ctx context.Context) error
+	LockLogin(ctx context.Context, arg LockLoginParams) (LoginThrottle, error)
+	MarkOutboxEventFailed(ctx context.Context, arg MarkOutboxEventFailedParams) error
+	MarkOutboxEventPublished(ctx context.Context, id int64) error
+	// delivered to listeners of account_activity once the transaction commits
+	NotifyAccountActivity(ctx context.Context, payload string) error
+	PostTransfer(ctx context.Context, id int64) (Transfer, error)
+	// failures older than the window no longer count
+	RecordLoginFailure(ctx context.Context, arg RecordLoginFailureParams) (LoginThrottle, error)
+	RedeliverWebhookDelivery(ctx context.Context, id int64) (WebhookDelivery, error)
+	ResetLoginThrottle(ctx context.Context, arg ResetLoginThrottleParams) (int64, error)
+	ReviewTransferRequest(ctx context.Context, arg ReviewTransferRequestParams) (TransferRequest, error)
+	RevokeAPIKey(ctx context.Context, arg RevokeAPIKeyParams) (ApiKey, error)
+	SetAccountOverdraftLimit(ctx context.Context, arg SetAccountOverdraftLimitParams) (Account, error)
+	// last use is recorded at most once a minute to spare a write on every request
+	TouchAPIKey(ctx context.Context, id int64) error
 	UpdateAccount(ctx context.Context, arg UpdateAccountParams) error
+	UpdateTransactionLimit(ctx context.Context, arg UpdateTransactionLimitParams) (TransactionLimit, error)
+	// a changed email has to be verified again
 	UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error)
+	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error)
+	UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (User, error)
+	UpdateWebhookDelivery(ctx context.Context, arg UpdateWebhookDeliveryParams) (WebhookDelivery, error)
+	// starting over an enrollment replaces the unconfirmed secret
+	UpsertMFAFactor(ctx context.Context, arg UpsertMFAFactorParams) (MfaFactor, error)
+	UseMFARecoveryCode(ctx context.Context, arg UseMFARecoveryCodeParams) (MfaRecoveryCode, error)
+	// fails with no rows when the step was already used, which is how replays are caught
+	UseMFAStep(ctx context.Context, arg UseMFAStepParams) (MfaFactor, error)
+	VerifyUserEmail(ctx context.Context, arg VerifyUserEmailParams) (User, error)
+	VoidTransfer(ctx context.Context, id int64) (Transfer, error)
 }
 
 var _ Querier = (*Queries)(nil)
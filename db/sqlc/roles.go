@@ -0,0 +1,8 @@
+package db
+
+// user roles, depositor is the default for every new user
+const (
+	RoleDepositor = "depositor"
+	RoleAdmin     = "admin"
+	RoleApprover  = "approver"
+)
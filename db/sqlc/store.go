@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -17,7 +18,33 @@ const (
 // Store provides all the functions to execute queries and transactions
 type Store interface {
 	Querier
-	TransferTx(ctx context.Context, args CreateTransferParams) (TransferTxResult, error)
+	TransferTx(ctx context.Context, args TransferTxParams) (TransferTxResult, error)
+	QuoteTransferFee(ctx context.Context, args TransferTxParams) (FeeQuote, error)
+	GetTransactionAllowances(ctx context.Context, accountID int64) ([]TransactionAllowance, error)
+	WithdrawTx(ctx context.Context, args WithdrawTxParams) (WithdrawTxResult, error)
+	SetOverdraftLimitTx(ctx context.Context, args SetOverdraftLimitTxParams) (SetOverdraftLimitTxResult, error)
+	ApproveTransferRequestTx(ctx context.Context, args ReviewTransferRequestTxParams) (ApproveTransferRequestTxResult, error)
+	RejectTransferRequestTx(ctx context.Context, args ReviewTransferRequestTxParams) (TransferRequest, error)
+	ExpireTransferRequestsTx(ctx context.Context) ([]TransferRequest, error)
+	ReserveTransferTx(ctx context.Context, args TransferTxParams) (TransferTxResult, error)
+	PostTransferTx(ctx context.Context, transferID int64) (TransferTxResult, error)
+	VoidTransferTx(ctx context.Context, transferID int64) (VoidTransferTxResult, error)
+	ResetPasswordTx(ctx context.Context, args ResetPasswordTxParams) (User, error)
+	ConfirmMFATx(ctx context.Context, args ConfirmMFATxParams) (MfaFactor, error)
+	DisableMFATx(ctx context.Context, username string) error
+	CheckLoginThrottle(ctx context.Context, username string, clientIP string, policy LoginPolicy) error
+	RecordLoginAttemptTx(ctx context.Context, args RecordLoginAttemptTxParams) (LoginEvent, error)
+	AppendAuditLogTx(ctx context.Context, args AppendAuditLogTxParams) (AuditLog, error)
+	VerifyAuditLog(ctx context.Context) (AuditLogVerification, error)
+	CreateEntryTx(ctx context.Context, args CreateEntryTxParams) (Entry, error)
+	DeleteEntryTx(ctx context.Context, entryID int64) error
+	VerifyEntryChain(ctx context.Context, accountID int64) (EntryChainVerification, error)
+	CreateUserTx(ctx context.Context, args CreateUserParams) (User, error)
+	CreateAccountTx(ctx context.Context, args CreateAccountParams) (Account, error)
+	RelayOutboxTx(ctx context.Context, args RelayOutboxTxParams) (RelayOutboxTxResult, error)
+	RecordWebhookAttemptTx(ctx context.Context, args RecordWebhookAttemptTxParams) (WebhookDelivery, error)
+	ListenAccountActivity(ctx context.Context, listening func(), handle func(AccountActivity)) error
+	ExecAuditedTx(ctx context.Context, fn func(ctx context.Context) (*AppendAuditLogTxParams, error)) error
 }
 
 // Store provides all the functions to execute SQL queries and transactions
@@ -30,98 +57,196 @@ type SQLStore struct {
 func NewStore(db *pgxpool.Pool) Store {
 	return &SQLStore{
 		db:      db,
-		Queries: New(db),
+		Queries: New(errorMappingDB{contextDB{db}}),
 	}
 }
 
 // TransferTx performs a money transfer from one account to other.
-// It created a transfer record, add account entries, and update accounts balance within a single db
-func (s *SQLStore) TransferTx(ctx context.Context, args CreateTransferParams) (TransferTxResult, error) {
+// It created a transfer record, add account entries, charges the applicable fee and update accounts balance within a single db.
+// Transfers that would take the sender below its overdraft limit are rejected with an OverdraftError,
+// and those going over one of its transaction limits with a TransactionLimitError.
+// Every entry is chained by its hash to the previous entry of its account, see VerifyEntryChain,
+// and a TransferCompleted event is written to the outbox.
+// Listeners of AccountActivityChannel learn about the new entries of both accounts once the transfer commits.
+func (s *SQLStore) TransferTx(ctx context.Context, args TransferTxParams) (TransferTxResult, error) {
 	var retval TransferTxResult
-	var err error
 
-	for i := 0; i < maxRetries; i++ {
-		err = s.execTx(ctx, func(queries *Queries) error {
-			var txErr error
-
-			retval.Transfer, txErr = queries.CreateTransfer(ctx, args)
-			if txErr != nil {
-				return txErr
-			}
-
-			retval.FromEntry, txErr = queries.CreateEntry(ctx, CreateEntryParams{
-				AccountID: args.FromAccountID,
-				Amount:    -args.Amount,
-			})
-			if txErr != nil {
-				return txErr
-			}
-
-			retval.ToEntry, txErr = queries.CreateEntry(ctx, CreateEntryParams{
-				AccountID: args.ToAccountID,
-				Amount:    args.Amount,
-			})
-			if txErr != nil {
-				return txErr
-			}
-
-			if args.FromAccountID < args.ToAccountID {
-				retval.FromAccount, retval.ToAccount, txErr = addMoney(ctx, queries, args.FromAccountID, args.ToAccountID, -args.Amount, args.Amount)
-			} else {
-				retval.FromAccount, retval.ToAccount, txErr = addMoney(ctx, queries, args.ToAccountID, args.FromAccountID, args.Amount, -args.Amount)
-			}
-			if txErr != nil {
-				return txErr
-			}
-
-			return nil
-		})
+	err := s.execTxWithRetry(ctx, func(queries *Queries) error {
+		var txErr error
+		retval, txErr = transfer(ctx, queries, args)
+		return txErr
+	})
 
-		if err == nil {
-			return retval, nil
-		}
+	return retval, err
+}
 
-		if !isRetryableError(err) {
-			break
+// execTxWithRetry runs fn in a db transaction, starting over when the transaction is picked as a deadlock victim
+func (s *SQLStore) execTxWithRetry(ctx context.Context, fn func(queries *Queries) error) error {
+	var err error
+
+	for i := 0; i < maxRetries; i++ {
+		err = s.execTx(ctx, fn)
+		if err == nil || !isRetryableError(err) {
+			return err
 		}
 
 		time.Sleep(retryDelay)
 	}
 
-	return retval, err
+	return err
 }
 
-func isRetryableError(err error) bool {
-	var pgErr *pgconn.PgError
-	if errors.As(err, &pgErr) {
-		return pgErr.Code == "40P01"
+// transfer moves money between two accounts and charges the fee for it, it must run inside a db transaction
+func transfer(ctx context.Context, queries *Queries, args TransferTxParams) (TransferTxResult, error) {
+	retval, quote, err := debitTransfer(ctx, queries, args, TransferPosted)
+	if err != nil {
+		return retval, err
 	}
-	return false
+
+	if err = creditTransfer(ctx, queries, &retval, quote.FeeAccountID); err != nil {
+		return retval, err
+	}
+
+	return retval, recordEvent(ctx, queries, EventTransferCompleted, AggregateTransfer, retval.Transfer.ID, retval.Transfer)
 }
 
-func addMoney(
-	ctx context.Context,
-	q *Queries,
-	accountID1 int64,
-	accountID2 int64,
-	amount1 int64,
-	amount2 int64,
-) (acc1 Account, acc2 Account, err error) {
-	acc1, err = q.AddAccountBalance(ctx, AddAccountBalanceParams{
-		ID:     accountID1,
-		Amount: amount1,
+// debitTransfer creates a transfer with the given status and takes its amount and fee from the sender.
+// Both accounts are locked up front, so the free allowance and the limits are counted against
+// a stable view of the sender's transfers.
+func debitTransfer(ctx context.Context, queries *Queries, args TransferTxParams, status string) (TransferTxResult, FeeQuote, error) {
+	var retval TransferTxResult
+
+	fromAccount, toAccount, err := lockAccounts(ctx, queries, args.FromAccountID, args.ToAccountID)
+	if err != nil {
+		return retval, FeeQuote{}, err
+	}
+	retval.ToAccount = toAccount
+
+	if err = checkOutgoingLimits(ctx, queries, fromAccount, args.Amount); err != nil {
+		return retval, FeeQuote{}, err
+	}
+
+	quote, err := quoteFee(ctx, queries, fromAccount, args.Amount, time.Now())
+	if err != nil {
+		return retval, quote, err
+	}
+
+	var postedAt pgtype.Timestamptz
+	if status == TransferPosted {
+		postedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	}
+
+	retval.Transfer, err = queries.CreateTransfer(ctx, CreateTransferParams{
+		FromAccountID: args.FromAccountID,
+		ToAccountID:   args.ToAccountID,
+		Amount:        args.Amount,
+		Fee:           quote.Fee,
+		FeeRuleID:     quote.FeeRuleID,
+		Status:        status,
+		PostedAt:      postedAt,
 	})
 	if err != nil {
-		return
+		return retval, quote, err
 	}
 
-	acc2, err = q.AddAccountBalance(ctx, AddAccountBalanceParams{
-		ID:     accountID2,
-		Amount: amount2,
+	retval.FromEntry, err = appendEntry(ctx, queries, CreateEntryParams{
+		AccountID: args.FromAccountID,
+		Amount:    -args.Amount,
 	})
 	if err != nil {
+		return retval, quote, err
+	}
+
+	// fee entries are only created when a non zero fee is charged
+	if quote.Fee > 0 {
+		feeEntry, err := appendEntry(ctx, queries, CreateEntryParams{
+			AccountID: args.FromAccountID,
+			Amount:    -quote.Fee,
+			FeeRuleID: quote.FeeRuleID,
+		})
+		if err != nil {
+			return retval, quote, err
+		}
+		retval.FeeEntry = &feeEntry
+	}
+
+	retval.FromAccount, err = queries.AddAccountBalance(ctx, AddAccountBalanceParams{
+		ID:     args.FromAccountID,
+		Amount: -(args.Amount + quote.Fee),
+	})
+	if err != nil {
+		return retval, quote, err
+	}
+
+	return retval, quote, checkOverdraft(retval.FromAccount)
+}
+
+// creditTransfer pays the amount of an already debited transfer to the receiver and books its fee as income
+func creditTransfer(ctx context.Context, queries *Queries, retval *TransferTxResult, feeAccountID int64) error {
+	var err error
+
+	retval.ToEntry, err = appendEntry(ctx, queries, CreateEntryParams{
+		AccountID: retval.Transfer.ToAccountID,
+		Amount:    retval.Transfer.Amount,
+	})
+	if err != nil {
+		return err
+	}
+
+	retval.ToAccount, err = queries.AddAccountBalance(ctx, AddAccountBalanceParams{
+		ID:     retval.Transfer.ToAccountID,
+		Amount: retval.Transfer.Amount,
+	})
+	if err != nil {
+		return err
+	}
+
+	if retval.Transfer.Fee == 0 {
+		return nil
+	}
+
+	feeIncomeEntry, err := appendEntry(ctx, queries, CreateEntryParams{
+		AccountID: feeAccountID,
+		Amount:    retval.Transfer.Fee,
+		FeeRuleID: retval.Transfer.FeeRuleID,
+	})
+	if err != nil {
+		return err
+	}
+
+	// the fee account is always locked last to keep the lock order consistent
+	_, err = queries.AddAccountBalance(ctx, AddAccountBalanceParams{
+		ID:     feeAccountID,
+		Amount: retval.Transfer.Fee,
+	})
+	if err != nil {
+		return err
+	}
+
+	retval.FeeIncomeEntry = &feeIncomeEntry
+	return nil
+}
+
+// lockAccounts locks both accounts of a transfer in id order and returns them in argument order
+func lockAccounts(ctx context.Context, q *Queries, fromAccountID int64, toAccountID int64) (from Account, to Account, err error) {
+	if fromAccountID > toAccountID {
+		to, from, err = lockAccounts(ctx, q, toAccountID, fromAccountID)
 		return
 	}
 
-	return acc1, acc2, err
+	from, err = q.GetAccountForUpdate(ctx, fromAccountID)
+	if err != nil {
+		return
+	}
+
+	to, err = q.GetAccountForUpdate(ctx, toAccountID)
+	return
+}
+
+func isRetryableError(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == deadlockDetected
+	}
+	return false
 }
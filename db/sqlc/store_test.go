@@ -7,11 +7,24 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+// createFundedAccount creates a random account with enough balance to be debited by the transfer tests
+func createFundedAccount(t *testing.T, amount int64) Account {
+	account := CreateRandomAccount(t)
+
+	account, err := testStore.AddAccountBalance(context.Background(), AddAccountBalanceParams{
+		ID:     account.ID,
+		Amount: amount,
+	})
+	require.NoError(t, err)
+
+	return account
+}
+
 func TestTransferTx(t *testing.T) {
 	store := testStore
 	ctx := context.Background()
 
-	account1 := CreateRandomAccount(t)
+	account1 := createFundedAccount(t, 1000)
 	account2 := CreateRandomAccount(t)
 
 	// run n concurrent transfer transactions
@@ -23,7 +36,7 @@ func TestTransferTx(t *testing.T) {
 
 	for i := 0; i < int(n); i++ {
 		go func() {
-			retval, err := store.TransferTx(ctx, CreateTransferParams{
+			retval, err := store.TransferTx(ctx, TransferTxParams{
 				FromAccountID: account1.ID,
 				ToAccountID:   account2.ID,
 				Amount:        int64(amount),
@@ -113,8 +126,8 @@ func TestTransferDeadlock(t *testing.T) {
 	store := testStore
 	ctx := context.Background()
 
-	account1 := CreateRandomAccount(t)
-	account2 := CreateRandomAccount(t)
+	account1 := createFundedAccount(t, 1000)
+	account2 := createFundedAccount(t, 1000)
 
 	// run n concurrent transfer transactions
 	n := int64(10)
@@ -130,7 +143,7 @@ func TestTransferDeadlock(t *testing.T) {
 		}
 
 		go func() {
-			_, err := store.TransferTx(ctx, CreateTransferParams{
+			_, err := store.TransferTx(ctx, TransferTxParams{
 				FromAccountID: fromAccID,
 				ToAccountID:   toAccID,
 				Amount:        int64(amount),
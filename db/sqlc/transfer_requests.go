@@ -0,0 +1,122 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// transfer request statuses
+const (
+	TransferRequestPending  = "pending"
+	TransferRequestApproved = "approved"
+	TransferRequestRejected = "rejected"
+	TransferRequestExpired  = "expired"
+)
+
+// ApproveTransferRequestTx approves a pending transfer request and executes the transfer in the same db transaction.
+// If the transfer is rejected, e.g. by the overdraft or transaction limits, the request stays pending.
+func (s *SQLStore) ApproveTransferRequestTx(ctx context.Context, args ReviewTransferRequestTxParams) (ApproveTransferRequestTxResult, error) {
+	var retval ApproveTransferRequestTxResult
+
+	err := s.execTxWithRetry(ctx, func(queries *Queries) error {
+		request, err := lockPendingTransferRequest(ctx, queries, args)
+		if err != nil {
+			return err
+		}
+
+		retval.Transfer, err = transfer(ctx, queries, TransferTxParams{
+			FromAccountID: request.FromAccountID,
+			ToAccountID:   request.ToAccountID,
+			Amount:        request.Amount,
+		})
+		if err != nil {
+			return err
+		}
+
+		retval.Request, err = queries.ReviewTransferRequest(ctx, ReviewTransferRequestParams{
+			ID:           request.ID,
+			Status:       TransferRequestApproved,
+			ReviewedBy:   pgtype.Text{String: args.ReviewedBy, Valid: true},
+			ReviewReason: optionalText(args.Reason),
+			TransferID:   pgtype.Int8{Int64: retval.Transfer.Transfer.ID, Valid: true},
+		})
+		return err
+	})
+
+	return retval, err
+}
+
+// RejectTransferRequestTx rejects a pending transfer request, no money is moved
+func (s *SQLStore) RejectTransferRequestTx(ctx context.Context, args ReviewTransferRequestTxParams) (TransferRequest, error) {
+	var retval TransferRequest
+
+	err := s.execTx(ctx, func(queries *Queries) error {
+		request, err := lockPendingTransferRequest(ctx, queries, args)
+		if err != nil {
+			return err
+		}
+
+		retval, err = queries.ReviewTransferRequest(ctx, ReviewTransferRequestParams{
+			ID:           request.ID,
+			Status:       TransferRequestRejected,
+			ReviewedBy:   pgtype.Text{String: args.ReviewedBy, Valid: true},
+			ReviewReason: optionalText(args.Reason),
+		})
+		return err
+	})
+
+	return retval, err
+}
+
+// lockPendingTransferRequest locks a transfer request and checks that the reviewer may still decide on it
+func lockPendingTransferRequest(ctx context.Context, q *Queries, args ReviewTransferRequestTxParams) (TransferRequest, error) {
+	request, err := q.GetTransferRequestForUpdate(ctx, args.ID)
+	if err != nil {
+		return request, err
+	}
+
+	if request.Status != TransferRequestPending {
+		return request, ErrTransferRequestNotPending
+	}
+
+	// the sweeper may not have caught up yet
+	if !request.ExpiresAt.After(time.Now()) {
+		return request, ErrTransferRequestExpired
+	}
+
+	if request.InitiatedBy == args.ReviewedBy {
+		return request, ErrSelfReview
+	}
+
+	return request, nil
+}
+
+func optionalText(s string) pgtype.Text {
+	return pgtype.Text{String: s, Valid: s != ""}
+}
+
+// ExpireTransferRequestsTx marks the pending transfer requests past their expiry as expired and audits each of them
+func (s *SQLStore) ExpireTransferRequestsTx(ctx context.Context) ([]TransferRequest, error) {
+	var retval []TransferRequest
+
+	err := s.execTx(ctx, func(queries *Queries) error {
+		var err error
+		retval, err = queries.ExpireTransferRequests(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, request := range retval {
+			before := request
+			before.Status = TransferRequestPending
+			if err = auditJob(ctx, queries, AuditActionExpireTransferRequest, "transfer_request", request.ID, before, request); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return retval, err
+}
@@ -0,0 +1,135 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func CreateRandomTransferRequest(t *testing.T, from Account, to Account, amount int64, ttl time.Duration) TransferRequest {
+	args := CreateTransferRequestParams{
+		FromAccountID: from.ID,
+		ToAccountID:   to.ID,
+		Amount:        amount,
+		InitiatedBy:   from.Owner,
+		ExpiresAt:     time.Now().Add(ttl),
+	}
+
+	request, err := testStore.CreateTransferRequest(context.Background(), args)
+	require.NoError(t, err)
+	require.NotZero(t, request.ID)
+	require.Equal(t, TransferRequestPending, request.Status)
+	require.Equal(t, args.InitiatedBy, request.InitiatedBy)
+	require.Equal(t, args.Amount, request.Amount)
+	require.False(t, request.ReviewedBy.Valid)
+	require.False(t, request.TransferID.Valid)
+
+	return request
+}
+
+func TestApproveTransferRequestTx(t *testing.T) {
+	ctx := context.Background()
+	account1 := createFundedAccount(t, 1000)
+	account2 := CreateRandomAccount(t)
+	approver := CreateRandomUser(t)
+	request := CreateRandomTransferRequest(t, account1, account2, 100, time.Hour)
+
+	// the initiator can't approve their own request
+	_, err := testStore.ApproveTransferRequestTx(ctx, ReviewTransferRequestTxParams{
+		ID:         request.ID,
+		ReviewedBy: request.InitiatedBy,
+	})
+	require.ErrorIs(t, err, ErrSelfReview)
+
+	result, err := testStore.ApproveTransferRequestTx(ctx, ReviewTransferRequestTxParams{
+		ID:         request.ID,
+		ReviewedBy: approver.Username,
+	})
+	require.NoError(t, err)
+	require.Equal(t, TransferRequestApproved, result.Request.Status)
+	require.Equal(t, approver.Username, result.Request.ReviewedBy.String)
+	require.True(t, result.Request.ReviewedAt.Valid)
+	require.Equal(t, result.Transfer.Transfer.ID, result.Request.TransferID.Int64)
+	require.Equal(t, account1.Balance-request.Amount, result.Transfer.FromAccount.Balance)
+	require.Equal(t, account2.Balance+request.Amount, result.Transfer.ToAccount.Balance)
+
+	// a request is executed at most once
+	_, err = testStore.ApproveTransferRequestTx(ctx, ReviewTransferRequestTxParams{
+		ID:         request.ID,
+		ReviewedBy: approver.Username,
+	})
+	require.ErrorIs(t, err, ErrTransferRequestNotPending)
+}
+
+func TestApproveTransferRequestTxOverdraft(t *testing.T) {
+	ctx := context.Background()
+	account1 := CreateRandomAccount(t)
+	account2 := CreateRandomAccount(t)
+	approver := CreateRandomUser(t)
+	request := CreateRandomTransferRequest(t, account1, account2, account1.Balance+1, time.Hour)
+
+	_, err := testStore.ApproveTransferRequestTx(ctx, ReviewTransferRequestTxParams{
+		ID:         request.ID,
+		ReviewedBy: approver.Username,
+	})
+	require.ErrorIs(t, err, ErrOverdraftLimitExceeded)
+
+	// the failed transfer rolled back the approval as well
+	request, err = testStore.GetTransferRequest(ctx, request.ID)
+	require.NoError(t, err)
+	require.Equal(t, TransferRequestPending, request.Status)
+}
+
+func TestRejectTransferRequestTx(t *testing.T) {
+	ctx := context.Background()
+	account1 := createFundedAccount(t, 1000)
+	account2 := CreateRandomAccount(t)
+	approver := CreateRandomUser(t)
+	request := CreateRandomTransferRequest(t, account1, account2, 100, time.Hour)
+
+	rejected, err := testStore.RejectTransferRequestTx(ctx, ReviewTransferRequestTxParams{
+		ID:         request.ID,
+		ReviewedBy: approver.Username,
+		Reason:     "unknown beneficiary",
+	})
+	require.NoError(t, err)
+	require.Equal(t, TransferRequestRejected, rejected.Status)
+	require.Equal(t, "unknown beneficiary", rejected.ReviewReason.String)
+	require.False(t, rejected.TransferID.Valid)
+
+	account1After, err := testStore.GetAccount(ctx, account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, account1After.Balance)
+}
+
+func TestExpireTransferRequests(t *testing.T) {
+	ctx := context.Background()
+	account1 := createFundedAccount(t, 1000)
+	account2 := CreateRandomAccount(t)
+	approver := CreateRandomUser(t)
+	request := CreateRandomTransferRequest(t, account1, account2, 100, -time.Minute)
+
+	_, err := testStore.ApproveTransferRequestTx(ctx, ReviewTransferRequestTxParams{
+		ID:         request.ID,
+		ReviewedBy: approver.Username,
+	})
+	require.ErrorIs(t, err, ErrTransferRequestExpired)
+
+	expired, err := testStore.ExpireTransferRequests(ctx)
+	require.NoError(t, err)
+	require.Contains(t, transferRequestIDs(expired), request.ID)
+
+	request, err = testStore.GetTransferRequest(ctx, request.ID)
+	require.NoError(t, err)
+	require.Equal(t, TransferRequestExpired, request.Status)
+}
+
+func transferRequestIDs(requests []TransferRequest) []int64 {
+	ids := make([]int64, 0, len(requests))
+	for _, request := range requests {
+		ids = append(ids, request.ID)
+	}
+	return ids
+}
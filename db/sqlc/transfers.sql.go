@@ -7,26 +7,64 @@ package db
 
 import (
 	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const countTransfersSince = `-- name: CountTransfersSince :one
+SELECT count(*) FROM transfers
+WHERE from_account_id = $1
+AND status <> 'voided'
+AND created_at >= $2
+`
+
+type CountTransfersSinceParams struct {
+	FromAccountID int64     `json:"from_account_id"`
+	Since         time.Time `json:"since"`
+}
+
+func (q *Queries) CountTransfersSince(ctx context.Context, arg CountTransfersSinceParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countTransfersSince, arg.FromAccountID, arg.Since)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createTransfer = `-- name: CreateTransfer :one
 INSERT INTO transfers (
     from_account_id,
     to_account_id,
-    amount
+    amount,
+    fee,
+    fee_rule_id,
+    status,
+    posted_at
 ) VALUES (
-    $1, $2, $3
-) RETURNING id, from_account_id, to_account_id, amount, created_at
+    $1, $2, $3, $4, $5, $6, $7
+) RETURNING id, from_account_id, to_account_id, amount, created_at, fee, fee_rule_id, status, posted_at, voided_at
 `
 
 type CreateTransferParams struct {
-	FromAccountID int64 `json:"from_account_id"`
-	ToAccountID   int64 `json:"to_account_id"`
-	Amount        int64 `json:"amount"`
+	FromAccountID int64              `json:"from_account_id"`
+	ToAccountID   int64              `json:"to_account_id"`
+	Amount        int64              `json:"amount"`
+	Fee           int64              `json:"fee"`
+	FeeRuleID     pgtype.Int8        `json:"fee_rule_id"`
+	Status        string             `json:"status"`
+	PostedAt      pgtype.Timestamptz `json:"posted_at"`
 }
 
 func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error) {
-	row := q.db.QueryRow(ctx, createTransfer, arg.FromAccountID, arg.ToAccountID, arg.Amount)
+	row := q.db.QueryRow(ctx, createTransfer,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.Fee,
+		arg.FeeRuleID,
+		arg.Status,
+		arg.PostedAt,
+	)
 	var i Transfer
 	err := row.Scan(
 		&i.ID,
@@ -34,6 +72,11 @@ func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams)
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.Fee,
+		&i.FeeRuleID,
+		&i.Status,
+		&i.PostedAt,
+		&i.VoidedAt,
 	)
 	return i, err
 }
@@ -48,7 +91,7 @@ func (q *Queries) DeleteTransfer(ctx context.Context, id int64) error {
 }
 
 const getTransfer = `-- name: GetTransfer :one
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers
+SELECT id, from_account_id, to_account_id, amount, created_at, fee, fee_rule_id, status, posted_at, voided_at FROM transfers
 WHERE id = $1 LIMIT 1
 `
 
@@ -61,12 +104,41 @@ func (q *Queries) GetTransfer(ctx context.Context, id int64) (Transfer, error) {
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.Fee,
+		&i.FeeRuleID,
+		&i.Status,
+		&i.PostedAt,
+		&i.VoidedAt,
+	)
+	return i, err
+}
+
+const getTransferForUpdate = `-- name: GetTransferForUpdate :one
+SELECT id, from_account_id, to_account_id, amount, created_at, fee, fee_rule_id, status, posted_at, voided_at FROM transfers
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE
+`
+
+func (q *Queries) GetTransferForUpdate(ctx context.Context, id int64) (Transfer, error) {
+	row := q.db.QueryRow(ctx, getTransferForUpdate, id)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.Fee,
+		&i.FeeRuleID,
+		&i.Status,
+		&i.PostedAt,
+		&i.VoidedAt,
 	)
 	return i, err
 }
 
 const listTransfers = `-- name: ListTransfers :many
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers
+SELECT id, from_account_id, to_account_id, amount, created_at, fee, fee_rule_id, status, posted_at, voided_at FROM transfers
 ORDER BY id
 LIMIT $1
 OFFSET $2
@@ -92,6 +164,11 @@ func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([
 			&i.ToAccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.Fee,
+			&i.FeeRuleID,
+			&i.Status,
+			&i.PostedAt,
+			&i.VoidedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -102,3 +179,53 @@ func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([
 	}
 	return items, nil
 }
+
+const postTransfer = `-- name: PostTransfer :one
+UPDATE transfers
+SET status = 'posted', posted_at = now()
+WHERE id = $1 AND status = 'pending'
+RETURNING id, from_account_id, to_account_id, amount, created_at, fee, fee_rule_id, status, posted_at, voided_at
+`
+
+func (q *Queries) PostTransfer(ctx context.Context, id int64) (Transfer, error) {
+	row := q.db.QueryRow(ctx, postTransfer, id)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.Fee,
+		&i.FeeRuleID,
+		&i.Status,
+		&i.PostedAt,
+		&i.VoidedAt,
+	)
+	return i, err
+}
+
+const voidTransfer = `-- name: VoidTransfer :one
+UPDATE transfers
+SET status = 'voided', voided_at = now()
+WHERE id = $1 AND status = 'pending'
+RETURNING id, from_account_id, to_account_id, amount, created_at, fee, fee_rule_id, status, posted_at, voided_at
+`
+
+func (q *Queries) VoidTransfer(ctx context.Context, id int64) (Transfer, error) {
+	row := q.db.QueryRow(ctx, voidTransfer, id)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.Fee,
+		&i.FeeRuleID,
+		&i.Status,
+		&i.PostedAt,
+		&i.VoidedAt,
+	)
+	return i, err
+}
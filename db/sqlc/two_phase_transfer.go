@@ -0,0 +1,130 @@
+package db
+
+import (
+	"context"
+)
+
+// transfer statuses
+const (
+	TransferPending = "pending"
+	TransferPosted  = "posted"
+	TransferVoided  = "voided"
+)
+
+// ReserveTransferTx is the first phase of a two-phase transfer.
+// It debits the sender with the amount and fee like TransferTx does, but leaves the transfer pending
+// until it is posted to the receiver with PostTransferTx or given back to the sender with VoidTransferTx.
+func (s *SQLStore) ReserveTransferTx(ctx context.Context, args TransferTxParams) (TransferTxResult, error) {
+	var retval TransferTxResult
+
+	err := s.execTxWithRetry(ctx, func(queries *Queries) error {
+		var txErr error
+		retval, _, txErr = debitTransfer(ctx, queries, args, TransferPending)
+		if txErr != nil {
+			return txErr
+		}
+
+		return recordEvent(ctx, queries, EventTransferReserved, AggregateTransfer, retval.Transfer.ID, retval.Transfer)
+	})
+
+	return retval, err
+}
+
+// PostTransferTx completes a pending transfer, crediting the receiver and the fee income account.
+// The sender side of the result is not filled in as it was booked when the transfer was reserved.
+func (s *SQLStore) PostTransferTx(ctx context.Context, transferID int64) (TransferTxResult, error) {
+	var retval TransferTxResult
+
+	err := s.execTxWithRetry(ctx, func(queries *Queries) error {
+		pending, err := lockPendingTransfer(ctx, queries, transferID)
+		if err != nil {
+			return err
+		}
+
+		// the rule may have been deactivated since, its fee account is still the one to credit
+		var feeAccountID int64
+		if pending.Fee > 0 {
+			rule, err := queries.GetFeeRule(ctx, pending.FeeRuleID.Int64)
+			if err != nil {
+				return err
+			}
+			feeAccountID = rule.FeeAccountID
+		}
+
+		retval.Transfer, err = queries.PostTransfer(ctx, transferID)
+		if err != nil {
+			return err
+		}
+
+		if err = creditTransfer(ctx, queries, &retval, feeAccountID); err != nil {
+			return err
+		}
+
+		return recordEvent(ctx, queries, EventTransferCompleted, AggregateTransfer, retval.Transfer.ID, retval.Transfer)
+	})
+
+	return retval, err
+}
+
+// VoidTransferTx cancels a pending transfer and gives the reserved amount and fee back to the sender
+func (s *SQLStore) VoidTransferTx(ctx context.Context, transferID int64) (VoidTransferTxResult, error) {
+	var retval VoidTransferTxResult
+
+	err := s.execTxWithRetry(ctx, func(queries *Queries) error {
+		pending, err := lockPendingTransfer(ctx, queries, transferID)
+		if err != nil {
+			return err
+		}
+
+		retval.Transfer, err = queries.VoidTransfer(ctx, transferID)
+		if err != nil {
+			return err
+		}
+
+		retval.RefundEntry, err = appendEntry(ctx, queries, CreateEntryParams{
+			AccountID: pending.FromAccountID,
+			Amount:    pending.Amount,
+		})
+		if err != nil {
+			return err
+		}
+
+		if pending.Fee > 0 {
+			feeRefundEntry, err := appendEntry(ctx, queries, CreateEntryParams{
+				AccountID: pending.FromAccountID,
+				Amount:    pending.Fee,
+				FeeRuleID: pending.FeeRuleID,
+			})
+			if err != nil {
+				return err
+			}
+			retval.FeeRefundEntry = &feeRefundEntry
+		}
+
+		retval.FromAccount, err = queries.AddAccountBalance(ctx, AddAccountBalanceParams{
+			ID:     pending.FromAccountID,
+			Amount: pending.Amount + pending.Fee,
+		})
+		if err != nil {
+			return err
+		}
+
+		return recordEvent(ctx, queries, EventTransferVoided, AggregateTransfer, retval.Transfer.ID, retval.Transfer)
+	})
+
+	return retval, err
+}
+
+// lockPendingTransfer locks a transfer so that only one caller can post or void it
+func lockPendingTransfer(ctx context.Context, q *Queries, transferID int64) (Transfer, error) {
+	transfer, err := q.GetTransferForUpdate(ctx, transferID)
+	if err != nil {
+		return transfer, err
+	}
+
+	if transfer.Status != TransferPending {
+		return transfer, &TransferStatusError{TransferID: transfer.ID, Status: transfer.Status}
+	}
+
+	return transfer, nil
+}
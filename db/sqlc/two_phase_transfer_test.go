@@ -0,0 +1,99 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func reserveRandomTransfer(t *testing.T, amount int64) (TransferTxResult, Account, Account) {
+	account1 := createFundedAccount(t, 1000)
+	account2 := CreateRandomAccount(t)
+
+	result, err := testStore.ReserveTransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        amount,
+	})
+	require.NoError(t, err)
+	require.Equal(t, TransferPending, result.Transfer.Status)
+	require.False(t, result.Transfer.PostedAt.Valid)
+	require.Equal(t, -amount, result.FromEntry.Amount)
+	require.Equal(t, account1.Balance-amount, result.FromAccount.Balance)
+	require.Equal(t, account2.Balance, result.ToAccount.Balance)
+	require.Zero(t, result.ToEntry.ID)
+
+	return result, account1, account2
+}
+
+func TestPostTransferTx(t *testing.T) {
+	ctx := context.Background()
+	reserved, account1, account2 := reserveRandomTransfer(t, 100)
+
+	posted, err := testStore.PostTransferTx(ctx, reserved.Transfer.ID)
+	require.NoError(t, err)
+	require.Equal(t, TransferPosted, posted.Transfer.Status)
+	require.True(t, posted.Transfer.PostedAt.Valid)
+	require.Equal(t, int64(100), posted.ToEntry.Amount)
+	require.Equal(t, account2.Balance+100, posted.ToAccount.Balance)
+
+	sender, err := testStore.GetAccount(ctx, account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance-100, sender.Balance)
+
+	_, err = testStore.PostTransferTx(ctx, reserved.Transfer.ID)
+	require.ErrorIs(t, err, ErrTransferNotPending)
+
+	_, err = testStore.VoidTransferTx(ctx, reserved.Transfer.ID)
+	require.ErrorIs(t, err, ErrTransferNotPending)
+}
+
+func TestVoidTransferTx(t *testing.T) {
+	ctx := context.Background()
+	reserved, account1, account2 := reserveRandomTransfer(t, 100)
+
+	voided, err := testStore.VoidTransferTx(ctx, reserved.Transfer.ID)
+	require.NoError(t, err)
+	require.Equal(t, TransferVoided, voided.Transfer.Status)
+	require.True(t, voided.Transfer.VoidedAt.Valid)
+	require.Equal(t, int64(100), voided.RefundEntry.Amount)
+	require.Equal(t, account1.Balance, voided.FromAccount.Balance)
+
+	receiver, err := testStore.GetAccount(ctx, account2.ID)
+	require.NoError(t, err)
+	require.Equal(t, account2.Balance, receiver.Balance)
+
+	_, err = testStore.PostTransferTx(ctx, reserved.Transfer.ID)
+	require.ErrorIs(t, err, ErrTransferNotPending)
+}
+
+func TestResolveTransferExactlyOnce(t *testing.T) {
+	ctx := context.Background()
+	reserved, _, _ := reserveRandomTransfer(t, 100)
+
+	n := 10
+	errs := make(chan error)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			var err error
+			if i%2 == 0 {
+				_, err = testStore.PostTransferTx(ctx, reserved.Transfer.ID)
+			} else {
+				_, err = testStore.VoidTransferTx(ctx, reserved.Transfer.ID)
+			}
+			errs <- err
+		}(i)
+	}
+
+	resolved := 0
+	for i := 0; i < n; i++ {
+		err := <-errs
+		if err == nil {
+			resolved++
+			continue
+		}
+		require.ErrorIs(t, err, ErrTransferNotPending)
+	}
+	require.Equal(t, 1, resolved)
+}
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	commonerrors "github.com/primarybank/common/errors"
 	commonutils "github.com/primarybank/common/utils"
 	"github.com/stretchr/testify/require"
 )
@@ -36,6 +37,40 @@ func CreateRandomUser(t *testing.T) User {
 	return user
 }
 
+// requireFieldError checks err is a typed error of the given kind and code blaming exactly fields
+func requireFieldError(t *testing.T, err error, kind commonerrors.Kind, code string, fields ...string) {
+	typed, ok := commonerrors.As(err)
+	require.True(t, ok, "expected a typed error, got %v", err)
+	require.Equal(t, kind, typed.Kind)
+	require.Equal(t, code, typed.Code)
+
+	blamed := make([]string, 0, len(typed.Fields))
+	for _, field := range typed.Fields {
+		blamed = append(blamed, field.Field)
+	}
+	require.Equal(t, fields, blamed)
+}
+
+func TestCreateUserDuplicate(t *testing.T) {
+	user := CreateRandomUser(t)
+
+	_, err := testStore.CreateUser(context.Background(), CreateUserParams{
+		Username: user.Username,
+		Password: user.Password,
+		FullName: user.FullName,
+		Email:    commonutils.RandomEmail(),
+	})
+	requireFieldError(t, err, commonerrors.KindConflict, "already_exists", "username")
+
+	_, err = testStore.CreateUser(context.Background(), CreateUserParams{
+		Username: commonutils.RandomString(10),
+		Password: user.Password,
+		FullName: user.FullName,
+		Email:    user.Email,
+	})
+	requireFieldError(t, err, commonerrors.KindConflict, "already_exists", "email")
+}
+
 func TestCreateUser(t *testing.T) {
 	CreateRandomUser(t)
 }
@@ -75,3 +110,26 @@ func TestUpdateUser(t *testing.T) {
 	require.WithinDuration(t, createdUser.CreatedAt, updatedUser.CreatedAt, time.Second)
 	require.NotEqual(t, createdUser.UpdatedAt, updatedUser.UpdatedAt)
 }
+
+func TestVerifyUserEmail(t *testing.T) {
+	ctx := context.Background()
+	user := CreateRandomUser(t)
+	require.False(t, user.EmailVerifiedAt.Valid)
+
+	// a code for an address the user no longer has does nothing
+	_, err := testStore.VerifyUserEmail(ctx, VerifyUserEmailParams{Username: user.Username, Email: commonutils.RandomEmail()})
+	require.ErrorIs(t, err, ErrNotFound)
+
+	verified, err := testStore.VerifyUserEmail(ctx, VerifyUserEmailParams{Username: user.Username, Email: user.Email})
+	require.NoError(t, err)
+	require.True(t, verified.EmailVerifiedAt.Valid)
+
+	// keeping the email keeps it verified, changing it does not
+	updated, err := testStore.UpdateUser(ctx, UpdateUserParams{Username: user.Username, FullName: "New Name", Email: user.Email, Password: user.Password})
+	require.NoError(t, err)
+	require.True(t, updated.EmailVerifiedAt.Valid)
+
+	updated, err = testStore.UpdateUser(ctx, UpdateUserParams{Username: user.Username, FullName: "New Name", Email: commonutils.RandomEmail(), Password: user.Password})
+	require.NoError(t, err)
+	require.False(t, updated.EmailVerifiedAt.Valid)
+}
@@ -0,0 +1,51 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// webhook delivery statuses
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryDead      = "dead"
+)
+
+// RecordWebhookAttemptTx logs an attempt to deliver a webhook and moves the delivery to the status it led to,
+// auditing the delivery it left
+func (s *SQLStore) RecordWebhookAttemptTx(ctx context.Context, args RecordWebhookAttemptTxParams) (WebhookDelivery, error) {
+	var retval WebhookDelivery
+
+	err := s.execTx(ctx, func(queries *Queries) error {
+		_, err := queries.CreateWebhookDeliveryAttempt(ctx, CreateWebhookDeliveryAttemptParams{
+			DeliveryID:     args.DeliveryID,
+			ResponseStatus: args.ResponseStatus,
+			Error:          args.Error,
+			DurationMs:     args.Duration.Milliseconds(),
+		})
+		if err != nil {
+			return err
+		}
+
+		var deliveredAt pgtype.Timestamptz
+		if args.Status == WebhookDeliverySucceeded {
+			deliveredAt = pgtype.Timestamptz{Time: args.AttemptedAt, Valid: true}
+		}
+
+		retval, err = queries.UpdateWebhookDelivery(ctx, UpdateWebhookDeliveryParams{
+			ID:            args.DeliveryID,
+			Status:        args.Status,
+			NextAttemptAt: args.NextAttemptAt,
+			DeliveredAt:   deliveredAt,
+		})
+		if err != nil {
+			return err
+		}
+
+		return auditJob(ctx, queries, AuditActionRecordWebhookAttempt, "webhook_delivery", retval.ID, nil, retval)
+	})
+
+	return retval, err
+}
@@ -0,0 +1,112 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	commonutils "github.com/primarybank/common/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomWebhook(t *testing.T, username string, eventTypes ...string) WebhookSubscription {
+	subscription, err := testStore.CreateWebhookSubscription(context.Background(), CreateWebhookSubscriptionParams{
+		Username:         username,
+		Url:              "https://example.com/" + commonutils.RandomString(6),
+		EventTypes:       eventTypes,
+		SecretCiphertext: []byte(commonutils.RandomString(16)),
+	})
+	require.NoError(t, err)
+	return subscription
+}
+
+func createRandomOutboxEvent(t *testing.T, eventType string) OutboxEvent {
+	event, err := testStore.CreateOutboxEvent(context.Background(), CreateOutboxEventParams{
+		EventType:     eventType,
+		AggregateType: AggregateUser,
+		AggregateID:   commonutils.RandomString(8),
+		Payload:       []byte(`{}`),
+	})
+	require.NoError(t, err)
+	return event
+}
+
+func TestEnqueueWebhookDeliveries(t *testing.T) {
+	user := CreateRandomUser(t)
+	subscribed := createRandomWebhook(t, user.Username, EventTransferCompleted)
+	createRandomWebhook(t, user.Username, EventAccountCreated)
+	event := createRandomOutboxEvent(t, EventTransferCompleted)
+
+	args := EnqueueWebhookDeliveriesParams{
+		EventID:   event.ID,
+		EventType: event.EventType,
+		Usernames: []string{user.Username},
+	}
+	deliveries, err := testStore.EnqueueWebhookDeliveries(context.Background(), args)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	require.Equal(t, subscribed.ID, deliveries[0].SubscriptionID)
+	require.Equal(t, WebhookDeliveryPending, deliveries[0].Status)
+
+	// an event relayed again is not delivered twice
+	deliveries, err = testStore.EnqueueWebhookDeliveries(context.Background(), args)
+	require.NoError(t, err)
+	require.Empty(t, deliveries)
+}
+
+func TestRecordWebhookAttemptTx(t *testing.T) {
+	user := CreateRandomUser(t)
+	subscription := createRandomWebhook(t, user.Username, EventTransferCompleted)
+	event := createRandomOutboxEvent(t, EventTransferCompleted)
+
+	deliveries, err := testStore.EnqueueWebhookDeliveries(context.Background(), EnqueueWebhookDeliveriesParams{
+		EventID:   event.ID,
+		EventType: event.EventType,
+		Usernames: []string{user.Username},
+	})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+
+	retryAt := time.Now().Add(time.Minute)
+	delivery, err := testStore.RecordWebhookAttemptTx(context.Background(), RecordWebhookAttemptTxParams{
+		DeliveryID:     deliveries[0].ID,
+		ResponseStatus: pgtype.Int4{Int32: 500, Valid: true},
+		Duration:       20 * time.Millisecond,
+		AttemptedAt:    time.Now(),
+		Status:         WebhookDeliveryPending,
+		NextAttemptAt:  retryAt,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int32(1), delivery.Attempts)
+	require.WithinDuration(t, retryAt, delivery.NextAttemptAt, time.Second)
+	require.False(t, delivery.DeliveredAt.Valid)
+
+	delivery, err = testStore.RecordWebhookAttemptTx(context.Background(), RecordWebhookAttemptTxParams{
+		DeliveryID:     delivery.ID,
+		ResponseStatus: pgtype.Int4{Int32: 204, Valid: true},
+		AttemptedAt:    time.Now(),
+		Status:         WebhookDeliverySucceeded,
+		NextAttemptAt:  time.Now(),
+	})
+	require.NoError(t, err)
+	require.Equal(t, int32(2), delivery.Attempts)
+	require.Equal(t, WebhookDeliverySucceeded, delivery.Status)
+	require.True(t, delivery.DeliveredAt.Valid)
+
+	attempts, err := testStore.ListWebhookDeliveryAttempts(context.Background(), delivery.ID)
+	require.NoError(t, err)
+	require.Len(t, attempts, 2)
+	require.Equal(t, int32(500), attempts[0].ResponseStatus.Int32)
+	require.Equal(t, int64(20), attempts[0].DurationMs)
+
+	// unsubscribing takes the delivery log along
+	_, err = testStore.DeleteWebhookSubscription(context.Background(), DeleteWebhookSubscriptionParams{
+		ID:       subscription.ID,
+		Username: user.Username,
+	})
+	require.NoError(t, err)
+
+	_, err = testStore.GetWebhookDelivery(context.Background(), delivery.ID)
+	require.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,18 @@
+package events
+
+import (
+	"context"
+	"log"
+)
+
+// LogPublisher writes every event to the standard logger, for local development
+type LogPublisher struct{}
+
+func NewLogPublisher() *LogPublisher {
+	return &LogPublisher{}
+}
+
+func (p *LogPublisher) Publish(_ context.Context, event Event) error {
+	log.Printf("event %d %s %s/%s: %s", event.ID, event.Type, event.AggregateType, event.AggregateID, event.Payload)
+	return nil
+}
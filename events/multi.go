@@ -0,0 +1,20 @@
+package events
+
+import (
+	"context"
+	"errors"
+)
+
+// MultiPublisher hands every event to each of its publishers. When one of them fails the event is
+// published again to all of them, which at least once delivery allows for.
+type MultiPublisher []EventPublisher
+
+func (p MultiPublisher) Publish(ctx context.Context, event Event) error {
+	var errs []error
+	for _, publisher := range p {
+		if err := publisher.Publish(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
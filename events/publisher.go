@@ -0,0 +1,53 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/primarybank/config"
+	db "github.com/primarybank/db/sqlc"
+)
+
+// Event is a domain event as it leaves the outbox. Events are delivered at least once,
+// consumers tell redeliveries apart by their id.
+type Event struct {
+	ID            int64           `json:"id"`
+	Type          string          `json:"type"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	Payload       json.RawMessage `json:"payload"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+}
+
+// EventPublisher delivers domain events to downstream systems
+type EventPublisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// NewPublisher builds the publisher selected by EVENT_PUBLISHER
+func NewPublisher(cfg config.Config) (EventPublisher, error) {
+	switch cfg.EventPublisher {
+	case "log", "":
+		return NewLogPublisher(), nil
+	case "http":
+		return NewHTTPPublisher(cfg.EventWebhookURL)
+	case "memory":
+		return NewMemoryPublisher(), nil
+	default:
+		return nil, fmt.Errorf("unknown event publisher %q", cfg.EventPublisher)
+	}
+}
+
+// FromOutbox turns an outbox row into the event it holds
+func FromOutbox(row db.OutboxEvent) Event {
+	return Event{
+		ID:            row.ID,
+		Type:          row.EventType,
+		AggregateType: row.AggregateType,
+		AggregateID:   row.AggregateID,
+		Payload:       row.Payload,
+		OccurredAt:    row.CreatedAt,
+	}
+}
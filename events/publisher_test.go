@@ -0,0 +1,106 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/primarybank/config"
+	"github.com/stretchr/testify/require"
+)
+
+func randomEvent(id int64) Event {
+	return Event{
+		ID:            id,
+		Type:          "TransferCompleted",
+		AggregateType: "transfer",
+		AggregateID:   "7",
+		Payload:       json.RawMessage(`{"id":7,"amount":10}`),
+		OccurredAt:    time.Now().UTC().Truncate(time.Microsecond),
+	}
+}
+
+func TestMemoryPublisher(t *testing.T) {
+	publisher := NewMemoryPublisher()
+
+	require.NoError(t, publisher.Publish(context.Background(), randomEvent(1)))
+
+	publisher.FailWith(errors.New("unavailable"))
+	require.Error(t, publisher.Publish(context.Background(), randomEvent(2)))
+
+	publisher.FailWith(nil)
+	require.NoError(t, publisher.Publish(context.Background(), randomEvent(3)))
+
+	published := publisher.Events()
+	require.Len(t, published, 2)
+	require.Equal(t, int64(1), published[0].ID)
+	require.Equal(t, int64(3), published[1].ID)
+}
+
+func TestHTTPPublisher(t *testing.T) {
+	var received []Event
+	status := http.StatusNoContent
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.Equal(t, "TransferCompleted", r.Header.Get(EventTypeHeader))
+
+		var event Event
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		require.Equal(t, "1", r.Header.Get(EventIDHeader))
+		received = append(received, event)
+		w.WriteHeader(status)
+	}))
+	defer receiver.Close()
+
+	publisher, err := NewHTTPPublisher(receiver.URL)
+	require.NoError(t, err)
+
+	event := randomEvent(1)
+	require.NoError(t, publisher.Publish(context.Background(), event))
+	require.Len(t, received, 1)
+	require.Equal(t, event.Type, received[0].Type)
+	require.JSONEq(t, string(event.Payload), string(received[0].Payload))
+	require.True(t, event.OccurredAt.Equal(received[0].OccurredAt))
+
+	status = http.StatusServiceUnavailable
+	require.ErrorContains(t, publisher.Publish(context.Background(), event), "503")
+}
+
+func TestNewPublisher(t *testing.T) {
+	publisher, err := NewPublisher(config.Config{})
+	require.NoError(t, err)
+	require.IsType(t, &LogPublisher{}, publisher)
+
+	publisher, err = NewPublisher(config.Config{EventPublisher: "memory"})
+	require.NoError(t, err)
+	require.IsType(t, &MemoryPublisher{}, publisher)
+
+	publisher, err = NewPublisher(config.Config{EventPublisher: "http", EventWebhookURL: "https://events.example.com/hook"})
+	require.NoError(t, err)
+	require.IsType(t, &HTTPPublisher{}, publisher)
+
+	_, err = NewPublisher(config.Config{EventPublisher: "http", EventWebhookURL: "events.example.com"})
+	require.Error(t, err)
+
+	_, err = NewPublisher(config.Config{EventPublisher: "kafka"})
+	require.Error(t, err)
+}
+
+func TestMultiPublisher(t *testing.T) {
+	first := NewMemoryPublisher()
+	second := NewMemoryPublisher()
+	publisher := MultiPublisher{first, second}
+
+	require.NoError(t, publisher.Publish(context.Background(), randomEvent(1)))
+
+	second.FailWith(errors.New("unavailable"))
+	require.ErrorContains(t, publisher.Publish(context.Background(), randomEvent(2)), "unavailable")
+
+	require.Len(t, first.Events(), 2)
+	require.Len(t, second.Events(), 1)
+}
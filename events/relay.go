@@ -0,0 +1,126 @@
+package events
+
+import (
+	"context"
+	"log"
+	"time"
+
+	commonutils "github.com/primarybank/common/utils"
+	"github.com/primarybank/config"
+	db "github.com/primarybank/db/sqlc"
+)
+
+// defaults for the relay settings left at zero
+const (
+	defaultBatchSize      = 100
+	defaultRetryBaseDelay = time.Second
+	defaultRetryMaxDelay  = time.Hour
+)
+
+// publishTimeout bounds the publishing of one event, the events of a batch are leased for as long as it takes
+// to publish all of them one after the other
+const publishTimeout = 10 * time.Second
+
+// Recorder writes the rows an event leads to in the db, with the queries of the transaction marking the event
+// published, so they are written for every event the relay publishes. An event published again after its lease
+// ran out is recorded again, recorders must not write its rows twice.
+type Recorder interface {
+	Record(ctx context.Context, queries db.Querier, event Event) error
+}
+
+// Relay moves domain events from the outbox to a publisher
+type Relay struct {
+	store          db.Store
+	publisher      EventPublisher
+	recorders      []Recorder
+	batchSize      int32
+	retryBaseDelay time.Duration
+	retryMaxDelay  time.Duration
+}
+
+func NewRelay(store db.Store, publisher EventPublisher, cfg config.Config, recorders ...Recorder) *Relay {
+	relay := &Relay{
+		store:          store,
+		publisher:      publisher,
+		recorders:      recorders,
+		batchSize:      cfg.OutboxBatchSize,
+		retryBaseDelay: cfg.OutboxRetryBaseDelay,
+		retryMaxDelay:  cfg.OutboxRetryMaxDelay,
+	}
+
+	if relay.batchSize <= 0 {
+		relay.batchSize = defaultBatchSize
+	}
+	if relay.retryBaseDelay <= 0 {
+		relay.retryBaseDelay = defaultRetryBaseDelay
+	}
+	if relay.retryMaxDelay <= 0 {
+		relay.retryMaxDelay = defaultRetryMaxDelay
+	}
+
+	return relay
+}
+
+// RelayOnce publishes the events that are due, one batch at a time until the outbox has no full batch left
+func (r *Relay) RelayOnce(ctx context.Context) (db.RelayOutboxTxResult, error) {
+	var total db.RelayOutboxTxResult
+
+	for {
+		result, err := r.store.RelayOutboxTx(ctx, db.RelayOutboxTxParams{
+			Limit: r.batchSize,
+			Lease: time.Duration(r.batchSize)*publishTimeout + time.Minute,
+			Publish: func(ctx context.Context, row db.OutboxEvent) error {
+				ctx, cancel := context.WithTimeout(ctx, publishTimeout)
+				defer cancel()
+				return r.publisher.Publish(ctx, FromOutbox(row))
+			},
+			Record:     r.record,
+			RetryDelay: r.retryDelay,
+		})
+		total.Published += result.Published
+		total.Failed += result.Failed
+		if err != nil {
+			return total, err
+		}
+
+		if result.Published+result.Failed < int(r.batchSize) {
+			return total, nil
+		}
+	}
+}
+
+// Run relays the outbox every interval until ctx is done
+func (r *Relay) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		result, err := r.RelayOnce(ctx)
+		if err != nil {
+			log.Println("cannot relay outbox: ", err)
+			continue
+		}
+		if result.Failed > 0 {
+			log.Printf("published %d events, %d failed and will be retried", result.Published, result.Failed)
+		}
+	}
+}
+
+func (r *Relay) record(ctx context.Context, queries db.Querier, row db.OutboxEvent) error {
+	for _, recorder := range r.recorders {
+		if err := recorder.Record(ctx, queries, FromOutbox(row)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Relay) retryDelay(attempts int32) time.Duration {
+	return commonutils.Backoff(r.retryBaseDelay, r.retryMaxDelay, attempts)
+}
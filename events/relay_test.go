@@ -0,0 +1,139 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/primarybank/config"
+	"github.com/primarybank/db/mocks"
+	db "github.com/primarybank/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+// relayBatch stubs one RelayOutboxTx call handing rows to the relay's publish func
+func relayBatch(store *mocks.MockStore, rows ...db.OutboxEvent) *gomock.Call {
+	return store.EXPECT().
+		RelayOutboxTx(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, args db.RelayOutboxTxParams) (db.RelayOutboxTxResult, error) {
+			var result db.RelayOutboxTxResult
+			for _, row := range rows {
+				if err := args.Publish(ctx, row); err != nil {
+					result.Failed++
+					continue
+				}
+				if err := args.Record(ctx, store, row); err != nil {
+					return db.RelayOutboxTxResult{}, err
+				}
+				result.Published++
+			}
+			return result, nil
+		})
+}
+
+func TestRelayOnce(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockStore(ctrl)
+	publisher := NewMemoryPublisher()
+	relay := NewRelay(store, publisher, config.Config{OutboxBatchSize: 2})
+
+	// a full batch means more events may be due, the relay goes on until a batch comes back short
+	gomock.InOrder(
+		relayBatch(store,
+			db.OutboxEvent{ID: 1, EventType: db.EventAccountCreated, Payload: []byte(`{}`)},
+			db.OutboxEvent{ID: 2, EventType: db.EventTransferCompleted, Payload: []byte(`{}`)},
+		),
+		relayBatch(store, db.OutboxEvent{ID: 3, EventType: db.EventTransferVoided, Payload: []byte(`{}`)}),
+	)
+
+	result, err := relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 3, result.Published)
+	require.Zero(t, result.Failed)
+
+	published := publisher.Events()
+	require.Len(t, published, 3)
+	require.Equal(t, db.EventTransferCompleted, published[1].Type)
+}
+
+func TestRelayOnceFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockStore(ctrl)
+	publisher := NewMemoryPublisher()
+	publisher.FailWith(errors.New("unavailable"))
+	relay := NewRelay(store, publisher, config.Config{
+		OutboxRetryBaseDelay: time.Second,
+		OutboxRetryMaxDelay:  time.Minute,
+	})
+
+	relayBatch(store, db.OutboxEvent{ID: 1}).Times(1)
+
+	result, err := relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, result.Failed)
+	require.Empty(t, publisher.Events())
+
+	require.Equal(t, time.Second, relay.retryDelay(1))
+	require.Equal(t, 8*time.Second, relay.retryDelay(4))
+	require.Equal(t, time.Minute, relay.retryDelay(20))
+}
+
+// recorderFunc records events with a func
+type recorderFunc func(ctx context.Context, queries db.Querier, event Event) error
+
+func (f recorderFunc) Record(ctx context.Context, queries db.Querier, event Event) error {
+	return f(ctx, queries, event)
+}
+
+func TestRelayOnceRecorders(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockStore(ctrl)
+	publisher := NewMemoryPublisher()
+
+	var recorded []int64
+	relay := NewRelay(store, publisher, config.Config{}, recorderFunc(func(_ context.Context, queries db.Querier, event Event) error {
+		// the recorder writes with the queries of the relay transaction
+		require.Equal(t, store, queries)
+		recorded = append(recorded, event.ID)
+		return nil
+	}))
+
+	relayBatch(store, db.OutboxEvent{ID: 1}, db.OutboxEvent{ID: 2}).Times(1)
+
+	result, err := relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, result.Published)
+	require.Equal(t, []int64{1, 2}, recorded)
+
+	// events that could not be published aren't recorded
+	publisher.FailWith(errors.New("unavailable"))
+	relayBatch(store, db.OutboxEvent{ID: 3}).Times(1)
+
+	_, err = relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []int64{1, 2}, recorded)
+}
+
+func TestRelayOnceStoreError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockStore(ctrl)
+	relay := NewRelay(store, NewMemoryPublisher(), config.Config{})
+
+	store.EXPECT().
+		RelayOutboxTx(gomock.Any(), gomock.Any()).
+		Return(db.RelayOutboxTxResult{}, errors.New("connection reset")).
+		Times(1)
+
+	_, err := relay.RelayOnce(context.Background())
+	require.Error(t, err)
+}
@@ -0,0 +1,145 @@
+package gapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"regexp"
+	"strings"
+
+	commonutils "github.com/primarybank/common/utils"
+	db "github.com/primarybank/db/sqlc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// RequestIDMetadataKey carries the id of a call, like the X-Request-ID header of the HTTP API
+const RequestIDMetadataKey = "x-request-id"
+
+// forwardedForMetadataKey is set by the gateway with the address of the http client
+const forwardedForMetadataKey = "x-forwarded-for"
+
+// request ids sent by clients are kept when they look like ids, anything else is replaced
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// auditChange is the resource a call changed with its snapshots before and after the change
+type auditChange struct {
+	resourceType string
+	resourceID   string
+	before       []byte
+	after        []byte
+}
+
+type auditChangeKey struct{}
+
+// audit describes the change made by the current call for the audit log, see the audit func of the HTTP API
+func audit(ctx context.Context, resourceType string, resourceID any, before any, after any) {
+	change, ok := ctx.Value(auditChangeKey{}).(*auditChange)
+	if !ok {
+		return
+	}
+
+	*change = auditChange{
+		resourceType: resourceType,
+		resourceID:   fmt.Sprint(resourceID),
+		before:       auditSnapshot(before),
+		after:        auditSnapshot(after),
+	}
+}
+
+func auditSnapshot(resource any) []byte {
+	if resource == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(resource)
+	if err != nil {
+		log.Printf("cannot snapshot %T for the audit log: %v", resource, err)
+		return nil
+	}
+	return data
+}
+
+// AuditInterceptor runs every call of a method making changes as one db transaction and appends the successful
+// ones to the audit log in that same transaction, like AuditMiddleware. It runs after AuthInterceptor so the
+// caller is known.
+func AuditInterceptor(store db.Store) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !methodPolicies[info.FullMethod].changes {
+			return handler(ctx, req)
+		}
+
+		var resp any
+		var handlerErr error
+		err := store.ExecAuditedTx(ctx, func(txCtx context.Context) (*db.AppendAuditLogTxParams, error) {
+			change := &auditChange{}
+			resp, handlerErr = handler(context.WithValue(txCtx, auditChangeKey{}, change), req)
+			if handlerErr != nil {
+				return nil, nil
+			}
+
+			return &db.AppendAuditLogTxParams{
+				Actor:        authzPayload(ctx).Username,
+				Action:       info.FullMethod,
+				ResourceType: change.resourceType,
+				ResourceID:   change.resourceID,
+				Before:       change.before,
+				After:        change.after,
+				RequestID:    requestID(ctx),
+				ClientIP:     clientIP(ctx),
+			}, nil
+		})
+
+		if handlerErr != nil {
+			if err != nil {
+				log.Printf("cannot commit failed call of %s: %v", info.FullMethod, err)
+			}
+			return resp, handlerErr
+		}
+		if err != nil {
+			return nil, err
+		}
+		return resp, nil
+	}
+}
+
+func requestID(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(RequestIDMetadataKey); len(values) > 0 && requestIDPattern.MatchString(values[0]) {
+			return values[0]
+		}
+	}
+
+	id, err := commonutils.RandomSecret(16)
+	if err != nil {
+		return ""
+	}
+	return id
+}
+
+// clientIP is the address of the caller, or of the client the gateway forwarded the call for. Only the calls
+// of the in-process gateway connection are trusted with x-forwarded-for, any other caller could set it.
+func clientIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+
+	if _, ok := p.Addr.(gatewayAddr); ok {
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(forwardedForMetadataKey); len(values) > 0 {
+				return strings.TrimSpace(strings.Split(values[0], ",")[0])
+			}
+		}
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
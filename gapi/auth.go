@@ -0,0 +1,101 @@
+package gapi
+
+import (
+	"context"
+
+	"github.com/primarybank/api"
+	"github.com/primarybank/config"
+	db "github.com/primarybank/db/sqlc"
+	"github.com/primarybank/pb"
+	"github.com/primarybank/token"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// AuthMetadataKey carries the credentials of a call, with the same value as the authorization header of the HTTP API
+const AuthMetadataKey = "authorization"
+
+// methodPolicy is what a method takes on top of authentication
+type methodPolicy struct {
+	// scopes are those of the HTTP route doing the same
+	scopes []string
+	// moneyMovement methods are closed to unverified users when configured
+	moneyMovement bool
+	// changes are appended to the audit log
+	changes bool
+}
+
+var methodPolicies = map[string]methodPolicy{
+	pb.PrimaryBank_GetUser_FullMethodName:        {scopes: []string{token.ScopeProfileRead}},
+	pb.PrimaryBank_CreateAccount_FullMethodName:  {scopes: []string{token.ScopeAccountsWrite}, changes: true},
+	pb.PrimaryBank_GetAccount_FullMethodName:     {scopes: []string{token.ScopeAccountsRead}},
+	pb.PrimaryBank_ListAccounts_FullMethodName:   {scopes: []string{token.ScopeAccountsRead}},
+	pb.PrimaryBank_CreateTransfer_FullMethodName: {scopes: []string{token.ScopeTransfersWrite}, moneyMovement: true, changes: true},
+	pb.PrimaryBank_GetTransfer_FullMethodName:    {scopes: []string{token.ScopeTransfersRead}},
+	pb.PrimaryBank_ListTransfers_FullMethodName:  {scopes: []string{token.ScopeTransfersRead}},
+	pb.PrimaryBank_GetEntry_FullMethodName:       {scopes: []string{token.ScopeEntriesRead}},
+	pb.PrimaryBank_ListEntries_FullMethodName:    {scopes: []string{token.ScopeEntriesRead}},
+}
+
+type authzPayloadKey struct{}
+
+// AuthInterceptor authenticates every call like AuthMiddleWare and only lets it through with the scopes of its method.
+// Money movement methods are refused to unverified users when emailVerificationRequiredFor says so,
+// like VerifiedEmailMiddleware. Methods missing from the policies are refused.
+func AuthInterceptor(tokenMaker token.Maker, store db.Store, emailVerificationRequiredFor string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		policy, ok := methodPolicies[info.FullMethod]
+		if !ok {
+			return nil, api.ErrInsufficientScope
+		}
+
+		var authzHeader string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(AuthMetadataKey); len(values) > 0 {
+				authzHeader = values[0]
+			}
+		}
+
+		payload, _, err := api.Authenticate(ctx, tokenMaker, store, authzHeader)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, scope := range policy.scopes {
+			if !payload.HasScope(scope) {
+				return nil, api.ErrInsufficientScope
+			}
+		}
+
+		if policy.moneyMovement {
+			if err := requireVerifiedEmail(ctx, store, emailVerificationRequiredFor, payload.Username); err != nil {
+				return nil, err
+			}
+		}
+
+		return handler(context.WithValue(ctx, authzPayloadKey{}, payload), req)
+	}
+}
+
+// requireVerifiedEmail keeps unverified users from moving money when requiredFor says so
+func requireVerifiedEmail(ctx context.Context, store db.Store, requiredFor string, username string) error {
+	switch requiredFor {
+	case config.EmailVerificationRequiredForLogin, config.EmailVerificationRequiredForMoneyMovement:
+	default:
+		return nil
+	}
+
+	user, err := store.GetUser(ctx, username)
+	if err != nil {
+		return err
+	}
+	if !user.EmailVerifiedAt.Valid {
+		return api.ErrEmailNotVerified
+	}
+	return nil
+}
+
+// authzPayload returns the payload of the caller, which every method has past AuthInterceptor
+func authzPayload(ctx context.Context) *token.Payload {
+	return ctx.Value(authzPayloadKey{}).(*token.Payload)
+}
@@ -0,0 +1,72 @@
+package gapi
+
+import (
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/primarybank/db/sqlc"
+	"github.com/primarybank/pb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func convertUser(user db.User) *pb.User {
+	return &pb.User{
+		Username:      user.Username,
+		FullName:      user.FullName,
+		Email:         user.Email,
+		Role:          user.Role,
+		EmailVerified: user.EmailVerifiedAt.Valid,
+		CreatedAt:     timestamppb.New(user.CreatedAt),
+		UpdatedAt:     timestamppb.New(user.UpdatedAt),
+	}
+}
+
+func convertAccount(account db.Account) *pb.Account {
+	return &pb.Account{
+		Id:             account.ID,
+		Owner:          account.Owner,
+		Balance:        account.Balance,
+		Currency:       account.Currency,
+		OverdraftLimit: account.OverdraftLimit,
+		CreatedAt:      timestamppb.New(account.CreatedAt),
+	}
+}
+
+func convertTransfer(transfer db.Transfer) *pb.Transfer {
+	return &pb.Transfer{
+		Id:            transfer.ID,
+		FromAccountId: transfer.FromAccountID,
+		ToAccountId:   transfer.ToAccountID,
+		Amount:        transfer.Amount,
+		Fee:           transfer.Fee,
+		FeeRuleId:     convertInt8(transfer.FeeRuleID),
+		Status:        transfer.Status,
+		CreatedAt:     timestamppb.New(transfer.CreatedAt),
+		PostedAt:      convertTimestamptz(transfer.PostedAt),
+		VoidedAt:      convertTimestamptz(transfer.VoidedAt),
+	}
+}
+
+func convertEntry(entry db.Entry) *pb.Entry {
+	return &pb.Entry{
+		Id:        entry.ID,
+		AccountId: entry.AccountID,
+		Amount:    entry.Amount,
+		FeeRuleId: convertInt8(entry.FeeRuleID),
+		PrevHash:  entry.PrevHash,
+		Hash:      entry.Hash,
+		CreatedAt: timestamppb.New(entry.CreatedAt),
+	}
+}
+
+func convertInt8(value pgtype.Int8) *int64 {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Int64
+}
+
+func convertTimestamptz(value pgtype.Timestamptz) *timestamppb.Timestamp {
+	if !value.Valid {
+		return nil
+	}
+	return timestamppb.New(value.Time)
+}
@@ -0,0 +1,97 @@
+package gapi
+
+import (
+	"context"
+	"log"
+
+	commonerrors "github.com/primarybank/common/errors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+// errorDomain names the bank in the ErrorInfo details of failed calls
+const errorDomain = "primarybank"
+
+var errInvalidRequest = commonerrors.New(commonerrors.KindValidation, "invalid_request", "request has invalid fields")
+
+var errApprovalRequired = commonerrors.New(commonerrors.KindUnprocessable, "approval_required", "transfers above the approval threshold are requested through the HTTP API")
+
+// errorKindMetadataKey holds the kind of a typed error in the metadata of its ErrorInfo,
+// the gateway needs it to render the problem the HTTP API would
+const errorKindMetadataKey = "kind"
+
+// ErrorInterceptor turns the errors of the handlers into statuses, the way ErrorHandler turns them into problems.
+// The code of a typed error is sent in an ErrorInfo and the fields it blames in a BadRequest.
+func ErrorInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		resp, err := handler(ctx, req)
+		if err == nil {
+			return resp, nil
+		}
+
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+
+		return nil, toStatus(info.FullMethod, err).Err()
+	}
+}
+
+func toStatus(method string, err error) *status.Status {
+	typed, ok := commonerrors.As(err)
+	if !ok || typed.Kind == commonerrors.KindInternal {
+		log.Printf("%s failed: %v", method, err)
+		return status.New(codes.Internal, "internal server error")
+	}
+
+	st := status.New(statusCode(typed), typed.Message)
+
+	details := []protoadapt.MessageV1{&errdetails.ErrorInfo{
+		Reason:   typed.Code,
+		Domain:   errorDomain,
+		Metadata: map[string]string{errorKindMetadataKey: string(typed.Kind)},
+	}}
+	if len(typed.Fields) > 0 {
+		badRequest := &errdetails.BadRequest{}
+		for _, field := range typed.Fields {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       field.Field,
+				Description: field.Reason,
+			})
+		}
+		details = append(details, badRequest)
+	}
+
+	withDetails, err := st.WithDetails(details...)
+	if err != nil {
+		return st
+	}
+	return withDetails
+}
+
+func statusCode(err *commonerrors.Error) codes.Code {
+	switch err.Kind {
+	case commonerrors.KindNotFound:
+		return codes.NotFound
+	case commonerrors.KindConflict:
+		if err.Code == "already_exists" {
+			return codes.AlreadyExists
+		}
+		return codes.FailedPrecondition
+	case commonerrors.KindInsufficientFunds, commonerrors.KindUnprocessable:
+		return codes.FailedPrecondition
+	case commonerrors.KindForbidden:
+		return codes.PermissionDenied
+	case commonerrors.KindUnauthorized:
+		return codes.Unauthenticated
+	case commonerrors.KindTooManyRequests:
+		return codes.ResourceExhausted
+	case commonerrors.KindValidation:
+		return codes.InvalidArgument
+	default:
+		return codes.Internal
+	}
+}
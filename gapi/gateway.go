@@ -0,0 +1,105 @@
+package gapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/primarybank/api"
+	commonerrors "github.com/primarybank/common/errors"
+	"github.com/primarybank/pb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// kinds of the statuses that were not made from a typed error, such as those of the gateway itself
+var codeKinds = map[codes.Code]commonerrors.Kind{
+	codes.InvalidArgument:    commonerrors.KindValidation,
+	codes.NotFound:           commonerrors.KindNotFound,
+	codes.AlreadyExists:      commonerrors.KindConflict,
+	codes.FailedPrecondition: commonerrors.KindUnprocessable,
+	codes.PermissionDenied:   commonerrors.KindForbidden,
+	codes.Unauthenticated:    commonerrors.KindUnauthorized,
+	codes.ResourceExhausted:  commonerrors.KindTooManyRequests,
+}
+
+// NewGateway returns an http handler serving the PrimaryBank service at the URLs of its http annotations.
+// Every request is forwarded to client, so it goes through the interceptors of the gRPC server.
+// Responses are encoded like those of the HTTP API and failures are rendered as its problems.
+func NewGateway(ctx context.Context, client pb.PrimaryBankClient) (http.Handler, error) {
+	mux := runtime.NewServeMux(
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, restMarshaler{&runtime.JSONPb{
+			MarshalOptions: protojson.MarshalOptions{
+				UseProtoNames:   true,
+				EmitUnpopulated: true,
+			},
+			UnmarshalOptions: protojson.UnmarshalOptions{
+				DiscardUnknown: true,
+			},
+		}}),
+		runtime.WithIncomingHeaderMatcher(gatewayHeaderMatcher),
+		runtime.WithErrorHandler(gatewayErrorHandler),
+	)
+
+	if err := pb.RegisterPrimaryBankHandlerClient(ctx, mux, client); err != nil {
+		return nil, fmt.Errorf("cannot register gateway handlers: %w", err)
+	}
+	return mux, nil
+}
+
+// gatewayHeaderMatcher forwards the request id along with the headers forwarded by default
+func gatewayHeaderMatcher(key string) (string, bool) {
+	if strings.EqualFold(key, api.RequestIDHeader) {
+		return RequestIDMetadataKey, true
+	}
+	return runtime.DefaultHeaderMatcher(key)
+}
+
+func gatewayErrorHandler(ctx context.Context, mux *runtime.ServeMux, marshaler runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+	problem := api.NewProblem(fromStatus(status.Convert(err)))
+	problem.Instance = r.URL.Path
+
+	w.Header().Set("Content-Type", api.ProblemContentType)
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
+}
+
+// fromStatus turns a status back into the typed error it was made from, see toStatus
+func fromStatus(st *status.Status) error {
+	kind, ok := codeKinds[st.Code()]
+	if !ok {
+		return st.Err()
+	}
+
+	typed := commonerrors.New(kind, string(kind), st.Message())
+	if kind == commonerrors.KindValidation {
+		typed.Code = errInvalidRequest.Code
+	}
+
+	for _, detail := range st.Details() {
+		switch detail := detail.(type) {
+		case *errdetails.ErrorInfo:
+			if detail.Domain != errorDomain {
+				continue
+			}
+			typed.Code = detail.Reason
+			if kind, ok := detail.Metadata[errorKindMetadataKey]; ok {
+				typed.Kind = commonerrors.Kind(kind)
+			}
+		case *errdetails.BadRequest:
+			for _, violation := range detail.FieldViolations {
+				typed.Fields = append(typed.Fields, commonerrors.FieldError{
+					Field:  violation.Field,
+					Reason: violation.Description,
+				})
+			}
+		}
+	}
+
+	return typed
+}
@@ -0,0 +1,66 @@
+package gapi
+
+import (
+	"context"
+	"net"
+	"sync"
+)
+
+// gatewayAddr is the remote address of the connections of the in-process gateway. Calls made over them are the
+// only ones trusted with the address of the http client they were forwarded for.
+type gatewayAddr struct{}
+
+func (gatewayAddr) Network() string { return "gateway" }
+func (gatewayAddr) String() string  { return "gateway" }
+
+// gatewayConn is the server side of a connection of the gateway
+type gatewayConn struct {
+	net.Conn
+}
+
+func (gatewayConn) RemoteAddr() net.Addr { return gatewayAddr{} }
+
+// gatewayListener hands the gRPC server the connections the gateway dials in memory
+type gatewayListener struct {
+	conns     chan net.Conn
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newGatewayListener() *gatewayListener {
+	return &gatewayListener{
+		conns: make(chan net.Conn),
+		done:  make(chan struct{}),
+	}
+}
+
+func (l *gatewayListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *gatewayListener) Close() error {
+	l.closeOnce.Do(func() { close(l.done) })
+	return nil
+}
+
+func (l *gatewayListener) Addr() net.Addr {
+	return gatewayAddr{}
+}
+
+// dial connects to the gRPC server serving the listener
+func (l *gatewayListener) dial(ctx context.Context, _ string) (net.Conn, error) {
+	client, server := net.Pipe()
+	select {
+	case l.conns <- gatewayConn{server}:
+		return client, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,124 @@
+package gapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// restMarshaler renders messages as the HTTP API renders the structs they mirror: protojson writes 64-bit
+// integers as strings, pads the fractions of timestamps and leaves out unset optional fields, the HTTP API
+// writes numbers, trimmed fractions and nulls
+type restMarshaler struct {
+	*runtime.JSONPb
+}
+
+// Marshal takes the messages and the lists of messages the gateway extracts as response bodies
+func (m restMarshaler) Marshal(v any) ([]byte, error) {
+	data, err := m.JSONPb.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var descriptor protoreflect.MessageDescriptor
+	switch value := v.(type) {
+	case proto.Message:
+		descriptor = value.ProtoReflect().Descriptor()
+	default:
+		t := reflect.TypeOf(v)
+		if t == nil || t.Kind() != reflect.Slice || !t.Elem().Implements(reflect.TypeFor[proto.Message]()) {
+			return data, nil
+		}
+		descriptor = reflect.Zero(t.Elem()).Interface().(proto.Message).ProtoReflect().Descriptor()
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var decoded any
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("cannot decode response: %w", err)
+	}
+
+	if list, ok := decoded.([]any); ok {
+		for _, element := range list {
+			restFields(element, descriptor)
+		}
+	} else {
+		restFields(decoded, descriptor)
+	}
+	return json.Marshal(decoded)
+}
+
+// restFields rewrites the fields of a message decoded from protojson in place
+func restFields(value any, descriptor protoreflect.MessageDescriptor) {
+	object, ok := value.(map[string]any)
+	if !ok {
+		return
+	}
+
+	fields := descriptor.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		name := string(field.Name())
+
+		fieldValue, present := object[name]
+		if !present {
+			if field.HasPresence() {
+				object[name] = nil
+			}
+			continue
+		}
+
+		switch {
+		case field.IsMap():
+			if entries, ok := fieldValue.(map[string]any); ok {
+				for key, entry := range entries {
+					entries[key] = restValue(entry, field.MapValue())
+				}
+			}
+		case field.IsList():
+			if elements, ok := fieldValue.([]any); ok {
+				for j, element := range elements {
+					elements[j] = restValue(element, field)
+				}
+			}
+		default:
+			object[name] = restValue(fieldValue, field)
+		}
+	}
+}
+
+// restValue turns the 64-bit integers protojson quoted back into numbers, formats timestamps as encoding/json
+// does and descends into messages
+func restValue(value any, field protoreflect.FieldDescriptor) any {
+	switch field.Kind() {
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		if quoted, ok := value.(string); ok {
+			return json.Number(quoted)
+		}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		switch field.Message().FullName() {
+		case timestampFullName:
+			if formatted, ok := value.(string); ok {
+				if timestamp, err := time.Parse(time.RFC3339Nano, formatted); err == nil {
+					return timestamp.Format(time.RFC3339Nano)
+				}
+			}
+		default:
+			if field.Message().FullName().Parent() != "google.protobuf" {
+				restFields(value, field.Message())
+			}
+		}
+	}
+	return value
+}
+
+var timestampFullName = (&timestamppb.Timestamp{}).ProtoReflect().Descriptor().FullName()
@@ -0,0 +1,65 @@
+package gapi
+
+import (
+	"context"
+
+	db "github.com/primarybank/db/sqlc"
+	"github.com/primarybank/pb"
+)
+
+func (s *Server) CreateAccount(ctx context.Context, req *pb.CreateAccountRequest) (*pb.CreateAccountResponse, error) {
+	var v violations
+	v.check(req.GetOwner() != "", "owner", "is required")
+	v.checkCurrency(req.GetCurrency())
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	account, err := s.store.CreateAccountTx(ctx, db.CreateAccountParams{
+		Owner:    req.GetOwner(),
+		Currency: req.GetCurrency(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	audit(ctx, "account", account.ID, nil, account)
+	return &pb.CreateAccountResponse{Account: convertAccount(account)}, nil
+}
+
+func (s *Server) GetAccount(ctx context.Context, req *pb.GetAccountRequest) (*pb.GetAccountResponse, error) {
+	var v violations
+	v.checkID(req.GetId(), "id")
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	account, err := s.store.GetAccount(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.GetAccountResponse{Account: convertAccount(account)}, nil
+}
+
+func (s *Server) ListAccounts(ctx context.Context, req *pb.ListAccountsRequest) (*pb.ListAccountsResponse, error) {
+	var v violations
+	v.checkPage(req.GetPageId(), req.GetPageSize())
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	accounts, err := s.store.ListAccounts(ctx, db.ListAccountsParams{
+		Limit:  req.GetPageSize(),
+		Offset: (req.GetPageId() - 1) * req.GetPageSize(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &pb.ListAccountsResponse{Accounts: make([]*pb.Account, 0, len(accounts))}
+	for _, account := range accounts {
+		resp.Accounts = append(resp.Accounts, convertAccount(account))
+	}
+	return resp, nil
+}
@@ -0,0 +1,45 @@
+package gapi
+
+import (
+	"context"
+
+	db "github.com/primarybank/db/sqlc"
+	"github.com/primarybank/pb"
+)
+
+func (s *Server) GetEntry(ctx context.Context, req *pb.GetEntryRequest) (*pb.GetEntryResponse, error) {
+	var v violations
+	v.checkID(req.GetId(), "id")
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	entry, err := s.store.GetEntry(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.GetEntryResponse{Entry: convertEntry(entry)}, nil
+}
+
+func (s *Server) ListEntries(ctx context.Context, req *pb.ListEntriesRequest) (*pb.ListEntriesResponse, error) {
+	var v violations
+	v.checkPage(req.GetPageId(), req.GetPageSize())
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	entries, err := s.store.ListEntries(ctx, db.ListEntriesParams{
+		Limit:  req.GetPageSize(),
+		Offset: (req.GetPageId() - 1) * req.GetPageSize(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &pb.ListEntriesResponse{Entries: make([]*pb.Entry, 0, len(entries))}
+	for _, entry := range entries {
+		resp.Entries = append(resp.Entries, convertEntry(entry))
+	}
+	return resp, nil
+}
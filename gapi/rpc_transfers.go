@@ -0,0 +1,106 @@
+package gapi
+
+import (
+	"context"
+	"time"
+
+	"github.com/primarybank/api"
+	db "github.com/primarybank/db/sqlc"
+	"github.com/primarybank/pb"
+)
+
+// CreateTransfer executes a transfer under the rules of the HTTP API, but for the approval flow:
+// amounts above the approval threshold are refused rather than requested
+func (s *Server) CreateTransfer(ctx context.Context, req *pb.CreateTransferRequest) (*pb.CreateTransferResponse, error) {
+	var v violations
+	v.checkID(req.GetFromAccountId(), "from_account_id")
+	v.checkID(req.GetToAccountId(), "to_account_id")
+	v.check(req.GetAmount() > 0, "amount", "must be greater than 0")
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	if err := s.requireStepUp(ctx, req.GetAmount()); err != nil {
+		return nil, err
+	}
+
+	if s.config.TransferApprovalThreshold > 0 && req.GetAmount() > s.config.TransferApprovalThreshold {
+		return nil, errApprovalRequired
+	}
+
+	result, err := s.store.TransferTx(ctx, db.TransferTxParams{
+		FromAccountID: req.GetFromAccountId(),
+		ToAccountID:   req.GetToAccountId(),
+		Amount:        req.GetAmount(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	audit(ctx, "transfer", result.Transfer.ID, nil, result.Transfer)
+
+	resp := &pb.CreateTransferResponse{
+		Transfer:    convertTransfer(result.Transfer),
+		FromAccount: convertAccount(result.FromAccount),
+		ToAccount:   convertAccount(result.ToAccount),
+		FromEntry:   convertEntry(result.FromEntry),
+		ToEntry:     convertEntry(result.ToEntry),
+	}
+	if result.FeeEntry != nil {
+		resp.FeeEntry = convertEntry(*result.FeeEntry)
+	}
+	if result.FeeIncomeEntry != nil {
+		resp.FeeIncomeEntry = convertEntry(*result.FeeIncomeEntry)
+	}
+	return resp, nil
+}
+
+func (s *Server) GetTransfer(ctx context.Context, req *pb.GetTransferRequest) (*pb.GetTransferResponse, error) {
+	var v violations
+	v.checkID(req.GetId(), "id")
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	transfer, err := s.store.GetTransfer(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.GetTransferResponse{Transfer: convertTransfer(transfer)}, nil
+}
+
+func (s *Server) ListTransfers(ctx context.Context, req *pb.ListTransfersRequest) (*pb.ListTransfersResponse, error) {
+	var v violations
+	v.checkPage(req.GetPageId(), req.GetPageSize())
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	transfers, err := s.store.ListTransfers(ctx, db.ListTransfersParams{
+		Limit:  req.GetPageSize(),
+		Offset: (req.GetPageId() - 1) * req.GetPageSize(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &pb.ListTransfersResponse{Transfers: make([]*pb.Transfer, 0, len(transfers))}
+	for _, transfer := range transfers {
+		resp.Transfers = append(resp.Transfers, convertTransfer(transfer))
+	}
+	return resp, nil
+}
+
+// requireStepUp asks for a recent second factor in the access token before amounts above the step-up threshold are moved.
+// Unlike the HTTP API there is no code to send along, API keys never carry one.
+func (s *Server) requireStepUp(ctx context.Context, amount int64) error {
+	if s.config.MFAStepUpThreshold == 0 || amount <= s.config.MFAStepUpThreshold {
+		return nil
+	}
+
+	if authzPayload(ctx).MFASince(time.Now().Add(-s.config.MFAStepUpMaxAge)) {
+		return nil
+	}
+	return api.ErrMFARequired
+}
@@ -0,0 +1,22 @@
+package gapi
+
+import (
+	"context"
+
+	"github.com/primarybank/pb"
+)
+
+func (s *Server) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.GetUserResponse, error) {
+	var v violations
+	v.check(req.GetUsername() != "", "username", "is required")
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	user, err := s.store.GetUser(ctx, req.GetUsername())
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.GetUserResponse{User: convertUser(user)}, nil
+}
@@ -0,0 +1,72 @@
+package gapi
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/primarybank/config"
+	db "github.com/primarybank/db/sqlc"
+	"github.com/primarybank/pb"
+	"github.com/primarybank/token"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Server serves the gRPC API of the bank, backed by the same store and token maker as the HTTP API
+type Server struct {
+	pb.UnimplementedPrimaryBankServer
+	store      db.Store
+	tokenMaker token.Maker
+	config     config.Config
+	gateway    *gatewayListener
+}
+
+func NewServer(cfg config.Config, store db.Store, tokenMaker token.Maker) *Server {
+	return &Server{
+		store:      store,
+		tokenMaker: tokenMaker,
+		config:     cfg,
+		gateway:    newGatewayListener(),
+	}
+}
+
+// GRPCServer returns a grpc server with the PrimaryBank service registered behind the error, auth and audit interceptors
+func (s *Server) GRPCServer() *grpc.Server {
+	grpcServer := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		ErrorInterceptor(),
+		AuthInterceptor(s.tokenMaker, s.store, s.config.EmailVerificationRequiredFor),
+		AuditInterceptor(s.store),
+	))
+	pb.RegisterPrimaryBankServer(grpcServer, s)
+
+	return grpcServer
+}
+
+func (s *Server) Start(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("cannot listen on %s: %w", addr, err)
+	}
+
+	grpcServer := s.GRPCServer()
+	go s.ServeGateway(grpcServer)
+	return grpcServer.Serve(listener)
+}
+
+// ServeGateway serves grpcServer to the connections of GatewayConn
+func (s *Server) ServeGateway(grpcServer *grpc.Server) error {
+	return grpcServer.Serve(s.gateway)
+}
+
+// GatewayConn returns the in-process connection the gateway forwards over. Its calls are the only ones trusted with
+// the address of the http client they were forwarded for.
+func (s *Server) GatewayConn() (*grpc.ClientConn, error) {
+	conn, err := grpc.NewClient("passthrough:///gateway",
+		grpc.WithContextDialer(s.gateway.dial),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create gateway connection: %w", err)
+	}
+	return conn, nil
+}
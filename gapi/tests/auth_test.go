@@ -0,0 +1,146 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	commonutils "github.com/primarybank/common/utils"
+	"github.com/primarybank/config"
+	"github.com/primarybank/db/mocks"
+	db "github.com/primarybank/db/sqlc"
+	"github.com/primarybank/gapi"
+	"github.com/primarybank/pb"
+	"github.com/primarybank/token"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestAuthInterceptor(t *testing.T) {
+	account := db.Account{ID: 1, Owner: "alice", Currency: "USD", CreatedAt: time.Now()}
+
+	testCases := []struct {
+		name       string
+		buildCtx   func(t *testing.T, client *testClient) context.Context
+		buildStubs func(store *mocks.MockStore)
+		checkResp  func(t *testing.T, resp *pb.GetAccountResponse, err error)
+	}{
+		{
+			name: "OK",
+			buildCtx: func(t *testing.T, client *testClient) context.Context {
+				return client.as(t, "alice", token.WithScopes(token.ScopeAccountsRead))
+			},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), account.ID).Return(account, nil).Times(1)
+			},
+			checkResp: func(t *testing.T, resp *pb.GetAccountResponse, err error) {
+				require.NoError(t, err)
+				require.Equal(t, account.Owner, resp.Account.Owner)
+			},
+		},
+		{
+			name: "No Authorization",
+			buildCtx: func(t *testing.T, client *testClient) context.Context {
+				return context.Background()
+			},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, resp *pb.GetAccountResponse, err error) {
+				requireStatus(t, err, codes.Unauthenticated, "unauthorized")
+			},
+		},
+		{
+			name: "Invalid Token",
+			buildCtx: func(t *testing.T, client *testClient) context.Context {
+				return metadata.AppendToOutgoingContext(context.Background(), gapi.AuthMetadataKey, "bearer "+commonutils.RandomString(20))
+			},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, resp *pb.GetAccountResponse, err error) {
+				requireStatus(t, err, codes.Unauthenticated, "unauthorized")
+			},
+		},
+		{
+			name: "Insufficient Scope",
+			buildCtx: func(t *testing.T, client *testClient) context.Context {
+				return client.as(t, "alice", token.WithScopes(token.ScopeTransfersRead))
+			},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, resp *pb.GetAccountResponse, err error) {
+				requireStatus(t, err, codes.PermissionDenied, "insufficient_scope")
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mocks.NewMockStore(ctrl)
+			client := newTestClient(t, store)
+			tc.buildStubs(store)
+
+			resp, err := client.GetAccount(tc.buildCtx(t, client), &pb.GetAccountRequest{Id: account.ID})
+			tc.checkResp(t, resp, err)
+		})
+	}
+}
+
+func TestAuthInterceptorAPIKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockStore(ctrl)
+	client := newTestClient(t, store)
+
+	secret := commonutils.RandomString(32)
+	apiKey := db.ApiKey{
+		ID:         1,
+		Username:   "alice",
+		Prefix:     "abcdef",
+		SecretHash: commonutils.HashSecret(secret),
+		Scopes:     []string{token.ScopeEntriesRead},
+	}
+	store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), apiKey.Prefix).Return(apiKey, nil).Times(1)
+	store.EXPECT().TouchAPIKey(gomock.Any(), apiKey.ID).Return(nil).Times(1)
+	store.EXPECT().GetEntry(gomock.Any(), int64(7)).Return(db.Entry{ID: 7, AccountID: 1, Amount: 10}, nil).Times(1)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), gapi.AuthMetadataKey, "ApiKey pbk_"+apiKey.Prefix+"."+secret)
+	resp, err := client.GetEntry(ctx, &pb.GetEntryRequest{Id: 7})
+	require.NoError(t, err)
+	require.Equal(t, int64(10), resp.Entry.Amount)
+	require.Nil(t, resp.Entry.FeeRuleId)
+}
+
+func TestAuthInterceptorVerifiedEmail(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockStore(ctrl)
+	client := newConfiguredTestClient(t, store, func(cfg *config.Config) {
+		cfg.EmailVerificationRequiredFor = config.EmailVerificationRequiredForMoneyMovement
+	})
+
+	// money movement is refused before the method looks at the request
+	store.EXPECT().GetUser(gomock.Any(), "alice").Return(db.User{Username: "alice"}, nil).Times(1)
+	store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+
+	_, err := client.CreateTransfer(client.as(t, "alice"), &pb.CreateTransferRequest{})
+	requireStatus(t, err, codes.PermissionDenied, "email_not_verified")
+
+	// the other methods stay open to unverified users
+	store.EXPECT().
+		CreateAccountTx(gomock.Any(), gomock.Any()).
+		Return(db.Account{ID: 1, Owner: "alice", Currency: "EUR", CreatedAt: time.Now()}, nil).
+		Times(1)
+
+	_, err = client.CreateAccount(client.as(t, "alice"), &pb.CreateAccountRequest{Owner: "alice", Currency: "EUR"})
+	require.NoError(t, err)
+}
@@ -0,0 +1,87 @@
+package tests
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	commonerrors "github.com/primarybank/common/errors"
+	"github.com/primarybank/db/mocks"
+	db "github.com/primarybank/db/sqlc"
+	"github.com/primarybank/pb"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errConnectionLost stands for untyped errors, whose message must not reach clients
+var errConnectionLost = errors.New("connection to 10.0.0.5:5432 lost")
+
+// alreadyExists builds the error the store returns on a unique violation of the given columns
+func alreadyExists(fields ...string) error {
+	fieldErrors := make([]commonerrors.FieldError, 0, len(fields))
+	for _, field := range fields {
+		fieldErrors = append(fieldErrors, commonerrors.FieldError{Field: field, Reason: "already exists"})
+	}
+
+	message := strings.Join(fields, ", ") + " already exists"
+	return commonerrors.New(commonerrors.KindConflict, "already_exists", message).WithFields(fieldErrors...)
+}
+
+func TestErrorInterceptor(t *testing.T) {
+	testCases := []struct {
+		name   string
+		err    error
+		code   codes.Code
+		reason string
+	}{
+		{
+			name:   "Not Found",
+			err:    db.ErrNotFound,
+			code:   codes.NotFound,
+			reason: "not_found",
+		},
+		{
+			name:   "Conflict",
+			err:    db.ErrEntryNotLatest,
+			code:   codes.FailedPrecondition,
+			reason: "entry_not_latest",
+		},
+		{
+			name:   "Overdraft Limit Exceeded",
+			err:    &db.OverdraftError{AccountID: 1},
+			code:   codes.FailedPrecondition,
+			reason: "overdraft_limit_exceeded",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mocks.NewMockStore(ctrl)
+			client := newTestClient(t, store)
+			store.EXPECT().GetTransfer(gomock.Any(), int64(1)).Return(db.Transfer{}, tc.err).Times(1)
+
+			_, err := client.GetTransfer(client.as(t, "alice"), &pb.GetTransferRequest{Id: 1})
+			requireStatus(t, err, tc.code, tc.reason)
+		})
+	}
+}
+
+func TestErrorInterceptorInternal(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockStore(ctrl)
+	client := newTestClient(t, store)
+	store.EXPECT().GetTransfer(gomock.Any(), int64(1)).Return(db.Transfer{}, errConnectionLost).Times(1)
+
+	_, err := client.GetTransfer(client.as(t, "alice"), &pb.GetTransferRequest{Id: 1})
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	require.Equal(t, codes.Internal, st.Code())
+	require.Equal(t, "internal server error", st.Message())
+}
@@ -0,0 +1,401 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/primarybank/api"
+	commonerrors "github.com/primarybank/common/errors"
+	"github.com/primarybank/config"
+	"github.com/primarybank/db/mocks"
+	db "github.com/primarybank/db/sqlc"
+	"github.com/primarybank/gapi"
+	"github.com/primarybank/pb"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+// serveGateway serves req with a gateway forwarding to the server of client, authenticated as username unless it is empty
+func serveGateway(t *testing.T, client *testClient, req *http.Request, username string) *httptest.ResponseRecorder {
+	gateway, err := gapi.NewGateway(context.Background(), client.gateway)
+	require.NoError(t, err)
+
+	if username != "" {
+		accessToken, err := client.tokenMaker.CreateToken(username, time.Minute)
+		require.NoError(t, err)
+		req.Header.Set(api.AuthHeaderKey, api.AuthType+" "+accessToken)
+	}
+
+	recorder := httptest.NewRecorder()
+	gateway.ServeHTTP(recorder, req)
+	return recorder
+}
+
+// requireGatewayProblem checks the gateway rendered the problem the HTTP API would
+func requireGatewayProblem(t *testing.T, recorder *httptest.ResponseRecorder, status int, code string) api.Problem {
+	require.Equal(t, status, recorder.Code)
+	require.Equal(t, api.ProblemContentType, recorder.Header().Get("Content-Type"))
+
+	var problem api.Problem
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &problem))
+	require.Equal(t, status, problem.Status)
+	require.Equal(t, code, problem.Code)
+	return problem
+}
+
+func TestGateway(t *testing.T) {
+	account := db.Account{
+		ID:        7,
+		Owner:     "alice",
+		Balance:   250,
+		Currency:  "USD",
+		CreatedAt: time.Now(),
+	}
+
+	testCases := []struct {
+		name       string
+		method     string
+		url        string
+		body       string
+		username   string
+		configure  func(cfg *config.Config)
+		buildStubs func(store *mocks.MockStore)
+		checkResp  func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "Get Account",
+			method:   http.MethodGet,
+			url:      "/v1/account/7",
+			username: "alice",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), account.ID).Return(account, nil).Times(1)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				// the resource is the body, with the field names and the number encoding of the HTTP API
+				var body map[string]any
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+				require.Equal(t, float64(7), body["id"])
+				require.Equal(t, "alice", body["owner"])
+				require.Equal(t, float64(0), body["overdraft_limit"])
+				require.Contains(t, body, "created_at")
+			},
+		},
+		{
+			name:     "List Accounts",
+			method:   http.MethodGet,
+			url:      "/v1/accounts?page_id=2&page_size=5",
+			username: "alice",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().
+					ListAccounts(gomock.Any(), db.ListAccountsParams{Limit: 5, Offset: 5}).
+					Return([]db.Account{account}, nil).
+					Times(1)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var body []map[string]any
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+				require.Len(t, body, 1)
+			},
+		},
+		{
+			name:     "Create Account",
+			method:   http.MethodPost,
+			url:      "/v1/account",
+			body:     `{"owner": "alice", "currency": "USD"}`,
+			username: "alice",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().
+					CreateAccountTx(gomock.Any(), db.CreateAccountParams{Owner: "alice", Currency: "USD"}).
+					Return(account, nil).
+					Times(1)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "Not Found",
+			method:   http.MethodGet,
+			url:      "/v1/account/7",
+			username: "alice",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), account.ID).Return(db.Account{}, db.ErrNotFound).Times(1)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				problem := requireGatewayProblem(t, recorder, http.StatusNotFound, "not_found")
+				require.Equal(t, "/v1/account/7", problem.Instance)
+			},
+		},
+		{
+			name:     "Invalid Page",
+			method:   http.MethodGet,
+			url:      "/v1/accounts?page_id=0&page_size=5",
+			username: "alice",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().ListAccounts(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				problem := requireGatewayProblem(t, recorder, http.StatusBadRequest, "invalid_request")
+				require.Len(t, problem.InvalidParams, 1)
+				require.Equal(t, "page_id", problem.InvalidParams[0].Name)
+			},
+		},
+		{
+			name:     "Malformed Path",
+			method:   http.MethodGet,
+			url:      "/v1/account/seven",
+			username: "alice",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireGatewayProblem(t, recorder, http.StatusBadRequest, "invalid_request")
+			},
+		},
+		{
+			name:   "Unauthenticated",
+			method: http.MethodGet,
+			url:    "/v1/account/7",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireGatewayProblem(t, recorder, http.StatusUnauthorized, "unauthorized")
+			},
+		},
+		{
+			name:     "Invalid Reference",
+			method:   http.MethodPost,
+			url:      "/v1/account",
+			body:     `{"owner": "nobody", "currency": "USD"}`,
+			username: "alice",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().
+					CreateAccountTx(gomock.Any(), gomock.Any()).
+					Return(db.Account{}, commonerrors.New(commonerrors.KindUnprocessable, "invalid_reference", "owner does not exist")).
+					Times(1)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// the same status as the HTTP API, not the one of the gRPC code
+				requireGatewayProblem(t, recorder, http.StatusUnprocessableEntity, "invalid_reference")
+			},
+		},
+		{
+			name:     "Transfer Not Served",
+			method:   http.MethodPost,
+			url:      "/v1/transfer",
+			body:     `{"from_account_id": 1, "to_account_id": 2, "amount": 50}`,
+			username: "alice",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireGatewayProblem(t, recorder, http.StatusNotFound, "not_found")
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mocks.NewMockStore(ctrl)
+			configure := tc.configure
+			if configure == nil {
+				configure = func(cfg *config.Config) {}
+			}
+			client := newConfiguredTestClient(t, store, configure)
+			tc.buildStubs(store)
+
+			req := httptest.NewRequest(tc.method, tc.url, strings.NewReader(tc.body))
+			tc.checkResp(t, serveGateway(t, client, req, tc.username))
+		})
+	}
+}
+
+func TestGatewayAudit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockStore(ctrl)
+	store.EXPECT().CreateAccountTx(gomock.Any(), gomock.Any()).Return(db.Account{ID: 3, Owner: "alice"}, nil).Times(1)
+	store.EXPECT().
+		AppendAuditLogTx(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ any, args db.AppendAuditLogTxParams) (db.AuditLog, error) {
+			// the call is audited with the request id and the address of the http client
+			require.Equal(t, "req-42", args.RequestID)
+			require.Equal(t, "203.0.113.9", args.ClientIP)
+			return db.AuditLog{}, nil
+		}).
+		Times(1)
+	client := newTestClient(t, store)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/account", strings.NewReader(`{"owner": "alice", "currency": "EUR"}`))
+	req.RemoteAddr = "203.0.113.9:4711"
+	req.Header.Set(api.RequestIDHeader, "req-42")
+
+	recorder := serveGateway(t, client, req, "alice")
+	require.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestAuditForwardedForUntrusted(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockStore(ctrl)
+	store.EXPECT().CreateAccountTx(gomock.Any(), gomock.Any()).Return(db.Account{ID: 3, Owner: "alice"}, nil).Times(1)
+	store.EXPECT().
+		AppendAuditLogTx(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ any, args db.AppendAuditLogTxParams) (db.AuditLog, error) {
+			// a caller other than the gateway is audited with its own address, whatever it claims to forward
+			require.NotEqual(t, "203.0.113.9", args.ClientIP)
+			require.NotEmpty(t, args.ClientIP)
+			return db.AuditLog{}, nil
+		}).
+		Times(1)
+	client := newTestClient(t, store)
+
+	ctx := metadata.AppendToOutgoingContext(client.as(t, "alice"), "x-forwarded-for", "203.0.113.9")
+	_, err := client.CreateAccount(ctx, &pb.CreateAccountRequest{Owner: "alice", Currency: "EUR"})
+	require.NoError(t, err)
+}
+
+// serveHTTPAPI serves req with the HTTP API sharing the config and the store of client, authenticated as username
+func serveHTTPAPI(t *testing.T, client *testClient, store db.Store, req *http.Request, username string) *httptest.ResponseRecorder {
+	cfg := client.config
+	cfg.MailSender = "memory"
+	cfg.MFAEncryptionKey = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+	cfg.WebhookEncryptionKey = "1f1e1d1c1b1a191817161514131211100f0e0d0c0b0a09080706050403020100"
+
+	gin.SetMode(gin.TestMode)
+	server, err := api.NewServer(cfg, store)
+	require.NoError(t, err)
+
+	accessToken, err := client.tokenMaker.CreateToken(username, time.Minute)
+	require.NoError(t, err)
+	req.Header.Set(api.AuthHeaderKey, api.AuthType+" "+accessToken)
+
+	recorder := httptest.NewRecorder()
+	server.Router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestGatewayMatchesHTTPAPI(t *testing.T) {
+	// timestamps keep a fraction, which protojson pads and encoding/json trims
+	createdAt := time.Date(2026, time.October, 19, 3, 19, 21, 123450000, time.UTC)
+	user := db.User{Username: "alice", FullName: "Alice", Email: "alice@example.com", Role: db.RoleDepositor, CreatedAt: createdAt, UpdatedAt: createdAt}
+	account := db.Account{ID: 7, Owner: "alice", Balance: 250, Currency: "USD", OverdraftLimit: 100, CreatedAt: createdAt}
+	transfer := db.Transfer{ID: 3, FromAccountID: 7, ToAccountID: 8, Amount: 50, Status: db.TransferPosted, CreatedAt: createdAt}
+	entry := db.Entry{ID: 5, AccountID: 7, Amount: -50, PrevHash: "ab", Hash: "cd", CreatedAt: createdAt}
+
+	testCases := []struct {
+		name       string
+		method     string
+		url        string
+		body       string
+		buildStubs func(store *mocks.MockStore)
+	}{
+		{
+			name:   "Get User",
+			method: http.MethodGet,
+			url:    "/v1/user/alice",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), "alice").Return(user, nil).AnyTimes()
+			},
+		},
+		{
+			name:   "Create Account",
+			method: http.MethodPost,
+			url:    "/v1/account",
+			body:   `{"owner": "alice", "currency": "USD"}`,
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().CreateAccountTx(gomock.Any(), gomock.Any()).Return(account, nil).AnyTimes()
+			},
+		},
+		{
+			name:   "Get Account",
+			method: http.MethodGet,
+			url:    "/v1/account/7",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), account.ID).Return(account, nil).AnyTimes()
+			},
+		},
+		{
+			name:   "List Accounts",
+			method: http.MethodGet,
+			url:    "/v1/accounts?page_id=1&page_size=5",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().ListAccounts(gomock.Any(), gomock.Any()).Return([]db.Account{account}, nil).AnyTimes()
+			},
+		},
+		{
+			name:   "Get Transfer",
+			method: http.MethodGet,
+			url:    "/v1/transfer/3",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), transfer.ID).Return(transfer, nil).AnyTimes()
+			},
+		},
+		{
+			name:   "List Transfers",
+			method: http.MethodGet,
+			url:    "/v1/transfers?page_id=1&page_size=5",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().ListTransfers(gomock.Any(), gomock.Any()).Return([]db.Transfer{transfer}, nil).AnyTimes()
+			},
+		},
+		{
+			name:   "Get Entry",
+			method: http.MethodGet,
+			url:    "/v1/entry/5",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().GetEntry(gomock.Any(), entry.ID).Return(entry, nil).AnyTimes()
+			},
+		},
+		{
+			name:   "List Entries",
+			method: http.MethodGet,
+			url:    "/v1/entries?page_id=1&page_size=5",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().ListEntries(gomock.Any(), gomock.Any()).Return([]db.Entry{entry}, nil).AnyTimes()
+			},
+		},
+		{
+			name:   "Not Found",
+			method: http.MethodGet,
+			url:    "/v1/account/9",
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), int64(9)).Return(db.Account{}, db.ErrNotFound).AnyTimes()
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mocks.NewMockStore(ctrl)
+			client := newTestClient(t, store)
+			tc.buildStubs(store)
+
+			viaGateway := serveGateway(t, client, httptest.NewRequest(tc.method, tc.url, strings.NewReader(tc.body)), "alice")
+			viaHTTPAPI := serveHTTPAPI(t, client, store, httptest.NewRequest(tc.method, tc.url, strings.NewReader(tc.body)), "alice")
+
+			require.Equal(t, viaHTTPAPI.Code, viaGateway.Code)
+			require.JSONEq(t, viaHTTPAPI.Body.String(), viaGateway.Body.String())
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	commonutils "github.com/primarybank/common/utils"
+	"github.com/primarybank/config"
+	"github.com/primarybank/db/mocks"
+	db "github.com/primarybank/db/sqlc"
+	"github.com/primarybank/gapi"
+	"github.com/primarybank/pb"
+	"github.com/primarybank/token"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// testClient calls a gRPC server listening in memory
+type testClient struct {
+	pb.PrimaryBankClient
+	// gateway calls the server over the connection of the gateway
+	gateway    pb.PrimaryBankClient
+	tokenMaker token.Maker
+	config     config.Config
+}
+
+func newTestClient(t *testing.T, store db.Store) *testClient {
+	return newConfiguredTestClient(t, store, func(cfg *config.Config) {})
+}
+
+// newConfiguredTestClient lets configure change the test config before the server is built
+func newConfiguredTestClient(t *testing.T, store db.Store, configure func(cfg *config.Config)) *testClient {
+	cfg := config.Config{
+		TokenSymmetricKey:   commonutils.RandomString(35),
+		AccessTokenDuration: 3 * time.Minute,
+		MFAStepUpMaxAge:     time.Minute,
+	}
+	configure(&cfg)
+
+	// every call making changes is audited, tests checking the audit log expect it before building the server
+	if mockStore, ok := store.(*mocks.MockStore); ok {
+		mockStore.EXPECT().AppendAuditLogTx(gomock.Any(), gomock.Any()).Return(db.AuditLog{}, nil).AnyTimes()
+		mocks.ExpectAuditedTx(mockStore)
+	}
+
+	tokenMaker, err := token.NewJWTMaker(cfg.TokenSymmetricKey)
+	require.NoError(t, err)
+
+	listener := bufconn.Listen(1024 * 1024)
+	server := gapi.NewServer(cfg, store, tokenMaker)
+	grpcServer := server.GRPCServer()
+	go grpcServer.Serve(listener)
+	go server.ServeGateway(grpcServer)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	gatewayConn, err := server.GatewayConn()
+	require.NoError(t, err)
+	t.Cleanup(func() { gatewayConn.Close() })
+
+	return &testClient{
+		PrimaryBankClient: pb.NewPrimaryBankClient(conn),
+		gateway:           pb.NewPrimaryBankClient(gatewayConn),
+		tokenMaker:        tokenMaker,
+		config:            cfg,
+	}
+}
+
+// as returns a context authenticating calls with an access token of username
+func (c *testClient) as(t *testing.T, username string, opts ...token.Option) context.Context {
+	accessToken, err := c.tokenMaker.CreateToken(username, time.Minute, opts...)
+	require.NoError(t, err)
+
+	return metadata.AppendToOutgoingContext(context.Background(), gapi.AuthMetadataKey, "bearer "+accessToken)
+}
+
+// requireStatus checks a call failed with the given code and the given reason in its ErrorInfo
+func requireStatus(t *testing.T, err error, code codes.Code, reason string) *status.Status {
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	require.Equal(t, code, st.Code())
+
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			require.Equal(t, reason, info.Reason)
+			return st
+		}
+	}
+	require.Failf(t, "missing error info", "status %v has no ErrorInfo", st)
+	return st
+}
+
+// fieldViolations returns the fields blamed by the BadRequest of a status
+func fieldViolations(st *status.Status) []string {
+	var fields []string
+	for _, detail := range st.Details() {
+		if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+			for _, violation := range badRequest.FieldViolations {
+				fields = append(fields, violation.Field)
+			}
+		}
+	}
+	return fields
+}
@@ -0,0 +1,171 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/primarybank/db/mocks"
+	db "github.com/primarybank/db/sqlc"
+	"github.com/primarybank/pb"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCreateAccount(t *testing.T) {
+	account := db.Account{ID: 1, Owner: "alice", Currency: "USD", CreatedAt: time.Now()}
+
+	testCases := []struct {
+		name       string
+		req        *pb.CreateAccountRequest
+		buildStubs func(store *mocks.MockStore)
+		checkResp  func(t *testing.T, resp *pb.CreateAccountResponse, err error)
+	}{
+		{
+			name: "OK",
+			req:  &pb.CreateAccountRequest{Owner: account.Owner, Currency: account.Currency},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().
+					CreateAccountTx(gomock.Any(), db.CreateAccountParams{Owner: account.Owner, Currency: account.Currency}).
+					Return(account, nil).
+					Times(1)
+			},
+			checkResp: func(t *testing.T, resp *pb.CreateAccountResponse, err error) {
+				require.NoError(t, err)
+				require.Equal(t, account.ID, resp.Account.Id)
+				require.Equal(t, account.CreatedAt.Unix(), resp.Account.CreatedAt.AsTime().Unix())
+			},
+		},
+		{
+			name: "Duplicate Currency",
+			req:  &pb.CreateAccountRequest{Owner: account.Owner, Currency: account.Currency},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().
+					CreateAccountTx(gomock.Any(), gomock.Any()).
+					Return(db.Account{}, alreadyExists("owner", "currency")).
+					Times(1)
+			},
+			checkResp: func(t *testing.T, resp *pb.CreateAccountResponse, err error) {
+				st := requireStatus(t, err, codes.AlreadyExists, "already_exists")
+				require.Equal(t, []string{"owner", "currency"}, fieldViolations(st))
+			},
+		},
+		{
+			name: "Invalid Currency",
+			req:  &pb.CreateAccountRequest{Owner: account.Owner, Currency: "GBP"},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().CreateAccountTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, resp *pb.CreateAccountResponse, err error) {
+				st := requireStatus(t, err, codes.InvalidArgument, "invalid_request")
+				require.Equal(t, []string{"currency"}, fieldViolations(st))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mocks.NewMockStore(ctrl)
+			client := newTestClient(t, store)
+			tc.buildStubs(store)
+
+			resp, err := client.CreateAccount(client.as(t, account.Owner), tc.req)
+			tc.checkResp(t, resp, err)
+		})
+	}
+}
+
+func TestCreateAccountAudit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockStore(ctrl)
+	account := db.Account{ID: 3, Owner: "alice", Currency: "EUR"}
+	store.EXPECT().CreateAccountTx(gomock.Any(), gomock.Any()).Return(account, nil).Times(1)
+	store.EXPECT().
+		AppendAuditLogTx(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ any, args db.AppendAuditLogTxParams) (db.AuditLog, error) {
+			require.Equal(t, "alice", args.Actor)
+			require.Equal(t, pb.PrimaryBank_CreateAccount_FullMethodName, args.Action)
+			require.Equal(t, "account", args.ResourceType)
+			require.Equal(t, "3", args.ResourceID)
+			require.Nil(t, args.Before)
+			require.NotEmpty(t, args.After)
+			require.NotEmpty(t, args.RequestID)
+			return db.AuditLog{}, nil
+		}).
+		Times(1)
+	client := newTestClient(t, store)
+
+	_, err := client.CreateAccount(client.as(t, "alice"), &pb.CreateAccountRequest{Owner: "alice", Currency: "EUR"})
+	require.NoError(t, err)
+}
+
+func TestCreateAccountAuditFailed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockStore(ctrl)
+	store.EXPECT().CreateAccountTx(gomock.Any(), gomock.Any()).Return(db.Account{ID: 3, Owner: "alice"}, nil).Times(1)
+	store.EXPECT().AppendAuditLogTx(gomock.Any(), gomock.Any()).Return(db.AuditLog{}, errors.New("audit log unavailable")).Times(1)
+	client := newTestClient(t, store)
+
+	// the account is rolled back with its audit row, the caller must not get it
+	resp, err := client.CreateAccount(client.as(t, "alice"), &pb.CreateAccountRequest{Owner: "alice", Currency: "EUR"})
+	require.Equal(t, codes.Internal, status.Code(err))
+	require.Nil(t, resp)
+}
+
+func TestListAccounts(t *testing.T) {
+	testCases := []struct {
+		name       string
+		req        *pb.ListAccountsRequest
+		buildStubs func(store *mocks.MockStore)
+		checkResp  func(t *testing.T, resp *pb.ListAccountsResponse, err error)
+	}{
+		{
+			name: "OK",
+			req:  &pb.ListAccountsRequest{PageId: 2, PageSize: 5},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().
+					ListAccounts(gomock.Any(), db.ListAccountsParams{Limit: 5, Offset: 5}).
+					Return([]db.Account{{ID: 6}, {ID: 7}}, nil).
+					Times(1)
+			},
+			checkResp: func(t *testing.T, resp *pb.ListAccountsResponse, err error) {
+				require.NoError(t, err)
+				require.Len(t, resp.Accounts, 2)
+			},
+		},
+		{
+			name: "Page Too Large",
+			req:  &pb.ListAccountsRequest{PageId: 1, PageSize: 11},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().ListAccounts(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, resp *pb.ListAccountsResponse, err error) {
+				st := requireStatus(t, err, codes.InvalidArgument, "invalid_request")
+				require.Equal(t, []string{"page_size"}, fieldViolations(st))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mocks.NewMockStore(ctrl)
+			client := newTestClient(t, store)
+			tc.buildStubs(store)
+
+			resp, err := client.ListAccounts(client.as(t, "alice"), tc.req)
+			tc.checkResp(t, resp, err)
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/primarybank/config"
+	"github.com/primarybank/db/mocks"
+	db "github.com/primarybank/db/sqlc"
+	"github.com/primarybank/pb"
+	"github.com/primarybank/token"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestCreateTransfer(t *testing.T) {
+	result := db.TransferTxResult{
+		Transfer:    db.Transfer{ID: 1, FromAccountID: 1, ToAccountID: 2, Amount: 500, Status: "posted", PostedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true}},
+		FromAccount: db.Account{ID: 1, Balance: 500},
+		ToAccount:   db.Account{ID: 2, Balance: 500},
+		FromEntry:   db.Entry{ID: 1, AccountID: 1, Amount: -500},
+		ToEntry:     db.Entry{ID: 2, AccountID: 2, Amount: 500},
+	}
+
+	testCases := []struct {
+		name       string
+		amount     int64
+		configure  func(cfg *config.Config)
+		opts       []token.Option
+		buildStubs func(store *mocks.MockStore)
+		checkResp  func(t *testing.T, resp *pb.CreateTransferResponse, err error)
+	}{
+		{
+			name:   "OK",
+			amount: 500,
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().
+					TransferTx(gomock.Any(), db.TransferTxParams{FromAccountID: 1, ToAccountID: 2, Amount: 500}).
+					Return(result, nil).
+					Times(1)
+			},
+			checkResp: func(t *testing.T, resp *pb.CreateTransferResponse, err error) {
+				require.NoError(t, err)
+				require.Equal(t, result.Transfer.ID, resp.Transfer.Id)
+				require.NotNil(t, resp.Transfer.PostedAt)
+				require.Nil(t, resp.Transfer.VoidedAt)
+				require.Equal(t, int64(-500), resp.FromEntry.Amount)
+				require.Nil(t, resp.FeeEntry)
+			},
+		},
+		{
+			name:   "Overdraft Limit Exceeded",
+			amount: 500,
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Return(db.TransferTxResult{}, &db.OverdraftError{AccountID: 1}).Times(1)
+			},
+			checkResp: func(t *testing.T, resp *pb.CreateTransferResponse, err error) {
+				requireStatus(t, err, codes.FailedPrecondition, "overdraft_limit_exceeded")
+			},
+		},
+		{
+			name:   "Above Approval Threshold",
+			amount: 1001,
+			configure: func(cfg *config.Config) {
+				cfg.TransferApprovalThreshold = 1000
+			},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateTransferRequest(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, resp *pb.CreateTransferResponse, err error) {
+				requireStatus(t, err, codes.FailedPrecondition, "approval_required")
+			},
+		},
+		{
+			name:   "Step Up Without Second Factor",
+			amount: 200,
+			configure: func(cfg *config.Config) {
+				cfg.MFAStepUpThreshold = 100
+			},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, resp *pb.CreateTransferResponse, err error) {
+				requireStatus(t, err, codes.PermissionDenied, "mfa_required")
+			},
+		},
+		{
+			name:   "Step Up With Recent Second Factor",
+			amount: 200,
+			configure: func(cfg *config.Config) {
+				cfg.MFAStepUpThreshold = 100
+			},
+			opts: []token.Option{token.WithMFA(time.Now())},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Return(result, nil).Times(1)
+			},
+			checkResp: func(t *testing.T, resp *pb.CreateTransferResponse, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name:   "Email Not Verified",
+			amount: 500,
+			configure: func(cfg *config.Config) {
+				cfg.EmailVerificationRequiredFor = config.EmailVerificationRequiredForMoneyMovement
+			},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), "alice").Return(db.User{Username: "alice"}, nil).Times(1)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, resp *pb.CreateTransferResponse, err error) {
+				requireStatus(t, err, codes.PermissionDenied, "email_not_verified")
+			},
+		},
+		{
+			name:   "Invalid Amount",
+			amount: 0,
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, resp *pb.CreateTransferResponse, err error) {
+				st := requireStatus(t, err, codes.InvalidArgument, "invalid_request")
+				require.Equal(t, []string{"amount"}, fieldViolations(st))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mocks.NewMockStore(ctrl)
+			configure := tc.configure
+			if configure == nil {
+				configure = func(cfg *config.Config) {}
+			}
+			client := newConfiguredTestClient(t, store, configure)
+			tc.buildStubs(store)
+
+			req := &pb.CreateTransferRequest{FromAccountId: 1, ToAccountId: 2, Amount: tc.amount}
+			resp, err := client.CreateTransfer(client.as(t, "alice", tc.opts...), req)
+			tc.checkResp(t, resp, err)
+		})
+	}
+}
+
+func TestListTransfers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockStore(ctrl)
+	client := newTestClient(t, store)
+	store.EXPECT().
+		ListTransfers(gomock.Any(), db.ListTransfersParams{Limit: 5, Offset: 0}).
+		Return([]db.Transfer{{ID: 1, FeeRuleID: pgtype.Int8{Int64: 3, Valid: true}}}, nil).
+		Times(1)
+
+	resp, err := client.ListTransfers(client.as(t, "alice"), &pb.ListTransfersRequest{PageId: 1, PageSize: 5})
+	require.NoError(t, err)
+	require.Len(t, resp.Transfers, 1)
+	require.Equal(t, int64(3), resp.Transfers[0].GetFeeRuleId())
+}
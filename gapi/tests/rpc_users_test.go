@@ -0,0 +1,41 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/primarybank/db/mocks"
+	db "github.com/primarybank/db/sqlc"
+	"github.com/primarybank/pb"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestGetUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockStore(ctrl)
+	client := newTestClient(t, store)
+
+	user := db.User{
+		Username:        "alice",
+		Password:        "hashed",
+		FullName:        "Alice",
+		Email:           "alice@example.com",
+		Role:            db.RoleDepositor,
+		EmailVerifiedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+	store.EXPECT().GetUser(gomock.Any(), user.Username).Return(user, nil).Times(1)
+
+	resp, err := client.GetUser(client.as(t, "bob"), &pb.GetUserRequest{Username: user.Username})
+	require.NoError(t, err)
+	require.Equal(t, user.Email, resp.User.Email)
+	require.True(t, resp.User.EmailVerified)
+	require.NotContains(t, resp.String(), "hashed")
+
+	_, err = client.GetUser(client.as(t, "bob"), &pb.GetUserRequest{})
+	requireStatus(t, err, codes.InvalidArgument, "invalid_request")
+}
@@ -0,0 +1,44 @@
+package gapi
+
+import (
+	"slices"
+
+	commonerrors "github.com/primarybank/common/errors"
+)
+
+// the constraints binding enforces on the same fields of the HTTP API
+var currencies = []string{"USD", "EUR", "RUP"}
+
+const (
+	minPageSize = 5
+	maxPageSize = 10
+)
+
+// violations collects the fields of a request failing validation
+type violations []commonerrors.FieldError
+
+func (v *violations) check(ok bool, field string, reason string) {
+	if !ok {
+		*v = append(*v, commonerrors.FieldError{Field: field, Reason: reason})
+	}
+}
+
+func (v *violations) checkID(id int64, field string) {
+	v.check(id >= 1, field, "must be at least 1")
+}
+
+func (v *violations) checkPage(pageID int32, pageSize int32) {
+	v.check(pageID >= 1, "page_id", "must be at least 1")
+	v.check(pageSize >= minPageSize && pageSize <= maxPageSize, "page_size", "must be between 5 and 10")
+}
+
+func (v *violations) checkCurrency(currency string) {
+	v.check(slices.Contains(currencies, currency), "currency", "must be one of USD, EUR, RUP")
+}
+
+func (v violations) err() error {
+	if len(v) == 0 {
+		return nil
+	}
+	return errInvalidRequest.WithFields(v...)
+}
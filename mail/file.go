@@ -0,0 +1,36 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// FileSender writes every email to its own .eml file so they can be read during local development
+type FileSender struct {
+	dir  string
+	from string
+	seq  atomic.Int64
+}
+
+func NewFileSender(dir string, from string) (*FileSender, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("cannot create mail dir: %w", err)
+	}
+
+	return &FileSender{dir: dir, from: from}, nil
+}
+
+func (s *FileSender) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	name := fmt.Sprintf("%s-%d.eml", now.UTC().Format("20060102T150405.000000000"), s.seq.Add(1))
+
+	return os.WriteFile(filepath.Join(s.dir, name), format(withDefaultFrom(msg, s.from), now), 0o600)
+}
@@ -0,0 +1,48 @@
+package mail
+
+import (
+	"context"
+	"sync"
+)
+
+// MemorySender keeps the emails it is given, for tests and local development
+type MemorySender struct {
+	from     string
+	mu       sync.Mutex
+	messages []Message
+}
+
+func NewMemorySender(from string) *MemorySender {
+	return &MemorySender{from: from}
+}
+
+func (s *MemorySender) Send(_ context.Context, msg Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.messages = append(s.messages, withDefaultFrom(msg, s.from))
+	return nil
+}
+
+// Messages returns the emails sent so far, oldest first
+func (s *MemorySender) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Message(nil), s.messages...)
+}
+
+// Last returns the most recent email sent to the address
+func (s *MemorySender) Last(to string) (Message, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := len(s.messages) - 1; i >= 0; i-- {
+		for _, recipient := range s.messages[i].To {
+			if recipient == to {
+				return s.messages[i], true
+			}
+		}
+	}
+	return Message{}, false
+}
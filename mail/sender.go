@@ -0,0 +1,61 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/primarybank/config"
+)
+
+// Message is a plain text email
+type Message struct {
+	From    string
+	To      []string
+	Subject string
+	Body    string
+}
+
+// Sender delivers emails
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewSender builds the sender selected by MAIL_SENDER
+func NewSender(cfg config.Config) (Sender, error) {
+	switch cfg.MailSender {
+	case "smtp":
+		return NewSMTPSender(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom), nil
+	case "file":
+		return NewFileSender(cfg.MailDir, cfg.MailFrom)
+	case "memory", "":
+		return NewMemorySender(cfg.MailFrom), nil
+	default:
+		return nil, fmt.Errorf("unknown mail sender %q", cfg.MailSender)
+	}
+}
+
+// format renders msg as an RFC 5322 message
+func format(msg Message, date time.Time) []byte {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "From: %s\r\n", msg.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(msg.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+
+	return buf.Bytes()
+}
+
+func withDefaultFrom(msg Message, from string) Message {
+	if msg.From == "" {
+		msg.From = from
+	}
+	return msg
+}
@@ -0,0 +1,85 @@
+package mail
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/primarybank/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemorySender(t *testing.T) {
+	sender := NewMemorySender("bank@example.com")
+
+	require.NoError(t, sender.Send(context.Background(), Message{To: []string{"alice@example.com"}, Subject: "first"}))
+	require.NoError(t, sender.Send(context.Background(), Message{To: []string{"bob@example.com"}, Subject: "second"}))
+	require.NoError(t, sender.Send(context.Background(), Message{To: []string{"alice@example.com"}, Subject: "third"}))
+
+	require.Len(t, sender.Messages(), 3)
+
+	msg, ok := sender.Last("alice@example.com")
+	require.True(t, ok)
+	require.Equal(t, "third", msg.Subject)
+	require.Equal(t, "bank@example.com", msg.From)
+
+	_, ok = sender.Last("carol@example.com")
+	require.False(t, ok)
+}
+
+func TestFileSender(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	sender, err := NewFileSender(dir, "bank@example.com")
+	require.NoError(t, err)
+
+	err = sender.Send(context.Background(), Message{
+		To:      []string{"alice@example.com"},
+		Subject: "Reset your password",
+		Body:    "line one\nline two",
+	})
+	require.NoError(t, err)
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	data, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	require.NoError(t, err)
+	require.Contains(t, string(data), "To: alice@example.com\r\n")
+	require.Contains(t, string(data), "\r\n\r\nline one\r\nline two")
+}
+
+func TestFormat(t *testing.T) {
+	date := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	msg := Message{
+		From:    "bank@example.com",
+		To:      []string{"alice@example.com", "bob@example.com"},
+		Subject: "Hello",
+		Body:    "Hi",
+	}
+
+	expected := "From: bank@example.com\r\n" +
+		"To: alice@example.com, bob@example.com\r\n" +
+		"Subject: Hello\r\n" +
+		"Date: Tue, 02 Jan 2024 03:04:05 +0000\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" +
+		"Hi"
+	require.Equal(t, expected, string(format(msg, date)))
+}
+
+func TestNewSender(t *testing.T) {
+	sender, err := NewSender(config.Config{MailSender: "memory"})
+	require.NoError(t, err)
+	require.IsType(t, &MemorySender{}, sender)
+
+	sender, err = NewSender(config.Config{MailSender: "smtp", SMTPHost: "localhost", SMTPPort: "25"})
+	require.NoError(t, err)
+	require.IsType(t, &SMTPSender{}, sender)
+
+	_, err = NewSender(config.Config{MailSender: "pigeon"})
+	require.Error(t, err)
+}
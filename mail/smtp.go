@@ -0,0 +1,38 @@
+package mail
+
+import (
+	"context"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+// SMTPSender delivers emails through an SMTP server
+type SMTPSender struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewSMTPSender authenticates with PLAIN auth when a username is given
+func NewSMTPSender(host string, port string, username string, password string, from string) *SMTPSender {
+	sender := &SMTPSender{
+		addr: net.JoinHostPort(host, port),
+		from: from,
+	}
+
+	if username != "" {
+		sender.auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return sender
+}
+
+func (s *SMTPSender) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	msg = withDefaultFrom(msg, s.from)
+	return smtp.SendMail(s.addr, s.auth, msg.From, msg.To, format(msg, time.Now()))
+}
@@ -3,11 +3,18 @@ package main
 import (
 	"context"
 	"log"
+	"net/http"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/primarybank/api"
 	"github.com/primarybank/config"
 	db "github.com/primarybank/db/sqlc"
+	"github.com/primarybank/events"
+	"github.com/primarybank/gapi"
+	"github.com/primarybank/mfa"
+	"github.com/primarybank/pb"
+	"github.com/primarybank/webhooks"
 )
 
 func main() {
@@ -22,12 +29,91 @@ func main() {
 	}
 
 	store := db.NewStore(conn)
+	go expireTransferRequests(store, transferRequestSweepInterval)
+
+	publisher, err := events.NewPublisher(cfg)
+	if err != nil {
+		log.Fatal("cannot create event publisher: ", err)
+	}
+
+	relayInterval := cfg.OutboxRelayInterval
+	if relayInterval <= 0 {
+		relayInterval = defaultOutboxRelayInterval
+	}
+	// webhook subscribers are told about the events that concern them
+	go events.NewRelay(store, publisher, cfg, webhooks.NewEnqueuer()).Run(context.Background(), relayInterval)
+
+	webhookCipher, err := mfa.NewCipher(cfg.WebhookEncryptionKey)
+	if err != nil {
+		log.Fatal("cannot create webhook secret cipher: ", err)
+	}
+
+	dispatchInterval := cfg.WebhookDispatchInterval
+	if dispatchInterval <= 0 {
+		dispatchInterval = defaultWebhookDispatchInterval
+	}
+	dispatcher, err := webhooks.NewDispatcher(store, webhookCipher, cfg)
+	if err != nil {
+		log.Fatal("cannot create webhook dispatcher: ", err)
+	}
+	go dispatcher.Run(context.Background(), dispatchInterval)
+
 	server, err := api.NewServer(cfg, store)
 	if err != nil {
 		log.Fatal("cannot create server: ", err)
 	}
 
+	go server.Activity.Run(context.Background())
+	grpcServer := gapi.NewServer(cfg, store, server.TokenMaker)
+	go runGRPCServer(cfg, grpcServer)
+
+	server.Gateway, err = newGateway(grpcServer)
+	if err != nil {
+		log.Fatal("cannot create gateway: ", err)
+	}
+
 	if err = server.Start(cfg.ServerAddr); err != nil {
 		log.Fatal("cannot start server: ", err)
 	}
 }
+
+const (
+	transferRequestSweepInterval   = time.Minute
+	defaultOutboxRelayInterval     = time.Second
+	defaultWebhookDispatchInterval = time.Second
+)
+
+// runGRPCServer serves the gRPC API, built with the token maker of the HTTP API so either takes the tokens of the other
+func runGRPCServer(cfg config.Config, grpcServer *gapi.Server) {
+	if err := grpcServer.Start(cfg.GRPCServerAddr); err != nil {
+		log.Fatal("cannot start grpc server: ", err)
+	}
+}
+
+// newGateway returns the gateway forwarding to the gRPC server over its in-process connection, it connects on the
+// first request it forwards
+func newGateway(grpcServer *gapi.Server) (http.Handler, error) {
+	conn, err := grpcServer.GatewayConn()
+	if err != nil {
+		return nil, err
+	}
+
+	return gapi.NewGateway(context.Background(), pb.NewPrimaryBankClient(conn))
+}
+
+// expireTransferRequests periodically marks pending transfer requests past their expiry as expired
+func expireTransferRequests(store db.Store, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		expired, err := store.ExpireTransferRequestsTx(context.Background())
+		if err != nil {
+			log.Println("cannot expire transfer requests: ", err)
+			continue
+		}
+		if len(expired) > 0 {
+			log.Printf("expired %d transfer requests", len(expired))
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package mfa
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnrollAndValidate(t *testing.T) {
+	enrollment, err := Enroll("PrimaryBank", "alice")
+	require.NoError(t, err)
+	require.NotEmpty(t, enrollment.Secret)
+	require.True(t, strings.HasPrefix(enrollment.URI, "otpauth://totp/PrimaryBank:alice?"))
+
+	now := time.Now()
+	code, err := GenerateCode(enrollment.Secret, now)
+	require.NoError(t, err)
+
+	step, ok := Validate(enrollment.Secret, code, now)
+	require.True(t, ok)
+	require.Equal(t, Step(now), step)
+
+	// one step of clock drift is tolerated, more is not
+	_, ok = Validate(enrollment.Secret, code, now.Add(Period))
+	require.True(t, ok)
+	_, ok = Validate(enrollment.Secret, code, now.Add(3*Period))
+	require.False(t, ok)
+
+	_, ok = Validate(enrollment.Secret, "not-a-code", now)
+	require.False(t, ok)
+}
+
+func TestValidateRFC6238Vector(t *testing.T) {
+	// RFC 6238 appendix B, SHA1 key "12345678901234567890" truncated to six digits
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	at := time.Unix(59, 0)
+
+	code, err := GenerateCode(secret, at)
+	require.NoError(t, err)
+	require.Equal(t, "287082", code)
+
+	step, ok := Validate(secret, "287082", at)
+	require.True(t, ok)
+	require.Equal(t, int64(1), step)
+}
+
+func TestCipher(t *testing.T) {
+	c, err := NewCipher(hex.EncodeToString(make([]byte, 32)))
+	require.NoError(t, err)
+
+	ciphertext, err := c.Encrypt([]byte("secret"))
+	require.NoError(t, err)
+	require.NotContains(t, string(ciphertext), "secret")
+
+	plaintext, err := c.Decrypt(ciphertext)
+	require.NoError(t, err)
+	require.Equal(t, "secret", string(plaintext))
+
+	ciphertext[len(ciphertext)-1] ^= 1
+	_, err = c.Decrypt(ciphertext)
+	require.Error(t, err)
+
+	_, err = NewCipher("too-short")
+	require.Error(t, err)
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(RecoveryCodeCount)
+	require.NoError(t, err)
+	require.Len(t, codes, RecoveryCodeCount)
+
+	seen := map[string]bool{}
+	for _, code := range codes {
+		require.Regexp(t, `^[a-z2-7]{5}-[a-z2-7]{5}$`, code)
+		require.False(t, seen[code])
+		seen[code] = true
+
+		require.True(t, IsRecoveryCode(code))
+		require.Equal(t, code, NormalizeRecoveryCode(" "+strings.ToUpper(strings.ReplaceAll(code, "-", ""))+" "))
+	}
+
+	require.False(t, IsRecoveryCode("123456"))
+}
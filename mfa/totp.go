@@ -0,0 +1,74 @@
+package mfa
+
+import (
+	"crypto/subtle"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/hotp"
+	"github.com/pquerna/otp/totp"
+)
+
+const (
+	// Period is the RFC 6238 time step
+	Period = 30 * time.Second
+	// skew is how many steps before and after the current one are accepted for clock drift
+	skew = 1
+)
+
+// Enrollment is a freshly generated TOTP secret and the otpauth URI authenticator apps scan
+type Enrollment struct {
+	Secret string
+	URI    string
+}
+
+// Enroll generates a new TOTP secret for account
+func Enroll(issuer string, account string) (Enrollment, error) {
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      issuer,
+		AccountName: account,
+		Period:      uint(Period / time.Second),
+		Digits:      otp.DigitsSix,
+		Algorithm:   otp.AlgorithmSHA1,
+	})
+	if err != nil {
+		return Enrollment{}, err
+	}
+
+	return Enrollment{Secret: key.Secret(), URI: key.URL()}, nil
+}
+
+// Step returns the RFC 6238 counter of t
+func Step(t time.Time) int64 {
+	return t.Unix() / int64(Period/time.Second)
+}
+
+// GenerateCode returns the code of secret for the step t falls in
+func GenerateCode(secret string, t time.Time) (string, error) {
+	return hotp.GenerateCodeCustom(secret, uint64(Step(t)), hotp.ValidateOpts{
+		Digits:    otp.DigitsSix,
+		Algorithm: otp.AlgorithmSHA1,
+	})
+}
+
+// Validate checks code against secret around now and returns the step it was generated for.
+// Callers must reject steps at or before the last one used so a code cannot be replayed.
+func Validate(secret string, code string, now time.Time) (int64, bool) {
+	current := Step(now)
+
+	for step := current - skew; step <= current+skew; step++ {
+		expected, err := hotp.GenerateCodeCustom(secret, uint64(step), hotp.ValidateOpts{
+			Digits:    otp.DigitsSix,
+			Algorithm: otp.AlgorithmSHA1,
+		})
+		if err != nil {
+			return 0, false
+		}
+
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
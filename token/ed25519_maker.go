@@ -0,0 +1,51 @@
+package token
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Ed25519Maker signs tokens with the active key of a keyring so that other services can verify them
+// with the published public keys. The kid header selects the key a token is verified with.
+type Ed25519Maker struct {
+	keyring *Keyring
+}
+
+func NewEd25519Maker(keyring *Keyring) Maker {
+	return &Ed25519Maker{keyring: keyring}
+}
+
+func (m *Ed25519Maker) CreateToken(username string, duration time.Duration, opts ...Option) (string, error) {
+	payload := NewPayload(username, duration, opts...)
+
+	jwtToken := jwt.NewWithClaims(jwt.SigningMethodEdDSA, payload)
+	jwtToken.Header["kid"] = m.keyring.activeID
+
+	return jwtToken.SignedString(m.keyring.active)
+}
+
+func (m *Ed25519Maker) VerifyToken(token string) (*Payload, error) {
+	jwtToken, err := jwt.ParseWithClaims(token, &Payload{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodEd25519); !ok {
+			return nil, ErrInvalidToken
+		}
+
+		kid, _ := token.Header["kid"].(string)
+		key, ok := m.keyring.PublicKey(kid)
+		if !ok {
+			return nil, ErrInvalidToken
+		}
+		return key, nil
+	})
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	payload, ok := jwtToken.Claims.(*Payload)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	return payload, nil
+}
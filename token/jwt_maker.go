@@ -23,8 +23,8 @@ func NewJWTMaker(secretKey string) (Maker, error) {
 	return &JWTMaker{secretKey: secretKey}, nil
 }
 
-func (m *JWTMaker) CreateToken(username string, duration time.Duration) (string, error) {
-	payload := NewPayload(username, duration)
+func (m *JWTMaker) CreateToken(username string, duration time.Duration, opts ...Option) (string, error) {
+	payload := NewPayload(username, duration, opts...)
 
 	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
 
@@ -0,0 +1,141 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Keyring holds the Ed25519 key tokens are signed with and the previous keys they are still verified with.
+// Keys are told apart by their kid, the RFC 7638 thumbprint of the public key.
+type Keyring struct {
+	activeID string
+	active   ed25519.PrivateKey
+	// every key tokens are accepted from by kid, the active one included
+	keys map[string]ed25519.PublicKey
+	// kids in the order the keys were given, active first
+	ids []string
+}
+
+// NewKeyring signs with signingKey and verifies with it and any of verificationKeys
+func NewKeyring(signingKey ed25519.PrivateKey, verificationKeys ...ed25519.PublicKey) *Keyring {
+	public := signingKey.Public().(ed25519.PublicKey)
+	keyring := &Keyring{
+		activeID: KeyID(public),
+		active:   signingKey,
+		keys:     make(map[string]ed25519.PublicKey),
+	}
+
+	for _, key := range append([]ed25519.PublicKey{public}, verificationKeys...) {
+		kid := KeyID(key)
+		if _, ok := keyring.keys[kid]; ok {
+			continue
+		}
+		keyring.keys[kid] = key
+		keyring.ids = append(keyring.ids, kid)
+	}
+
+	return keyring
+}
+
+// ParseKeyring builds a keyring from a base64 signing key, either the 32 byte seed or the 64 byte private key,
+// and a comma separated list of base64 public keys that tokens are still accepted from
+func ParseKeyring(signingKey string, verificationKeys string) (*Keyring, error) {
+	raw, err := base64.StdEncoding.DecodeString(signingKey)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode signing key: %w", err)
+	}
+
+	var private ed25519.PrivateKey
+	switch len(raw) {
+	case ed25519.SeedSize:
+		private = ed25519.NewKeyFromSeed(raw)
+	case ed25519.PrivateKeySize:
+		private = ed25519.PrivateKey(raw)
+	default:
+		return nil, errors.New("signing key must be an Ed25519 seed or private key")
+	}
+
+	var public []ed25519.PublicKey
+	for _, key := range strings.Split(verificationKeys, ",") {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+
+		raw, err := base64.StdEncoding.DecodeString(key)
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode verification key: %w", err)
+		}
+		if len(raw) != ed25519.PublicKeySize {
+			return nil, errors.New("verification key must be an Ed25519 public key")
+		}
+		public = append(public, ed25519.PublicKey(raw))
+	}
+
+	return NewKeyring(private, public...), nil
+}
+
+// GenerateSigningKey returns a new base64 signing key and its base64 public key
+func GenerateSigningKey() (signingKey string, publicKey string, err error) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(private.Seed()), base64.StdEncoding.EncodeToString(public), nil
+}
+
+// KeyID returns the RFC 7638 thumbprint of an Ed25519 public key
+func KeyID(key ed25519.PublicKey) string {
+	// members in lexicographic order without whitespace as the thumbprint requires
+	canonical := fmt.Sprintf(`{"crv":"Ed25519","kty":"OKP","x":"%s"}`, base64.RawURLEncoding.EncodeToString(key))
+	sum := sha256.Sum256([]byte(canonical))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// ActiveKeyID is the kid of the key new tokens are signed with
+func (k *Keyring) ActiveKeyID() string {
+	return k.activeID
+}
+
+// PublicKey returns the key of kid if tokens signed with it are accepted
+func (k *Keyring) PublicKey(kid string) (ed25519.PublicKey, bool) {
+	key, ok := k.keys[kid]
+	return key, ok
+}
+
+// JWK is a public key in the JSON Web Key format of RFC 8037
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+}
+
+// JWKSet is the document served at /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS publishes every key tokens are accepted from, the active one first
+func (k *Keyring) JWKS() JWKSet {
+	set := JWKSet{Keys: make([]JWK, 0, len(k.ids))}
+	for _, kid := range k.ids {
+		set.Keys = append(set.Keys, JWK{
+			Kty: "OKP",
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(k.keys[kid]),
+			Kid: kid,
+			Use: "sig",
+			Alg: "EdDSA",
+		})
+	}
+	return set
+}
@@ -1,21 +1,58 @@
 package token
 
 import (
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
+// PurposeMFAChallenge marks the token handed out after the password step of a login with MFA.
+// It can only be traded for an access token along with a second factor.
+const PurposeMFAChallenge = "mfa_challenge"
+
 // Payload contains the payload data of the token
 type Payload struct {
 	Username string `json:"username"`
+	// Purpose restricts what the token can be used for, access tokens have none
+	Purpose string `json:"purpose,omitempty"`
+	// MFAAt is when the user last passed a second factor, unset for password only logins
+	MFAAt *jwt.NumericDate `json:"mfa_at,omitempty"`
+	// Scope lists the granted scopes separated by spaces as in RFC 8693.
+	// Tokens issued before scopes existed have none and keep full access until they expire.
+	Scope string `json:"scope,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func NewPayload(username string, duration time.Duration) *Payload {
+// Option customizes the payload of a new token
+type Option func(*Payload)
+
+// WithPurpose restricts the token to purpose
+func WithPurpose(purpose string) Option {
+	return func(p *Payload) {
+		p.Purpose = purpose
+	}
+}
+
+// WithMFA records the user passed a second factor at the given time
+func WithMFA(at time.Time) Option {
+	return func(p *Payload) {
+		p.MFAAt = jwt.NewNumericDate(at)
+	}
+}
+
+// WithScopes restricts the token to the given scopes
+func WithScopes(scopes ...string) Option {
+	return func(p *Payload) {
+		p.Scope = strings.Join(scopes, " ")
+	}
+}
+
+func NewPayload(username string, duration time.Duration, opts ...Option) *Payload {
 	now := time.Now()
-	return &Payload{
+	payload := &Payload{
 		Username: username,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ID:        uuid.NewString(),
@@ -23,4 +60,33 @@ func NewPayload(username string, duration time.Duration) *Payload {
 			ExpiresAt: jwt.NewNumericDate(now.Add(duration)),
 		},
 	}
+
+	for _, opt := range opts {
+		opt(payload)
+	}
+
+	return payload
+}
+
+// IsAccess reports whether the token grants access to the API rather than serving a narrower purpose
+func (p *Payload) IsAccess() bool {
+	return p.Purpose == ""
+}
+
+// MFASince reports whether the user passed a second factor no earlier than since
+func (p *Payload) MFASince(since time.Time) bool {
+	return p.MFAAt != nil && !p.MFAAt.Time.Before(since)
+}
+
+// Scopes returns the scopes granted to the token
+func (p *Payload) Scopes() []string {
+	if p.Scope == "" {
+		return slices.Clone(Scopes)
+	}
+	return strings.Fields(p.Scope)
+}
+
+// HasScope reports whether the token was granted scope
+func (p *Payload) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes(), scope)
 }
@@ -0,0 +1,38 @@
+package token
+
+import "slices"
+
+// scopes limit what a credential may be used for
+const (
+	ScopeProfileRead    = "profile:read"
+	ScopeProfileWrite   = "profile:write"
+	ScopeAccountsRead   = "accounts:read"
+	ScopeAccountsWrite  = "accounts:write"
+	ScopeTransfersRead  = "transfers:read"
+	ScopeTransfersWrite = "transfers:write"
+	ScopeEntriesRead    = "entries:read"
+	ScopeEntriesWrite   = "entries:write"
+	ScopeWebhooksRead   = "webhooks:read"
+	ScopeWebhooksWrite  = "webhooks:write"
+	ScopeAdmin          = "admin"
+)
+
+// Scopes lists every scope a credential can be granted
+var Scopes = []string{
+	ScopeProfileRead,
+	ScopeProfileWrite,
+	ScopeAccountsRead,
+	ScopeAccountsWrite,
+	ScopeTransfersRead,
+	ScopeTransfersWrite,
+	ScopeEntriesRead,
+	ScopeEntriesWrite,
+	ScopeWebhooksRead,
+	ScopeWebhooksWrite,
+	ScopeAdmin,
+}
+
+// IsScope reports whether scope is a known scope
+func IsScope(scope string) bool {
+	return slices.Contains(Scopes, scope)
+}
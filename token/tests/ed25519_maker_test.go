@@ -0,0 +1,126 @@
+package tests
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/primarybank/token"
+	"github.com/stretchr/testify/require"
+)
+
+func newSigningKey(t *testing.T) ed25519.PrivateKey {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	return private
+}
+
+func TestEd25519Maker(t *testing.T) {
+	keyring := token.NewKeyring(newSigningKey(t))
+	maker := token.NewEd25519Maker(keyring)
+
+	tokenStr, err := maker.CreateToken("test_user", time.Minute, token.WithScopes(token.ScopeAccountsRead))
+	require.NoError(t, err)
+
+	parsed, _, err := jwt.NewParser().ParseUnverified(tokenStr, &token.Payload{})
+	require.NoError(t, err)
+	require.Equal(t, "EdDSA", parsed.Header["alg"])
+	require.Equal(t, keyring.ActiveKeyID(), parsed.Header["kid"])
+
+	payload, err := maker.VerifyToken(tokenStr)
+	require.NoError(t, err)
+	require.Equal(t, "test_user", payload.Username)
+	require.Equal(t, []string{token.ScopeAccountsRead}, payload.Scopes())
+
+	expired, err := maker.CreateToken("test_user", -time.Minute)
+	require.NoError(t, err)
+	_, err = maker.VerifyToken(expired)
+	require.ErrorIs(t, err, token.ErrInvalidToken)
+}
+
+func TestEd25519MakerRotation(t *testing.T) {
+	oldKey := newSigningKey(t)
+	newKey := newSigningKey(t)
+
+	oldMaker := token.NewEd25519Maker(token.NewKeyring(oldKey))
+	oldToken, err := oldMaker.CreateToken("test_user", time.Minute)
+	require.NoError(t, err)
+
+	// the old key stays for verification after the new one takes over signing
+	rotated := token.NewEd25519Maker(token.NewKeyring(newKey, oldKey.Public().(ed25519.PublicKey)))
+	_, err = rotated.VerifyToken(oldToken)
+	require.NoError(t, err)
+
+	newToken, err := rotated.CreateToken("test_user", time.Minute)
+	require.NoError(t, err)
+	_, err = rotated.VerifyToken(newToken)
+	require.NoError(t, err)
+
+	// once the old key is retired its tokens are rejected
+	retired := token.NewEd25519Maker(token.NewKeyring(newKey))
+	_, err = retired.VerifyToken(oldToken)
+	require.ErrorIs(t, err, token.ErrInvalidToken)
+	_, err = oldMaker.VerifyToken(newToken)
+	require.ErrorIs(t, err, token.ErrInvalidToken)
+}
+
+func TestEd25519MakerRejectsOtherAlgorithms(t *testing.T) {
+	keyring := token.NewKeyring(newSigningKey(t))
+	maker := token.NewEd25519Maker(keyring)
+
+	hmacToken := jwt.NewWithClaims(jwt.SigningMethodHS256, token.NewPayload("test_user", time.Minute))
+	hmacToken.Header["kid"] = keyring.ActiveKeyID()
+	tokenStr, err := hmacToken.SignedString([]byte("a_very_secure_secret_key_with_min_length"))
+	require.NoError(t, err)
+	_, err = maker.VerifyToken(tokenStr)
+	require.ErrorIs(t, err, token.ErrInvalidToken)
+
+	noneToken := jwt.NewWithClaims(jwt.SigningMethodNone, token.NewPayload("test_user", time.Minute))
+	noneToken.Header["kid"] = keyring.ActiveKeyID()
+	tokenStr, err = noneToken.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	require.NoError(t, err)
+	_, err = maker.VerifyToken(tokenStr)
+	require.ErrorIs(t, err, token.ErrInvalidToken)
+}
+
+func TestParseKeyring(t *testing.T) {
+	signingKey, publicKey, err := token.GenerateSigningKey()
+	require.NoError(t, err)
+	_, previousKey, err := token.GenerateSigningKey()
+	require.NoError(t, err)
+
+	keyring, err := token.ParseKeyring(signingKey, previousKey+", "+publicKey)
+	require.NoError(t, err)
+
+	raw, err := base64.StdEncoding.DecodeString(publicKey)
+	require.NoError(t, err)
+	require.Equal(t, token.KeyID(raw), keyring.ActiveKeyID())
+
+	// the active key is listed once and first
+	set := keyring.JWKS()
+	require.Len(t, set.Keys, 2)
+	require.Equal(t, keyring.ActiveKeyID(), set.Keys[0].Kid)
+	require.Equal(t, base64.RawURLEncoding.EncodeToString(raw), set.Keys[0].X)
+	for _, key := range set.Keys {
+		require.Equal(t, "OKP", key.Kty)
+		require.Equal(t, "Ed25519", key.Crv)
+		require.Equal(t, "EdDSA", key.Alg)
+	}
+
+	_, err = token.ParseKeyring("not base64", "")
+	require.Error(t, err)
+	_, err = token.ParseKeyring(base64.StdEncoding.EncodeToString([]byte("short")), "")
+	require.Error(t, err)
+	_, err = token.ParseKeyring(signingKey, signingKey+"AAAA")
+	require.Error(t, err)
+}
+
+func TestKeyID(t *testing.T) {
+	// RFC 8037 appendix A.3
+	x, err := base64.RawURLEncoding.DecodeString("11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo")
+	require.NoError(t, err)
+	require.Equal(t, "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k", token.KeyID(x))
+}
@@ -0,0 +1,58 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/primarybank/token"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmailVerifier(t *testing.T) {
+	verifier, err := token.NewEmailVerifier("a_very_secure_secret_key_with_min_length")
+	require.NoError(t, err)
+
+	code, err := verifier.CreateCode("test_user", "test@example.com", time.Minute)
+	require.NoError(t, err)
+
+	verification, err := verifier.VerifyCode(code)
+	require.NoError(t, err)
+	require.Equal(t, "test_user", verification.Username)
+	require.Equal(t, "test@example.com", verification.Email)
+	require.WithinDuration(t, time.Now().Add(time.Minute), verification.ExpiresAt, time.Second)
+}
+
+func TestEmailVerifierRejectsBadCodes(t *testing.T) {
+	secretKey := "a_very_secure_secret_key_with_min_length"
+	verifier, err := token.NewEmailVerifier(secretKey)
+	require.NoError(t, err)
+
+	expired, err := verifier.CreateCode("test_user", "test@example.com", -time.Minute)
+	require.NoError(t, err)
+	_, err = verifier.VerifyCode(expired)
+	require.ErrorIs(t, err, token.ErrExpiredToken)
+
+	code, err := verifier.CreateCode("test_user", "test@example.com", time.Minute)
+	require.NoError(t, err)
+	payload, signature, _ := strings.Cut(code, ".")
+
+	other, err := verifier.CreateCode("other_user", "test@example.com", time.Minute)
+	require.NoError(t, err)
+	otherPayload, _, _ := strings.Cut(other, ".")
+
+	// access tokens signed with the same secret are not verification codes
+	maker, err := token.NewJWTMaker(secretKey)
+	require.NoError(t, err)
+	accessToken, err := maker.CreateToken("test_user", time.Minute)
+	require.NoError(t, err)
+
+	for _, bad := range []string{"", payload, otherPayload + "." + signature, accessToken} {
+		_, err = verifier.VerifyCode(bad)
+		require.ErrorIs(t, err, token.ErrInvalidToken)
+	}
+
+	// and verification codes are not access tokens
+	_, err = maker.VerifyToken(code)
+	require.ErrorIs(t, err, token.ErrInvalidToken)
+}
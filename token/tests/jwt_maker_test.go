@@ -50,3 +50,53 @@ func TestInvalidToken(t *testing.T) {
 	require.Error(t, err, token.ErrInvalidToken)
 	require.Nil(t, payload)
 }
+
+func TestTokenOptions(t *testing.T) {
+	maker, err := token.NewJWTMaker("a_very_secure_secret_key_with_min_length")
+	require.NoError(t, err)
+
+	tokenStr, err := maker.CreateToken("test_user", time.Minute)
+	require.NoError(t, err)
+	payload, err := maker.VerifyToken(tokenStr)
+	require.NoError(t, err)
+	require.True(t, payload.IsAccess())
+	require.False(t, payload.MFASince(time.Now().Add(-time.Hour)))
+
+	mfaAt := time.Now().Add(-time.Minute)
+	tokenStr, err = maker.CreateToken("test_user", time.Minute, token.WithMFA(mfaAt))
+	require.NoError(t, err)
+	payload, err = maker.VerifyToken(tokenStr)
+	require.NoError(t, err)
+	require.True(t, payload.IsAccess())
+	require.True(t, payload.MFASince(mfaAt.Add(-time.Second)))
+	require.False(t, payload.MFASince(time.Now()))
+
+	tokenStr, err = maker.CreateToken("test_user", time.Minute, token.WithPurpose(token.PurposeMFAChallenge))
+	require.NoError(t, err)
+	payload, err = maker.VerifyToken(tokenStr)
+	require.NoError(t, err)
+	require.False(t, payload.IsAccess())
+	require.Equal(t, token.PurposeMFAChallenge, payload.Purpose)
+}
+
+func TestTokenScopes(t *testing.T) {
+	maker, err := token.NewJWTMaker("a_very_secure_secret_key_with_min_length")
+	require.NoError(t, err)
+
+	tokenStr, err := maker.CreateToken("test_user", time.Minute, token.WithScopes(token.ScopeAccountsRead, token.ScopeTransfersRead))
+	require.NoError(t, err)
+	payload, err := maker.VerifyToken(tokenStr)
+	require.NoError(t, err)
+	require.Equal(t, "accounts:read transfers:read", payload.Scope)
+	require.Equal(t, []string{token.ScopeAccountsRead, token.ScopeTransfersRead}, payload.Scopes())
+	require.True(t, payload.HasScope(token.ScopeAccountsRead))
+	require.False(t, payload.HasScope(token.ScopeTransfersWrite))
+
+	// tokens without scopes predate them and keep full access
+	tokenStr, err = maker.CreateToken("test_user", time.Minute)
+	require.NoError(t, err)
+	payload, err = maker.VerifyToken(tokenStr)
+	require.NoError(t, err)
+	require.Equal(t, token.Scopes, payload.Scopes())
+	require.True(t, payload.HasScope(token.ScopeAdmin))
+}
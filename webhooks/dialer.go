@@ -0,0 +1,68 @@
+package webhooks
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrAddressNotAllowed is returned when a webhook url resolves to an address of the bank's own networks
+var ErrAddressNotAllowed = errors.New("webhook address not allowed")
+
+// sharedAddressSpace is the carrier-grade NAT range of RFC 6598, private to the networks using it
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// parseAllowedNetworks reads WEBHOOK_ALLOWED_NETWORKS, comma separated CIDRs
+func parseAllowedNetworks(value string) ([]netip.Prefix, error) {
+	var networks []netip.Prefix
+	for _, network := range strings.Split(value, ",") {
+		network = strings.TrimSpace(network)
+		if network == "" {
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(network)
+		if err != nil {
+			return nil, fmt.Errorf("invalid webhook allowed network %q: %w", network, err)
+		}
+		networks = append(networks, prefix.Masked())
+	}
+	return networks, nil
+}
+
+// checkAddress fails for the addresses a subscriber could use to reach the bank's own hosts: loopback, private,
+// link-local (the cloud metadata service at 169.254.169.254 among them), shared, multicast and unspecified
+// addresses, unless they are in an allowed network
+func checkAddress(addr netip.Addr, allowed []netip.Prefix) error {
+	addr = addr.Unmap()
+	for _, network := range allowed {
+		if network.Contains(addr) {
+			return nil
+		}
+	}
+
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() || addr.IsUnspecified() || sharedAddressSpace.Contains(addr) {
+		return fmt.Errorf("%w: %s", ErrAddressNotAllowed, addr)
+	}
+	return nil
+}
+
+// newDialer checks the address every connection is made to once it is resolved, so a host resolving to another
+// address after the subscription was validated can't reach the bank's own networks either
+func newDialer(timeout time.Duration, allowed []netip.Prefix) *net.Dialer {
+	return &net.Dialer{
+		Timeout: timeout,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return fmt.Errorf("%w: %s", ErrAddressNotAllowed, address)
+			}
+			return checkAddress(addrPort.Addr(), allowed)
+		},
+	}
+}
@@ -0,0 +1,243 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	commonutils "github.com/primarybank/common/utils"
+	"github.com/primarybank/config"
+	db "github.com/primarybank/db/sqlc"
+	"github.com/primarybank/events"
+	"github.com/primarybank/mfa"
+)
+
+// defaults for the dispatcher settings left at zero
+const (
+	defaultBatchSize      = 20
+	defaultTimeout        = 10 * time.Second
+	defaultMaxAttempts    = 10
+	defaultRetryBaseDelay = 30 * time.Second
+	defaultRetryMaxDelay  = 6 * time.Hour
+)
+
+// DispatchResult counts what became of the deliveries of a dispatch
+type DispatchResult struct {
+	Succeeded int
+	Retried   int
+	Dead      int
+}
+
+// Dispatcher posts due webhook deliveries to their subscriptions, signed with the subscription secret
+type Dispatcher struct {
+	store          db.Store
+	cipher         *mfa.Cipher
+	client         *http.Client
+	batchSize      int32
+	timeout        time.Duration
+	maxAttempts    int32
+	retryBaseDelay time.Duration
+	retryMaxDelay  time.Duration
+}
+
+func NewDispatcher(store db.Store, cipher *mfa.Cipher, cfg config.Config) (*Dispatcher, error) {
+	allowedNetworks, err := parseAllowedNetworks(cfg.WebhookAllowedNetworks)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Dispatcher{
+		store:          store,
+		cipher:         cipher,
+		batchSize:      cfg.WebhookBatchSize,
+		timeout:        cfg.WebhookTimeout,
+		maxAttempts:    cfg.WebhookMaxAttempts,
+		retryBaseDelay: cfg.WebhookRetryBaseDelay,
+		retryMaxDelay:  cfg.WebhookRetryMaxDelay,
+	}
+
+	if d.batchSize <= 0 {
+		d.batchSize = defaultBatchSize
+	}
+	if d.timeout <= 0 {
+		d.timeout = defaultTimeout
+	}
+	if d.maxAttempts <= 0 {
+		d.maxAttempts = defaultMaxAttempts
+	}
+	if d.retryBaseDelay <= 0 {
+		d.retryBaseDelay = defaultRetryBaseDelay
+	}
+	if d.retryMaxDelay <= 0 {
+		d.retryMaxDelay = defaultRetryMaxDelay
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// webhooks are delivered straight to their receivers, never through a proxy that would dial for us
+	transport.Proxy = nil
+	transport.DialContext = newDialer(d.timeout, allowedNetworks).DialContext
+
+	d.client = &http.Client{
+		Timeout:   d.timeout,
+		Transport: transport,
+		// a redirect is a failed delivery, receivers must be reachable at the url they subscribed with
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	return d, nil
+}
+
+// DispatchOnce claims a batch of due deliveries and attempts them side by side.
+// A delivery whose outcome cannot be recorded is attempted again once its claim runs out.
+func (d *Dispatcher) DispatchOnce(ctx context.Context) (DispatchResult, error) {
+	var result DispatchResult
+
+	deliveries, err := d.store.ClaimWebhookDeliveries(ctx, db.ClaimWebhookDeliveriesParams{
+		LeaseUntil: time.Now().Add(d.timeout + time.Minute),
+		LimitCount: d.batchSize,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func(delivery db.WebhookDelivery) {
+			defer wg.Done()
+
+			updated, err := d.attempt(ctx, delivery)
+			if err != nil {
+				log.Printf("cannot attempt webhook delivery %d: %v", delivery.ID, err)
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch updated.Status {
+			case db.WebhookDeliverySucceeded:
+				result.Succeeded++
+			case db.WebhookDeliveryDead:
+				result.Dead++
+			default:
+				result.Retried++
+			}
+		}(delivery)
+	}
+	wg.Wait()
+
+	return result, nil
+}
+
+// Run dispatches due deliveries every interval until ctx is done
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		result, err := d.DispatchOnce(ctx)
+		if err != nil {
+			log.Println("cannot dispatch webhooks: ", err)
+			continue
+		}
+		if result.Dead > 0 {
+			log.Printf("%d webhook deliveries failed for good", result.Dead)
+		}
+	}
+}
+
+// attempt posts a delivery once and records how it went
+func (d *Dispatcher) attempt(ctx context.Context, delivery db.WebhookDelivery) (db.WebhookDelivery, error) {
+	subscription, err := d.store.GetWebhookSubscription(ctx, delivery.SubscriptionID)
+	if err != nil {
+		return delivery, err
+	}
+
+	row, err := d.store.GetOutboxEvent(ctx, delivery.EventID)
+	if err != nil {
+		return delivery, err
+	}
+
+	secret, err := d.cipher.Decrypt(subscription.SecretCiphertext)
+	if err != nil {
+		return delivery, fmt.Errorf("cannot decrypt secret of webhook %d: %w", subscription.ID, err)
+	}
+
+	body, err := json.Marshal(events.FromOutbox(row))
+	if err != nil {
+		return delivery, err
+	}
+
+	attemptedAt := time.Now()
+	status, postErr := d.post(ctx, subscription.Url, delivery, row, string(secret), body, attemptedAt)
+
+	args := db.RecordWebhookAttemptTxParams{
+		DeliveryID:    delivery.ID,
+		Duration:      time.Since(attemptedAt),
+		AttemptedAt:   attemptedAt,
+		Status:        db.WebhookDeliverySucceeded,
+		NextAttemptAt: attemptedAt,
+	}
+	if status != 0 {
+		args.ResponseStatus = pgtype.Int4{Int32: int32(status), Valid: true}
+	}
+
+	if postErr != nil {
+		args.Error = postErr.Error()
+
+		attempts := delivery.Attempts + 1
+		if attempts >= d.maxAttempts {
+			args.Status = db.WebhookDeliveryDead
+		} else {
+			args.Status = db.WebhookDeliveryPending
+			args.NextAttemptAt = attemptedAt.Add(commonutils.Backoff(d.retryBaseDelay, d.retryMaxDelay, attempts))
+		}
+	}
+
+	return d.store.RecordWebhookAttemptTx(ctx, args)
+}
+
+// post returns the response status, zero when there was no response, and an error unless it is 2xx
+func (d *Dispatcher) post(ctx context.Context, url string, delivery db.WebhookDelivery, event db.OutboxEvent,
+	secret string, body []byte, timestamp time.Time) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(DeliveryIDHeader, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(events.EventIDHeader, strconv.FormatInt(event.ID, 10))
+	req.Header.Set(events.EventTypeHeader, event.EventType)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp.Unix(), 10))
+	req.Header.Set(SignatureHeader, Sign(secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// drain the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,292 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/primarybank/config"
+	"github.com/primarybank/db/mocks"
+	db "github.com/primarybank/db/sqlc"
+	"github.com/primarybank/events"
+	"github.com/primarybank/mfa"
+	"github.com/stretchr/testify/require"
+)
+
+const testEncryptionKey = "9f2c4e6a8b0d1f3e5a7c9b1d3f5e7a9c0b2d4f6e8a1c3e5b7d9f0a2c4e6b8d1f"
+
+// receiver is an httptest server standing in for a partner, it verifies every delivery like a partner should
+type receiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	status   int
+	received []events.Event
+	errs     []error
+}
+
+func newReceiver(t *testing.T, secret string) *receiver {
+	r := &receiver{status: http.StatusOK}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		body, err := io.ReadAll(req.Body)
+		if err == nil {
+			err = VerifySignature(secret, req.Header.Get(TimestampHeader), req.Header.Get(SignatureHeader), body, time.Minute, time.Now())
+		}
+		if err != nil {
+			r.errs = append(r.errs, err)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var event events.Event
+		if err := json.Unmarshal(body, &event); err != nil {
+			r.errs = append(r.errs, err)
+		}
+		r.received = append(r.received, event)
+		w.WriteHeader(r.status)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func newTestDispatcher(t *testing.T, store *mocks.MockStore, url string, secret string) *Dispatcher {
+	// the receivers listen on loopback
+	return newConfiguredTestDispatcher(t, store, url, secret, "127.0.0.0/8")
+}
+
+func newConfiguredTestDispatcher(t *testing.T, store *mocks.MockStore, url string, secret string, allowedNetworks string) *Dispatcher {
+	cipher, err := mfa.NewCipher(testEncryptionKey)
+	require.NoError(t, err)
+
+	ciphertext, err := cipher.Encrypt([]byte(secret))
+	require.NoError(t, err)
+
+	store.EXPECT().
+		GetWebhookSubscription(gomock.Any(), int64(1)).
+		Return(db.WebhookSubscription{ID: 1, Username: "alice", Url: url, SecretCiphertext: ciphertext}, nil).
+		AnyTimes()
+	store.EXPECT().
+		GetOutboxEvent(gomock.Any(), int64(7)).
+		Return(db.OutboxEvent{ID: 7, EventType: db.EventTransferCompleted, AggregateType: db.AggregateTransfer, AggregateID: "3", Payload: []byte(`{"id":3}`)}, nil).
+		AnyTimes()
+
+	dispatcher, err := NewDispatcher(store, cipher, config.Config{
+		WebhookMaxAttempts:     3,
+		WebhookRetryBaseDelay:  time.Minute,
+		WebhookRetryMaxDelay:   time.Hour,
+		WebhookAllowedNetworks: allowedNetworks,
+	})
+	require.NoError(t, err)
+	return dispatcher
+}
+
+// expectAttempt captures the one attempt the dispatcher records, to be checked once DispatchOnce returned
+func expectAttempt(store *mocks.MockStore) *db.RecordWebhookAttemptTxParams {
+	recorded := &db.RecordWebhookAttemptTxParams{}
+	store.EXPECT().
+		RecordWebhookAttemptTx(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ any, args db.RecordWebhookAttemptTxParams) (db.WebhookDelivery, error) {
+			*recorded = args
+			return db.WebhookDelivery{ID: args.DeliveryID, Status: args.Status}, nil
+		}).
+		Times(1)
+	return recorded
+}
+
+func TestDispatchOnce(t *testing.T) {
+	testCases := []struct {
+		name           string
+		responseStatus int
+		attempts       int32
+		check          func(t *testing.T, args db.RecordWebhookAttemptTxParams, result DispatchResult)
+	}{
+		{
+			name:           "Delivered",
+			responseStatus: http.StatusNoContent,
+			check: func(t *testing.T, args db.RecordWebhookAttemptTxParams, result DispatchResult) {
+				require.Equal(t, db.WebhookDeliverySucceeded, args.Status)
+				require.Equal(t, int32(http.StatusNoContent), args.ResponseStatus.Int32)
+				require.Empty(t, args.Error)
+				require.Equal(t, 1, result.Succeeded)
+			},
+		},
+		{
+			name:           "Retried With Backoff",
+			responseStatus: http.StatusInternalServerError,
+			attempts:       1,
+			check: func(t *testing.T, args db.RecordWebhookAttemptTxParams, result DispatchResult) {
+				require.Equal(t, db.WebhookDeliveryPending, args.Status)
+				require.Equal(t, int32(http.StatusInternalServerError), args.ResponseStatus.Int32)
+				require.Contains(t, args.Error, "500")
+				// second failure, twice the base delay
+				require.WithinDuration(t, args.AttemptedAt.Add(2*time.Minute), args.NextAttemptAt, time.Second)
+				require.Equal(t, 1, result.Retried)
+			},
+		},
+		{
+			name:           "Dead After Last Attempt",
+			responseStatus: http.StatusBadGateway,
+			attempts:       2,
+			check: func(t *testing.T, args db.RecordWebhookAttemptTxParams, result DispatchResult) {
+				require.Equal(t, db.WebhookDeliveryDead, args.Status)
+				require.Equal(t, 1, result.Dead)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			secret := "whsec_test"
+			rcv := newReceiver(t, secret)
+			rcv.status = tc.responseStatus
+
+			store := mocks.NewMockStore(ctrl)
+			dispatcher := newTestDispatcher(t, store, rcv.URL, secret)
+
+			store.EXPECT().
+				ClaimWebhookDeliveries(gomock.Any(), gomock.Any()).
+				Return([]db.WebhookDelivery{{ID: 5, SubscriptionID: 1, EventID: 7, Status: db.WebhookDeliveryPending, Attempts: tc.attempts}}, nil).
+				Times(1)
+
+			recorded := expectAttempt(store)
+
+			result, err := dispatcher.DispatchOnce(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, int64(5), recorded.DeliveryID)
+			tc.check(t, *recorded, result)
+
+			require.Empty(t, rcv.errs)
+			require.Len(t, rcv.received, 1)
+			require.Equal(t, int64(7), rcv.received[0].ID)
+			require.Equal(t, db.EventTransferCompleted, rcv.received[0].Type)
+		})
+	}
+}
+
+func TestDispatchOnceUnreachable(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	rcv := newReceiver(t, "whsec_test")
+	url := rcv.URL
+	rcv.Close()
+
+	store := mocks.NewMockStore(ctrl)
+	dispatcher := newTestDispatcher(t, store, url, "whsec_test")
+
+	store.EXPECT().
+		ClaimWebhookDeliveries(gomock.Any(), gomock.Any()).
+		Return([]db.WebhookDelivery{{ID: 5, SubscriptionID: 1, EventID: 7}}, nil).
+		Times(1)
+	recorded := expectAttempt(store)
+
+	result, err := dispatcher.DispatchOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, result.Retried)
+	require.False(t, recorded.ResponseStatus.Valid)
+	require.NotEmpty(t, recorded.Error)
+	require.Equal(t, db.WebhookDeliveryPending, recorded.Status)
+}
+
+func TestDispatchOnceRedirect(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	target := newReceiver(t, "whsec_test")
+	redirect := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusFound))
+	defer redirect.Close()
+
+	store := mocks.NewMockStore(ctrl)
+	dispatcher := newTestDispatcher(t, store, redirect.URL, "whsec_test")
+
+	store.EXPECT().
+		ClaimWebhookDeliveries(gomock.Any(), gomock.Any()).
+		Return([]db.WebhookDelivery{{ID: 5, SubscriptionID: 1, EventID: 7}}, nil).
+		Times(1)
+	recorded := expectAttempt(store)
+
+	_, err := dispatcher.DispatchOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, int32(http.StatusFound), recorded.ResponseStatus.Int32)
+	require.Empty(t, target.received)
+}
+
+func TestDispatchOnceBlockedAddress(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	rcv := newReceiver(t, "whsec_test")
+
+	store := mocks.NewMockStore(ctrl)
+	dispatcher := newConfiguredTestDispatcher(t, store, rcv.URL, "whsec_test", "")
+
+	store.EXPECT().
+		ClaimWebhookDeliveries(gomock.Any(), gomock.Any()).
+		Return([]db.WebhookDelivery{{ID: 5, SubscriptionID: 1, EventID: 7}}, nil).
+		Times(1)
+	recorded := expectAttempt(store)
+
+	// the receiver listens on loopback, which webhooks may not reach
+	result, err := dispatcher.DispatchOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, result.Retried)
+	require.False(t, recorded.ResponseStatus.Valid)
+	require.Contains(t, recorded.Error, ErrAddressNotAllowed.Error())
+	require.Empty(t, rcv.received)
+}
+
+func TestCheckAddress(t *testing.T) {
+	allowed, err := parseAllowedNetworks("10.1.0.0/16, fd00:1::/32")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		address string
+		allowed bool
+	}{
+		{address: "93.184.216.34", allowed: true},
+		{address: "2606:2800:220:1:248:1893:25c8:1946", allowed: true},
+		{address: "127.0.0.1"},
+		{address: "::1"},
+		{address: "::ffff:127.0.0.1"},
+		{address: "10.0.0.1"},
+		{address: "172.16.5.4"},
+		{address: "192.168.1.1"},
+		{address: "169.254.169.254"},
+		{address: "fe80::1"},
+		{address: "fc00::1"},
+		{address: "100.64.0.1"},
+		{address: "0.0.0.0"},
+		{address: "224.0.0.1"},
+		// allowed networks are exceptions
+		{address: "10.1.2.3", allowed: true},
+		{address: "fd00:1::5", allowed: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.address, func(t *testing.T) {
+			err := checkAddress(netip.MustParseAddr(tc.address), allowed)
+			if tc.allowed {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, ErrAddressNotAllowed)
+			}
+		})
+	}
+}
+
+func TestParseAllowedNetworksInvalid(t *testing.T) {
+	_, err := parseAllowedNetworks("10.0.0.0/8,localhost")
+	require.ErrorContains(t, err, "localhost")
+}
@@ -0,0 +1,86 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+
+	db "github.com/primarybank/db/sqlc"
+	"github.com/primarybank/events"
+)
+
+// Enqueuer is the recorder that turns domain events into deliveries to the webhooks subscribed to them.
+// An event concerns the users it is about: the owners of both accounts of a transfer, the owner of an account,
+// or the user itself.
+// Deliveries reference the outbox row of their event and are written in the transaction marking it published.
+// The relay only leases an event while it publishes it, so an event whose lease ran out is recorded again:
+// a subscription gets a single delivery per event all the same, the second one is dropped by its unique
+// (subscription_id, event_id) with ON CONFLICT DO NOTHING. Subscribers must still expect an event more than once,
+// a delivery can reach them again when its own attempt fails after they received it.
+type Enqueuer struct{}
+
+func NewEnqueuer() *Enqueuer {
+	return &Enqueuer{}
+}
+
+func (e *Enqueuer) Record(ctx context.Context, queries db.Querier, event events.Event) error {
+	usernames, err := e.concernedUsers(ctx, queries, event)
+	if err != nil {
+		return err
+	}
+	if len(usernames) == 0 {
+		return nil
+	}
+
+	_, err = queries.EnqueueWebhookDeliveries(ctx, db.EnqueueWebhookDeliveriesParams{
+		EventID:   event.ID,
+		EventType: event.Type,
+		Usernames: usernames,
+	})
+	return err
+}
+
+func (e *Enqueuer) concernedUsers(ctx context.Context, queries db.Querier, event events.Event) ([]string, error) {
+	switch event.AggregateType {
+	case db.AggregateUser:
+		return []string{event.AggregateID}, nil
+	case db.AggregateAccount:
+		accountID, err := strconv.ParseInt(event.AggregateID, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid account id of event %d: %w", event.ID, err)
+		}
+		return e.accountOwners(ctx, queries, accountID)
+	case db.AggregateTransfer:
+		var transfer db.Transfer
+		if err := json.Unmarshal(event.Payload, &transfer); err != nil {
+			return nil, fmt.Errorf("cannot decode transfer of event %d: %w", event.ID, err)
+		}
+		return e.accountOwners(ctx, queries, transfer.FromAccountID, transfer.ToAccountID)
+	default:
+		return nil, nil
+	}
+}
+
+// accountOwners skips accounts deleted since the event, there is nobody left to notify about them
+func (e *Enqueuer) accountOwners(ctx context.Context, queries db.Querier, accountIDs ...int64) ([]string, error) {
+	var owners []string
+
+	for _, accountID := range accountIDs {
+		account, err := queries.GetAccount(ctx, accountID)
+		if errors.Is(err, db.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if !slices.Contains(owners, account.Owner) {
+			owners = append(owners, account.Owner)
+		}
+	}
+
+	return owners, nil
+}
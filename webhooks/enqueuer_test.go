@@ -0,0 +1,89 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/primarybank/db/mocks"
+	db "github.com/primarybank/db/sqlc"
+	"github.com/primarybank/events"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnqueuer(t *testing.T) {
+	transfer, err := json.Marshal(db.Transfer{ID: 3, FromAccountID: 1, ToAccountID: 2, Amount: 10})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name       string
+		event      events.Event
+		buildStubs func(store *mocks.MockStore)
+		usernames  []string
+	}{
+		{
+			name:  "Transfer",
+			event: events.Event{ID: 9, Type: db.EventTransferCompleted, AggregateType: db.AggregateTransfer, AggregateID: "3", Payload: transfer},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), int64(1)).Return(db.Account{ID: 1, Owner: "alice"}, nil)
+				store.EXPECT().GetAccount(gomock.Any(), int64(2)).Return(db.Account{ID: 2, Owner: "bob"}, nil)
+			},
+			usernames: []string{"alice", "bob"},
+		},
+		{
+			name:  "Transfer Between Own Accounts",
+			event: events.Event{ID: 9, Type: db.EventTransferCompleted, AggregateType: db.AggregateTransfer, AggregateID: "3", Payload: transfer},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Return(db.Account{Owner: "alice"}, nil).Times(2)
+			},
+			usernames: []string{"alice"},
+		},
+		{
+			name:  "Account",
+			event: events.Event{ID: 9, Type: db.EventWithdrawalCompleted, AggregateType: db.AggregateAccount, AggregateID: "1"},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), int64(1)).Return(db.Account{ID: 1, Owner: "alice"}, nil)
+			},
+			usernames: []string{"alice"},
+		},
+		{
+			name:       "User",
+			event:      events.Event{ID: 9, Type: db.EventUserRegistered, AggregateType: db.AggregateUser, AggregateID: "carol"},
+			buildStubs: func(store *mocks.MockStore) {},
+			usernames:  []string{"carol"},
+		},
+		{
+			name:  "Deleted Account",
+			event: events.Event{ID: 9, Type: db.EventWithdrawalCompleted, AggregateType: db.AggregateAccount, AggregateID: "1"},
+			buildStubs: func(store *mocks.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), int64(1)).Return(db.Account{}, db.ErrNotFound)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mocks.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			if tc.usernames == nil {
+				store.EXPECT().EnqueueWebhookDeliveries(gomock.Any(), gomock.Any()).Times(0)
+			} else {
+				store.EXPECT().
+					EnqueueWebhookDeliveries(gomock.Any(), db.EnqueueWebhookDeliveriesParams{
+						EventID:   tc.event.ID,
+						EventType: tc.event.Type,
+						Usernames: tc.usernames,
+					}).
+					Return([]db.WebhookDelivery{}, nil).
+					Times(1)
+			}
+
+			require.NoError(t, NewEnqueuer().Record(context.Background(), store, tc.event))
+		})
+	}
+}
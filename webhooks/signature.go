@@ -0,0 +1,57 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// headers sent along with every webhook delivery, next to the event id and type headers of the events package
+const (
+	DeliveryIDHeader = "X-Webhook-ID"
+	TimestampHeader  = "X-Webhook-Timestamp"
+	SignatureHeader  = "X-Webhook-Signature"
+)
+
+// signatures are sent as sha256=<hex>, the scheme leaves room for another algorithm later
+const signaturePrefix = "sha256="
+
+var (
+	ErrInvalidSignature = errors.New("webhook signature does not match")
+	ErrStaleTimestamp   = errors.New("webhook timestamp is outside the tolerance")
+)
+
+// Sign returns the signature header of a delivery: the HMAC-SHA256 with the subscription secret of
+// the unix timestamp, a dot and the body. Covering the timestamp keeps old deliveries from being replayed.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature checks the timestamp and signature headers of a delivery the way receivers should,
+// rejecting deliveries signed more than tolerance away from now
+func VerifySignature(secret string, timestampHeader string, signatureHeader string, body []byte, tolerance time.Duration, now time.Time) error {
+	unix, err := strconv.ParseInt(timestampHeader, 10, 64)
+	if err != nil {
+		return ErrStaleTimestamp
+	}
+
+	timestamp := time.Unix(unix, 0)
+	if timestamp.Before(now.Add(-tolerance)) || timestamp.After(now.Add(tolerance)) {
+		return ErrStaleTimestamp
+	}
+
+	if !strings.HasPrefix(signatureHeader, signaturePrefix) ||
+		!hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signatureHeader)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package webhooks
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignature(t *testing.T) {
+	secret := "whsec_test"
+	body := []byte(`{"id":1}`)
+	now := time.Now()
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	signature := Sign(secret, now, body)
+	require.Regexp(t, "^sha256=[0-9a-f]{64}$", signature)
+	require.NoError(t, VerifySignature(secret, timestamp, signature, body, 5*time.Minute, now))
+
+	require.ErrorIs(t, VerifySignature("whsec_other", timestamp, signature, body, 5*time.Minute, now), ErrInvalidSignature)
+	require.ErrorIs(t, VerifySignature(secret, timestamp, signature, []byte(`{"id":2}`), 5*time.Minute, now), ErrInvalidSignature)
+	require.ErrorIs(t, VerifySignature(secret, timestamp, signature[len(signaturePrefix):], body, 5*time.Minute, now), ErrInvalidSignature)
+
+	// the timestamp is signed, an old delivery cannot be replayed with a fresh one
+	later := now.Add(time.Hour)
+	require.ErrorIs(t, VerifySignature(secret, timestamp, signature, body, 5*time.Minute, later), ErrStaleTimestamp)
+	require.ErrorIs(t, VerifySignature(secret, strconv.FormatInt(later.Unix(), 10), signature, body, 5*time.Minute, later), ErrInvalidSignature)
+	require.ErrorIs(t, VerifySignature(secret, "yesterday", signature, body, 5*time.Minute, now), ErrStaleTimestamp)
+}